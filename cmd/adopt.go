@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/fileid"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/jy-eggroll/flk/internal/trace"
+	"github.com/spf13/cobra"
+)
+
+var adoptCmd = &cobra.Command{
+	Use:   "adopt <路径>...",
+	Short: "将已存在的符号链接登记到存储",
+	Long: `将手动创建的符号链接按其当前指向登记到存储，之后即可由 flk 检查和修复。
+路径是目录（而不是指向目录的符号链接）时递归扫描其中尚未记录的符号链接，逐个询问是否收编；
+指定 --hardlinks 时还会按文件标识找出目录中互为硬链接的文件，每组作为一条硬链接记录，路径排序最前的文件作为主文件`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: RunAdopt,
+}
+
+// adoptHardlinks 扫描目录时同时收编互为硬链接的文件
+var adoptHardlinks bool
+
+func init() {
+	rootCmd.AddCommand(adoptCmd)
+	adoptCmd.Flags().StringVarP(&createDevice, "device", "d", "all", "设备名称，用于后续设备过滤")
+	adoptCmd.Flags().BoolVar(&adoptHardlinks, "hardlinks", false, "扫描目录时同时按文件标识收编互为硬链接的文件")
+}
+
+// RunAdopt 登记参数中的每个符号链接，参数为目录时扫描其中未记录的链接
+func RunAdopt(cmd *cobra.Command, args []string) error {
+	format := output.OutputFormat(outputFormat)
+	failed := 0
+	for _, arg := range args {
+		if dir, ok := adoptScanDir(arg); ok {
+			n, err := adoptDir(dir)
+			failed += n
+			if err != nil {
+				logger.Error(err.Error())
+				failed++
+			}
+			continue
+		}
+		var result output.CreateResult
+		if real, err := adoptSymlink(arg); err != nil {
+			failed++
+			result = output.CreateResult{Success: false, Type: "符号链接", Error: err.Error()}
+		} else {
+			result = output.CreateResult{Success: true, Type: "符号链接", Message: "已收编 " + arg + " -> " + real}
+		}
+		output.PrintCreateResult(format, result)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个路径收编失败", failed)
+	}
+	return nil
+}
+
+// adoptScanDir 判断参数是否为需要扫描的目录（不跟随符号链接），是时返回其绝对路径
+func adoptScanDir(path string) (string, bool) {
+	normalized, err := pathutil.NormalizePath(path)
+	if err != nil {
+		return "", false
+	}
+	abs, err := pathutil.ToAbsolute(normalized)
+	if err != nil {
+		return "", false
+	}
+	info, err := trace.Lstat(abs)
+	if err != nil || !info.IsDir() {
+		return "", false
+	}
+	return abs, true
+}
+
+// adoptCandidate 扫描目录找到的一个未记录的链接：符号链接只有 link，硬链接组中 link 为主文件、secos 为其余文件
+type adoptCandidate struct {
+	linkType string
+	link     string
+	target   string
+	secos    []string
+}
+
+// adoptDir 扫描目录中未记录的链接并逐个询问是否收编，返回收编失败的数量
+func adoptDir(dir string) (int, error) {
+	candidates, err := scanAdoptCandidates(dir)
+	if err != nil {
+		return 0, err
+	}
+	format := output.OutputFormat(outputFormat)
+	if len(candidates) == 0 {
+		output.PrintCreateResult(format, output.CreateResult{Success: true, Type: "扫描", Message: dir + " 中没有未记录的链接"})
+		return 0, nil
+	}
+	logger.Info(fmt.Sprintf("在 %s 中找到 %d 个未记录的链接", dir, len(candidates)))
+
+	failed := 0
+	for _, c := range candidates {
+		var result output.CreateResult
+		switch c.linkType {
+		case "symlink":
+			if !confirm("adopt", "收编 "+c.link+" -> "+c.target+"？", false) {
+				continue
+			}
+			result = output.CreateResult{Success: true, Type: "符号链接", Message: "已收编 " + c.link + " -> " + c.target}
+			if err := recordSymlink(c.target, c.link); err != nil {
+				result = output.CreateResult{Success: false, Type: "符号链接", Error: "写入存储失败 " + err.Error()}
+			}
+		case "hardlink":
+			if !confirm("adopt", "收编硬链接 "+c.link+" <- "+strings.Join(c.secos, ", ")+"？", false) {
+				continue
+			}
+			result = output.CreateResult{Success: true, Type: "硬链接", Message: fmt.Sprintf("已收编 %s 及 %d 个次要文件", c.link, len(c.secos))}
+			if err := recordAdoptedHardlink(c.link, c.secos); err != nil {
+				result = output.CreateResult{Success: false, Type: "硬链接", Error: "写入存储失败 " + err.Error()}
+			}
+		}
+		if !result.Success {
+			failed++
+		}
+		output.PrintCreateResult(format, result)
+	}
+	return failed, nil
+}
+
+// scanAdoptCandidates 递归扫描目录（不进入符号链接指向的目录，跳过 flk 的数据目录），
+// 找出当前平台上没有记录的符号链接，以及指定 --hardlinks 时互为硬链接且都没有记录的文件组
+func scanAdoptCandidates(dir string) ([]adoptCandidate, error) {
+	mgr, err := store.Current()
+	if err != nil {
+		return nil, err
+	}
+	tracked := make(map[string]bool)
+	for _, l := range recordLinks(mgr, "") {
+		tracked[l.Link] = true
+		if l.Type == "hardlink" {
+			tracked[l.Target] = true
+		}
+	}
+	dataDirs := flkDataDirs()
+
+	var candidates []adoptCandidate
+	groups := make(map[string][]string) // 文件标识 -> 具有该标识的文件
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			logger.Warn("无法读取 " + path + " " + err.Error())
+			return nil
+		}
+		if d.IsDir() {
+			for _, dataDir := range dataDirs {
+				if within(path, dataDir) {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if tracked[path] {
+			return nil
+		}
+		if d.Type()&os.ModeSymlink != 0 {
+			target, err := trace.Readlink(path)
+			if err != nil {
+				return nil
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(path), target)
+			}
+			candidates = append(candidates, adoptCandidate{linkType: "symlink", link: path, target: filepath.Clean(target)})
+			return nil
+		}
+		if adoptHardlinks && d.Type().IsRegular() {
+			if id, err := fileid.Get(path); err == nil {
+				groups[id] = append(groups[id], path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, paths := range groups {
+		if len(paths) < 2 {
+			continue
+		}
+		slices.Sort(paths)
+		candidates = append(candidates, adoptCandidate{linkType: "hardlink", link: paths[0], target: paths[0], secos: paths[1:]})
+	}
+	slices.SortFunc(candidates, func(a, b adoptCandidate) int { return strings.Compare(a.link, b.link) })
+	return candidates, nil
+}
+
+// recordAdoptedHardlink 将一组已存在的硬链接作为一条记录写入存储
+func recordAdoptedHardlink(prim string, secos []string) error {
+	fields := map[string]string{
+		"prim":          prim,
+		store.SourceKey: store.SourceCLI,
+	}
+	for i, seco := range secos {
+		fields[store.SecoKeyAt(i)] = seco
+	}
+	if id, err := fileid.Get(prim); err == nil {
+		fields[store.InodeKey] = id
+	}
+	return saveRecord("hardlink", fields)
+}
+
+// adoptSymlink 读取符号链接的目标并写入存储，返回目标的绝对路径
+func adoptSymlink(path string) (string, error) {
+	normalized, err := pathutil.NormalizePath(path)
+	if err != nil {
+		return "", err
+	}
+	fake, err := pathutil.ToAbsolute(normalized)
+	if err != nil {
+		return "", err
+	}
+	info, err := trace.Lstat(fake)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return "", errors.New(path + " 不是符号链接")
+	}
+	target, err := trace.Readlink(fake)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(fake), target)
+	}
+	target = filepath.Clean(target)
+
+	logger.Info("收编符号链接 real=" + target + ", fake=" + fake)
+	if err := recordSymlink(target, fake); err != nil {
+		return "", err
+	}
+	return target, nil
+}
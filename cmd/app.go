@@ -0,0 +1,378 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/audit"
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/create/hardlink"
+	"github.com/jy-eggroll/flk/internal/create/junction"
+	"github.com/jy-eggroll/flk/internal/create/symlink"
+	"github.com/jy-eggroll/flk/internal/create/template"
+	"github.com/jy-eggroll/flk/internal/fileid"
+	"github.com/jy-eggroll/flk/internal/fileperm"
+	"github.com/jy-eggroll/flk/internal/interference"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/manifest"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/jy-eggroll/flk/internal/trace"
+	"github.com/spf13/cobra"
+)
+
+var appCmd = &cobra.Command{
+	Use:   "app",
+	Short: "按应用整体添加或移除一组链接",
+	Long:  "将一个应用的所有链接作为整体管理：添加时把链接位置上已有的文件移入隔离区，移除时删除链接并恢复原文件，便于试用他人的配置",
+}
+
+var appAddCmd = &cobra.Command{
+	Use:   "add <应用名>",
+	Short: "按清单创建应用的全部链接",
+	Long:  "按清单创建应用的全部链接，清单中存在 bundle 为该应用名的记录时只使用这些记录；任一链接失败时撤销已完成的部分",
+	Args:  cobra.ExactArgs(1),
+	RunE:  RunAppAdd,
+}
+
+var appRemoveCmd = &cobra.Command{
+	Use:   "remove <应用名>",
+	Short: "移除应用的全部链接并恢复原文件",
+	Args:  cobra.ExactArgs(1),
+	RunE:  RunAppRemove,
+}
+
+var (
+	appManifest string
+	appDevice   string
+)
+
+func init() {
+	rootCmd.AddCommand(appCmd)
+	appCmd.AddCommand(appAddCmd)
+	appCmd.AddCommand(appRemoveCmd)
+	appAddCmd.Flags().StringVarP(&appManifest, "manifest", "m", "", "清单文件路径")
+	appAddCmd.Flags().StringVarP(&appDevice, "device", "d", "all", "设备名称，仅使用适用于该设备的记录")
+	appAddCmd.MarkFlagRequired("manifest")
+}
+
+// appSource 返回应用链接在存储中的来源标记
+func appSource(name string) string {
+	return "app:" + name
+}
+
+// appName 从来源标记中取出应用名称，不是应用链接时返回空字符串
+func appName(source string) string {
+	name, ok := strings.CutPrefix(source, "app:")
+	if !ok {
+		return ""
+	}
+	return name
+}
+
+// appEntry 存储中属于某个应用的一条记录
+type appEntry struct {
+	device     string
+	linkType   string
+	parentPath string
+	entry      store.Entry
+}
+
+// findAppEntries 返回当前平台上来源为该应用的全部记录
+func findAppEntries(mgr *store.Manager, name string) []appEntry {
+	var found []appEntry
+	for device, deviceData := range mgr.Data[runtime.GOOS] {
+		for linkType, typeData := range deviceData {
+			for parentPath, entries := range typeData {
+				for _, entry := range entries {
+					if entry[store.SourceKey] == appSource(name) {
+						found = append(found, appEntry{device: device, linkType: linkType, parentPath: parentPath, entry: entry})
+					}
+				}
+			}
+		}
+	}
+	return found
+}
+
+// RunAppAdd 按依赖顺序创建应用的全部链接，任一失败时撤销已创建的链接
+func RunAppAdd(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	format := output.OutputFormat(outputFormat)
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	if len(findAppEntries(mgr, name)) > 0 {
+		return fmt.Errorf("应用 %s 已添加，请先执行 flk app remove %s", name, name)
+	}
+
+	m, err := manifest.LoadFromFile(appManifest)
+	if err != nil {
+		logger.Error("读取清单失败 " + err.Error())
+		return err
+	}
+	applyManifestRules(m)
+	records := appRecords(m, name)
+	if len(records) == 0 {
+		return fmt.Errorf("清单中没有适用于设备 %s 的记录", appDevice)
+	}
+	levels, err := manifest.Levels(records)
+	if err != nil {
+		return err
+	}
+
+	quarantineDir := filepath.Join(filepath.Dir(mustNormalize(store.StorePath)), "quarantine", name)
+	var results []output.ApplyResult
+	var added []appEntry
+	var addErr error
+	for _, level := range levels {
+		for _, i := range level {
+			rec := records[i]
+			result := output.ApplyResult{Name: rec.Name, Action: "add", Type: rec.Type, Link: rec.Link()}
+			fields, err := addAppLink(rec, name, quarantineDir, len(added))
+			if err != nil {
+				result.Error = err.Error()
+				results = append(results, result)
+				addErr = err
+				break
+			}
+			result.Success = true
+			results = append(results, result)
+			parentPath := filepath.Dir(rec.Link())
+			mgr.AddRecord(appDevice, rec.Type, parentPath, fields)
+			foldedLink, _ := pathutil.FoldHome(rec.Link())
+			_, entry, _ := mgr.FindEntry(runtime.GOOS, appDevice, rec.Type, store.Entry{linkKey(rec.Type): foldedLink, store.SourceKey: appSource(name)})
+			folded, _ := pathutil.FoldHome(parentPath)
+			added = append(added, appEntry{device: appDevice, linkType: rec.Type, parentPath: folded, entry: entry})
+		}
+		if addErr != nil {
+			break
+		}
+	}
+
+	if addErr != nil {
+		// 整体撤销，使文件系统恢复原状
+		logger.Warn("应用 " + name + " 添加失败，正在撤销已创建的链接")
+		for i := len(added) - 1; i >= 0; i-- {
+			if err := removeAppEntry(mgr, added[i]); err != nil {
+				logger.Error("撤销失败 " + err.Error())
+			}
+		}
+	}
+
+	if err := output.PrintApplyResults(format, results); err != nil {
+		logger.Error("输出失败 " + err.Error())
+	}
+	if err := mgr.Save(store.StorePath); err != nil {
+		logger.Error("持久化失败 " + err.Error())
+		return err
+	}
+	return addErr
+}
+
+// RunAppRemove 删除应用的全部链接，恢复隔离区中的原文件并移除记录
+func RunAppRemove(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	format := output.OutputFormat(outputFormat)
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	entries := findAppEntries(mgr, name)
+	if len(entries) == 0 {
+		return fmt.Errorf("存储中没有属于应用 %s 的记录", name)
+	}
+
+	var results []output.ApplyResult
+	failed := 0
+	for _, e := range entries {
+		result := output.ApplyResult{Action: "remove", Type: e.linkType, Link: e.entry[linkKey(e.linkType)]}
+		if err := removeAppEntry(mgr, e); err != nil {
+			failed++
+			result.Error = err.Error()
+		} else {
+			result.Success = true
+		}
+		results = append(results, result)
+	}
+
+	if err := output.PrintApplyResults(format, results); err != nil {
+		logger.Error("输出失败 " + err.Error())
+	}
+	if err := mgr.Save(store.StorePath); err != nil {
+		logger.Error("持久化失败 " + err.Error())
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个链接移除失败", failed)
+	}
+	return nil
+}
+
+// appRecords 返回清单中属于该应用且适用于当前平台和设备的记录，清单未使用 bundle 区分应用时返回全部记录
+func appRecords(m *manifest.Manifest, name string) []manifest.Record {
+	var all, bundled []manifest.Record
+	for _, rec := range m.Records {
+		if !rec.Matches(runtime.GOOS, appDevice) {
+			continue
+		}
+		all = append(all, rec)
+		if rec.Bundle == name {
+			bundled = append(bundled, rec)
+		}
+	}
+	if len(bundled) > 0 {
+		return bundled
+	}
+	return all
+}
+
+// addAppLink 隔离链接位置上已有的文件后创建链接，返回应写入存储的字段
+func addAppLink(rec manifest.Record, name, quarantineDir string, seq int) (map[string]string, error) {
+	link := rec.Link()
+	fields := map[string]string{store.SourceKey: appSource(name)}
+
+	// 记录为创建链接而新建的最上层目录，移除时一并清理
+	if dir := firstMissingDir(filepath.Dir(link)); dir != "" {
+		fields[store.CreatedDirKey] = dir
+	}
+
+	if _, err := trace.Lstat(link); err == nil {
+		if err := os.MkdirAll(quarantineDir, 0755); err != nil {
+			return nil, err
+		}
+		quarantined := filepath.Join(quarantineDir, strconv.Itoa(seq)+"-"+filepath.Base(link))
+		if err := trace.Rename(link, quarantined); err != nil {
+			return nil, fmt.Errorf("无法将 %s 移入隔离区: %v", link, interference.Explain(link, err))
+		}
+		logger.Info("已将 " + link + " 移入隔离区 " + quarantined)
+		fields[store.QuarantineKey] = quarantined
+	}
+
+	var err error
+	switch rec.Type {
+	case "symlink":
+		err = symlink.CreateWithStyle(rec.Real, rec.Fake, false, rec.LinkStyle == config.LinkStyleAbsolute)
+		fields["real"], fields["fake"] = rec.Real, rec.Fake
+	case "junction":
+		err = junction.Create(rec.Real, rec.Fake, false)
+		fields["real"], fields["fake"] = rec.Real, rec.Fake
+	case "hardlink":
+		err = hardlink.Create(rec.Prim, rec.Seco, false)
+		fields["prim"], fields[store.SecoKey] = rec.Prim, rec.Seco
+		if id, idErr := fileid.Get(rec.Prim); idErr == nil {
+			fields[store.InodeKey] = id
+		}
+	case "template":
+		err = template.Create(rec.Real, rec.Fake, template.DeviceVars(appDevice), false)
+		fields["real"], fields["fake"] = rec.Real, rec.Fake
+	default:
+		err = fmt.Errorf("未知类型 %s", rec.Type)
+	}
+	if err == nil {
+		err = fileperm.Apply(link, rec.Mode)
+	}
+	if err == nil {
+		err = applyAttrib(rec)
+	}
+	if err != nil {
+		// 恢复本条记录隔离的文件和新建的目录
+		restoreAppLink(link, fields)
+		return nil, err
+	}
+	audit.Emit(audit.Event{Action: audit.ActionCreate, Type: rec.Type, Link: link, Target: rec.Target()})
+
+	if rec.Mode != "" {
+		fields[store.ModeKey] = rec.Mode
+	}
+	if rec.Frequency != "" {
+		fields[store.FrequencyKey] = rec.Frequency
+	}
+	if rec.Attrib != "" {
+		fields[store.AttribKey] = rec.Attrib
+	}
+	if rec.LinkStyle != "" {
+		fields[store.LinkStyleKey] = rec.LinkStyle
+	}
+	if rec.Validate != "" {
+		fields[store.ValidateKey] = rec.Validate
+	}
+	if rec.Note != "" {
+		fields[store.NoteKey] = rec.Note
+	}
+	return fields, nil
+}
+
+// removeAppEntry 删除应用的一条链接，恢复被隔离的原文件并从存储中移除记录
+func removeAppEntry(mgr *store.Manager, e appEntry) error {
+	link := mustNormalize(e.entry[linkKey(e.linkType)])
+	target := mustNormalize(e.entry[targetKey(e.linkType)])
+	if err := removeManagedLink(e.linkType, target, link, e.device); err != nil {
+		return err
+	}
+	audit.Emit(audit.Event{Action: audit.ActionDelete, Type: e.linkType, Link: link, Target: target})
+
+	fields := map[string]string{}
+	for k, v := range e.entry {
+		fields[k] = mustNormalize(v)
+	}
+	if err := restoreAppLink(link, fields); err != nil {
+		return err
+	}
+	mgr.RemoveMatchingEntry(runtime.GOOS, e.device, e.linkType, e.parentPath, store.Entry{linkKey(e.linkType): e.entry[linkKey(e.linkType)], store.SourceKey: e.entry[store.SourceKey]})
+	return nil
+}
+
+// restoreAppLink 将隔离区中的原文件移回链接位置，并删除为创建链接而新建的空目录
+func restoreAppLink(link string, fields map[string]string) error {
+	if quarantined := fields[store.QuarantineKey]; quarantined != "" {
+		if err := trace.Rename(quarantined, link); err != nil {
+			return fmt.Errorf("无法从隔离区恢复 %s: %v", link, err)
+		}
+		return nil
+	}
+	createdDir := fields[store.CreatedDirKey]
+	if createdDir == "" {
+		return nil
+	}
+	for dir := filepath.Dir(link); ; dir = filepath.Dir(dir) {
+		// 目录非空时 Remove 会失败，说明其中有其他文件，停止清理
+		if err := trace.Remove(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if dir == createdDir || dir == filepath.Dir(dir) {
+			return nil
+		}
+	}
+}
+
+// firstMissingDir 返回 dir 的祖先中最上层的不存在的目录，dir 已存在时返回空字符串
+func firstMissingDir(dir string) string {
+	missing := ""
+	for {
+		if _, err := trace.Lstat(dir); err == nil {
+			return missing
+		}
+		missing = dir
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return missing
+		}
+		dir = parent
+	}
+}
+
+// mustNormalize 展开路径中的 ~，失败时原样返回
+func mustNormalize(path string) string {
+	if normalized, err := pathutil.NormalizePath(path); err == nil {
+		return normalized
+	}
+	return path
+}
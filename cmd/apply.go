@@ -0,0 +1,592 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/jy-eggroll/flk/internal/audit"
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/create/hardlink"
+	"github.com/jy-eggroll/flk/internal/create/junction"
+	"github.com/jy-eggroll/flk/internal/create/symlink"
+	"github.com/jy-eggroll/flk/internal/create/template"
+	"github.com/jy-eggroll/flk/internal/elevate"
+	"github.com/jy-eggroll/flk/internal/fileattr"
+	"github.com/jy-eggroll/flk/internal/fileid"
+	"github.com/jy-eggroll/flk/internal/fileperm"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/manifest"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/jy-eggroll/flk/internal/trace"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "按清单或存储创建链接并同步存储",
+	Long: `读取链接清单，对比存储与文件系统后创建缺失的链接、修正失效的链接，并移除清单中已删除的记录；使用 --check 时仅报告差异。
+省略 --manifest 时按存储中当前平台的记录（所选设备和 all 下的记录）创建缺失或失效的链接，不修改存储，用于在新机器上克隆配置后一次性恢复全部链接`,
+	RunE: RunApply,
+	// 存在差异时返回的错误用于 CI 判定，不需要打印用法
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+	applyCmd.Flags().StringVarP(&applyManifest, "manifest", "m", "", "清单文件路径，省略时按存储中的记录创建链接")
+	applyCmd.Flags().BoolVar(&applyCheck, "check", false, "仅检测清单与当前环境的差异，存在差异时以非零状态退出")
+	applyCmd.Flags().StringVarP(&applyDevice, "device", "d", "all", "设备名称，仅应用适用于该设备的记录")
+	applyCmd.Flags().StringSliceVar(&applyOnly, "only", nil, "仅应用被选中的记录，可重复，如 bundle:nvim、tag:shell、path:~/.config/*、name:git")
+	applyCmd.Flags().StringSliceVar(&applySkip, "skip", nil, "跳过被选中的记录，语法同 --only")
+	applyCmd.Flags().IntVarP(&applyJobs, "jobs", "j", runtime.NumCPU(), "并发应用的最大记录数")
+}
+
+var (
+	applyManifest string
+	applyCheck    bool
+	applyDevice   string
+	applyOnly     []string
+	applySkip     []string
+	applyJobs     int
+)
+
+// applyStoreMu 保护并发应用时对存储的修改
+var applyStoreMu sync.Mutex
+
+// applyStep 应用计划中的一步，除展示用的 PlanItem 外还保存执行所需的信息
+type applyStep struct {
+	item       output.PlanItem
+	record     manifest.Record
+	linkValid  bool
+	parentPath string      // 已存在于存储中的记录所在的父路径
+	entry      store.Entry // 已存在于存储中的记录
+}
+
+// RunApply 计算并执行清单的应用计划
+func RunApply(cmd *cobra.Command, args []string) error {
+	if applyManifest == "" {
+		return applyStore()
+	}
+	m, err := manifest.LoadFromFile(applyManifest)
+	if err != nil {
+		logger.Error("读取清单失败 " + err.Error())
+		return err
+	}
+	applyManifestRules(m)
+	manifestPath, err := pathutil.NormalizePath(applyManifest)
+	if err != nil {
+		return err
+	}
+	manifestPath, err = filepath.Abs(manifestPath)
+	if err != nil {
+		return err
+	}
+	foldedManifest, _ := pathutil.FoldHome(manifestPath)
+	source := "apply:" + foldedManifest
+
+	only, err := parseSelectors(applyOnly)
+	if err != nil {
+		return err
+	}
+	skip, err := parseSelectors(applySkip)
+	if err != nil {
+		return err
+	}
+
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	steps := buildApplyPlan(mgr, m, source, applyDevice, only, skip)
+
+	var changes []applyStep
+	for _, step := range steps {
+		if step.item.Action != "none" {
+			changes = append(changes, step)
+		}
+	}
+
+	format := output.OutputFormat(outputFormat)
+	if len(changes) == 0 {
+		pterm.Info.Println("清单与当前环境一致，无需变更")
+		return nil
+	}
+	items := make([]output.PlanItem, 0, len(changes))
+	for _, step := range changes {
+		items = append(items, step.item)
+	}
+	if err := output.PrintPlan(format, items); err != nil {
+		logger.Error("输出失败 " + err.Error())
+	}
+
+	if applyCheck {
+		return fmt.Errorf("检测到 %d 处差异", len(changes))
+	}
+
+	results, err := runApplySteps(mgr, changes, source)
+	if err != nil {
+		return err
+	}
+	if err := output.PrintApplyResults(format, results); err != nil {
+		logger.Error("输出失败 " + err.Error())
+	}
+	if err := mgr.Save(store.StorePath); err != nil {
+		logger.Error("持久化失败 " + err.Error())
+		return err
+	}
+
+	failed := 0
+	for _, r := range results {
+		if !r.Success {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 项应用失败", failed)
+	}
+	return nil
+}
+
+// runApplySteps 按依赖分层执行变更，同一层内最多 applyJobs 项并发，依赖失败的记录不会执行
+func runApplySteps(mgr *store.Manager, changes []applyStep, source string) ([]output.ApplyResult, error) {
+	records := make([]manifest.Record, len(changes))
+	for i, step := range changes {
+		records[i] = step.record
+	}
+	levels, err := manifest.Levels(records)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := applyJobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	results := make([]output.ApplyResult, len(changes))
+	failedNames := make(map[string]bool)
+	for _, level := range levels {
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, jobs)
+		for _, i := range level {
+			step := changes[i]
+			results[i] = output.ApplyResult{
+				Name:   step.item.Name,
+				Action: step.item.Action,
+				Type:   step.item.Type,
+				Link:   step.item.Link,
+			}
+			if dep := failedDependency(step.record, failedNames); dep != "" {
+				results[i].Error = "依赖的记录 " + dep + " 应用失败，已跳过"
+				continue
+			}
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, step applyStep) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if err := executeApplyStep(mgr, step, source); err != nil {
+					results[i].Error = err.Error()
+				} else {
+					results[i].Success = true
+				}
+			}(i, step)
+		}
+		wg.Wait()
+		for _, i := range level {
+			if !results[i].Success && changes[i].record.Name != "" {
+				failedNames[changes[i].record.Name] = true
+			}
+		}
+	}
+	return results, nil
+}
+
+// failedDependency 返回记录依赖中第一个应用失败的记录名称
+func failedDependency(rec manifest.Record, failedNames map[string]bool) string {
+	for _, dep := range rec.DependsOn {
+		if failedNames[dep] {
+			return dep
+		}
+	}
+	return ""
+}
+
+// parseSelectors 解析 --only/--skip 传入的选择器
+func parseSelectors(raw []string) ([]manifest.Selector, error) {
+	selectors := make([]manifest.Selector, 0, len(raw))
+	for _, r := range raw {
+		sel, err := manifest.ParseSelector(r)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, sel)
+	}
+	return selectors, nil
+}
+
+// selectedByAny 判断记录是否被任一选择器选中
+func selectedByAny(rec manifest.Record, selectors []manifest.Selector) bool {
+	for _, sel := range selectors {
+		if rec.Selected(sel) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildApplyPlan 对比清单、存储和文件系统，得到每条记录需要执行的操作
+// 指定了 only 时只处理被选中的记录，且不会计划删除，因为已删除的记录无法再按分组或标签判断归属
+func buildApplyPlan(mgr *store.Manager, m *manifest.Manifest, source, device string, only, skip []manifest.Selector) []applyStep {
+	platform := runtime.GOOS
+	var steps []applyStep
+
+	declared := make(map[string]bool)
+	for _, rec := range m.Records {
+		if !rec.Matches(platform, device) {
+			continue
+		}
+		foldedLink, _ := pathutil.FoldHome(rec.Link())
+		foldedTarget, _ := pathutil.FoldHome(rec.Target())
+		declared[rec.Type+"\x00"+foldedLink] = true
+		if (len(only) > 0 && !selectedByAny(rec, only)) || selectedByAny(rec, skip) {
+			continue
+		}
+
+		step := applyStep{
+			record: rec,
+			item: output.PlanItem{
+				Name:   rec.Name,
+				Type:   rec.Type,
+				Device: device,
+				Link:   rec.Link(),
+				Target: rec.Target(),
+			},
+		}
+
+		var errMsg string
+		step.linkValid, errMsg = recordLinkValid(rec, device)
+		modeValid := true
+		if step.linkValid && rec.Mode != "" {
+			modeValid, errMsg, _ = checkModeValid(rec.Link(), rec.Mode)
+		}
+
+		parentPath, entry, found := mgr.FindEntry(platform, device, rec.Type, linkEntry(rec.Type, foldedLink))
+		step.parentPath, step.entry = parentPath, entry
+		switch {
+		case !found && step.linkValid && modeValid:
+			step.item.Action, step.item.Detail = "add", "链接已存在，仅写入记录"
+		case !found:
+			step.item.Action, step.item.Detail = "add", "创建链接并写入记录"
+		case entry[targetKey(rec.Type)] != foldedTarget:
+			step.item.Action, step.item.Detail = "modify", "目标由 "+entry[targetKey(rec.Type)]+" 变为 "+foldedTarget
+		case entry[store.ModeKey] != rec.Mode:
+			step.item.Action, step.item.Detail = "modify", "权限由 "+entry[store.ModeKey]+" 变为 "+rec.Mode
+		case !step.linkValid || !modeValid:
+			step.item.Action, step.item.Detail = "modify", errMsg
+		default:
+			step.item.Action = "none"
+		}
+		steps = append(steps, step)
+	}
+
+	if len(only) > 0 {
+		return steps
+	}
+
+	// 由本清单创建但已不在清单中的记录
+	for linkType, typeData := range mgr.Data[platform][device] {
+		for parentPath, entries := range typeData {
+			for _, entry := range entries {
+				if entry[store.SourceKey] != source {
+					continue
+				}
+				link := entry[linkKey(linkType)]
+				if declared[linkType+"\x00"+link] {
+					continue
+				}
+				steps = append(steps, applyStep{
+					parentPath: parentPath,
+					entry:      entry,
+					item: output.PlanItem{
+						Action: "remove",
+						Type:   linkType,
+						Device: device,
+						Link:   link,
+						Target: entry[targetKey(linkType)],
+						Detail: "清单中已不存在该记录",
+					},
+				})
+			}
+		}
+	}
+	return steps
+}
+
+// recordLinkValid 检查记录对应的链接在文件系统上是否有效，无效时返回原因
+func recordLinkValid(rec manifest.Record, device string) (bool, string) {
+	var valid bool
+	var errMsg string
+	switch rec.Type {
+	case "symlink":
+		valid, errMsg, _ = checkSymlinkValid(rec.Real, rec.Fake, "")
+	case "hardlink":
+		valid, errMsg, _ = checkHardlinkValid(rec.Prim, rec.Seco, "", "")
+	case "template":
+		valid, errMsg, _ = checkTemplateValid(rec.Real, rec.Fake, "", device, true)
+	case "junction":
+		valid, errMsg, _ = checkJunctionValid(rec.Real, rec.Fake, "")
+	}
+	return valid, errMsg
+}
+
+// executeApplyStep 执行计划中的一步并同步存储，不负责保存
+func executeApplyStep(mgr *store.Manager, step applyStep, source string) error {
+	platform := runtime.GOOS
+	if step.item.Action == "link" {
+		// 按存储应用时记录已存在，只创建链接
+		_, err := applyLink(step.record, applyDevice)
+		return err
+	}
+	if step.item.Action == "remove" {
+		target, _ := pathutil.NormalizePath(step.item.Target)
+		link, _ := pathutil.NormalizePath(step.item.Link)
+		if err := removeManagedLink(step.item.Type, target, link, step.item.Device); err != nil {
+			return err
+		}
+		audit.Emit(audit.Event{Action: audit.ActionDelete, Type: step.item.Type, Link: link, Target: target})
+		applyStoreMu.Lock()
+		defer applyStoreMu.Unlock()
+		mgr.RemoveMatchingEntry(platform, step.item.Device, step.item.Type, step.parentPath, step.entry)
+		return nil
+	}
+
+	rec := step.record
+	if !step.linkValid {
+		replaced := pathExists(rec.Link())
+		adopted, err := applyLink(rec, step.item.Device)
+		if err != nil {
+			return err
+		}
+		if adopted == rec.Target() {
+			auditLink(replaced, rec.Type, rec.Link(), rec.Target())
+		}
+		if adopted == "" {
+			return nil
+		}
+		rec.Real = adopted
+	}
+	if err := fileperm.Apply(rec.Link(), rec.Mode); err != nil {
+		return err
+	}
+	if err := applyAttrib(rec); err != nil {
+		return err
+	}
+
+	applyStoreMu.Lock()
+	defer applyStoreMu.Unlock()
+	if step.entry != nil {
+		mgr.RemoveMatchingEntry(platform, step.item.Device, rec.Type, step.parentPath, step.entry)
+	}
+	fields := recordFields(rec, source)
+	if rec.Type == "hardlink" {
+		if id, err := fileid.Get(rec.Seco); err == nil {
+			fields[store.InodeKey] = id
+		}
+	}
+	store.KeepOrigin(fields, step.entry)
+	mgr.AddRecord(step.item.Device, rec.Type, filepath.Dir(rec.Link()), fields)
+	return nil
+}
+
+// recordFields 将清单记录转换为存储条目的字段，来源记为 source
+func recordFields(rec manifest.Record, source string) map[string]string {
+	fields := map[string]string{store.SourceKey: source}
+	if rec.Mode != "" {
+		fields[store.ModeKey] = rec.Mode
+	}
+	if rec.Frequency != "" {
+		fields[store.FrequencyKey] = rec.Frequency
+	}
+	if rec.Attrib != "" {
+		fields[store.AttribKey] = rec.Attrib
+	}
+	if rec.LinkStyle != "" {
+		fields[store.LinkStyleKey] = rec.LinkStyle
+	}
+	if rec.Validate != "" {
+		fields[store.ValidateKey] = rec.Validate
+	}
+	if rec.Note != "" {
+		fields[store.NoteKey] = rec.Note
+	}
+	switch rec.Type {
+	case "symlink", "template", "junction":
+		fields["real"], fields["fake"] = rec.Real, rec.Fake
+	case "hardlink":
+		fields["prim"], fields["seco"] = rec.Prim, rec.Seco
+	}
+	return fields
+}
+
+// applyAttrib 为符号链接记录的链接自身设置声明的 Windows 文件属性
+func applyAttrib(rec manifest.Record) error {
+	if rec.Type != "symlink" || rec.Attrib == "" {
+		return nil
+	}
+	attrib, err := fileattr.Parse(rec.Attrib)
+	if err != nil {
+		return err
+	}
+	return fileattr.Set(rec.Fake, attrib)
+}
+
+// applyLink 在文件系统上创建清单记录对应的链接，返回应写入存储的目标路径，返回空字符串表示保留现有链接且不写入记录
+func applyLink(rec manifest.Record, device string) (string, error) {
+	switch rec.Type {
+	case "symlink":
+		force := false
+		conflict, err := symlink.DetectConflict(rec.Real, rec.Fake)
+		if err != nil {
+			return "", err
+		}
+		if conflict != nil {
+			switch resolveConflict(conflict) {
+			case conflictKeep:
+				return "", nil
+			case conflictAdopt:
+				return conflict.ExistingTarget, nil
+			case conflictSkip:
+				return "", errors.New("链接文件已指向 " + conflict.ExistingTarget + "，已跳过")
+			}
+			force = true
+		} else if _, err := trace.Lstat(rec.Fake); err == nil {
+			if !confirmForceDelete(rec.Fake) {
+				return "", errors.New("已取消覆盖 " + rec.Fake)
+			}
+			if err := moveToTrash(rec.Fake); err != nil {
+				return "", err
+			}
+			force = true
+		}
+		absolute := rec.LinkStyle == config.LinkStyleAbsolute
+		if err := symlink.CreateWithStyle(rec.Real, rec.Fake, force, absolute); err != nil {
+			if !elevate.Needed(err) {
+				return "", err
+			}
+			return rec.Real, createSymlinkElevated(rec.Real, rec.Fake, force, absolute)
+		}
+		return rec.Real, nil
+	case "hardlink":
+		force := false
+		if _, err := trace.Lstat(rec.Seco); err == nil {
+			if !confirmForceDelete(rec.Seco) {
+				return "", errors.New("已取消覆盖 " + rec.Seco)
+			}
+			if err := moveToTrash(rec.Seco); err != nil {
+				return "", err
+			}
+			force = true
+		}
+		return rec.Prim, hardlink.Create(rec.Prim, rec.Seco, force)
+	case "template":
+		force := false
+		if _, err := trace.Lstat(rec.Fake); err == nil {
+			if !confirmForceDelete(rec.Fake) {
+				return "", errors.New("已取消覆盖 " + rec.Fake)
+			}
+			if err := moveToTrash(rec.Fake); err != nil {
+				return "", err
+			}
+			force = true
+		}
+		return rec.Real, template.Create(rec.Real, rec.Fake, template.DeviceVars(device), force)
+	case "junction":
+		force := false
+		if _, err := trace.Lstat(rec.Fake); err == nil {
+			if !confirmForceDelete(rec.Fake) {
+				return "", errors.New("已取消覆盖 " + rec.Fake)
+			}
+			if err := moveToTrash(rec.Fake); err != nil {
+				return "", err
+			}
+			force = true
+		}
+		return rec.Real, junction.Create(rec.Real, rec.Fake, force)
+	}
+	return "", fmt.Errorf("未知类型 %s", rec.Type)
+}
+
+// removeManagedLink 删除由 flk 管理的链接，仅当其确实是指向目标的链接时才删除
+func removeManagedLink(linkType, target, link, device string) error {
+	info, err := trace.Lstat(link)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	switch linkType {
+	case "symlink":
+		if info.Mode()&os.ModeSymlink == 0 {
+			return fmt.Errorf("%s 不是符号链接，未删除", link)
+		}
+		current, err := trace.Readlink(link)
+		if err != nil {
+			return fmt.Errorf("无法读取符号链接 %s 的目标，未删除: %v", link, err)
+		}
+		if !filepath.IsAbs(current) {
+			current = filepath.Join(filepath.Dir(link), current)
+		}
+		if !samePath(filepath.Clean(current), filepath.Clean(target)) {
+			return fmt.Errorf("%s 指向 %s 而不是 %s，未删除", link, current, target)
+		}
+	case "template":
+		current, err := template.IsCurrent(target, link, template.DeviceVars(device))
+		if err != nil || !current {
+			return fmt.Errorf("%s 已被修改或无法渲染模板，未删除", link)
+		}
+	case "hardlink":
+		targetInfo, err := trace.Stat(target)
+		if err != nil || !os.SameFile(targetInfo, info) {
+			return fmt.Errorf("%s 与 %s 不是同一文件的硬链接，未删除", link, target)
+		}
+	case "junction":
+		// 删除目录联接只移除联接本身，不会删除目标目录中的内容
+		current, err := junction.Target(link)
+		if err != nil {
+			return fmt.Errorf("%s 不是目录联接，未删除", link)
+		}
+		if !samePath(filepath.Clean(current), filepath.Clean(target)) {
+			return fmt.Errorf("%s 指向 %s 而不是 %s，未删除", link, current, target)
+		}
+	}
+	return trace.Remove(link)
+}
+
+// linkKey 返回记录中链接文件路径的字段名
+func linkKey(linkType string) string {
+	if linkType == "hardlink" {
+		return "seco"
+	}
+	return "fake"
+}
+
+// targetKey 返回记录中目标文件路径的字段名
+func targetKey(linkType string) string {
+	if linkType == "hardlink" {
+		return "prim"
+	}
+	return "real"
+}
+
+// linkEntry 构造按链接文件路径匹配记录的条件
+func linkEntry(linkType, link string) store.Entry {
+	return store.Entry{linkKey(linkType): link}
+}
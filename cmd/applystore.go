@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/manifest"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/pterm/pterm"
+)
+
+// applyStore 按存储中适用于 applyDevice 的记录创建缺失或失效的链接，存储本身保持不变
+func applyStore() error {
+	only, err := parseSelectors(applyOnly)
+	if err != nil {
+		return err
+	}
+	skip, err := parseSelectors(applySkip)
+	if err != nil {
+		return err
+	}
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+
+	var changes []applyStep
+	for _, rec := range storeRecords(mgr) {
+		if rec.Device != "all" && rec.Device != applyDevice {
+			continue
+		}
+		if (len(only) > 0 && !selectedByAny(rec, only)) || selectedByAny(rec, skip) {
+			continue
+		}
+		valid, errMsg := recordLinkValid(rec, applyDevice)
+		if valid {
+			continue
+		}
+		changes = append(changes, applyStep{
+			record: rec,
+			item: output.PlanItem{
+				Action: "link",
+				Type:   rec.Type,
+				Device: rec.Device,
+				Link:   rec.Link(),
+				Target: rec.Target(),
+				Detail: errMsg,
+			},
+		})
+	}
+
+	format := output.OutputFormat(outputFormat)
+	if len(changes) == 0 {
+		pterm.Info.Println("存储中的链接均已存在，无需创建")
+		return nil
+	}
+	items := make([]output.PlanItem, 0, len(changes))
+	for _, step := range changes {
+		items = append(items, step.item)
+	}
+	if err := output.PrintPlan(format, items); err != nil {
+		logger.Error("输出失败 " + err.Error())
+	}
+	if applyCheck {
+		return fmt.Errorf("检测到 %d 个缺失或失效的链接", len(changes))
+	}
+
+	results, err := runApplySteps(mgr, changes, "")
+	if err != nil {
+		return err
+	}
+	if err := output.PrintApplyResults(format, results); err != nil {
+		logger.Error("输出失败 " + err.Error())
+	}
+	failed := 0
+	for _, r := range results {
+		if !r.Success {
+			failed++
+		}
+	}
+	pterm.Info.Printfln("共 %d 个链接，成功 %d 个，失败 %d 个", len(results), len(results)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d 个链接创建失败", failed)
+	}
+	return nil
+}
+
+// storeRecords 将存储中当前平台的全部记录转换为清单记录，每个链接一条，路径均为绝对路径；
+// 源根目录未配置的记录无法解析目标，会被跳过
+func storeRecords(mgr *store.Manager) []manifest.Record {
+	var records []manifest.Record
+	for d, deviceData := range mgr.Data[runtime.GOOS] {
+		for linkType, typeData := range deviceData {
+			for parentPath, group := range typeData {
+				for _, entry := range group {
+					basePath, err := recordBasePath(parentPath, entry)
+					if err != nil {
+						logger.Warn(err.Error())
+						continue
+					}
+					target := resolveRecordPath(entry[targetKey(linkType)], basePath)
+					links := []string{entry[linkKey(linkType)]}
+					if linkType == "hardlink" {
+						links = store.SecoPaths(entry)
+					}
+					for _, l := range links {
+						rec := manifest.Record{
+							Type:      linkType,
+							Device:    d,
+							Bundle:    appName(entry[store.SourceKey]),
+							Mode:      entry[store.ModeKey],
+							Frequency: entry[store.FrequencyKey],
+							Attrib:    entry[store.AttribKey],
+							LinkStyle: entry[store.LinkStyleKey],
+							Validate:  entry[store.ValidateKey],
+							Note:      entry[store.NoteKey],
+						}
+						link := resolveRecordPath(l, mustNormalize(parentPath))
+						if linkType == "hardlink" {
+							rec.Prim, rec.Seco = target, link
+						} else {
+							rec.Real, rec.Fake = target, link
+						}
+						records = append(records, rec)
+					}
+				}
+			}
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Link() < records[j].Link() })
+	return records
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/redact"
+	"github.com/jy-eggroll/flk/internal/status"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/jy-eggroll/flk/internal/trace"
+	"github.com/jy-eggroll/flk/internal/version"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var bugReportCmd = &cobra.Command{
+	Use:   "bug-report",
+	Short: "生成可附加到 GitHub issue 的问题报告压缩包",
+	Long:  "收集版本、系统与文件系统信息、无效记录的存储片段、最近的日志和跟踪记录，打包为一个 zip 文件；默认将主目录、用户名和主机名替换为占位符",
+	RunE:  RunBugReport,
+}
+
+var (
+	bugReportFile   string
+	bugReportLink   string
+	bugReportRedact bool
+)
+
+func init() {
+	rootCmd.AddCommand(bugReportCmd)
+	bugReportCmd.Flags().StringVarP(&bugReportFile, "file", "f", "", "输出文件路径，默认为当前目录下的 flk-bug-report-<时间>.zip")
+	bugReportCmd.Flags().StringVar(&bugReportLink, "link", "", "只收集链接文件为该路径的记录，默认收集所有无效记录")
+	bugReportCmd.Flags().BoolVar(&bugReportRedact, "redact", true, "将主目录、用户名和主机名替换为占位符，使用 --redact=false 关闭")
+}
+
+// bugReportSystem 报告中的版本与系统信息
+type bugReportSystem struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	StorePath string `json:"store_path"`
+	Sandbox   string `json:"sandbox,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// bugReportPath 记录涉及的路径在文件系统上的状态
+type bugReportPath struct {
+	Path       string `json:"path"`
+	Mode       string `json:"mode,omitempty"`
+	Size       int64  `json:"size,omitempty"`
+	LinkTarget string `json:"link_target,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// RunBugReport 生成问题报告压缩包
+func RunBugReport(cmd *cobra.Command, args []string) error {
+	var r *redact.Redactor
+	if bugReportRedact {
+		r = redact.New()
+	}
+
+	results, err := bugReportRecords()
+	if err != nil {
+		return err
+	}
+
+	file := bugReportFile
+	if file == "" {
+		file = "flk-bug-report-" + time.Now().Format("20060102-150405") + ".zip"
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+
+	addJSON := func(name string, v any) error {
+		data, err := json.MarshalIndent(v, "", "    ")
+		if err != nil {
+			return err
+		}
+		return addZipFile(zw, name, r.Bytes(data))
+	}
+
+	if err := addJSON("system.json", bugReportSystemInfo()); err != nil {
+		return err
+	}
+	if err := addJSON("records.json", results); err != nil {
+		return err
+	}
+	if err := addJSON("paths.json", bugReportPaths(results)); err != nil {
+		return err
+	}
+	if summary, err := status.Load(store.StorePath); err == nil {
+		if err := addJSON("status.json", summary); err != nil {
+			return err
+		}
+	}
+
+	// 日志和跟踪记录可能不存在，存在时才收集
+	storeDir := filepath.Dir(mustNormalize(store.StorePath))
+	optional := map[string]string{
+		"last-trace.jsonl": filepath.Join(storeDir, trace.LastTracePath),
+		"flk.log":          mustNormalize(logger.DefaultConfig().FilePath),
+	}
+	for name, path := range optional {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if err := addZipFile(zw, name, r.Bytes(data)); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	pterm.Success.Printf("问题报告已写入 %s，包含 %d 条记录\n", file, len(results))
+	if !bugReportRedact {
+		pterm.Warning.Println("报告未做脱敏处理，分享前请检查其中的路径和用户名")
+	}
+	return nil
+}
+
+// bugReportRecords 返回需要收集的记录：指定 --link 时为该链接的记录，否则为所有无效记录
+func bugReportRecords() ([]output.CheckResult, error) {
+	results, err := performCheck(CheckOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var link string
+	if bugReportLink != "" {
+		link, err = pathutil.ToAbsolute(mustNormalize(bugReportLink))
+		if err != nil {
+			return nil, err
+		}
+	}
+	var selected []output.CheckResult
+	for _, r := range results {
+		if link != "" {
+			l := r.Fake
+			if r.Type == "hardlink" {
+				l = r.Seco
+			}
+			if resolveRecordPath(l, r.BasePath) == link {
+				selected = append(selected, r)
+			}
+			continue
+		}
+		if !r.Valid {
+			selected = append(selected, r)
+		}
+	}
+	if link != "" && len(selected) == 0 {
+		return nil, fmt.Errorf("存储中没有链接文件为 %s 的记录", link)
+	}
+	return selected, nil
+}
+
+func bugReportSystemInfo() bugReportSystem {
+	info := bugReportSystem{
+		Version:   version.String(),
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		StorePath: mustNormalize(store.StorePath),
+		Sandbox:   pathutil.Sandbox,
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			if s.Key == "vcs.revision" {
+				info.Revision = s.Value
+			}
+		}
+	}
+	return info
+}
+
+// bugReportPaths 收集记录中链接和目标路径的文件系统状态
+func bugReportPaths(results []output.CheckResult) []bugReportPath {
+	seen := make(map[string]bool)
+	var paths []bugReportPath
+	for _, r := range results {
+		for _, p := range []string{r.Real, r.Fake, r.Prim, r.Seco} {
+			if p == "" {
+				continue
+			}
+			abs := resolveRecordPath(p, r.BasePath)
+			if seen[abs] {
+				continue
+			}
+			seen[abs] = true
+			bp := bugReportPath{Path: abs}
+			if info, err := trace.Lstat(abs); err != nil {
+				bp.Error = err.Error()
+			} else {
+				bp.Mode = info.Mode().String()
+				bp.Size = info.Size()
+				if info.Mode()&os.ModeSymlink != 0 {
+					bp.LinkTarget, _ = trace.Readlink(abs)
+				}
+			}
+			paths = append(paths, bp)
+		}
+	}
+	return paths
+}
+
+func addZipFile(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
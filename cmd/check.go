@@ -1,80 +1,475 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
-
+	"sync"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/contenthash"
+	"github.com/jy-eggroll/flk/internal/create/junction"
+	"github.com/jy-eggroll/flk/internal/create/template"
+	"github.com/jy-eggroll/flk/internal/fileattr"
+	"github.com/jy-eggroll/flk/internal/fileid"
+	"github.com/jy-eggroll/flk/internal/fileperm"
+	"github.com/jy-eggroll/flk/internal/lint"
 	"github.com/jy-eggroll/flk/internal/logger"
 	"github.com/jy-eggroll/flk/internal/output"
 	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/progress"
+	"github.com/jy-eggroll/flk/internal/publish"
+	"github.com/jy-eggroll/flk/internal/state"
+	"github.com/jy-eggroll/flk/internal/status"
 	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/jy-eggroll/flk/internal/trace"
+	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
 
 var checkCmd = &cobra.Command{
 	Use:   "check",
 	Short: "检查全局软硬链接的生效情况",
-	Long:  "检查全局软硬链接的生效情况",
-	Run:   RunCheck,
+	Long:  "检查全局软硬链接的生效情况。使用 --fix 时检查后无人值守地修复可安全修复的链接，仍有无效链接时以非零状态退出，适合在脚本中使用",
+	RunE:  RunCheck,
+	// 仍有无效链接时返回的错误用于脚本判定，不需要打印用法；出错原因已写入日志
+	SilenceUsage:  true,
+	SilenceErrors: true,
 }
 
 func init() {
 	logger.Init(nil)
 	rootCmd.AddCommand(checkCmd)
+	checkCmd.SetFlagErrorFunc(printFlagError)
 	checkCmd.Flags().StringVarP(&checkDevice, "device", "d", "", "设备名称，用于过滤检查")
 	checkCmd.Flags().BoolVar(&checkSymlink, "symlink", false, "仅检查符号链接")
 	checkCmd.Flags().BoolVar(&checkHardlink, "hardlink", false, "仅检查硬链接")
+	checkCmd.Flags().BoolVar(&checkTemplate, "template", false, "仅检查模板")
+	checkCmd.Flags().BoolVar(&checkJunction, "junction", false, "仅检查目录联接")
+	checkCmd.Flags().BoolVar(&checkFix, "fix", false, "检查后修复可安全修复的链接（重建缺失的链接、替换指向错误的链接），并报告无法自动修复的记录；与 fix --yes 相同，静默时段内不修复并遵循自动修复的限流策略")
+	checkCmd.Flags().IntVarP(&checkJobs, "jobs", "j", runtime.NumCPU(), "并发检查的最大记录数，链接位于网络驱动器或机械硬盘时可适当调低")
 	checkCmd.Flags().StringVar(&checkDir, "dir", "", "仅检查包含该路径的记录")
+	checkCmd.Flags().StringVar(&checkSource, "source", "", "仅检查来源以该值开头的记录，如 cli、apply:")
+	checkCmd.Flags().BoolVar(&checkDueOnly, "due-only", false, "仅检查按记录声明的检查频率已到期的记录")
+	checkCmd.Flags().BoolVar(&checkHardlinkBreaks, "hardlink-breaks", false, "仅检查创建时记录了文件标识的硬链接，识别被编辑器保存断开的链接")
+	checkCmd.Flags().BoolVar(&checkUntracked, "untracked", false, "同时报告链接所在目录中指向已记录源文件但没有记录的符号链接，并询问是否收编")
+	checkCmd.Flags().BoolVar(&checkBackupCoverage, "backup-coverage", false, "同时检查真实文件是否位于配置文件 backup_roots 声明的备份目录下")
+	addRedactFlag(checkCmd)
+	checkCmd.Flags().BoolVar(&checkHydrate, "hydrate", false, "允许读取云端占位文件的内容（会触发下载），默认将其报告为 PLACEHOLDER")
+	checkCmd.Flags().BoolVar(&output.ShowNotes, "show-notes", false, "在表格中完整显示记录备注，默认截断")
+	addColumnsFlag(checkCmd)
+	checkCmd.Flags().StringVar(&checkOutputFile, "output-file", "", "同时将完整的检查结果以 JSON 写入该文件，终端仍按 --output 输出")
+	checkCmd.Flags().BoolVarP(&checkQuiet, "quiet", "q", false, "不输出检查结果表格和日志，只通过退出码报告结果：0 全部有效，1 存在无效链接，2 执行出错")
 }
 
 var (
 	checkDevice   string
 	checkSymlink  bool
 	checkHardlink bool
+	checkTemplate bool
+	checkJunction bool
+	checkJobs     int
+	checkFix      bool
 	checkDir      string
+	checkSource   string
+	checkDueOnly  bool
+	checkHydrate  bool
+
+	checkHardlinkBreaks bool
+	checkUntracked      bool
+	checkBackupCoverage bool
+	checkOutputFile     string
+	checkQuiet          bool
 )
 
+// hardlinkEditGuidance 检测到硬链接被断开时给出的编辑器配置建议
+const hardlinkEditGuidance = `检测到硬链接被保存操作断开。许多编辑器先写入临时文件再重命名覆盖原文件，这会使硬链接失效：
+  Vim/Neovim: set backupcopy=yes
+  Emacs: (setq backup-by-copying-when-linked t)
+  JetBrains IDE: 关闭 Settings > Appearance & Behavior > System Settings > Use "safe write"
+也可以改用符号链接，或使用 flk fix 重新链接并合并内容`
+
+// hardlinkBreakTypes 表示硬链接被保存操作断开的错误类型，依据创建时记录的文件标识判断被替换的一侧
+var hardlinkBreakTypes = map[string]bool{
+	"PRIM_REPLACED":   true,
+	"SECO_REPLACED":   true,
+	"HARDLINK_BROKEN": true,
+}
+
 // CheckResult 单个链接的检查结果
 type CheckResult = output.CheckResult
 
+// tableColumns 通过 --columns 指定的表格列
+var tableColumns []string
+
+// addColumnsFlag 为输出检查结果表格的命令添加 --columns
+func addColumnsFlag(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&tableColumns, "columns", nil, "表格显示的列及其顺序，逗号分隔，可选 "+strings.Join(output.ColumnNames(), ",")+"，未指定时使用配置文件中的 columns")
+}
+
+// applyColumns 按 --columns 或配置文件设置检查结果表格的列
+func applyColumns(cmd *cobra.Command) error {
+	names := config.Global.Columns
+	if cmd != nil && cmd.Flags().Changed("columns") {
+		names = tableColumns
+	}
+	columns, err := output.ParseColumns(names)
+	if err != nil {
+		return err
+	}
+	output.Columns = columns
+	return nil
+}
+
+// overlapCodes 检查时一并提示的 lint 问题，这些配置错误会让 fix 的行为难以理解
+var overlapCodes = map[string]bool{
+	"DUPLICATE_LINK":   true,
+	"DEVICE_DUPLICATE": true,
+	"CHAINED_LINK":     true,
+}
+
+// warnRecordOverlaps 对当前平台上链接重复或链式管理的记录给出警告
+func warnRecordOverlaps() {
+	mgr, err := store.Current()
+	if err != nil {
+		return
+	}
+	for _, issue := range lint.Store(mgr.Data, false) {
+		if overlapCodes[issue.Code] && strings.HasPrefix(issue.Location, runtime.GOOS+"/") {
+			logger.Warn(issue.Location + " " + issue.Detail)
+		}
+	}
+}
+
 // RunCheck 执行链接检查并输出结果
-func RunCheck(cmd *cobra.Command, args []string) {
-	results, err := performCheck(CheckOptions{
-		DeviceFilter:  checkDevice,
-		CheckSymlink:  checkSymlink,
-		CheckHardlink: checkHardlink,
-		CheckDir:      checkDir,
+func RunCheck(cmd *cobra.Command, args []string) error {
+	progress.Emit(progress.Event{Event: progress.Started, Command: "check"})
+	var results []output.CheckResult
+	defer func() {
+		summary := status.Summarize(results)
+		progress.Emit(progress.Event{Event: progress.Done, Command: "check", Counts: map[string]int{
+			"total": summary.Total, "valid": summary.Total - summary.Invalid, "invalid": summary.Invalid,
+		}})
+	}()
+	format := output.OutputFormat(outputFormat)
+	quiet := checkQuiet && format == output.Table
+	if quiet {
+		logger.SetWriter(io.Discard)
+	}
+	if err := applyColumns(cmd); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+	statePath := state.PathFor(store.StorePath)
+	st, err := state.Load(statePath)
+	if err != nil {
+		logger.Warn("读取状态文件失败 " + err.Error())
+		st = nil
+	}
+
+	results, err = performCheck(CheckOptions{
+		DeviceFilter:   checkDevice,
+		IncludeShared:  implicitDevice,
+		CheckSymlink:   checkSymlink,
+		CheckHardlink:  checkHardlink,
+		CheckTemplate:  checkTemplate,
+		CheckJunction:  checkJunction,
+		Jobs:           checkJobs,
+		CheckDir:       checkDir,
+		Source:         checkSource,
+		DueOnly:        checkDueOnly,
+		Hydrate:        checkHydrate,
+		HardlinkBreaks: checkHardlinkBreaks,
+		Untracked:      checkUntracked,
+		BackupCoverage: checkBackupCoverage,
+		State:          st,
 	})
 	if err != nil {
 		logger.Error("检查失败 " + err.Error())
+		return err
+	}
+
+	if st != nil {
+		now := time.Now()
+		for _, r := range results {
+			if r.ErrorType == "UNTRACKED" {
+				continue
+			}
+			st.MarkChecked(resultKey(r), now)
+			if r.Valid {
+				st.MarkValid(resultKey(r), now)
+			}
+		}
+		if err := st.Save(statePath); err != nil {
+			logger.Warn("写入状态文件失败 " + err.Error())
+		}
+	}
+
+	summary := status.Summarize(results)
+	if st != nil {
+		summary.GaveUp = st.GaveUpCount()
+	}
+	// 筛选后的结果只覆盖部分记录，不能代替全部记录的摘要
+	if !checkFiltered() {
+		if err := status.Save(store.StorePath, summary); err != nil {
+			logger.Warn("写入检查摘要缓存失败 " + err.Error())
+		}
+	}
+	publishMirror(summary, results)
+	publishMQTT(summary, results)
+
+	warnRecordOverlaps()
+
+	if !quiet {
+		if err := output.PrintCheckResults(format, redactCheckResults(results)); err != nil {
+			logger.Error("输出失败 " + err.Error())
+			return err
+		}
+	}
+	if checkOutputFile != "" {
+		if err := output.WriteCheckResults(mustNormalize(checkOutputFile), redactCheckResults(results)); err != nil {
+			logger.Error("写入结果文件失败 " + err.Error())
+		}
+	}
+
+	if format == output.Table && !quiet {
+		for _, r := range results {
+			if hardlinkBreakTypes[r.ErrorType] {
+				pterm.Info.Println(hardlinkEditGuidance)
+				break
+			}
+		}
+		offerAdoption(results)
+	}
+
+	if checkFix {
+		if err := fixChecked(results, quiet, st, statePath); err != nil {
+			logger.Error(err.Error())
+			return err
+		}
+		return nil
+	}
+	logger.Info("检查完成")
+	invalid := 0
+	for _, r := range results {
+		if !r.Valid {
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		return invalidLinksError(invalid)
+	}
+	return nil
+}
+
+// checkFiltered 判断本次检查是否只检查了部分记录
+func checkFiltered() bool {
+	return checkDevice != "" || checkSymlink || checkHardlink || checkTemplate || checkJunction ||
+		checkDir != "" || checkSource != "" || checkDueOnly || checkHardlinkBreaks
+}
+
+// refreshStatusSummary 修复后重新检查当前平台的全部记录并更新摘要缓存，--dry-run 时不更新
+func refreshStatusSummary() {
+	if trace.DryRun {
+		return
+	}
+	results, err := performCheck(CheckOptions{IncludeShared: implicitDevice})
+	if err != nil {
+		logger.Warn("更新检查摘要缓存失败 " + err.Error())
 		return
 	}
+	summary := status.Summarize(results)
+	if st, err := state.Load(state.PathFor(store.StorePath)); err == nil {
+		summary.GaveUp = st.GaveUpCount()
+	}
+	if err := status.Save(store.StorePath, summary); err != nil {
+		logger.Warn("写入检查摘要缓存失败 " + err.Error())
+	}
+}
 
-	format := output.OutputFormat(outputFormat)
-	if err := output.PrintCheckResults(format, results); err != nil {
-		logger.Error("输出失败 " + err.Error())
+// fixChecked 修复检查结果中可安全修复的无效链接，quiet 为 false 时输出每条的处理结果；仍有未修复的链接时返回错误。
+// 与 fix --yes 和 watch 相同，静默时段内不修复，并按配置的限流策略跳过修复过于频繁或已放弃的记录
+func fixChecked(results []output.CheckResult, quiet bool, st *state.State, statePath string) error {
+	invalid := 0
+	for _, r := range results {
+		if !r.Valid {
+			invalid++
+		}
+	}
+	if invalid == 0 {
+		return nil
+	}
+	if b, ok := config.Global.ActiveBlackout(time.Now()); ok {
+		msg := fmt.Sprintf("当前处于静默时段 %s-%s，%d 个无效链接暂不修复", b.Start, b.End, invalid)
+		if b.Reason != "" {
+			msg += "（" + b.Reason + "）"
+		}
+		logger.Info(msg)
+		return &exitCodeError{code: ExitInvalid, err: errors.New(msg)}
+	}
+	if st == nil {
+		st = &state.State{Records: make(map[string]state.RecordState)}
+	}
+	policy := repairPolicy()
+
+	// 重新创建链接的结果已体现在修复结果中，不再逐条输出
+	createResultHook = func(output.CreateResult) {}
+	defer resetCreateOptions()
+	var items []output.ApplyResult
+	failed := 0
+	for i, r := range results {
+		if r.Valid {
+			continue
+		}
+		item := output.ApplyResult{Action: "repair", Type: r.Type, Link: resultLink(r)}
+		now := time.Now()
+		key := resultKey(r)
+		err := unsafeToFix(r)
+		if err == nil {
+			err = st.AllowRepair(key, policy, now)
+		}
+		if err == nil {
+			err = repairResult(r, i)
+			emitRepaired(r, err)
+			if err == nil && st.RecordRepair(key, policy, now) {
+				logger.Warn(fmt.Sprintf("%s 连续 %d 次修复后仍被改回，已放弃自动修复", resultLink(r), policy.GiveUpAfter))
+			}
+		}
+		if err != nil {
+			item.Error = err.Error()
+			failed++
+		} else {
+			item.Success = true
+		}
+		items = append(items, item)
+	}
+	if err := st.Save(statePath); err != nil {
+		logger.Warn("写入状态文件失败 " + err.Error())
+	}
+	if failed < len(items) {
+		refreshStatusSummary()
+	}
+	if !quiet {
+		if err := output.PrintApplyResults(output.OutputFormat(outputFormat), redactApplyResults(items)); err != nil {
+			logger.Error("输出失败 " + err.Error())
+		}
+	}
+	if failed > 0 {
+		return &exitCodeError{code: ExitInvalid, err: fmt.Errorf("%d 个链接未能自动修复，请使用 flk fix 确认后处理", failed)}
+	}
+	return nil
+}
+
+// unsafeToFix 判断无效记录能否无人值守地修复：真实文件必须存在，且修复只会创建或替换链接，不会删除占据链接位置的文件
+func unsafeToFix(r output.CheckResult) error {
+	switch r.ErrorType {
+	case "UNTRACKED":
+		return errors.New("链接没有记录，请使用 flk adopt 收编")
+	case "NOT_BACKED_UP", "PLACEHOLDER", "ROOT_UNDEFINED":
+		return errors.New("需要手动处理：" + r.Error)
+	}
+	if riskOf(r) > riskMedium {
+		return errors.New("修复需要删除或合并占据链接位置的文件：" + r.Error)
+	}
+	target := r.Real
+	if r.Type == "hardlink" {
+		target = r.Prim
+	}
+	if _, err := trace.Stat(resolveRecordPath(target, r.BasePath)); err != nil {
+		return fmt.Errorf("真实文件 %s 无法访问: %v", target, err)
+	}
+	return nil
+}
+
+// publishMirror 按配置将本次检查的链接状态发布到镜像文件，未配置时不做任何事
+func publishMirror(summary status.Summary, results []output.CheckResult) {
+	cfg := config.Global.Mirror
+	if cfg == nil {
 		return
 	}
+	path := status.DefaultMirrorPath()
+	if cfg.Path != "" {
+		path = mustNormalize(cfg.Path)
+	}
+	links := make([]string, len(results))
+	targets := make([]string, len(results))
+	for i, r := range results {
+		link, target := r.Fake, r.Real
+		if r.Type == "hardlink" {
+			link, target = r.Seco, r.Prim
+		}
+		links[i] = resolveRecordPath(link, mustNormalize(r.Path))
+		targets[i] = resolveRecordPath(target, r.BasePath)
+	}
+	if err := status.WriteMirror(path, cfg.Format, status.NewMirror(summary, results, links, targets)); err != nil {
+		logger.Warn("写入链接状态镜像失败 " + err.Error())
+		return
+	}
+	logger.Debug("已更新链接状态镜像 " + path)
+}
 
-	logger.Info("检查完成")
+// publishMQTT 按配置向 MQTT 服务器发布本次检查的链接健康状况，未配置时不做任何事
+func publishMQTT(summary status.Summary, results []output.CheckResult) {
+	cfg := config.Global.MQTT
+	if cfg == nil {
+		return
+	}
+	if err := publish.MQTT(cfg, summary, results); err != nil {
+		logger.Warn("发布链接健康状况失败 " + err.Error())
+		return
+	}
+	logger.Debug("已向 " + cfg.Broker + " 发布链接健康状况")
 }
 
 // CheckOptions 检查选项
 type CheckOptions struct {
-	DeviceFilter  string
-	CheckSymlink  bool
-	CheckHardlink bool
-	CheckDir      string
+	DeviceFilter   string
+	IncludeShared  bool // 按 DeviceFilter 过滤时同时包含设备为 all 的记录
+	CheckSymlink   bool
+	CheckHardlink  bool
+	CheckTemplate  bool
+	CheckJunction  bool
+	Jobs           int // 并发检查的最大记录数，小于 1 时按 CPU 核数
+	CheckDir       string
+	Source         string // 按记录来源前缀过滤
+	DueOnly        bool   // 跳过按检查频率尚未到期的记录，需要同时提供 State
+	Hydrate        bool   // 允许读取云端占位文件的内容，否则直接报告 PLACEHOLDER
+	HardlinkBreaks bool   // 仅检查记录了文件标识的硬链接
+	Untracked      bool   // 同时报告链接文件所在目录中指向已记录源文件但没有记录的符号链接
+	BackupCoverage bool   // 将真实文件不在任何备份目录下的有效记录报告为 NOT_BACKED_UP
+	State          *state.State
+}
+
+// resultKey 返回检查结果对应记录在状态文件中的标识
+func resultKey(r output.CheckResult) string {
+	return state.Key(runtime.GOOS, r.Device, r.Type, resultLink(r))
+}
+
+// resultLink 返回检查结果中的链接文件路径，硬链接为次要文件
+func resultLink(r output.CheckResult) string {
+	if r.Type == "hardlink" {
+		return r.Seco
+	}
+	return r.Fake
 }
 
 func performCheck(options CheckOptions) ([]output.CheckResult, error) {
 	platform := runtime.GOOS
+	now := time.Now()
 	var results []CheckResult
 
-	data := store.GlobalManager.Data
+	mgr, err := store.Current()
+	if err != nil {
+		return nil, err
+	}
+	data := mgr.Data
 	if data == nil {
 		return results, nil
 	}
@@ -84,67 +479,199 @@ func performCheck(options CheckOptions) ([]output.CheckResult, error) {
 		return results, nil
 	}
 
-	if !options.CheckSymlink && !options.CheckHardlink {
+	if options.BackupCoverage && len(config.Global.BackupRoots) == 0 {
+		return nil, errors.New("--backup-coverage 需要在配置文件的 backup_roots 中声明至少一个备份目录")
+	}
+	if options.HardlinkBreaks {
+		options.CheckSymlink, options.CheckHardlink, options.CheckTemplate, options.CheckJunction = false, true, false, false
+	}
+	if !options.CheckSymlink && !options.CheckHardlink && !options.CheckTemplate && !options.CheckJunction {
 		options.CheckSymlink = true
 		options.CheckHardlink = true
+		options.CheckTemplate = true
+		options.CheckJunction = true
 	}
 
-	for device, deviceData := range platformData {
-		if options.DeviceFilter != "" && device != options.DeviceFilter {
+	// 按设备、类型、父路径排序遍历，使结果顺序（以及 fix 使用的编号）在多次运行之间保持一致
+	var checks []func() []CheckResult
+	for _, device := range slices.Sorted(maps.Keys(platformData)) {
+		if options.DeviceFilter != "" && device != options.DeviceFilter && !(options.IncludeShared && device == "all") {
 			continue
 		}
+		deviceData := platformData[device]
 
-		for linkType, typeData := range deviceData {
+		for _, linkType := range slices.Sorted(maps.Keys(deviceData)) {
 			if (linkType == "symlink" && !options.CheckSymlink) ||
-				(linkType == "hardlink" && !options.CheckHardlink) {
+				(linkType == "hardlink" && !options.CheckHardlink) ||
+				(linkType == "template" && !options.CheckTemplate) ||
+				(linkType == "junction" && !options.CheckJunction) {
 				continue
 			}
+			typeData := deviceData[linkType]
 
-			for path, entries := range typeData {
+			for _, path := range slices.Sorted(maps.Keys(typeData)) {
 				if options.CheckDir != "" && !strings.Contains(path, options.CheckDir) {
 					continue
 				}
 
-				basePath, err := pathutil.NormalizePath(path)
-				if err != nil {
-					basePath = path
-				}
-
-				for _, entry := range entries {
-					result := output.CheckResult{
-						Type:     linkType,
-						Device:   device,
-						Path:     path,
-						BasePath: basePath,
+				for _, entry := range typeData[path] {
+					if options.Source != "" && !strings.HasPrefix(entry[store.SourceKey], options.Source) {
+						continue
 					}
-
-					switch linkType {
-					case "symlink":
-						result.Real = entry["real"]
-						result.Fake = entry["fake"]
-						result.Valid, result.Error, result.ErrorType = checkSymlinkValid(result.Real, result.Fake, basePath)
-					case "hardlink":
-						result.Prim = entry["prim"]
-						result.Seco = entry["seco"]
-						result.Valid, result.Error, result.ErrorType = checkHardlinkValid(result.Prim, result.Seco, basePath)
+					if options.HardlinkBreaks && entry[store.InodeKey] == "" {
+						continue
 					}
-
-					results = append(results, result)
+					if options.DueOnly && options.State != nil {
+						frequency, err := state.ParseFrequency(entry[store.FrequencyKey])
+						if err != nil {
+							logger.Warn(err.Error())
+						}
+						key := state.Key(platform, device, linkType, entry[linkKey(linkType)])
+						if !options.State.Due(key, frequency, now) {
+							continue
+						}
+					}
+					checks = append(checks, func() []CheckResult {
+						return checkEntry(device, linkType, path, entry, options)
+					})
 				}
 			}
 		}
 	}
 
+	// 各记录的检查互不依赖，并发执行后按收集顺序合并
+	jobs := options.Jobs
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	checked := make([][]CheckResult, len(checks))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			checked[i] = check()
+		}()
+	}
+	wg.Wait()
+	for _, r := range checked {
+		results = append(results, r...)
+	}
+
+	if options.Untracked {
+		results = append(results, findUntrackedLinks(results)...)
+	}
+
 	return results, nil
 }
 
+// checkEntry 检查一条记录，一组硬链接的每个次要文件各产生一条结果；可与其他记录并发调用
+func checkEntry(device, linkType, path string, entry store.Entry, options CheckOptions) []CheckResult {
+	var results []CheckResult
+	basePath, rootErr := recordBasePath(path, entry)
+	result := output.CheckResult{
+		Type:      linkType,
+		Device:    device,
+		Path:      path,
+		BasePath:  basePath,
+		Root:      entry[store.RootKey],
+		Source:    entry[store.SourceKey],
+		Mode:      entry[store.ModeKey],
+		Attrib:    entry[store.AttribKey],
+		LinkStyle: entry[store.LinkStyleKey],
+		Validate:  entry[store.ValidateKey],
+		Note:      entry[store.NoteKey],
+		Inode:     entry[store.InodeKey],
+	}
+
+	if rootErr != nil {
+		result.Real, result.Fake = entry["real"], entry["fake"]
+		result.Prim, result.Seco = entry["prim"], entry[store.SecoKey]
+		result.Error, result.ErrorType = rootErr.Error(), "ROOT_UNDEFINED"
+		emitChecked(result)
+		return []CheckResult{result}
+	}
+
+	// 一组硬链接的每个次要文件各产生一条结果
+	var entryResults []CheckResult
+	switch linkType {
+	case "symlink":
+		result.Real = entry["real"]
+		result.Fake = entry["fake"]
+		result.Valid, result.Error, result.ErrorType = checkSymlinkValid(result.Real, result.Fake, basePath)
+		entryResults = append(entryResults, result)
+	case "hardlink":
+		secos := store.SecoPaths(entry)
+		for _, seco := range secos {
+			r := result
+			r.Prim = entry["prim"]
+			r.Seco = seco
+			if len(secos) > 1 {
+				r.Group = secos
+			}
+			r.Valid, r.Error, r.ErrorType = checkHardlinkValid(r.Prim, r.Seco, basePath, r.Inode)
+			entryResults = append(entryResults, r)
+		}
+	case "template":
+		result.Real = entry["real"]
+		result.Fake = entry["fake"]
+		result.Valid, result.Error, result.ErrorType = checkTemplateValid(result.Real, result.Fake, basePath, device, options.Hydrate)
+		entryResults = append(entryResults, result)
+	case "junction":
+		result.Real = entry["real"]
+		result.Fake = entry["fake"]
+		result.Valid, result.Error, result.ErrorType = checkJunctionValid(result.Real, result.Fake, basePath)
+		entryResults = append(entryResults, result)
+	}
+
+	for _, result := range entryResults {
+		if result.Valid && result.Mode != "" {
+			link := result.Fake
+			if linkType == "hardlink" {
+				link = result.Seco
+			}
+			result.Valid, result.Error, result.ErrorType = checkModeValid(link, result.Mode)
+		}
+		if result.Valid && result.Attrib != "" && linkType == "symlink" {
+			result.Valid, result.Error, result.ErrorType = checkAttribValid(result.Fake, result.Attrib)
+		}
+		if result.Valid && options.BackupCoverage {
+			result.Valid, result.Error, result.ErrorType = checkBackupCovered(result)
+		}
+		results = append(results, result)
+		emitChecked(result)
+	}
+	return results
+}
+
+// emitChecked 输出检查完一条记录的进度事件
+func emitChecked(r output.CheckResult) {
+	progress.Emit(progress.Event{Event: progress.Checked, Type: r.Type, Device: r.Device, Link: resultLink(r), Valid: &r.Valid, ErrorType: r.ErrorType})
+}
+
+// checkBackupCovered 检查记录的真实文件是否位于某个备份目录下
+func checkBackupCovered(r output.CheckResult) (bool, string, string) {
+	target := r.Real
+	if r.Type == "hardlink" {
+		target = r.Prim
+	}
+	abs := resolveRecordPath(target, r.BasePath)
+	if _, ok := config.Global.BackupRoot(abs); !ok {
+		return false, fmt.Sprintf("真实文件 %s 不在任何备份目录下，可能从未被移入 dotfiles 仓库", abs), "NOT_BACKED_UP"
+	}
+	return true, "", ""
+}
+
 func checkSymlinkValid(real, fake, basePath string) (bool, string, string) {
 	expandedFake, err := pathutil.NormalizePath(fake)
 	if err != nil {
 		return false, fmt.Sprintf("无法展开符号链接路径 %s: %v", fake, err), "PATH_EXPAND_FAIL"
 	}
 
-	fakeInfo, err := os.Lstat(expandedFake)
+	fakeInfo, err := trace.Lstat(expandedFake)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return false, fmt.Sprintf("符号链接文件 %s 不存在", fake), "LINK_MISSING"
@@ -156,7 +683,7 @@ func checkSymlinkValid(real, fake, basePath string) (bool, string, string) {
 		return false, fmt.Sprintf("%s 存在但不是符号链接", fake), "NOT_SYMLINK"
 	}
 
-	target, err := os.Readlink(expandedFake)
+	target, err := trace.Readlink(expandedFake)
 	if err != nil {
 		return false, fmt.Sprintf("无法读取符号链接 %s 的目标: %v", fake, err), "READLINK_FAIL"
 	}
@@ -168,17 +695,9 @@ func checkSymlinkValid(real, fake, basePath string) (bool, string, string) {
 		targetAbs = filepath.Join(filepath.Dir(expandedFake), target)
 	}
 
-	var expectedAbs string
-	if filepath.IsAbs(real) {
-		expectedAbs = real
-	} else {
-		expectedAbs = filepath.Join(basePath, real)
-	}
-	if expanded, expandErr := pathutil.NormalizePath(expectedAbs); expandErr == nil {
-		expectedAbs = expanded
-	}
+	expectedAbs := resolveRecordPath(real, basePath)
 
-	targetInfo, err := os.Stat(targetAbs)
+	targetInfo, err := trace.Stat(targetAbs)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return false, fmt.Sprintf("符号链接的目标文件 %s 不存在", targetAbs), "TARGET_MISSING"
@@ -186,7 +705,7 @@ func checkSymlinkValid(real, fake, basePath string) (bool, string, string) {
 		return false, fmt.Sprintf("无法访问符号链接的目标文件 %s: %v", targetAbs, err), "TARGET_ACCESS_FAIL"
 	}
 
-	expectedInfo, err := os.Stat(expectedAbs)
+	expectedInfo, err := trace.Stat(expectedAbs)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return false, fmt.Sprintf("期望的目标文件 %s 不存在", expectedAbs), "EXPECTED_MISSING"
@@ -201,17 +720,58 @@ func checkSymlinkValid(real, fake, basePath string) (bool, string, string) {
 	return true, "", ""
 }
 
-func checkHardlinkValid(prim, seco, basePath string) (bool, string, string) {
-	var expandedPrim string
-	if filepath.IsAbs(prim) {
-		expandedPrim = prim
-	} else {
-		expandedPrim = filepath.Join(basePath, prim)
+// checkJunctionValid 检查目录联接是否存在且指向期望的目录
+func checkJunctionValid(real, fake, basePath string) (bool, string, string) {
+	expandedFake, err := pathutil.NormalizePath(fake)
+	if err != nil {
+		return false, fmt.Sprintf("无法展开目录联接路径 %s: %v", fake, err), "PATH_EXPAND_FAIL"
+	}
+	if _, err := trace.Lstat(expandedFake); err != nil {
+		if os.IsNotExist(err) {
+			return false, fmt.Sprintf("目录联接 %s 不存在", fake), "LINK_MISSING"
+		}
+		return false, fmt.Sprintf("无法访问目录联接 %s: %v", fake, err), "LINK_ACCESS_FAIL"
+	}
+	target, err := junction.Target(expandedFake)
+	if err != nil {
+		return false, fmt.Sprintf("%s 存在但不是目录联接: %v", fake, err), "NOT_JUNCTION"
+	}
+
+	expectedAbs := resolveRecordPath(real, basePath)
+	if _, err := trace.Stat(expectedAbs); err != nil {
+		if os.IsNotExist(err) {
+			return false, fmt.Sprintf("期望的目标目录 %s 不存在", expectedAbs), "EXPECTED_MISSING"
+		}
+		return false, fmt.Sprintf("无法访问期望的目标目录 %s: %v", expectedAbs, err), "EXPECTED_ACCESS_FAIL"
+	}
+	if !samePath(target, expectedAbs) {
+		return false, fmt.Sprintf("目录联接 %s 指向 %s，而不是期望的 %s", fake, target, expectedAbs), "TARGET_MISMATCH"
 	}
+	return true, "", ""
+}
 
-	expandedSeco := seco
+// contentNote 比较已断开的两个硬链接文件的内容，用于判断修复时是否需要合并修改
+func contentNote(prim, seco string) string {
+	same, err := contenthash.Same(prim, seco)
+	switch {
+	case err != nil:
+		return ""
+	case same:
+		return "，两者内容仍相同"
+	}
+	return "，两者内容已不同"
+}
+
+// checkHardlinkValid 检查硬链接是否有效，inode 为创建时记录的文件标识，非空时可识别被保存操作断开的一侧
+func checkHardlinkValid(prim, seco, basePath, inode string) (bool, string, string) {
+	expandedPrim := resolveRecordPath(prim, basePath)
+
+	expandedSeco, err := pathutil.NormalizePath(seco)
+	if err != nil {
+		return false, fmt.Sprintf("无法展开硬链接文件路径 %s: %v", seco, err), "PATH_EXPAND_FAIL"
+	}
 
-	primInfo, err := os.Stat(expandedPrim)
+	primInfo, err := trace.Stat(expandedPrim)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return false, fmt.Sprintf("主文件 %s 不存在", prim), "PRIM_MISSING"
@@ -219,7 +779,7 @@ func checkHardlinkValid(prim, seco, basePath string) (bool, string, string) {
 		return false, fmt.Sprintf("无法访问主文件 %s: %v", prim, err), "PRIM_ACCESS_FAIL"
 	}
 
-	secoInfo, err := os.Stat(expandedSeco)
+	secoInfo, err := trace.Stat(expandedSeco)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return false, fmt.Sprintf("硬链接文件 %s 不存在", seco), "SECO_MISSING"
@@ -228,8 +788,108 @@ func checkHardlinkValid(prim, seco, basePath string) (bool, string, string) {
 	}
 
 	if !os.SameFile(primInfo, secoInfo) {
-		return false, fmt.Sprintf("%s 和 %s 不是同一个文件的硬链接", seco, prim), "NOT_SAME_FILE"
+		if inode != "" {
+			primID, primErr := fileid.Get(expandedPrim)
+			secoID, secoErr := fileid.Get(expandedSeco)
+			primReplaced := primErr == nil && primID != inode
+			secoReplaced := secoErr == nil && secoID != inode
+			switch {
+			case primReplaced && secoReplaced:
+				return false, fmt.Sprintf("%s 和 %s 都已被替换为新文件，硬链接已断开%s", prim, seco, contentNote(expandedPrim, expandedSeco)), "HARDLINK_BROKEN"
+			case primReplaced:
+				return false, fmt.Sprintf("主文件 %s 已被替换为新文件，通常是编辑器以重命名方式保存导致硬链接断开%s", prim, contentNote(expandedPrim, expandedSeco)), "PRIM_REPLACED"
+			case secoReplaced:
+				return false, fmt.Sprintf("硬链接文件 %s 已被替换为新文件，通常是编辑器以重命名方式保存导致硬链接断开%s", seco, contentNote(expandedPrim, expandedSeco)), "SECO_REPLACED"
+			}
+		}
+		return false, fmt.Sprintf("%s 和 %s 不是同一个文件的硬链接%s", seco, prim, contentNote(expandedPrim, expandedSeco)), "NOT_SAME_FILE"
+	}
+
+	// 同步客户端在下载或释放空间时会替换文件，导致硬链接断开
+	if placeholder, _ := fileattr.IsPlaceholder(expandedPrim); placeholder {
+		return false, fmt.Sprintf("主文件 %s 是仅在线的云端占位文件，硬链接可能随同步断开", prim), "PLACEHOLDER"
+	}
+
+	return true, "", ""
+}
+
+func checkTemplateValid(real, fake, basePath, device string, hydrate bool) (bool, string, string) {
+	expandedReal := resolveRecordPath(real, basePath)
+	expandedFake, err := pathutil.NormalizePath(fake)
+	if err != nil {
+		return false, fmt.Sprintf("无法展开渲染结果路径 %s: %v", fake, err), "PATH_EXPAND_FAIL"
+	}
+
+	if _, err := trace.Stat(expandedReal); err != nil {
+		if os.IsNotExist(err) {
+			return false, fmt.Sprintf("模板文件 %s 不存在", real), "EXPECTED_MISSING"
+		}
+		return false, fmt.Sprintf("无法访问模板文件 %s: %v", real, err), "EXPECTED_ACCESS_FAIL"
+	}
+
+	if _, err := trace.Stat(expandedFake); err != nil {
+		if os.IsNotExist(err) {
+			return false, fmt.Sprintf("渲染结果 %s 不存在", fake), "RENDERED_MISSING"
+		}
+		return false, fmt.Sprintf("无法访问渲染结果 %s: %v", fake, err), "LINK_ACCESS_FAIL"
 	}
 
+	if !hydrate {
+		for _, p := range []string{expandedReal, expandedFake} {
+			if placeholder, _ := fileattr.IsPlaceholder(p); placeholder {
+				return false, fmt.Sprintf("%s 是仅在线的云端占位文件，已跳过内容比较，使用 --hydrate 强制检查", p), "PLACEHOLDER"
+			}
+		}
+	}
+
+	// cmd 函数可能调用 op read 等需要交互或较慢的命令，检查时不执行，这类模板只检查渲染结果是否存在
+	current, err := template.IsCurrentWithoutCommands(expandedReal, expandedFake, template.DeviceVars(device))
+	if errors.Is(err, template.ErrUsesCommand) {
+		logger.Debug(fmt.Sprintf("模板 %s 使用 cmd 函数，已跳过内容比较", real))
+		return true, "", ""
+	}
+	if err != nil {
+		return false, fmt.Sprintf("渲染模板 %s 失败: %v", real, err), "TEMPLATE_RENDER_FAIL"
+	}
+	if !current {
+		return false, fmt.Sprintf("渲染结果 %s 与模板 %s 的当前渲染不一致", fake, real), "RENDERED_STALE"
+	}
+
+	return true, "", ""
+}
+
+// checkModeValid 检查链接目标的权限是否与记录声明的一致
+func checkModeValid(link, mode string) (bool, string, string) {
+	expandedLink, err := pathutil.NormalizePath(link)
+	if err != nil {
+		return false, fmt.Sprintf("无法展开路径 %s: %v", link, err), "PATH_EXPAND_FAIL"
+	}
+	ok, actual, err := fileperm.Check(expandedLink, mode)
+	if err != nil {
+		return false, fmt.Sprintf("无法检查 %s 的权限: %v", link, err), "MODE_DRIFT"
+	}
+	if !ok {
+		return false, fmt.Sprintf("%s 的权限为 %s，与声明的 %s 不一致", link, actual, mode), "MODE_DRIFT"
+	}
+	return true, "", ""
+}
+
+// checkAttribValid 检查符号链接自身的文件属性是否包含记录声明的属性
+func checkAttribValid(link, attrib string) (bool, string, string) {
+	want, err := fileattr.Parse(attrib)
+	if err != nil {
+		return false, err.Error(), "ATTRIB_DRIFT"
+	}
+	expandedLink, err := pathutil.NormalizePath(link)
+	if err != nil {
+		return false, fmt.Sprintf("无法展开链接路径 %s: %v", link, err), "PATH_EXPAND_FAIL"
+	}
+	ok, actual, err := fileattr.Check(expandedLink, want)
+	if err != nil {
+		return false, fmt.Sprintf("无法读取 %s 的文件属性: %v", link, err), "ATTRIB_DRIFT"
+	}
+	if !ok {
+		return false, fmt.Sprintf("%s 的文件属性为 %q，缺少声明的 %s", link, actual.String(), attrib), "ATTRIB_DRIFT"
+	}
 	return true, "", ""
 }
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish|powershell",
+	Short: "生成 shell 自动补全脚本",
+	Long: `生成 shell 自动补全脚本，--device 可补全存储中已有的设备名称，--store 可补全存储文件。
+  bash:       source <(flk completion bash)，或写入 /etc/bash_completion.d/flk
+  zsh:        flk completion zsh > "${fpath[1]}/_flk"
+  fish:       flk completion fish > ~/.config/fish/completions/flk.fish
+  powershell: flk completion powershell | Out-String | Invoke-Expression，可加入 $PROFILE`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	// 补全脚本只写入标准输出，不需要加载配置和存储
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	RunE:             RunCompletion,
+}
+
+func init() {
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.AddCommand(completionCmd)
+}
+
+// RunCompletion 将指定 shell 的补全脚本写入标准输出
+func RunCompletion(cmd *cobra.Command, args []string) error {
+	switch args[0] {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(os.Stdout, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(os.Stdout)
+	case "fish":
+		return rootCmd.GenFishCompletion(os.Stdout, true)
+	default:
+		return rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+	}
+}
+
+// registerCompletions 为所有带 --device 的命令注册设备名称补全，为 --store 注册存储文件补全；
+// 需要在所有命令注册完成后调用
+func registerCompletions() {
+	storeExts := []string{"json", "yaml", "yml", "toml"}
+	rootCmd.MarkPersistentFlagFilename("store", storeExts...)
+	rootCmd.MarkPersistentFlagFilename("storePath", storeExts...)
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		if c.LocalFlags().Lookup("device") != nil {
+			c.RegisterFlagCompletionFunc("device", completeDevices)
+		}
+		for _, sub := range c.Commands() {
+			walk(sub)
+		}
+	}
+	walk(rootCmd)
+}
+
+// completeDevices 补全设备名称：命令行中的存储（或默认存储）里已有的设备、all 和配置文件中本机的设备名称
+func completeDevices(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// 补全时根命令的初始化没有解析被补全命令的选项，需要按相同的优先级自行确定存储和配置文件
+	resolveStorePath(cmd)
+	devices := map[string]bool{"all": true}
+	if mgr, err := store.LoadFromFile(store.StorePath); err == nil {
+		for d := range mgr.DeviceCounts() {
+			devices[d] = true
+		}
+	}
+	if c, err := config.LoadFromFile(configPath); err == nil && c.Device != "" {
+		devices[c.Device] = true
+	}
+	return slices.Sorted(maps.Keys(devices)), cobra.ShellCompDirectiveNoFileComp
+}
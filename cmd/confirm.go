@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/pterm/pterm"
+)
+
+var (
+	assumeYes bool
+	assumeNo  bool
+)
+
+// promptMu 保证并发执行时同一时刻只有一个交互提示
+var promptMu sync.Mutex
+
+// confirmPolicy 返回命令生效的确认策略，--yes/--no 优先于配置文件
+func confirmPolicy(command string) string {
+	switch {
+	case assumeYes:
+		return config.ConfirmYes
+	case assumeNo:
+		return config.ConfirmNo
+	}
+	return config.Global.ConfirmPolicy(command)
+}
+
+// confirm 是所有需要用户确认的操作的统一入口，command 用于查找配置中的确认策略
+func confirm(command, message string, defaultYes bool) bool {
+	switch confirmPolicy(command) {
+	case config.ConfirmYes:
+		logger.Info(message + " 已自动同意")
+		return true
+	case config.ConfirmNo:
+		logger.Info(message + " 已自动拒绝")
+		return false
+	}
+
+	if !isInteractive() {
+		return defaultYes
+	}
+	promptMu.Lock()
+	defer promptMu.Unlock()
+	ok, err := pterm.DefaultInteractiveConfirm.WithDefaultValue(defaultYes).Show(message)
+	if err != nil {
+		logger.Error("输入错误 " + err.Error())
+		return false
+	}
+	return ok
+}
+
+// confirmForceDelete 在 --force 即将覆盖一个已存在且不是链接的文件或文件夹前征求确认
+func confirmForceDelete(path string) bool {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSymlink != 0 {
+		return true
+	}
+	return confirm("force", fmt.Sprintf("%s 已存在且不是符号链接，--force 将把它移到回收站，是否继续", path), true)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/create/symlink"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/pterm/pterm"
+	"golang.org/x/term"
+)
+
+// conflictChoice 表示遇到冲突链接时用户选择的处理方式
+type conflictChoice string
+
+const (
+	conflictKeep      conflictChoice = "keep"      // 保留现有链接，不修改文件系统也不写入存储
+	conflictOverwrite conflictChoice = "overwrite" // 覆盖现有链接，等同于 --force
+	conflictAdopt     conflictChoice = "adopt"     // 将现有链接按其实际目标收编进存储
+	conflictSkip      conflictChoice = "skip"      // 跳过本条，按失败处理
+)
+
+var conflictOptions = []string{
+	"保留现有链接",
+	"覆盖为新的链接",
+	"收编现有链接到存储",
+	"跳过",
+}
+
+var conflictChoices = map[string]conflictChoice{
+	"保留现有链接":    conflictKeep,
+	"覆盖为新的链接":   conflictOverwrite,
+	"收编现有链接到存储": conflictAdopt,
+	"跳过":        conflictSkip,
+}
+
+// rememberedConflictChoice 记录用户选择“记住选择”后的处理方式，在本次运行内对后续冲突生效
+var rememberedConflictChoice conflictChoice
+
+// headless 为 true 时（如作为 HTTP 服务运行）始终视为非交互环境，即使标准输入是终端
+var headless bool
+
+// isInteractive 判断标准输入是否为终端，非终端环境下不能弹出交互提示
+func isInteractive() bool {
+	return !headless && term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// resolveConflict 询问用户如何处理冲突，--yes/--no 时分别覆盖或跳过，非交互环境下保持原有行为，即跳过
+func resolveConflict(conflict *symlink.Conflict) conflictChoice {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+	if rememberedConflictChoice != "" {
+		logger.Info("沿用已记住的冲突处理方式 " + string(rememberedConflictChoice))
+		return rememberedConflictChoice
+	}
+	switch confirmPolicy("force") {
+	case config.ConfirmYes:
+		return conflictOverwrite
+	case config.ConfirmNo:
+		return conflictSkip
+	}
+	if !isInteractive() {
+		logger.Warn("非交互环境，跳过冲突的链接 " + conflict.FakePath)
+		return conflictSkip
+	}
+
+	pterm.Warning.Printf("%s 已是指向 %s 的符号链接\n", conflict.FakePath, conflict.ExistingTarget)
+	selected, err := pterm.DefaultInteractiveSelect.WithOptions(conflictOptions).Show("请选择处理方式")
+	if err != nil {
+		logger.Error("输入错误 " + err.Error())
+		return conflictSkip
+	}
+	choice := conflictChoices[selected]
+
+	remember, err := pterm.DefaultInteractiveConfirm.WithDefaultValue(false).Show("本次运行中的后续冲突是否使用相同的处理方式")
+	if err == nil && remember {
+		rememberedConflictChoice = choice
+	}
+	return choice
+}
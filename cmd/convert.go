@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/create/hardlink"
+	"github.com/jy-eggroll/flk/internal/create/junction"
+	"github.com/jy-eggroll/flk/internal/create/symlink"
+	"github.com/jy-eggroll/flk/internal/elevate"
+	"github.com/jy-eggroll/flk/internal/fileid"
+	"github.com/jy-eggroll/flk/internal/interference"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/jy-eggroll/flk/internal/trace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	convertTo     string
+	convertDevice string
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert <链接路径>",
+	Short: "原地转换已记录链接的类型",
+	Long: `将已记录的链接在磁盘上和存储中转换为另一种类型：
+  symlink  硬链接或目录联接转为指向同一目标的符号链接
+  hardlink 符号链接转为指向真实文件的硬链接（仅限同分区的文件）
+  junction 指向目录的符号链接转为目录联接（仅限 Windows）
+  copy     替换为目标文件内容的独立副本，并从存储中移除记录
+转换失败时会恢复原有链接`,
+	Args: cobra.ExactArgs(1),
+	RunE: RunConvert,
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+	convertCmd.Flags().StringVar(&convertTo, "to", "", "目标类型：symlink、hardlink、junction、copy")
+	convertCmd.Flags().StringVarP(&convertDevice, "device", "d", "", "仅在该设备的记录中查找")
+	convertCmd.MarkFlagRequired("to")
+}
+
+// convertRecord 待转换的存储记录
+type convertRecord struct {
+	device     string
+	linkType   string
+	parentPath string
+	entry      store.Entry
+	link       string // 链接文件的绝对路径
+	target     string // 目标文件的绝对路径
+}
+
+// RunConvert 转换参数指定的链接
+func RunConvert(cmd *cobra.Command, args []string) error {
+	format := output.OutputFormat(outputFormat)
+	fail := func(err error) error {
+		output.PrintCreateResult(format, output.CreateResult{Success: false, Type: "转换", Error: err.Error()})
+		return err
+	}
+
+	switch convertTo {
+	case "symlink", "hardlink", "copy":
+	case "junction":
+		if !junction.Supported {
+			return fail(errors.New("目录联接仅支持 Windows"))
+		}
+	default:
+		return fail(fmt.Errorf("无效的目标类型 %s，可选 symlink、hardlink、junction、copy", convertTo))
+	}
+
+	mgr, err := store.Current()
+	if err != nil {
+		return fail(err)
+	}
+	rec, err := findConvertRecord(mgr, args[0])
+	if err != nil {
+		return fail(err)
+	}
+	if rec.linkType == convertTo {
+		return fail(fmt.Errorf("%s 已经是 %s", rec.link, convertTo))
+	}
+
+	logger.Info(fmt.Sprintf("转换 %s：%s -> %s", rec.link, rec.linkType, convertTo))
+	if err := convertOnDisk(rec); err != nil {
+		return fail(err)
+	}
+	auditLink(true, convertTo, rec.link, rec.target)
+
+	removeConvertedLink(mgr, rec)
+	if convertTo != "copy" {
+		fields := map[string]string{}
+		for k, v := range rec.entry {
+			if store.IsMetadataKey(k) {
+				fields[k] = v
+			}
+		}
+		delete(fields, store.InodeKey)
+		delete(fields, store.AttribKey)
+		switch convertTo {
+		case "symlink", "junction":
+			fields["real"], fields["fake"] = rec.target, rec.link
+		case "hardlink":
+			fields["prim"], fields[store.SecoKey] = rec.target, rec.link
+			if id, err := fileid.Get(rec.link); err == nil {
+				fields[store.InodeKey] = id
+			}
+		}
+		foldSourceRoot(fields, targetKey(convertTo))
+		parentPath, err := pathutil.NormalizePath(rec.parentPath)
+		if err != nil {
+			parentPath = rec.parentPath
+		}
+		mgr.AddRecord(rec.device, convertTo, parentPath, fields)
+	}
+	if err := mgr.Save(store.StorePath); err != nil {
+		logger.Error("持久化失败 " + err.Error())
+	}
+
+	output.PrintCreateResult(format, output.CreateResult{Success: true, Type: "转换", Message: rec.linkType + " 已转换为 " + convertTo})
+	return nil
+}
+
+// findConvertRecord 在当前平台的符号链接、硬链接和目录联接记录中查找链接文件为 path 的记录
+func findConvertRecord(mgr *store.Manager, path string) (*convertRecord, error) {
+	normalized, err := pathutil.NormalizePath(path)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := pathutil.ToAbsolute(normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	for device, deviceData := range mgr.Data[runtime.GOOS] {
+		if convertDevice != "" && device != convertDevice {
+			continue
+		}
+		for _, linkType := range []string{"symlink", "hardlink", "junction"} {
+			for parentPath, entries := range deviceData[linkType] {
+				for _, entry := range entries {
+					basePath, err := recordBasePath(parentPath, entry)
+					if err != nil {
+						continue
+					}
+					links := []string{entry["fake"]}
+					if linkType == "hardlink" {
+						links = store.SecoPaths(entry)
+					}
+					for _, link := range links {
+						if resolveRecordPath(link, basePath) != abs {
+							continue
+						}
+						return &convertRecord{
+							device:     device,
+							linkType:   linkType,
+							parentPath: parentPath,
+							entry:      entry,
+							link:       abs,
+							target:     resolveRecordPath(entry[targetKey(linkType)], basePath),
+						}, nil
+					}
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("存储中没有链接文件为 %s 的符号链接、硬链接或目录联接记录", abs)
+}
+
+// resolveRecordPath 将记录中的路径展开为绝对路径，相对路径基于记录所在的父路径
+func resolveRecordPath(path, basePath string) string {
+	if expanded, err := pathutil.NormalizePath(path); err == nil {
+		path = expanded
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(basePath, path)
+	}
+	return filepath.Clean(path)
+}
+
+// recordBasePath 返回解析记录中相对路径的基准目录：记录了源根目录时为本机配置的该目录，否则为记录所在的父路径
+func recordBasePath(parentPath string, entry store.Entry) (string, error) {
+	name := entry[store.RootKey]
+	if name == "" {
+		return mustNormalize(parentPath), nil
+	}
+	root, ok := config.Global.SourceRoots[name]
+	if !ok {
+		return "", fmt.Errorf("源根目录 %s 未在本机的配置文件 source_roots 中声明", name)
+	}
+	return pathutil.ToAbsolute(mustNormalize(root))
+}
+
+// convertOnDisk 先将原链接移到备份位置再创建新的表示，失败时恢复原链接
+func convertOnDisk(rec *convertRecord) error {
+	info, err := trace.Stat(rec.target)
+	if err != nil {
+		return fmt.Errorf("无法访问目标文件 %s: %v", rec.target, err)
+	}
+	if info.IsDir() && convertTo != "symlink" && convertTo != "junction" {
+		return fmt.Errorf("%s 是目录，无法转换为 %s", rec.target, convertTo)
+	}
+	if !info.IsDir() && convertTo == "junction" {
+		return fmt.Errorf("%s 不是目录，目录联接只能指向目录", rec.target)
+	}
+
+	backup := rec.link + ".flk-convert"
+	if err := trace.Rename(rec.link, backup); err != nil {
+		return interference.Explain(rec.link, err)
+	}
+
+	switch convertTo {
+	case "symlink":
+		err = symlink.Create(rec.target, rec.link, false)
+		if elevate.Needed(err) {
+			err = createSymlinkElevated(rec.target, rec.link, false, false)
+		}
+	case "hardlink":
+		err = hardlink.Create(rec.target, rec.link, false)
+	case "junction":
+		err = junction.Create(rec.target, rec.link, false)
+	case "copy":
+		err = copyFile(rec.target, rec.link, info.Mode().Perm())
+	}
+	if err != nil {
+		trace.Remove(rec.link)
+		if restoreErr := trace.Rename(backup, rec.link); restoreErr != nil {
+			return fmt.Errorf("%v，且恢复原链接失败，原链接位于 %s: %v", err, backup, restoreErr)
+		}
+		return err
+	}
+	return trace.Remove(backup)
+}
+
+// copyFile 将 src 的内容复制为新文件 dst
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// removeConvertedLink 从存储中移除被转换的链接，一组硬链接只移除其中的该次要文件
+func removeConvertedLink(mgr *store.Manager, rec *convertRecord) {
+	dropRecordLink(mgr, rec.device, rec.linkType, rec.parentPath, rec.entry, rec.link)
+}
@@ -1,14 +1,49 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 
+	"github.com/jy-eggroll/flk/internal/audit"
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/create/junction"
+	"github.com/jy-eggroll/flk/internal/fileattr"
+	"github.com/jy-eggroll/flk/internal/interference"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/manifest"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/state"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/jy-eggroll/flk/internal/trace"
+	"github.com/jy-eggroll/flk/internal/trash"
+	"github.com/jy-eggroll/flk/internal/validate"
 	"github.com/spf13/cobra"
 )
 
 var (
-	createForce  bool
-	createDevice string
+	createForce   bool
+	createDevice  string
+	createMode    string
+	createEvery   string
+	createAttrib  string
+	createHydrate bool
+	// createValidate 修复前对真实文件的校验规格
+	createValidate string
+	// createNote 记录的备注
+	createNote string
+	// createLinkStyle 符号链接的写法 relative 或 absolute，为空时使用相对路径
+	createLinkStyle string
+	// createDirLink 真实文件为目录时创建的链接类型 symlink 或 junction，为空时创建符号链接
+	createDirLink string
+
+	// createWarnings 本次创建过程中产生的警告，随创建结果一起输出
+	createWarnings []string
 )
 
 var createCmd = &cobra.Command{
@@ -23,3 +58,379 @@ var createCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(createCmd)
 }
+
+// createResultHook 非空时创建结果交给它处理而不输出，用于 server 等在进程内直接调用创建函数的场景
+var createResultHook func(output.CreateResult)
+
+// printCreateResult 输出创建结果
+func printCreateResult(format output.OutputFormat, result output.CreateResult) error {
+	if createResultHook != nil {
+		createResultHook(result)
+		return nil
+	}
+	return output.PrintCreateResult(format, result)
+}
+
+// warnCreate 记录一条创建过程中的警告
+func warnCreate(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	logger.Warn(msg)
+	createWarnings = append(createWarnings, msg)
+}
+
+// forceRemovalNotes 返回 --force 创建时将被覆盖或移走的非链接文件的说明，移走本身由 beginCreate 完成
+func forceRemovalNotes(link string) []string {
+	var notes []string
+	if info, err := os.Lstat(link); err == nil && info.Mode()&os.ModeSymlink == 0 {
+		notes = append(notes, "已覆盖原有的 "+link)
+	}
+	if info, err := os.Stat(filepath.Dir(link)); err == nil && !info.IsDir() {
+		notes = append(notes, "父路径 "+filepath.Dir(link)+" 是文件，已移到回收站以创建目录")
+	}
+	return notes
+}
+
+// createTxn 一次创建对文件系统的改动，写入存储失败时据此撤销，使存储与文件系统不会悄然不一致
+type createTxn struct {
+	link   string
+	backup string   // --force 覆盖前原文件的备份位置，为空表示原先不存在
+	notes  []string // 事务完成时作为警告输出的说明
+	// existed 未使用 --force 时链接位置原本就有文件，此时创建必然失败，撤销时不能删除这个不属于 flk 的文件
+	existed bool
+	// parent 为创建目录而移到回收站的父路径文件
+	parent *trash.Item
+	// trashable 被覆盖的原文件不是符号链接，事务完成时移到回收站
+	trashable bool
+}
+
+// beginCreate 在创建链接前开始事务，需要覆盖已存在的文件时先将其移到备份位置
+func beginCreate(link string, force bool) (*createTxn, error) {
+	t := &createTxn{link: link}
+	if !force {
+		t.existed = pathExists(link)
+		return t, nil
+	}
+	t.notes = forceRemovalNotes(link)
+	if info, err := os.Stat(filepath.Dir(link)); err == nil && !info.IsDir() {
+		item, err := trash.Move(trashDir(), filepath.Dir(link), filepath.Dir(link))
+		if err != nil {
+			return nil, err
+		}
+		t.parent = &item
+	}
+	if !pathExists(link) {
+		return t, nil
+	}
+	if info, err := os.Lstat(link); err == nil {
+		t.trashable = info.Mode()&os.ModeSymlink == 0
+	}
+	t.backup = fmt.Sprintf("%s.flk-bak-%d", link, time.Now().UnixNano())
+	if err := trace.Rename(link, t.backup); err != nil {
+		return nil, fmt.Errorf("无法备份 %s: %v", link, interference.Explain(link, err))
+	}
+	return t, nil
+}
+
+// rollback 删除新创建的链接并恢复被覆盖的原文件
+func (t *createTxn) rollback() error {
+	if t.existed {
+		return nil
+	}
+	if err := trace.RemoveAll(t.link); err != nil {
+		return err
+	}
+	if t.backup != "" {
+		return trace.Rename(t.backup, t.link)
+	}
+	if t.parent != nil {
+		// 移除创建链接时建立的父目录后放回原来的文件
+		trace.Remove(filepath.Dir(t.link))
+		_, err := trash.Restore(trashDir(), t.parent.ID)
+		return err
+	}
+	return nil
+}
+
+// commit 完成事务：被覆盖的原文件从备份位置移到回收站，原先是符号链接时直接删除
+func (t *createTxn) commit() {
+	for _, note := range t.notes {
+		warnCreate("%s", note)
+	}
+	if t.parent != nil {
+		warnTrashed(*t.parent)
+	}
+	if t.backup == "" {
+		return
+	}
+	if t.trashable {
+		item, err := trash.Move(trashDir(), t.backup, t.link)
+		if err != nil {
+			warnCreate("无法将 %s 移到回收站：%v", t.backup, err)
+			return
+		}
+		warnTrashed(item)
+		return
+	}
+	if err := trace.RemoveAll(t.backup); err != nil {
+		warnCreate("无法删除备份 %s：%v", t.backup, err)
+	}
+}
+
+// trashDir 返回回收站目录
+func trashDir() string {
+	return mustNormalize(trash.DefaultDir)
+}
+
+// moveToTrash 将 --force 即将覆盖的非链接文件移到回收站，用于不经过 beginCreate 直接调用创建函数的场景；
+// 符号链接不需要保留，仍由创建函数直接删除
+func moveToTrash(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSymlink != 0 {
+		return nil
+	}
+	item, err := trash.Move(trashDir(), path, path)
+	if err != nil {
+		return err
+	}
+	warnTrashed(item)
+	return nil
+}
+
+// warnTrashed 提示被覆盖的文件已移到回收站及恢复方法
+func warnTrashed(item trash.Item) {
+	warnCreate("原有的 %s 已移到回收站，可用 flk trash restore %s 恢复", item.Path, item.ID)
+}
+
+// abort 撤销事务并返回描述失败原因的错误
+func (t *createTxn) abort(cause error) error {
+	if err := t.rollback(); err != nil {
+		return fmt.Errorf("%v，且撤销失败: %v", cause, err)
+	}
+	return cause
+}
+
+// saveRecord 写入一条创建记录并持久化，同一设备下链接文件相同的旧记录会被替换；
+// 持久化失败时撤销内存中的改动并返回错误
+func saveRecord(linkType string, fields map[string]string) error {
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	platform := runtime.GOOS
+	link := store.LinkPath(fields)
+	foldedLink, _ := pathutil.FoldHome(link)
+
+	type oldRecord struct {
+		parentPath string
+		entry      store.Entry
+	}
+	var replaced []oldRecord
+	for {
+		parentPath, entry, ok := mgr.FindEntry(platform, createDevice, linkType, store.Entry{linkKey(linkType): foldedLink})
+		if !ok {
+			break
+		}
+		mgr.RemoveMatchingEntry(platform, createDevice, linkType, parentPath, entry)
+		replaced = append(replaced, oldRecord{parentPath, entry})
+	}
+
+	if len(replaced) > 0 {
+		store.KeepOrigin(fields, replaced[0].entry)
+	}
+	foldSourceRoot(fields, targetKey(linkType))
+	parentPath := filepath.Dir(link)
+	mgr.AddRecord(createDevice, linkType, parentPath, fields)
+	if err := mgr.Save(store.StorePath); err != nil {
+		foldedParent, _ := pathutil.FoldHome(parentPath)
+		mgr.RemoveMatchingEntry(platform, createDevice, linkType, foldedParent, store.Entry{linkKey(linkType): foldedLink})
+		paths := mgr.Data[platform][createDevice][linkType]
+		for _, r := range replaced {
+			paths[r.parentPath] = append(paths[r.parentPath], r.entry)
+		}
+		return err
+	}
+	return nil
+}
+
+// parseCreateOptions 校验创建命令中与链接类型无关的选项
+func parseCreateOptions() error {
+	if _, err := state.ParseFrequency(createEvery); err != nil {
+		return err
+	}
+	switch createLinkStyle {
+	case "", config.LinkStyleRelative, config.LinkStyleAbsolute:
+	default:
+		return fmt.Errorf("无效的链接写法 %s，可选 %s、%s", createLinkStyle, config.LinkStyleRelative, config.LinkStyleAbsolute)
+	}
+	switch createDirLink {
+	case "", config.DirLinkSymlink, config.DirLinkJunction:
+	default:
+		return fmt.Errorf("无效的目录链接类型 %s，可选 %s、%s", createDirLink, config.DirLinkSymlink, config.DirLinkJunction)
+	}
+	_, err := validate.Parse(createValidate)
+	return err
+}
+
+// addOptionFields 将创建选项中需要记录的部分写入 fields
+func addOptionFields(fields map[string]string) {
+	if createMode != "" {
+		fields[store.ModeKey] = createMode
+	}
+	if createEvery != "" {
+		fields[store.FrequencyKey] = createEvery
+	}
+	if createValidate != "" {
+		fields[store.ValidateKey] = createValidate
+	}
+	if createNote != "" {
+		fields[store.NoteKey] = createNote
+	}
+}
+
+// pathExists 判断路径（不跟随符号链接）是否存在
+func pathExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// auditLink 为一次成功的链接创建写入审计事件，replaced 表示创建前删除了已存在的文件
+func auditLink(replaced bool, linkType, link, target string) {
+	action := audit.ActionCreate
+	if replaced {
+		action = audit.ActionReplace
+	}
+	audit.Emit(audit.Event{Action: action, Type: linkType, Link: link, Target: target})
+}
+
+// ensureHydrated 检查源文件是否为云端占位文件，未指定 --hydrate 时拒绝继续，指定时先将其下载到本地
+func ensureHydrated(path string) error {
+	placeholder, err := fileattr.IsPlaceholder(path)
+	if err != nil || !placeholder {
+		return nil
+	}
+	if !createHydrate {
+		return fmt.Errorf("%s 是仅在线的云端占位文件，继续操作会触发下载，如需继续请指定 --hydrate", path)
+	}
+	logger.Info("正在下载云端占位文件 " + path)
+	return fileattr.Hydrate(path)
+}
+
+// foldSourceRoot 源文件位于某个源根目录下时，将 fields[key] 改写为相对于该目录的路径并记录目录名称，
+// 使记录在源根目录位置不同的机器上同样有效
+func foldSourceRoot(fields map[string]string, key string) {
+	delete(fields, store.RootKey)
+	abs, err := pathutil.ToAbsolute(mustNormalize(fields[key]))
+	if err != nil {
+		return
+	}
+	if name, rel, ok := config.Global.SourceRoot(abs); ok {
+		fields[key] = rel
+		fields[store.RootKey] = name
+		if currentWorkspace != nil && name == currentWorkspace.Root {
+			persistWorkspaceRoot()
+		}
+	}
+}
+
+// applyLinkRules 按配置中的目录规则为链接文件填充命令行中未显式指定的选项
+func applyLinkRules(cmd *cobra.Command, link string) {
+	if cmd == nil {
+		// 由 fix 等内部调用时选项已由调用方设置
+		return
+	}
+	abs, err := pathutil.ToAbsolute(link)
+	if err != nil {
+		return
+	}
+	d := config.Global.DefaultsFor(abs)
+	set := func(flag string, target *string, value string) {
+		if value == "" || cmd.Flags().Lookup(flag) == nil || cmd.Flags().Changed(flag) {
+			return
+		}
+		*target = value
+		logger.Info("按配置规则使用 --" + flag + "=" + value)
+	}
+	set("mode", &createMode, d.Mode)
+	set("attrib", &createAttrib, d.Attrib)
+	set("check-every", &createEvery, d.CheckEvery)
+	set("link-style", &createLinkStyle, d.LinkStyle)
+	set("dir-link", &createDirLink, d.DirLink)
+}
+
+// applyManifestRules 按配置中的目录规则为清单记录填充未声明的选项
+func applyManifestRules(m *manifest.Manifest) {
+	for i := range m.Records {
+		rec := &m.Records[i]
+		d := config.Global.DefaultsFor(rec.Link())
+		if rec.Mode == "" {
+			rec.Mode = d.Mode
+		}
+		if rec.Frequency == "" {
+			rec.Frequency = d.CheckEvery
+		}
+		if rec.Type != "symlink" {
+			continue
+		}
+		if rec.Attrib == "" {
+			rec.Attrib = d.Attrib
+		}
+		if rec.LinkStyle == "" {
+			rec.LinkStyle = d.LinkStyle
+		}
+		if d.DirLink == config.DirLinkJunction && junctionFor(rec.Real) {
+			rec.Type, rec.Attrib, rec.LinkStyle = "junction", "", ""
+		}
+	}
+}
+
+// junctionFor 判断按规则应创建目录联接时能否为 target 创建：target 必须是目录，且当前平台支持目录联接；
+// 规则可能在多个平台间共享，不支持目录联接的平台上仍创建符号链接
+func junctionFor(target string) bool {
+	if info, err := trace.Stat(target); err != nil || !info.IsDir() {
+		return false
+	}
+	if !junction.Supported {
+		logger.Debug("当前平台不支持目录联接，为 " + target + " 创建符号链接")
+		return false
+	}
+	return true
+}
+
+// checkStrict 在严格模式下拒绝不可移植的记录：源文件不在任何源根目录下，或链接文件位于非系统盘符
+func checkStrict(target, link string) error {
+	cfg := config.Global
+	if !cfg.Strict {
+		return nil
+	}
+	absTarget, err := pathutil.ToAbsolute(target)
+	if err != nil {
+		return err
+	}
+	if len(cfg.SourceRoots) == 0 {
+		return errors.New("严格模式要求在配置文件的 source_roots 中声明至少一个源根目录")
+	}
+	if _, _, ok := cfg.SourceRoot(absTarget); !ok {
+		return fmt.Errorf("严格模式：%s 不在任何源根目录下，请将其移入源根目录或在 source_roots 中添加其所在目录", absTarget)
+	}
+	if drive := driveLetter(link); drive != "" && !strings.EqualFold(drive, systemDrive()) {
+		return fmt.Errorf("严格模式：%s 位于盘符 %s，其他机器上可能不存在该盘符，请改用 ~ 下的路径", link, drive)
+	}
+	return nil
+}
+
+// driveLetter 返回 Windows 风格绝对路径的盘符（如 D:），不是此类路径时返回空字符串
+func driveLetter(path string) string {
+	if len(path) >= 3 && path[1] == ':' && (path[2] == '\\' || path[2] == '/') &&
+		(path[0] >= 'a' && path[0] <= 'z' || path[0] >= 'A' && path[0] <= 'Z') {
+		return path[:2]
+	}
+	return ""
+}
+
+// systemDrive 返回系统盘符，只有系统盘符在所有 Windows 机器上都存在
+func systemDrive() string {
+	if drive := os.Getenv("SystemDrive"); drive != "" {
+		return drive
+	}
+	return "C:"
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/audit"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete [链接路径]...",
+	Short: "删除由 flk 管理的链接及其记录",
+	Long: `删除当前平台上被选中的链接文件，并从存储中移除对应的记录；真实文件不会被删除。
+按链接路径指定要删除的链接，或用 --path 按子串选择链接路径或真实文件路径包含该内容的全部记录。
+链接文件已被替换为普通文件或指向其他位置时不会删除，文件和记录都会保留并显示警告，以免误删用户数据；使用 --record-only 只移除记录、保留磁盘上的文件。
+非交互式运行时默认取消，需要指定 --yes`,
+	RunE: RunDelete,
+}
+
+var (
+	deleteSymlink    bool
+	deleteHardlink   bool
+	deleteTemplate   bool
+	deleteDevice     string
+	deletePath       string
+	deleteRecordOnly bool
+)
+
+func init() {
+	rootCmd.AddCommand(deleteCmd)
+	deleteCmd.Flags().BoolVar(&deleteSymlink, "symlink", false, "只删除符号链接")
+	deleteCmd.Flags().BoolVar(&deleteHardlink, "hardlink", false, "只删除硬链接")
+	deleteCmd.Flags().BoolVar(&deleteTemplate, "template", false, "只删除模板生成的文件")
+	deleteCmd.Flags().StringVarP(&deleteDevice, "device", "d", "", "只删除该设备的记录")
+	deleteCmd.Flags().StringVar(&deletePath, "path", "", "删除链接路径或真实文件路径包含该子串的记录")
+	deleteCmd.Flags().BoolVar(&deleteRecordOnly, "record-only", false, "只从存储中移除记录，不删除磁盘上的链接")
+}
+
+// deleteTarget 一个待删除的链接
+type deleteTarget struct {
+	device     string
+	linkType   string
+	parentPath string
+	entry      store.Entry
+	link       string // 链接文件的绝对路径
+	target     string // 真实文件的绝对路径
+}
+
+// RunDelete 删除选中的链接并移除记录
+func RunDelete(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 && deletePath == "" {
+		return errors.New("请指定要删除的链接路径，或使用 --path 选择记录")
+	}
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	targets := selectDeleteTargets(mgr, args)
+	if len(targets) == 0 {
+		return errors.New("没有匹配的记录")
+	}
+
+	action := "删除"
+	if deleteRecordOnly {
+		action = "移除记录"
+	}
+	var lines []string
+	for _, t := range targets {
+		lines = append(lines, fmt.Sprintf("  %s %s -> %s", t.linkType, t.link, t.target))
+	}
+	if !confirm("delete", fmt.Sprintf("将%s以下 %d 个链接：\n%s\n是否继续", action, len(targets), strings.Join(lines, "\n")), false) {
+		return errors.New("已取消删除")
+	}
+
+	var results []output.ApplyResult
+	failed := 0
+	for _, t := range targets {
+		result := output.ApplyResult{Action: "remove", Type: t.linkType, Link: t.link, Success: true}
+		if err := deleteLink(mgr, t); err != nil {
+			// 链接已被替换或指向其他位置时保留文件和记录
+			logger.Warn("已跳过 " + err.Error())
+			result.Success, result.Error = false, err.Error()
+			failed++
+		}
+		results = append(results, result)
+	}
+	if err := mgr.Save(store.StorePath); err != nil {
+		logger.Error("持久化失败 " + err.Error())
+		return err
+	}
+	if err := output.PrintApplyResults(output.OutputFormat(outputFormat), results); err != nil {
+		logger.Error("输出失败 " + err.Error())
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个链接删除失败", failed)
+	}
+	return nil
+}
+
+// selectDeleteTargets 按类型、设备和路径条件选出当前平台上待删除的链接，按链接路径排序
+func selectDeleteTargets(mgr *store.Manager, links []string) []deleteTarget {
+	wanted := make(map[string]bool)
+	for _, l := range links {
+		if abs, err := pathutil.ToAbsolute(mustNormalize(l)); err == nil {
+			wanted[abs] = true
+		}
+	}
+	typeFilter := map[string]bool{"symlink": deleteSymlink, "hardlink": deleteHardlink, "template": deleteTemplate}
+	anyType := !deleteSymlink && !deleteHardlink && !deleteTemplate
+
+	var targets []deleteTarget
+	for device, types := range mgr.Data[runtime.GOOS] {
+		if deleteDevice != "" && device != deleteDevice {
+			continue
+		}
+		for linkType, paths := range types {
+			if !anyType && !typeFilter[linkType] {
+				continue
+			}
+			for parentPath, entries := range paths {
+				for _, entry := range entries {
+					basePath, err := recordBasePath(parentPath, entry)
+					if err != nil {
+						logger.Warn(err.Error())
+						continue
+					}
+					target := resolveRecordPath(entry[targetKey(linkType)], basePath)
+					recordLinks := []string{entry[linkKey(linkType)]}
+					if linkType == "hardlink" {
+						recordLinks = store.SecoPaths(entry)
+					}
+					for _, l := range recordLinks {
+						link := resolveRecordPath(l, mustNormalize(parentPath))
+						matched := false
+						for w := range wanted {
+							if samePath(w, link) {
+								matched = true
+								break
+							}
+						}
+						if deletePath != "" && (strings.Contains(link, deletePath) || strings.Contains(target, deletePath)) {
+							matched = true
+						}
+						if matched {
+							targets = append(targets, deleteTarget{device, linkType, parentPath, entry, link, target})
+						}
+					}
+				}
+			}
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].link < targets[j].link })
+	return targets
+}
+
+// deleteLink 删除一个链接并从存储中移除，链接不是 flk 创建的样子时保留文件并返回错误
+func deleteLink(mgr *store.Manager, t deleteTarget) error {
+	if !deleteRecordOnly {
+		if err := removeManagedLink(t.linkType, t.target, t.link, t.device); err != nil {
+			return err
+		}
+		audit.Emit(audit.Event{Action: audit.ActionDelete, Type: t.linkType, Link: t.link, Target: t.target})
+		fields := map[string]string{}
+		for k, v := range t.entry {
+			fields[k] = mustNormalize(v)
+		}
+		if err := restoreAppLink(t.link, fields); err != nil {
+			logger.Warn(err.Error())
+		}
+	}
+	dropRecordLink(mgr, t.device, t.linkType, t.parentPath, t.entry, t.link)
+	return nil
+}
+
+// dropRecordLink 从存储中移除链接 link 的记录，一组硬链接只移除其中的该次要文件
+func dropRecordLink(mgr *store.Manager, device, linkType, parentPath string, entry store.Entry, link string) {
+	if linkType != "hardlink" {
+		mgr.RemoveMatchingEntry(runtime.GOOS, device, linkType, parentPath, store.Entry{"real": entry["real"], "fake": entry["fake"]})
+		return
+	}
+	basePath, err := pathutil.NormalizePath(parentPath)
+	if err != nil {
+		basePath = parentPath
+	}
+	var remaining []string
+	for _, seco := range store.SecoPaths(entry) {
+		if resolveRecordPath(seco, basePath) != link {
+			remaining = append(remaining, seco)
+		}
+	}
+	if len(remaining) == 0 {
+		mgr.RemoveMatchingEntry(runtime.GOOS, device, linkType, parentPath, store.Entry{"prim": entry["prim"], store.SecoKey: entry[store.SecoKey]})
+		return
+	}
+	// 条目是引用类型，直接重写次要文件字段
+	for i := 0; ; i++ {
+		if _, ok := entry[store.SecoKeyAt(i)]; !ok {
+			break
+		}
+		delete(entry, store.SecoKeyAt(i))
+	}
+	for i, seco := range remaining {
+		entry[store.SecoKeyAt(i)] = seco
+	}
+}
@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/state"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var deviceCmd = &cobra.Command{
+	Use:   "device",
+	Short: "管理记录中的设备名称",
+}
+
+var deviceRenameCmd = &cobra.Command{
+	Use:   "rename <旧名称> <新名称>",
+	Short: "将所有记录的设备名称批量改名",
+	Long:  "将所有平台下旧设备名称的记录移到新名称下，新名称已有记录时合并；模板会按设备名称渲染，可使用 --recheck 在改名后立即检查这些记录",
+	Args:  cobra.ExactArgs(2),
+	RunE:  RunDeviceRename,
+}
+
+var deviceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出存储中的设备及其在各平台下的记录数",
+	Args:  cobra.NoArgs,
+	RunE:  RunDeviceList,
+}
+
+var deviceRemoveCmd = &cobra.Command{
+	Use:   "remove <名称>",
+	Short: "删除一个设备在所有平台下的全部记录",
+	Long:  "删除一个设备在所有平台下的全部记录，只修改存储，不删除链接文件；删除前会列出各平台的记录数并征求确认",
+	Args:  cobra.ExactArgs(1),
+	RunE:  RunDeviceRemove,
+}
+
+var deviceSetCmd = &cobra.Command{
+	Use:   "set <名称>",
+	Short: "将本机的设备名称写入配置文件",
+	Long:  "将本机的设备名称写入配置文件，之后 create、check、fix 未指定 --device 时使用该名称；check 和 fix 同时包含设备为 all 的记录",
+	Args:  cobra.ExactArgs(1),
+	RunE:  RunDeviceSet,
+}
+
+var deviceAutoCmd = &cobra.Command{
+	Use:   "auto",
+	Short: "以主机名作为本机的设备名称写入配置文件",
+	Args:  cobra.NoArgs,
+	RunE:  RunDeviceAuto,
+}
+
+var deviceRecheck bool
+
+// implicitDevice 为 true 时 --device 取自配置文件而非命令行，check 和 fix 需要同时包含设备为 all 的记录
+var implicitDevice bool
+
+func init() {
+	rootCmd.AddCommand(deviceCmd)
+	deviceCmd.AddCommand(deviceListCmd)
+	deviceCmd.AddCommand(deviceRenameCmd)
+	deviceCmd.AddCommand(deviceRemoveCmd)
+	deviceCmd.AddCommand(deviceSetCmd)
+	deviceCmd.AddCommand(deviceAutoCmd)
+	deviceRenameCmd.Flags().BoolVar(&deviceRecheck, "recheck", false, "改名后检查新设备名称下的全部记录")
+}
+
+// initDevice 配置文件中设置了设备名称时，将其作为 create、adopt、check、fix 中未显式指定的 --device
+func initDevice(cmd *cobra.Command) {
+	implicitDevice = false
+	device := config.Global.Device
+	if device == "" || (cmd.Parent() != createCmd && cmd != adoptCmd && cmd != checkCmd && cmd != fixCmd) {
+		return
+	}
+	f := cmd.Flags().Lookup("device")
+	if f == nil || f.Changed {
+		return
+	}
+	f.Value.Set(device)
+	implicitDevice = cmd == checkCmd || cmd == fixCmd
+	logger.Debug("使用配置文件中的设备名称 " + device)
+}
+
+// RunDeviceSet 将指定的设备名称写入配置文件
+func RunDeviceSet(cmd *cobra.Command, args []string) error {
+	return saveDevice(args[0])
+}
+
+// RunDeviceAuto 将主机名作为设备名称写入配置文件
+func RunDeviceAuto(cmd *cobra.Command, args []string) error {
+	host, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("无法获取主机名：%v", err)
+	}
+	return saveDevice(host)
+}
+
+// saveDevice 将设备名称写入配置文件，不影响其他配置项
+func saveDevice(device string) error {
+	if device == "" || device == "all" {
+		return fmt.Errorf("设备名称不能为空或 all")
+	}
+	c, err := config.LoadFromFile(configPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		c = &config.Config{}
+	}
+	c.Device = device
+	if err := c.Save(configPath); err != nil {
+		logger.Error("保存配置失败 " + err.Error())
+		return err
+	}
+	config.Global.Device = device
+	output.PrintCreateResult(output.OutputFormat(outputFormat), output.CreateResult{Success: true, Type: "设备名称", Message: "本机设备名称已设为 " + device})
+	return nil
+}
+
+// RunDeviceRename 批量修改设备名称，并同步状态文件中的检查时间
+func RunDeviceRename(cmd *cobra.Command, args []string) error {
+	from, to := args[0], args[1]
+	if from == to {
+		return fmt.Errorf("新旧设备名称相同")
+	}
+
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	moved := mgr.RenameDevice(from, to)
+	if moved == 0 {
+		return fmt.Errorf("存储中没有设备 %s 的记录", from)
+	}
+	if err := mgr.SaveAtomic(store.StorePath); err != nil {
+		logger.Error("持久化失败 " + err.Error())
+		return err
+	}
+
+	statePath := state.PathFor(store.StorePath)
+	if st, err := state.Load(statePath); err == nil {
+		st.RenameDevice(from, to)
+		if err := st.Save(statePath); err != nil {
+			logger.Warn("写入状态文件失败 " + err.Error())
+		}
+	}
+
+	format := output.OutputFormat(outputFormat)
+	if !deviceRecheck {
+		output.PrintCreateResult(format, output.CreateResult{Success: true, Type: "设备改名", Message: fmt.Sprintf("已将 %d 条记录从 %s 移到 %s", moved, from, to)})
+		return nil
+	}
+
+	pterm.Info.Printf("已将 %d 条记录从 %s 移到 %s，正在检查\n", moved, from, to)
+	results, err := performCheck(CheckOptions{DeviceFilter: to})
+	if err != nil {
+		return err
+	}
+	if err := output.PrintCheckResults(format, results); err != nil {
+		return err
+	}
+	invalid := 0
+	for _, r := range results {
+		if !r.Valid {
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		return fmt.Errorf("改名后有 %d 条记录无效，可使用 flk fix -d %s 修复", invalid, to)
+	}
+	return nil
+}
+
+// RunDeviceList 列出存储中的设备及其在各平台下的记录数
+func RunDeviceList(cmd *cobra.Command, args []string) error {
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	counts := mgr.DeviceCounts()
+	format := output.OutputFormat(outputFormat)
+	if len(counts) == 0 && format == output.Table {
+		fmt.Println("存储中没有记录")
+		return nil
+	}
+	var devices []output.DeviceSummary
+	for _, device := range slices.Sorted(maps.Keys(counts)) {
+		d := output.DeviceSummary{Device: device, Counts: counts[device], Current: device == config.Global.Device}
+		for _, n := range d.Counts {
+			d.Total += n
+		}
+		devices = append(devices, d)
+	}
+	return output.PrintDevices(format, slices.Sorted(maps.Keys(mgr.Data)), devices)
+}
+
+// RunDeviceRemove 确认后删除一个设备的全部记录及其运行状态
+func RunDeviceRemove(cmd *cobra.Command, args []string) error {
+	device := args[0]
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	counts := mgr.DeviceCounts()[device]
+	if len(counts) == 0 {
+		return fmt.Errorf("存储中没有设备 %s 的记录", device)
+	}
+	var lines []string
+	for _, platform := range slices.Sorted(maps.Keys(counts)) {
+		lines = append(lines, fmt.Sprintf("  %s: %d", platform, counts[platform]))
+	}
+	if !confirm("device", fmt.Sprintf("将从存储中删除设备 %s 的以下记录（链接文件保持不变）：\n%s\n是否继续", device, strings.Join(lines, "\n")), false) {
+		return errors.New("已取消删除")
+	}
+
+	removed := mgr.RemoveByDevice("", device)
+	if err := mgr.SaveAtomic(store.StorePath); err != nil {
+		logger.Error("持久化失败 " + err.Error())
+		return err
+	}
+	statePath := state.PathFor(store.StorePath)
+	if st, err := state.Load(statePath); err == nil {
+		st.RemoveDevice(device)
+		if err := st.Save(statePath); err != nil {
+			logger.Warn("写入状态文件失败 " + err.Error())
+		}
+	}
+	output.PrintCreateResult(output.OutputFormat(outputFormat), output.CreateResult{Success: true, Type: "删除设备", Message: fmt.Sprintf("已删除设备 %s 的 %d 条记录", device, removed)})
+	return nil
+}
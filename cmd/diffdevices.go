@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var diffDevicesCmd = &cobra.Command{
+	Use:   "diff-devices <设备A> <设备B>",
+	Short: "比较两个设备的记录，并可将缺少的记录复制过去",
+	Long: `按目标文件比较当前平台上两个设备的记录，列出只有一方记录了的目标和两边链接位置不同的目标。
+使用 --copy-to 将另一设备独有的记录复制到指定设备，--map 可在复制时替换路径前缀，如 --map /home/alice=/home/bob`,
+	Args: cobra.ExactArgs(2),
+	RunE: RunDiffDevices,
+}
+
+var (
+	diffCopyTo string
+	diffMaps   []string
+)
+
+func init() {
+	rootCmd.AddCommand(diffDevicesCmd)
+	diffDevicesCmd.Flags().StringVar(&diffCopyTo, "copy-to", "", "将另一设备独有的记录复制到该设备，须为比较的两个设备之一")
+	diffDevicesCmd.Flags().StringArrayVar(&diffMaps, "map", nil, "复制时的路径前缀替换，格式为 旧前缀=新前缀，可重复指定")
+}
+
+// deviceRecords 设备的记录按类型和目标文件分组，值为链接路径到条目的映射
+type deviceRecords map[string]map[string]store.Entry
+
+// diffKey 返回记录的比较键，记录了源根目录的目标以 root:名称/相对路径 表示
+func diffKey(linkType string, entry store.Entry) string {
+	target := entry[targetKey(linkType)]
+	if root := entry[store.RootKey]; root != "" {
+		target = "root:" + root + "/" + target
+	}
+	return linkType + "\x00" + target
+}
+
+// collectDeviceRecords 收集当前平台上设备的全部记录
+func collectDeviceRecords(mgr *store.Manager, device string) deviceRecords {
+	records := make(deviceRecords)
+	for linkType, paths := range mgr.Data[runtime.GOOS][device] {
+		for _, entries := range paths {
+			for _, entry := range entries {
+				key := diffKey(linkType, entry)
+				if records[key] == nil {
+					records[key] = make(map[string]store.Entry)
+				}
+				links := []string{entry["fake"]}
+				if linkType == "hardlink" {
+					links = store.SecoPaths(entry)
+				}
+				for _, link := range links {
+					records[key][link] = entry
+				}
+			}
+		}
+	}
+	return records
+}
+
+// RunDiffDevices 比较两个设备的记录
+func RunDiffDevices(cmd *cobra.Command, args []string) error {
+	left, right := args[0], args[1]
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	if diffCopyTo != "" && diffCopyTo != left && diffCopyTo != right {
+		return fmt.Errorf("--copy-to 必须是 %s 或 %s", left, right)
+	}
+	mappings, err := parsePathMaps(diffMaps)
+	if err != nil {
+		return err
+	}
+
+	leftRecords, rightRecords := collectDeviceRecords(mgr, left), collectDeviceRecords(mgr, right)
+	keys := slices.Sorted(maps.Keys(leftRecords))
+	for key := range rightRecords {
+		if _, ok := leftRecords[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	var diffs []output.DeviceDiff
+	copied := 0
+	for _, key := range keys {
+		linkType, target, _ := strings.Cut(key, "\x00")
+		l, r := leftRecords[key], rightRecords[key]
+		leftLinks, rightLinks := slices.Sorted(maps.Keys(l)), slices.Sorted(maps.Keys(r))
+		if slices.Equal(leftLinks, rightLinks) {
+			continue
+		}
+		d := output.DeviceDiff{Type: linkType, Target: target, Left: leftLinks, Right: rightLinks, Status: "different"}
+		switch {
+		case len(leftLinks) == 0:
+			d.Status = "only-right"
+		case len(rightLinks) == 0:
+			d.Status = "only-left"
+		}
+
+		var from map[string]store.Entry
+		switch {
+		case diffCopyTo == left && d.Status == "only-right":
+			from = r
+		case diffCopyTo == right && d.Status == "only-left":
+			from = l
+		}
+		if from != nil && confirm("diff-devices", fmt.Sprintf("将 %s 的记录复制到设备 %s", target, diffCopyTo), true) {
+			copyDeviceRecords(mgr, from, linkType, diffCopyTo, mappings)
+			d.Copied = true
+			copied++
+		}
+		diffs = append(diffs, d)
+	}
+
+	if copied > 0 {
+		if err := mgr.Save(store.StorePath); err != nil {
+			logger.Error("持久化失败 " + err.Error())
+			return err
+		}
+		logger.Info(fmt.Sprintf("已向设备 %s 复制 %d 组记录，可使用 flk fix -d %s 在该设备上创建链接", diffCopyTo, copied, diffCopyTo))
+	}
+	return output.PrintDeviceDiffs(output.OutputFormat(outputFormat), left, right, diffs)
+}
+
+// copyDeviceRecords 将同一目标的记录复制到设备 device，路径按 mappings 替换前缀
+func copyDeviceRecords(mgr *store.Manager, entries map[string]store.Entry, linkType, device string, mappings [][2]string) {
+	seen := make(map[string]bool)
+	for _, link := range slices.Sorted(maps.Keys(entries)) {
+		entry := entries[link]
+		// 一组硬链接的多个次要文件属于同一条目，只复制一次
+		if seen[store.LinkPath(entry)] {
+			continue
+		}
+		seen[store.LinkPath(entry)] = true
+		fields := make(map[string]string)
+		for k, v := range entry {
+			switch {
+			case k == store.InodeKey, store.IsStampKey(k):
+				// 复制到其他设备是一次新的记录
+				continue
+			case store.IsMetadataKey(k), entry[store.RootKey] != "" && k == targetKey(linkType):
+				fields[k] = v
+			default:
+				fields[k] = mapPathPrefix(v, mappings)
+			}
+		}
+		mgr.AddRecord(device, linkType, filepath.Dir(mustNormalize(store.LinkPath(fields))), fields)
+	}
+}
+
+// parsePathMaps 解析 旧前缀=新前缀 形式的路径替换规则
+func parsePathMaps(raw []string) ([][2]string, error) {
+	var mappings [][2]string
+	for _, m := range raw {
+		from, to, ok := strings.Cut(m, "=")
+		if !ok || from == "" {
+			return nil, fmt.Errorf("无效的路径替换规则 %s，格式应为 旧前缀=新前缀", m)
+		}
+		mappings = append(mappings, [2]string{from, to})
+	}
+	return mappings, nil
+}
+
+// mapPathPrefix 按第一条匹配的规则替换路径前缀
+func mapPathPrefix(path string, mappings [][2]string) string {
+	for _, m := range mappings {
+		if rest, ok := strings.CutPrefix(path, m[0]); ok {
+			return m[1] + rest
+		}
+	}
+	return path
+}
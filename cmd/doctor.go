@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/fsinfo"
+	"github.com/jy-eggroll/flk/internal/lint"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "诊断运行环境中可能导致链接创建或检查失败的问题",
+	Long: `诊断运行环境中可能导致链接创建或检查失败的问题：
+Windows 上的管理员权限和开发人员模式、能否在临时目录中创建符号链接、存储文件能否解析且结构正确、
+配置文件中的设备名称是否存在于存储中，以及记录涉及的各个卷是否支持硬链接。存在失败项时返回非零退出码`,
+	Args:         cobra.NoArgs,
+	RunE:         RunDoctor,
+	SilenceUsage: true,
+}
+
+// windowsDoctorChecks 由 doctor_windows.go 赋值，其他平台保持为 nil
+var windowsDoctorChecks func() []output.DoctorCheck
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// RunDoctor 依次执行各项诊断并输出结果
+func RunDoctor(cmd *cobra.Command, args []string) error {
+	var checks []output.DoctorCheck
+	if windowsDoctorChecks != nil {
+		checks = append(checks, windowsDoctorChecks()...)
+	}
+	checks = append(checks, doctorSymlink())
+	checks = append(checks, doctorStore()...)
+	checks = append(checks, doctorDevice())
+	checks = append(checks, doctorVolumes()...)
+
+	if err := output.PrintDoctorChecks(output.OutputFormat(outputFormat), checks); err != nil {
+		return err
+	}
+	failed := 0
+	for _, c := range checks {
+		if c.Status == output.DoctorFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 项诊断失败", failed)
+	}
+	return nil
+}
+
+// doctorSymlink 在临时目录中实际创建一个符号链接，验证当前用户的权限
+func doctorSymlink() output.DoctorCheck {
+	c := output.DoctorCheck{Name: "创建符号链接"}
+	dir, err := os.MkdirTemp("", "flk-doctor-*")
+	if err != nil {
+		c.Status, c.Detail = output.DoctorFail, "无法创建临时目录："+err.Error()
+		return c
+	}
+	defer os.RemoveAll(dir)
+	target := filepath.Join(dir, "target")
+	link := filepath.Join(dir, "link")
+	if err := os.WriteFile(target, nil, 0644); err != nil {
+		c.Status, c.Detail = output.DoctorFail, "无法写入临时文件："+err.Error()
+		return c
+	}
+	if err := os.Symlink(target, link); err != nil {
+		c.Status, c.Detail = output.DoctorFail, "在 "+dir+" 中创建失败："+err.Error()
+		return c
+	}
+	if got, err := os.Readlink(link); err != nil || got != target {
+		c.Status, c.Detail = output.DoctorFail, "创建的符号链接无法读取或指向错误"
+		return c
+	}
+	c.Status, c.Detail = output.DoctorOK, "可以在 "+os.TempDir()+" 中创建符号链接"
+	return c
+}
+
+// doctorStore 检查存储文件能否按其格式解析，以及结构是否符合存储的层级和字段要求
+func doctorStore() []output.DoctorCheck {
+	path := mustNormalize(store.StorePath)
+	parse := output.DoctorCheck{Name: "存储文件"}
+	schema := output.DoctorCheck{Name: "存储结构"}
+	mgr, err := store.LoadFromFile(store.StorePath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		parse.Status, parse.Detail = output.DoctorWarn, path+" 不存在，首次创建链接时会自动创建"
+		return []output.DoctorCheck{parse}
+	case err != nil:
+		parse.Status, parse.Detail = output.DoctorFail, path+" 无法解析："+err.Error()
+		return []output.DoctorCheck{parse}
+	}
+	parse.Status, parse.Detail = output.DoctorOK, path+" 可以正常解析"
+
+	errorCount, warningCount := 0, 0
+	for _, issue := range lint.Store(mgr.Data, false) {
+		switch issue.Level {
+		case lint.LevelError:
+			errorCount++
+		case lint.LevelWarning:
+			warningCount++
+		}
+	}
+	switch {
+	case errorCount > 0:
+		schema.Status = output.DoctorFail
+	case warningCount > 0:
+		schema.Status = output.DoctorWarn
+	default:
+		schema.Status = output.DoctorOK
+	}
+	schema.Detail = fmt.Sprintf("%d 个错误，%d 个警告", errorCount, warningCount)
+	if errorCount+warningCount > 0 {
+		schema.Detail += "，使用 flk lint 查看详情"
+	}
+	return []output.DoctorCheck{parse, schema}
+}
+
+// doctorDevice 检查配置文件中的设备名称是否在存储中有记录
+func doctorDevice() output.DoctorCheck {
+	c := output.DoctorCheck{Name: "设备名称"}
+	device := config.Global.Device
+	if device == "" {
+		c.Status, c.Detail = output.DoctorWarn, "配置文件中未设置设备名称，可使用 flk device set 或 flk device auto 设置"
+		return c
+	}
+	mgr, err := store.Current()
+	if err != nil {
+		c.Status, c.Detail = output.DoctorFail, err.Error()
+		return c
+	}
+	if _, ok := mgr.DeviceCounts()[device]; !ok {
+		c.Status, c.Detail = output.DoctorWarn, "存储中没有设备 "+device+" 的记录"
+		return c
+	}
+	c.Status, c.Detail = output.DoctorOK, device
+	return c
+}
+
+// doctorVolumes 检查当前平台记录涉及的各个卷是否支持硬链接，有硬链接记录的卷不支持时为失败
+func doctorVolumes() []output.DoctorCheck {
+	mgr, err := store.Current()
+	if err != nil {
+		return nil
+	}
+	volumes := make(map[string]*fsinfo.Info)
+	hardlinked := make(map[string]bool)
+	for _, l := range recordLinks(mgr, "") {
+		for _, p := range []string{l.Link, l.Target} {
+			abs, err := pathutil.ToAbsolute(p)
+			if err != nil {
+				continue
+			}
+			info, err := fsinfo.For(abs)
+			if err != nil {
+				continue
+			}
+			volumes[info.Volume] = info
+			if l.Type == "hardlink" {
+				hardlinked[info.Volume] = true
+			}
+		}
+	}
+
+	var checks []output.DoctorCheck
+	for _, volume := range slices.Sorted(maps.Keys(volumes)) {
+		info := volumes[volume]
+		c := output.DoctorCheck{Name: "硬链接支持 " + volume}
+		fsType := info.Type
+		if fsType == "" {
+			fsType = "未知文件系统"
+		}
+		switch {
+		case info.Hardlink:
+			c.Status, c.Detail = output.DoctorOK, fsType
+		case hardlinked[volume]:
+			c.Status, c.Detail = output.DoctorFail, fsType+" 不支持硬链接，但存储中有位于该卷的硬链接记录"
+		default:
+			c.Status, c.Detail = output.DoctorWarn, fsType+" 不支持硬链接，该卷上只能使用符号链接或模板"
+		}
+		if !info.Probed {
+			c.Detail += "（卷不可写，按文件系统类型推测）"
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
@@ -0,0 +1,40 @@
+//go:build windows
+
+package cmd
+
+import (
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+
+	"github.com/jy-eggroll/flk/internal/output"
+)
+
+// init 为 doctor.go 的 windowsDoctorChecks 赋值
+func init() {
+	windowsDoctorChecks = func() []output.DoctorCheck {
+		admin := output.DoctorCheck{Name: "管理员权限", Status: output.DoctorOK, Detail: "当前以管理员权限运行"}
+		elevated := windows.GetCurrentProcessToken().IsElevated()
+		if !elevated {
+			admin.Status, admin.Detail = output.DoctorWarn, "当前未以管理员权限运行"
+		}
+		devMode := output.DoctorCheck{Name: "开发人员模式", Status: output.DoctorOK, Detail: "已开启，无需管理员权限即可创建符号链接"}
+		if !developerModeEnabled() {
+			devMode.Status, devMode.Detail = output.DoctorWarn, "未开启，创建符号链接需要管理员权限，可在“设置 > 系统 > 开发者选项”中开启"
+			if !elevated {
+				devMode.Detail += "；当前也未以管理员权限运行，创建符号链接时会请求提权"
+			}
+		}
+		return []output.DoctorCheck{admin, devMode}
+	}
+}
+
+// developerModeEnabled 读取注册表判断是否开启了开发人员模式
+func developerModeEnabled() bool {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows\CurrentVersion\AppModelUnlock`, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer k.Close()
+	v, _, err := k.GetIntegerValue("AllowDevelopmentWithoutDevLicense")
+	return err == nil && v == 1
+}
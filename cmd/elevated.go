@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/create/symlink"
+	"github.com/jy-eggroll/flk/internal/elevate"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// elevatedCmd 提权子进程的入口，只执行父进程请求的文件系统操作，不加载配置和存储，
+// 存储由未提权的父进程根据返回的结果统一写入
+var elevatedCmd = &cobra.Command{
+	Use:    elevate.ChildCommand + " <请求文件>",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	// 覆盖根命令的 PersistentPreRun，避免子进程初始化（以及迁移写入）存储
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	RunE:             RunElevated,
+}
+
+// noElevate 为 true 时权限不足直接失败，不弹出 UAC，用于受限账户下的自动化
+var noElevate bool
+
+func init() {
+	rootCmd.AddCommand(elevatedCmd)
+	rootCmd.PersistentFlags().BoolVar(&noElevate, "no-elevate", false, "权限不足时直接失败而不请求管理员权限（仅 Windows）")
+}
+
+// RunElevated 执行请求文件中的每个操作并写回结果
+func RunElevated(cmd *cobra.Command, args []string) error {
+	ops, err := elevate.ReadRequest(args[0])
+	if err != nil {
+		return err
+	}
+	results := make([]elevate.Result, len(ops))
+	for i, op := range ops {
+		var err error
+		switch op.Type {
+		case "symlink":
+			err = symlink.CreateWithStyle(op.Target, op.Link, op.Force, op.Absolute)
+		default:
+			err = fmt.Errorf("不支持的提权操作类型 %s", op.Type)
+		}
+		if err != nil {
+			results[i].Error = err.Error()
+		}
+	}
+	return elevate.WriteResults(args[0], results)
+}
+
+// previewElevation 在弹出 UAC 前展示子进程将执行的命令及其操作
+func previewElevation(req *elevate.Request) {
+	lines := []string{
+		"权限不足，需要以管理员权限执行：",
+		"  程序：" + req.Command.Exe,
+		"  参数：" + strings.Join(req.Command.Args, " "),
+		"  工作目录：" + req.Command.Dir,
+	}
+	for _, op := range req.Ops {
+		lines = append(lines, fmt.Sprintf("  操作：%s %s -> %s", op.Type, op.Link, op.Target))
+	}
+	msg := strings.Join(lines, "\n")
+	// JSON 输出时标准输出需要保持可解析，改为写入日志
+	if output.OutputFormat(outputFormat) == output.Table {
+		pterm.Info.Println(msg)
+	} else {
+		logger.Info(msg)
+	}
+}
+
+// createSymlinkElevated 以管理员权限的子进程创建符号链接，路径先在本进程解析为绝对路径，
+// 子进程的工作目录与本进程不同，不能依赖相对路径
+func createSymlinkElevated(normalizedReal, normalizedFake string, force, absolute bool) error {
+	absReal, err := pathutil.ToAbsolute(normalizedReal)
+	if err != nil {
+		return err
+	}
+	absFake, err := pathutil.ToAbsolute(normalizedFake)
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	req, err := elevate.Prepare(exe, []elevate.Op{{Type: "symlink", Target: absReal, Link: absFake, Force: force, Absolute: absolute}})
+	if err != nil {
+		return err
+	}
+	defer req.Close()
+	previewElevation(req)
+	if noElevate {
+		return fmt.Errorf("创建 %s 需要管理员权限，已指定 --no-elevate，请开启开发者模式或以管理员身份运行", absFake)
+	}
+	results, err := req.Execute()
+	if err != nil {
+		return err
+	}
+	if results[0].Error != "" {
+		return errors.New(results[0].Error)
+	}
+	return nil
+}
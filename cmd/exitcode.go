@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// check 和 fix 的退出码，脚本可据此判断结果而无需解析输出；其他命令失败时退出码为 1
+const (
+	ExitOK      = 0 // 所有链接有效，fix 后没有剩余的无效链接
+	ExitInvalid = 1 // 存在无效链接，或 fix 后仍有未修复的链接
+	ExitError   = 2 // 执行出错，如存储无法读取、选项无效或输出失败
+)
+
+// exitCodeError 携带退出码的错误，Execute 按 code 退出
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Unwrap() error {
+	return e.err
+}
+
+// invalidLinksError 返回表示仍有 n 条无效链接的错误，对应退出码 ExitInvalid
+func invalidLinksError(n int) error {
+	return &exitCodeError{code: ExitInvalid, err: fmt.Errorf("%d 条链接无效", n)}
+}
+
+// exitCode 返回命令执行结束后的退出码：err 携带退出码时使用它，
+// 否则 check、fix 的错误视为执行出错，其他命令的错误为 1
+func exitCode(cmd *cobra.Command, err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var e *exitCodeError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	if cmd == checkCmd || cmd == fixCmd {
+		return ExitError
+	}
+	return 1
+}
+
+// printFlagError 作为 check、fix 的选项解析错误处理函数，它们设置了 SilenceErrors，选项错误需要自行输出
+func printFlagError(cmd *cobra.Command, err error) error {
+	cmd.PrintErrln(cmd.ErrPrefix(), err.Error())
+	cmd.PrintErrf("运行 '%s --help' 查看用法\n", cmd.CommandPath())
+	return err
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestExitCode(t *testing.T) {
+	cases := []struct {
+		name string
+		cmd  *cobra.Command
+		err  error
+		want int
+	}{
+		{"check 成功", checkCmd, nil, ExitOK},
+		{"check 存在无效链接", checkCmd, invalidLinksError(3), ExitInvalid},
+		{"check 执行出错", checkCmd, errors.New("无法读取存储"), ExitError},
+		{"fix 成功", fixCmd, nil, ExitOK},
+		{"fix 仍有无效链接", fixCmd, invalidLinksError(1), ExitInvalid},
+		{"fix 执行出错", fixCmd, errors.New("未知选项"), ExitError},
+		{"其他命令出错", listCmd, errors.New("失败"), 1},
+		{"包装后的退出码", listCmd, fmt.Errorf("外层：%w", &exitCodeError{code: ExitError, err: errors.New("内层")}), ExitError},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := exitCode(c.cmd, c.err); got != c.want {
+				t.Fatalf("exitCode = %d，期望 %d", got, c.want)
+			}
+		})
+	}
+}
+
+func TestExitCodeErrorUnwrap(t *testing.T) {
+	inner := errors.New("存储已损坏")
+	err := error(&exitCodeError{code: ExitError, err: inner})
+	if !errors.Is(err, inner) {
+		t.Fatal("errors.Is 应能找到被包装的错误")
+	}
+	if err.Error() != inner.Error() {
+		t.Fatalf("Error() = %q，期望与被包装的错误相同", err.Error())
+	}
+}
+
+func TestFixExitError(t *testing.T) {
+	defer func() { fixFailed, fixCounts = false, nil }()
+	cases := []struct {
+		name      string
+		failed    bool
+		remaining int
+		want      int
+	}{
+		{"全部修复", false, 0, ExitOK},
+		{"仍有无效链接", false, 2, ExitInvalid},
+		{"修复出错", true, 2, ExitError},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fixFailed, fixCounts = c.failed, map[string]int{"remaining": c.remaining}
+			if got := exitCode(fixCmd, fixExitError()); got != c.want {
+				t.Fatalf("退出码为 %d，期望 %d", got, c.want)
+			}
+		})
+	}
+}
+
+// TestCheckExitCode 通过命令行执行 check，存储中的链接有效时退出码为 ExitOK，链接缺失时为 ExitInvalid
+func TestCheckExitCode(t *testing.T) {
+	dir := t.TempDir()
+	// check 会写入状态缓存，避免改动真实的用户目录
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, ".cache"))
+	real := filepath.Join(dir, "real.txt")
+	fake := filepath.Join(dir, "fake.txt")
+	if err := os.WriteFile(real, []byte("flk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	storePath := filepath.Join(dir, "flk-store.json")
+	data := fmt.Sprintf(`{%q: {"all": {"symlink": {%q: [{"real": %q, "fake": %q}]}}}}`, runtime.GOOS, dir, real, fake)
+	if err := os.WriteFile(storePath, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run := func() int {
+		rootCmd.SetArgs([]string{"check", "--store", storePath, "--config", filepath.Join(dir, "flk.toml"), "--output", "json"})
+		cmd, err := rootCmd.ExecuteC()
+		return exitCode(cmd, err)
+	}
+
+	if got := run(); got != ExitInvalid {
+		t.Fatalf("链接缺失时退出码为 %d，期望 %d", got, ExitInvalid)
+	}
+	if err := os.Symlink(real, fake); err != nil {
+		t.Skip("无法创建符号链接：", err)
+	}
+	if got := run(); got != ExitOK {
+		t.Fatalf("链接有效时退出码为 %d，期望 %d", got, ExitOK)
+	}
+}
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/manifest"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "将存储中的记录导出为其他工具使用的格式",
+	Long: `将当前平台的记录导出为其他工具使用的格式：
+  restic-exclude  restic --exclude-file 使用的排除列表，排除所有链接文件，只备份真实文件
+  borg-exclude    borg --exclude-from 使用的排除列表，含义同上
+  dot             Graphviz 图，真实文件指向各链接，链接按设备和应用分组，可用 dot -Tsvg 渲染
+  mermaid         Mermaid 流程图，内容同上，可直接嵌入 Markdown
+  json/yaml/toml  可移植的链接清单，可单独纳入版本管理，用 flk import 合并回存储或用 flk apply -m 直接应用
+导出备份排除列表时，如果配置了 backup_roots，还会提示真实文件不在任何备份目录下的记录。
+--redact 将导出内容中的主目录、用户名和主机名替换为占位符，便于分享，脱敏后的排除列表和清单不能直接使用`,
+	Args: cobra.NoArgs,
+	RunE: RunExport,
+}
+
+var (
+	exportFormat string
+	exportFile   string
+	exportDevice string
+)
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVar(&exportFormat, "format", "", "导出格式：restic-exclude、borg-exclude、dot、mermaid、json、yaml、toml")
+	exportCmd.Flags().StringVarP(&exportFile, "file", "f", "", "输出文件路径，默认输出到标准输出")
+	exportCmd.Flags().StringVarP(&exportDevice, "device", "d", "", "仅导出该设备的记录")
+	exportCmd.MarkFlagRequired("format")
+	addRedactFlag(exportCmd)
+}
+
+// linkRecord 一条记录中的一个链接，路径均已解析为绝对路径
+type linkRecord struct {
+	Type   string `json:"type"`
+	Device string `json:"device"`
+	Link   string `json:"link"`
+	Target string `json:"target"`
+	// Bundle 通过 flk app 添加的链接所属的应用名称
+	Bundle string `json:"bundle,omitempty"`
+	// key 存储中记录的链接文件路径原文，用于查找状态文件中的运行状态
+	key string
+	// platform 记录所属的平台
+	platform string
+	// created、updated、host、version 条目中的记录时间、主机和 flk 版本，早期版本写入的条目为空
+	created, updated, host, version string
+}
+
+// recordLinks 返回当前平台上的全部链接，device 非空时只返回该设备的记录；源根目录未配置的记录无法解析目标，会被跳过
+func recordLinks(mgr *store.Manager, device string) []linkRecord {
+	return platformLinks(mgr, runtime.GOOS, device)
+}
+
+// platformLinks 返回指定平台上的全部链接，其他平台的路径按本机的主目录和源根目录解析
+func platformLinks(mgr *store.Manager, platform, device string) []linkRecord {
+	var links []linkRecord
+	for d, deviceData := range mgr.Data[platform] {
+		if device != "" && d != device {
+			continue
+		}
+		for linkType, typeData := range deviceData {
+			for parentPath, entries := range typeData {
+				for _, entry := range entries {
+					basePath, err := recordBasePath(parentPath, entry)
+					if err != nil {
+						logger.Warn(err.Error())
+						continue
+					}
+					target := resolveRecordPath(entry[targetKey(linkType)], basePath)
+					paths := []string{entry[linkKey(linkType)]}
+					if linkType == "hardlink" {
+						paths = store.SecoPaths(entry)
+					}
+					for _, p := range paths {
+						links = append(links, linkRecord{
+							Type:     linkType,
+							Device:   d,
+							Link:     resolveRecordPath(p, mustNormalize(parentPath)),
+							Target:   target,
+							Bundle:   appName(entry[store.SourceKey]),
+							key:      p,
+							platform: platform,
+							created:  entry[store.CreatedAtKey],
+							updated:  entry[store.UpdatedAtKey],
+							host:     entry[store.CreatedByHostKey],
+							version:  entry[store.VersionKey],
+						})
+					}
+				}
+			}
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].Link < links[j].Link })
+	return links
+}
+
+// RunExport 按 --format 导出记录
+func RunExport(cmd *cobra.Command, args []string) error {
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	links := recordLinks(mgr, exportDevice)
+	exported := redactLinkRecords(links)
+
+	var content string
+	switch exportFormat {
+	case manifest.JSON, manifest.YAML, manifest.TOML:
+		m := exportManifest(mgr, exportDevice)
+		redactManifest(m)
+		data, err := manifest.Marshal(exportFormat, m)
+		if err != nil {
+			return err
+		}
+		content = string(data)
+	case "restic-exclude":
+		content = backupExcludes(exported, func(p string) string { return escapeGlob(p) })
+	case "borg-exclude":
+		content = backupExcludes(exported, func(p string) string { return "pp:" + p })
+	case "dot":
+		content = dotGraph(exported)
+	case "mermaid":
+		content = mermaidGraph(exported)
+	default:
+		return fmt.Errorf("不支持的导出格式 %s，可选 restic-exclude、borg-exclude、dot、mermaid、json、yaml、toml", exportFormat)
+	}
+	if strings.HasSuffix(exportFormat, "-exclude") {
+		warnUncoveredTargets(links)
+	}
+
+	if exportFile == "" {
+		fmt.Print(content)
+		return nil
+	}
+	if err := os.WriteFile(mustNormalize(exportFile), []byte(content), 0644); err != nil {
+		return err
+	}
+	logger.Info("已导出到 " + exportFile)
+	return nil
+}
+
+// exportManifest 将当前平台的记录转换为清单，device 非空时只包含该设备的记录；
+// 路径中的主目录折叠为 ~，all 设备的记录不限定设备
+func exportManifest(mgr *store.Manager, device string) *manifest.Manifest {
+	m := &manifest.Manifest{Version: 1, Records: []manifest.Record{}}
+	for _, rec := range storeRecords(mgr) {
+		if device != "" && rec.Device != device {
+			continue
+		}
+		if rec.Device == "all" {
+			rec.Device = ""
+		}
+		rec.Platform = runtime.GOOS
+		for _, p := range []*string{&rec.Real, &rec.Fake, &rec.Prim, &rec.Seco} {
+			*p = store.CanonicalPath(*p, "")
+		}
+		m.Records = append(m.Records, rec)
+	}
+	return m
+}
+
+// backupExcludes 生成排除所有链接文件的列表，链接指向的真实文件仍会被备份，避免同一内容被备份两次
+func backupExcludes(links []linkRecord, pattern func(string) string) string {
+	var b strings.Builder
+	b.WriteString("# 由 flk export 生成，排除 flk 管理的链接文件，其内容已随真实文件备份\n")
+	seen := make(map[string]bool)
+	for _, l := range links {
+		if seen[l.Link] {
+			continue
+		}
+		seen[l.Link] = true
+		fmt.Fprintf(&b, "# %s -> %s\n%s\n", l.Type, l.Target, pattern(filepath.ToSlash(l.Link)))
+	}
+	return b.String()
+}
+
+// escapeGlob 转义路径中的通配符，使 restic 按字面匹配
+func escapeGlob(path string) string {
+	return strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`).Replace(path)
+}
+
+// warnUncoveredTargets 对真实文件不在任何备份目录下的记录给出警告，未配置备份目录时不做检查
+func warnUncoveredTargets(links []linkRecord) {
+	if len(config.Global.BackupRoots) == 0 {
+		return
+	}
+	for _, l := range links {
+		if _, ok := config.Global.BackupRoot(l.Target); !ok {
+			logger.Warn(fmt.Sprintf("%s 的真实文件 %s 不在任何备份目录下", l.Link, l.Target))
+		}
+	}
+}
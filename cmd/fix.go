@@ -1,52 +1,110 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"path/filepath"
+	"os"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/contenthash"
+	"github.com/jy-eggroll/flk/internal/create/hardlink"
+	"github.com/jy-eggroll/flk/internal/fileattr"
+	"github.com/jy-eggroll/flk/internal/fileid"
+	"github.com/jy-eggroll/flk/internal/fileperm"
 	"github.com/jy-eggroll/flk/internal/logger"
 	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/progress"
+	"github.com/jy-eggroll/flk/internal/state"
 	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/jy-eggroll/flk/internal/validate"
+	"github.com/jy-eggroll/flk/internal/trace"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
 
 var fixCmd = &cobra.Command{
-	Use:   "fix",
+	Use:   "fix [编号]...",
 	Short: "交互式修复无效链接",
-	Long:  "检查链接状态并进入交互模式，允许用户选择编号修复无效链接",
-	Run:   RunFix,
+	Long:  "检查链接状态并进入交互模式，允许用户选择编号修复无效链接；给出编号时直接修复这些编号对应的链接，不进入交互模式。退出码：0 没有剩余的无效链接，1 仍有无效链接，2 执行出错",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		RunFix(cmd, args)
+		return fixExitError()
+	},
+	// 退出码用于脚本判定，出错原因已写入日志
+	SilenceUsage:  true,
+	SilenceErrors: true,
 }
 
 func init() {
 	rootCmd.AddCommand(fixCmd)
+	fixCmd.SetFlagErrorFunc(printFlagError)
 	// 复用check的flags
 	fixCmd.Flags().StringVarP(&fixDevice, "device", "d","", "设备名称，用于过滤检查")
 	fixCmd.Flags().BoolVar(&fixSymlink, "symlink", false, "仅检查符号链接")
 	fixCmd.Flags().BoolVar(&fixHardlink, "hardlink", false, "仅检查硬链接")
+	fixCmd.Flags().BoolVar(&fixTemplate, "template", false, "仅检查模板")
+	fixCmd.Flags().BoolVar(&fixJunction, "junction", false, "仅检查目录联接")
 	fixCmd.Flags().StringVar(&fixDir, "dir", "", "仅检查包含该路径的记录")
+	fixCmd.Flags().StringVar(&fixSource, "source", "", "仅检查来源以该值开头的记录，如 cli、apply:")
+	fixCmd.Flags().BoolVar(&fixUntracked, "untracked", false, "同时列出指向已记录源文件但没有记录的符号链接，修复即收编")
+	fixCmd.Flags().BoolVar(&output.ShowNotes, "show-notes", false, "在表格中完整显示记录备注，默认截断")
+	addColumnsFlag(fixCmd)
+	fixCmd.Flags().StringVar(&fixMaxRisk, "max-risk", "", "自动修复（--yes 或配置为 yes）允许的最高风险：low 只重建缺失的链接，medium 还会替换指向错误的链接和过期的渲染结果，high 还会删除占据链接位置的文件；默认使用配置文件中的 repair.max_risk，未配置时为 high")
 }
 
 var (
 	fixDevice   string
 	fixSymlink  bool
 	fixHardlink bool
+	fixTemplate bool
+	fixJunction bool
 	fixDir      string
+	fixSource   string
+
+	fixUntracked bool
+	fixMaxRisk   string
+
+	// fixCounts 本次 fix 的修复计数，随 done 进度事件输出
+	fixCounts map[string]int
+	// fixFailed 本次 fix 执行出错，如检查失败或选项无效
+	fixFailed bool
 )
 
 func RunFix(cmd *cobra.Command, args []string) {
+	progress.Emit(progress.Event{Event: progress.Started, Command: "fix"})
+	fixCounts = map[string]int{"repaired": 0, "failed": 0, "remaining": 0}
+	fixFailed = false
+	defer func() { progress.Emit(progress.Event{Event: progress.Done, Command: "fix", Counts: fixCounts}) }()
+	defer func() {
+		if fixCounts["repaired"] > 0 {
+			refreshStatusSummary()
+		}
+	}()
+	if err := applyColumns(cmd); err != nil {
+		logger.Error(err.Error())
+		fixFailed = true
+		return
+	}
 	checkAndDisplay := func() []output.CheckResult {
 		results, err := performCheck(CheckOptions{
 			DeviceFilter:  fixDevice,
+			IncludeShared: implicitDevice,
 			CheckSymlink:  fixSymlink,
 			CheckHardlink: fixHardlink,
+			CheckTemplate: fixTemplate,
+			CheckJunction: fixJunction,
 			CheckDir:      fixDir,
+			Source:        fixSource,
+			Untracked:     fixUntracked,
 		})
 		if err != nil {
 			logger.Error("检查失败：" + err.Error())
+			fixFailed = true
 			return nil
 		}
 
@@ -57,6 +115,7 @@ func RunFix(cmd *cobra.Command, args []string) {
 				invalidResults = append(invalidResults, r)
 			}
 		}
+		fixCounts["remaining"] = len(invalidResults)
 
 		if len(invalidResults) > 0 {
 			format := output.OutputFormat(outputFormat)
@@ -76,6 +135,59 @@ func RunFix(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	if len(args) > 0 {
+		var indices []int
+		for _, arg := range args {
+			idx, err := strconv.Atoi(arg)
+			if err != nil || idx < 1 || idx > len(invalidResults) {
+				pterm.Warning.Printf("无效编号 %s\n", arg)
+				continue
+			}
+			indices = append(indices, idx-1)
+		}
+		repairIndices(invalidResults, indices)
+		fixCounts["remaining"] -= fixCounts["repaired"]
+		return
+	}
+
+	// 确认策略已确定时无需进入交互循环：同意则修复全部，拒绝则仅展示
+	switch confirmPolicy("fix") {
+	case config.ConfirmYes:
+		if b, ok := config.Global.ActiveBlackout(time.Now()); ok {
+			msg := fmt.Sprintf("当前处于静默时段 %s-%s，只报告不修复", b.Start, b.End)
+			if b.Reason != "" {
+				msg += "（" + b.Reason + "）"
+			}
+			pterm.Info.Println(msg)
+			return
+		}
+		maxRisk, err := parseRisk(fixMaxRisk, config.Global.Repair.MaxRisk)
+		if err != nil {
+			logger.Error(err.Error())
+			fixFailed = true
+			return
+		}
+		// 风险低的修复先执行，超过上限的只报告
+		var indices []int
+		for _, risk := range []repairRisk{riskLow, riskMedium, riskHigh} {
+			for i, r := range invalidResults {
+				if riskOf(r) != risk {
+					continue
+				}
+				if risk > maxRisk {
+					pterm.Warning.Printf("跳过 #%d 修复风险为 %s，超过允许的 %s\n", i+1, risk, maxRisk)
+					continue
+				}
+				indices = append(indices, i)
+			}
+		}
+		repairAutomatically(invalidResults, indices)
+		checkAndDisplay()
+		return
+	case config.ConfirmNo:
+		return
+	}
+
 	// 交互循环
 	for {
 		input, err := pterm.DefaultInteractiveTextInput.WithMultiLine(false).Show("输入要修复的编号（空格分隔），'all' 或 'a' 修复所有，'d<编号>' 删除条目，如 d7，单次只能删除一个，'exit' 或 'e' 退出")
@@ -105,19 +217,19 @@ func RunFix(cmd *cobra.Command, args []string) {
 			if len(indices) == 0 {
 				continue
 			}
+			if !confirm("fix", fmt.Sprintf("确认从存储中删除 %d 条记录", len(indices)), true) {
+				continue
+			}
 
 			platform := runtime.GOOS
-			mgr := store.GlobalManager
+			mgr, err := store.Current()
+			if err != nil {
+				logger.Error(err.Error())
+				continue
+			}
 			for _, idx := range indices {
 				result := invalidResults[idx]
-				var entry map[string]string
-				switch result.Type {
-				case "symlink":
-					entry = map[string]string{"real": result.Real, "fake": result.Fake}
-				case "hardlink":
-					entry = map[string]string{"prim": result.Prim, "seco": result.Seco}
-				}
-				mgr.RemoveMatchingEntry(platform, result.Device, result.Type, result.Path, entry)
+				mgr.RemoveMatchingEntry(platform, result.Device, result.Type, result.Path, resultEntry(result))
 			}
 			if err := mgr.Save(store.StorePath); err != nil {
 				logger.Error("保存失败 " + err.Error())
@@ -152,15 +264,7 @@ func RunFix(cmd *cobra.Command, args []string) {
 			continue
 		}
 
-		// 修复选中的
-		for _, idx := range indices {
-			result := invalidResults[idx]
-			if err := repairResult(result, idx); err != nil {
-				pterm.Error.Printf("修复失败 #%d %v\n", idx+1, err)
-			} else {
-				pterm.Success.Printf("修复成功 #%d\n", idx+1)
-			}
-		}
+		repairIndices(invalidResults, indices)
 
 		invalidResults = checkAndDisplay()
 		if len(invalidResults) == 0 {
@@ -169,8 +273,231 @@ func RunFix(cmd *cobra.Command, args []string) {
 	}
 }
 
+// fixExitError 按本次 fix 的结果返回携带退出码的错误，没有剩余的无效链接时返回 nil
+func fixExitError() error {
+	switch {
+	case fixFailed:
+		return &exitCodeError{code: ExitError, err: errors.New("修复过程中出错")}
+	case fixCounts["remaining"] > 0:
+		return invalidLinksError(fixCounts["remaining"])
+	}
+	return nil
+}
+
+// repairIndices 修复选中的无效链接
+func repairIndices(invalidResults []output.CheckResult, indices []int) {
+	statePath := state.PathFor(store.StorePath)
+	st, err := state.Load(statePath)
+	if err != nil {
+		logger.Warn("读取状态文件失败 " + err.Error())
+	}
+	for _, idx := range indices {
+		result := invalidResults[idx]
+		if err := repairResult(result, idx); err != nil {
+			emitRepaired(result, err)
+			pterm.Error.Printf("修复失败 #%d %v\n", idx+1, err)
+		} else {
+			emitRepaired(result, nil)
+			pterm.Success.Printf("修复成功 #%d\n", idx+1)
+			if st != nil {
+				// 手动修复后重新允许自动修复
+				st.ResetRepairs(resultKey(result))
+			}
+		}
+	}
+	if st != nil {
+		if err := st.Save(statePath); err != nil {
+			logger.Warn("写入状态文件失败 " + err.Error())
+		}
+	}
+}
+
+// repairAutomatically 无人值守地修复选中的记录，按配置的限流策略跳过修复过于频繁或已放弃的记录
+func repairAutomatically(invalidResults []output.CheckResult, indices []int) {
+	statePath := state.PathFor(store.StorePath)
+	st, err := state.Load(statePath)
+	if err != nil {
+		logger.Warn("读取状态文件失败，本次不限制自动修复 " + err.Error())
+		st = &state.State{Records: make(map[string]state.RecordState)}
+	}
+	policy := repairPolicy()
+	now := time.Now()
+	for _, idx := range indices {
+		result := invalidResults[idx]
+		key := resultKey(result)
+		if err := st.AllowRepair(key, policy, now); err != nil {
+			pterm.Warning.Printf("跳过 #%d %v\n", idx+1, err)
+			continue
+		}
+		if err := repairResult(result, idx); err != nil {
+			emitRepaired(result, err)
+			pterm.Error.Printf("修复失败 #%d %v\n", idx+1, err)
+			continue
+		}
+		emitRepaired(result, nil)
+		pterm.Success.Printf("修复成功 #%d\n", idx+1)
+		if st.RecordRepair(key, policy, now) {
+			logger.Warn(fmt.Sprintf("%s 连续 %d 次修复后仍被改回，已放弃自动修复", resultLink(result), policy.GiveUpAfter))
+		}
+	}
+	if err := st.Save(statePath); err != nil {
+		logger.Warn("写入状态文件失败 " + err.Error())
+	}
+}
+
+// emitRepaired 输出修复一条记录的进度事件并计数
+func emitRepaired(result output.CheckResult, err error) {
+	e := progress.Event{Event: progress.Repaired, Type: result.Type, Device: result.Device, Link: resultLink(result), ErrorType: result.ErrorType}
+	counter := "repaired"
+	if err != nil {
+		e.Error = err.Error()
+		counter = "failed"
+	}
+	// 服务器等调用方不经过 RunFix，没有计数
+	if fixCounts != nil {
+		fixCounts[counter]++
+	}
+	progress.Emit(e)
+}
+
+// repairPolicy 返回配置的自动修复限流策略，未设置或无效的字段使用默认值
+func repairPolicy() state.RepairPolicy {
+	p := state.DefaultRepairPolicy
+	cfg := config.Global.Repair
+	if cfg.MaxPerHour > 0 {
+		p.MaxPerHour = cfg.MaxPerHour
+	}
+	if cfg.GiveUpAfter > 0 {
+		p.GiveUpAfter = cfg.GiveUpAfter
+	}
+	if cfg.Backoff != "" {
+		if d, err := time.ParseDuration(cfg.Backoff); err == nil {
+			p.Backoff = d
+		} else {
+			logger.Warn("无效的修复退避时间 " + cfg.Backoff)
+		}
+	}
+	return p
+}
+
+// repairRisk 修复操作的风险等级
+type repairRisk int
+
+const (
+	riskLow    repairRisk = iota // 只创建缺失的文件或调整元数据，不会删除任何内容
+	riskMedium                   // 替换链接或可重新生成的文件，不会丢失用户数据
+	riskHigh                     // 删除或合并占据链接位置的文件，可能丢失用户数据
+)
+
+var riskNames = []string{"low", "medium", "high"}
+
+func (r repairRisk) String() string {
+	return riskNames[r]
+}
+
+// parseRisk 解析 --max-risk，未指定时使用配置值，均未设置时不限制
+func parseRisk(flag, configured string) (repairRisk, error) {
+	name := flag
+	if name == "" {
+		name = configured
+	}
+	if name == "" {
+		return riskHigh, nil
+	}
+	for i, n := range riskNames {
+		if strings.EqualFold(name, n) {
+			return repairRisk(i), nil
+		}
+	}
+	return riskHigh, fmt.Errorf("无效的风险等级 %s，可选 low、medium、high", name)
+}
+
+// riskOf 评估修复一条无效记录的风险，未知的错误类型按高风险处理
+func riskOf(result output.CheckResult) repairRisk {
+	switch result.ErrorType {
+	case "LINK_MISSING", "SECO_MISSING", "RENDERED_MISSING", "MODE_DRIFT", "ATTRIB_DRIFT", "UNTRACKED",
+		"TARGET_MISSING", "EXPECTED_MISSING", "PRIM_MISSING", "NOT_BACKED_UP", "PLACEHOLDER", "ROOT_UNDEFINED":
+		// 后几类无法自动修复，尝试修复也不会改动文件
+		return riskLow
+	case "TARGET_MISMATCH", "READLINK_FAIL", "RENDERED_STALE", "TEMPLATE_RENDER_FAIL":
+		return riskMedium
+	}
+	return riskHigh
+}
+
+// validateTarget 按记录声明的校验规格检查真实文件，未通过时拒绝重建链接，
+// 避免把写了一半的文件链接到正在使用的配置路径
+func validateTarget(result output.CheckResult) error {
+	if result.Validate == "" {
+		return nil
+	}
+	target := result.Real
+	if result.Type == "hardlink" {
+		target = result.Prim
+	}
+	if err := validate.File(resolveRecordPath(target, result.BasePath), result.Validate); err != nil {
+		return fmt.Errorf("真实文件校验失败，已跳过修复：%v", err)
+	}
+	return nil
+}
+
 func repairResult(result output.CheckResult, idx int) error {
 	logger.Info(fmt.Sprintf("开始修复 #%d, 类型=%s, 设备=%s, 路径=%s, BasePath=%s, Real=%s, Fake=%s", idx+1, result.Type, result.Device, result.Path, result.BasePath, result.Real, result.Fake))
+	if result.ErrorType == "MODE_DRIFT" {
+		// 链接本身有效，只需重新设置权限
+		link := result.Fake
+		if result.Type == "hardlink" {
+			link = result.Seco
+		}
+		expandedLink, err := pathutil.NormalizePath(link)
+		if err != nil {
+			return err
+		}
+		return fileperm.Apply(expandedLink, result.Mode)
+	}
+	if hardlinkBreakTypes[result.ErrorType] {
+		if err := validateTarget(result); err != nil {
+			return err
+		}
+		return relinkBrokenHardlink(result)
+	}
+	if result.ErrorType == "UNTRACKED" {
+		oldDevice := createDevice
+		createDevice = result.Device
+		defer func() { createDevice = oldDevice }()
+		_, err := adoptSymlink(result.Fake)
+		return err
+	}
+	if result.ErrorType == "NOT_BACKED_UP" {
+		return fmt.Errorf("需要手动将真实文件移入备份目录，再使用 flk convert 或重新创建链接")
+	}
+	if result.ErrorType == "PLACEHOLDER" {
+		return fmt.Errorf("云端占位文件无法自动修复，请先在同步客户端中将其设为始终保留在此设备上")
+	}
+	if result.ErrorType == "ATTRIB_DRIFT" {
+		attrib, err := fileattr.Parse(result.Attrib)
+		if err != nil {
+			return err
+		}
+		expandedLink, err := pathutil.NormalizePath(result.Fake)
+		if err != nil {
+			return err
+		}
+		return fileattr.Set(expandedLink, attrib)
+	}
+
+	if err := validateTarget(result); err != nil {
+		return err
+	}
+
+	oldMode, oldAttrib, oldValidate, oldNote, oldStyle := createMode, createAttrib, createValidate, createNote, createLinkStyle
+	createMode, createAttrib, createValidate, createNote = result.Mode, result.Attrib, result.Validate, result.Note
+	createLinkStyle = result.LinkStyle
+	defer func() {
+		createMode, createAttrib, createValidate, createNote = oldMode, oldAttrib, oldValidate, oldNote
+		createLinkStyle = oldStyle
+	}()
+
 	switch result.Type {
 	case "symlink":
 		// 临时设置全局变量
@@ -179,10 +506,7 @@ func repairResult(result output.CheckResult, idx int) error {
 		oldForce := createForce
 		oldDevice := createDevice
 
-		symlinkReal = result.Real
-		if !filepath.IsAbs(symlinkReal) {
-			symlinkReal = filepath.Join(result.BasePath, symlinkReal)
-		}
+		symlinkReal = resolveRecordPath(result.Real, result.BasePath)
 		symlinkFake = result.Fake
 		createForce = true
 		createDevice = result.Device
@@ -195,29 +519,160 @@ func repairResult(result output.CheckResult, idx int) error {
 		}()
 		return Symlink(nil, nil)
 	case "hardlink":
-		oldPrim := hardlinkPrim
-		oldSeco := hardlinkSeco
+		return relinkHardlinkGroup(result)
+	case "template":
+		oldReal := templateReal
+		oldFake := templateFake
 		oldForce := createForce
 		oldDevice := createDevice
 
-		hardlinkPrim = result.Prim
-		if !filepath.IsAbs(hardlinkPrim) {
-			hardlinkPrim = filepath.Join(result.BasePath, hardlinkPrim)
-		}
-		hardlinkSeco = result.Seco
-		if !filepath.IsAbs(hardlinkSeco) {
-			hardlinkSeco = filepath.Join(result.BasePath, hardlinkSeco)
-		}
+		templateReal = resolveRecordPath(result.Real, result.BasePath)
+		templateFake = result.Fake
 		createForce = true
 		createDevice = result.Device
 
 		defer func() {
-			hardlinkPrim = oldPrim
-			hardlinkSeco = oldSeco
+			templateReal = oldReal
+			templateFake = oldFake
 			createForce = oldForce
 			createDevice = oldDevice
 		}()
-		return Hardlink(nil, nil)
+		return Template(nil, nil)
+	case "junction":
+		oldReal, oldFake, oldForce, oldDevice := junctionReal, junctionFake, createForce, createDevice
+		junctionReal = resolveRecordPath(result.Real, result.BasePath)
+		junctionFake = result.Fake
+		createForce = true
+		createDevice = result.Device
+		defer func() {
+			junctionReal, junctionFake, createForce, createDevice = oldReal, oldFake, oldForce, oldDevice
+		}()
+		return Junction(nil, nil)
 	}
 	return fmt.Errorf("未知类型 %s", result.Type)
 }
+
+// relinkBrokenHardlink 重新链接被保存操作断开的硬链接，最后以主文件为准重建次要文件的硬链接。
+// 被替换的一侧持有最新的编辑：次要文件被替换时先将其内容写回主文件；主文件被替换时直接重新链接；
+// 两侧都被替换时无法判断，以修改时间较新的一侧为准
+func relinkBrokenHardlink(result output.CheckResult) error {
+	prim := resolveRecordPath(result.Prim, result.BasePath)
+	seco, err := pathutil.NormalizePath(result.Seco)
+	if err != nil {
+		return err
+	}
+
+	primInfo, err := trace.Stat(prim)
+	if err != nil {
+		return err
+	}
+	secoInfo, err := trace.Stat(seco)
+	if err != nil {
+		return err
+	}
+	secoWins := result.ErrorType == "SECO_REPLACED" ||
+		(result.ErrorType == "HARDLINK_BROKEN" && secoInfo.ModTime().After(primInfo.ModTime()))
+	if secoWins {
+		same, err := contenthash.Same(prim, seco)
+		if err != nil {
+			return err
+		}
+		if !same {
+			secoData, err := os.ReadFile(seco)
+			if err != nil {
+				return err
+			}
+			// 原地写入以保留主文件的文件标识
+			logger.Info("将次要文件 " + seco + " 的内容写回主文件 " + prim)
+			if err := trace.WriteFile(prim, secoData, primInfo.Mode().Perm()); err != nil {
+				return err
+			}
+		}
+	} else if err := trashDivergedSeco(prim, seco); err != nil {
+		// 以主文件为准时次要文件上的改动会被覆盖，先移到回收站
+		return err
+	}
+
+	if err := hardlink.Create(prim, seco, true); err != nil {
+		return err
+	}
+	if err := fileperm.Apply(seco, result.Mode); err != nil {
+		return err
+	}
+
+	mgr, err := store.Current()
+	if err != nil {
+		return nil
+	}
+	if _, entry, ok := mgr.FindEntry(runtime.GOOS, result.Device, "hardlink", resultEntry(result)); ok {
+		if id, err := fileid.Get(seco); err == nil {
+			entry[store.InodeKey] = id
+		}
+	}
+	return mgr.Save(store.StorePath)
+}
+
+// resultEntry 返回用于在存储中定位检查结果所属条目的匹配字段，一组硬链接以第一个次要文件定位
+func resultEntry(result output.CheckResult) store.Entry {
+	if result.Type == "hardlink" {
+		seco := result.Seco
+		if len(result.Group) > 0 {
+			seco = result.Group[0]
+		}
+		return store.Entry{"prim": result.Prim, store.SecoKey: seco}
+	}
+	return store.Entry{"real": result.Real, "fake": result.Fake}
+}
+
+// relinkHardlinkGroup 将一组硬链接中所有未指向主文件的次要文件重新链接到主文件
+func relinkHardlinkGroup(result output.CheckResult) error {
+	prim := resolveRecordPath(result.Prim, result.BasePath)
+	primInfo, err := trace.Stat(prim)
+	if err != nil {
+		return err
+	}
+
+	secos := result.Group
+	if len(secos) == 0 {
+		secos = []string{result.Seco}
+	}
+	for _, seco := range secos {
+		seco, err := pathutil.NormalizePath(seco)
+		if err != nil {
+			return err
+		}
+		if secoInfo, err := trace.Stat(seco); err == nil && os.SameFile(primInfo, secoInfo) {
+			continue
+		}
+		if err := trashDivergedSeco(prim, seco); err != nil {
+			return err
+		}
+		if err := hardlink.Create(prim, seco, true); err != nil {
+			return err
+		}
+		auditLink(true, "hardlink", seco, prim)
+		if err := fileperm.Apply(seco, result.Mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trashDivergedSeco 次要文件是内容与主文件不同的普通文件时，确认后将其移到回收站，避免重新链接时丢失其内容
+func trashDivergedSeco(prim, seco string) error {
+	info, err := trace.Lstat(seco)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	same, err := contenthash.Same(prim, seco)
+	if err != nil {
+		return err
+	}
+	if same {
+		return nil
+	}
+	if !confirmForceDelete(seco) {
+		return errors.New("已取消覆盖 " + seco)
+	}
+	return moveToTrash(seco)
+}
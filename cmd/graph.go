@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// linkGraph 链接关系图：真实文件节点在外层，链接节点按设备、应用分组
+type linkGraph struct {
+	targets []string          // 真实文件路径，按字典序
+	ids     map[string]string // 真实文件路径到节点标识
+	devices []graphDevice
+	edges   []graphEdge
+}
+
+type graphDevice struct {
+	name    string
+	bundles []graphBundle // 第一组的 name 为空，表示不属于任何应用的链接
+}
+
+type graphBundle struct {
+	name  string
+	links []graphNode
+}
+
+type graphNode struct {
+	id    string
+	label string
+}
+
+type graphEdge struct {
+	from, to, label string
+}
+
+// buildLinkGraph 按设备和应用对链接分组，同一链接在不同设备下各有一个节点
+func buildLinkGraph(links []linkRecord) *linkGraph {
+	g := &linkGraph{ids: make(map[string]string)}
+	for _, l := range links {
+		if _, ok := g.ids[l.Target]; !ok {
+			g.ids[l.Target] = ""
+			g.targets = append(g.targets, l.Target)
+		}
+	}
+	sort.Strings(g.targets)
+	for i, t := range g.targets {
+		g.ids[t] = fmt.Sprintf("t%d", i)
+	}
+
+	grouped := make(map[string]map[string][]linkRecord)
+	for _, l := range links {
+		if grouped[l.Device] == nil {
+			grouped[l.Device] = make(map[string][]linkRecord)
+		}
+		grouped[l.Device][l.Bundle] = append(grouped[l.Device][l.Bundle], l)
+	}
+	n := 0
+	for _, device := range sortedKeys(grouped) {
+		d := graphDevice{name: device}
+		for _, bundle := range sortedKeys(grouped[device]) {
+			b := graphBundle{name: bundle}
+			for _, l := range grouped[device][bundle] {
+				id := fmt.Sprintf("l%d", n)
+				n++
+				b.links = append(b.links, graphNode{id: id, label: l.Link})
+				g.edges = append(g.edges, graphEdge{from: g.ids[l.Target], to: id, label: l.Type})
+			}
+			d.bundles = append(d.bundles, b)
+		}
+		g.devices = append(g.devices, d)
+	}
+	return g
+}
+
+// sortedKeys 返回按字典序排列的键，空字符串排在最前
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// dotGraph 生成 Graphviz 格式的链接关系图
+func dotGraph(links []linkRecord) string {
+	g := buildLinkGraph(links)
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace
+	var b strings.Builder
+	b.WriteString("digraph flk {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	for _, t := range g.targets {
+		fmt.Fprintf(&b, "\t%s [label=\"%s\", style=filled, fillcolor=lightyellow];\n", g.ids[t], quote(t))
+	}
+	for i, d := range g.devices {
+		fmt.Fprintf(&b, "\tsubgraph cluster_d%d {\n\t\tlabel=\"设备 %s\";\n", i, quote(d.name))
+		for j, bundle := range d.bundles {
+			indent := "\t\t"
+			if bundle.name != "" {
+				fmt.Fprintf(&b, "\t\tsubgraph cluster_d%d_b%d {\n\t\t\tlabel=\"应用 %s\";\n", i, j, quote(bundle.name))
+				indent = "\t\t\t"
+			}
+			for _, l := range bundle.links {
+				fmt.Fprintf(&b, "%s%s [label=\"%s\"];\n", indent, l.id, quote(l.label))
+			}
+			if bundle.name != "" {
+				b.WriteString("\t\t}\n")
+			}
+		}
+		b.WriteString("\t}\n")
+	}
+	for _, e := range g.edges {
+		style := ""
+		if e.label == "hardlink" {
+			style = ", style=dashed, dir=both"
+		}
+		fmt.Fprintf(&b, "\t%s -> %s [label=\"%s\"%s];\n", e.from, e.to, e.label, style)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// mermaidGraph 生成 Mermaid 流程图格式的链接关系图
+func mermaidGraph(links []linkRecord) string {
+	g := buildLinkGraph(links)
+	quote := strings.NewReplacer(`"`, `#quot;`).Replace
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, t := range g.targets {
+		fmt.Fprintf(&b, "    %s[\"%s\"]\n", g.ids[t], quote(t))
+	}
+	for i, d := range g.devices {
+		fmt.Fprintf(&b, "    subgraph d%d[\"设备 %s\"]\n", i, quote(d.name))
+		for j, bundle := range d.bundles {
+			indent := "        "
+			if bundle.name != "" {
+				fmt.Fprintf(&b, "        subgraph d%db%d[\"应用 %s\"]\n", i, j, quote(bundle.name))
+				indent = "            "
+			}
+			for _, l := range bundle.links {
+				fmt.Fprintf(&b, "%s%s[\"%s\"]\n", indent, l.id, quote(l.label))
+			}
+			if bundle.name != "" {
+				b.WriteString("        end\n")
+			}
+		}
+		b.WriteString("    end\n")
+	}
+	for _, e := range g.edges {
+		arrow := "-->"
+		if e.label == "hardlink" {
+			arrow = "<-.->"
+		}
+		fmt.Fprintf(&b, "    %s %s|%s| %s\n", e.from, arrow, e.label, e.to)
+	}
+	return b.String()
+}
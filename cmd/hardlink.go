@@ -2,10 +2,14 @@ package cmd
 
 import (
 	"errors"
-	"os"
+	"fmt"
+	"strings"
 
 	"github.com/jy-eggroll/flk/internal/create/hardlink"
-	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/fileid"
+	"github.com/jy-eggroll/flk/internal/fileperm"
+	"github.com/jy-eggroll/flk/internal/fsinfo"
+	"github.com/jy-eggroll/flk/internal/interference"
 	"github.com/jy-eggroll/flk/internal/output"
 	"github.com/jy-eggroll/flk/internal/pathutil"
 	"github.com/jy-eggroll/flk/internal/store"
@@ -13,8 +17,8 @@ import (
 )
 
 var (
-	hardlinkPrim string
-	hardlinkSeco string
+	hardlinkPrim  string
+	hardlinkSecos []string
 )
 
 var hardlinkCmd = &cobra.Command{
@@ -27,9 +31,14 @@ var hardlinkCmd = &cobra.Command{
 func init() {
 	createCmd.AddCommand(hardlinkCmd)
 	hardlinkCmd.Flags().StringVarP(&hardlinkPrim, "prim", "p", "", "主要文件路径")
-	hardlinkCmd.Flags().StringVarP(&hardlinkSeco, "seco", "s", "", "次要文件路径")
+	hardlinkCmd.Flags().StringArrayVarP(&hardlinkSecos, "seco", "s", nil, "次要文件路径，可重复指定以创建共享同一主文件的一组硬链接")
 	hardlinkCmd.Flags().BoolVar(&createForce, "force", false, "强制覆盖已存在的文件或文件夹")
 	hardlinkCmd.Flags().StringVarP(&createDevice, "device", "d", "all", "设备名称，用于后续设备过滤")
+	hardlinkCmd.Flags().StringVar(&createMode, "mode", "", "创建后将目标文件权限设置为该值（八进制，如 600），Windows 上忽略")
+	hardlinkCmd.Flags().BoolVar(&createHydrate, "hydrate", false, "源文件为云端占位文件时允许触发下载后继续")
+	hardlinkCmd.Flags().StringVar(&createEvery, "check-every", "", "检查频率提示，如 1h、7d，配合 check --due-only 减少对网络驱动器等昂贵路径的检查")
+	hardlinkCmd.Flags().StringVar(&createValidate, "validate", "", "修复前对真实文件的校验，逗号分隔，可选 nonempty、json、toml、yaml，最后可跟 cmd:<命令>（文件路径在环境变量 FLK_FILE 中）")
+	hardlinkCmd.Flags().StringVar(&createNote, "note", "", "记录的备注，如为什么链接指向非常规位置，检查时随结果显示")
 	hardlinkCmd.MarkFlagRequired("prim")
 	hardlinkCmd.MarkFlagRequired("seco")
 }
@@ -40,45 +49,154 @@ func Hardlink(cmd *cobra.Command, args []string) error {
 	normalizedPrim, err := pathutil.NormalizePath(hardlinkPrim)
 	if err != nil {
 		result := output.CreateResult{Success: false, Type: "硬链接", Error: "主要文件路径标准化失败: " + err.Error()}
-		output.PrintCreateResult(format, result)
+		printCreateResult(format, result)
 		return nil
 	}
 
-	normalizedSeco, err := pathutil.NormalizePath(hardlinkSeco)
-	if err != nil {
-		result := output.CreateResult{Success: false, Type: "硬链接", Error: "次要文件路径标准化失败: " + err.Error()}
-		output.PrintCreateResult(format, result)
-		return nil
+	var normalizedSecos []string
+	for _, seco := range hardlinkSecos {
+		normalizedSeco, err := pathutil.NormalizePath(seco)
+		if err != nil {
+			result := output.CreateResult{Success: false, Type: "硬链接", Error: "次要文件路径标准化失败: " + err.Error()}
+			printCreateResult(format, result)
+			return nil
+		}
+		normalizedSecos = append(normalizedSecos, normalizedSeco)
 	}
 
-	var result output.CreateResult
-	if err := hardlink.Create(normalizedPrim, normalizedSeco, createForce); err != nil {
-		result = output.CreateResult{Success: false, Type: "硬链接", Error: err.Error()}
-	} else {
-		result = output.CreateResult{Success: true, Type: "硬链接", Message: "创建成功"}
-		// 存储逻辑
-		if store.GlobalManager == nil {
-			if err := store.InitStore(store.StorePath); err != nil {
-				logger.Error("初始化存储失败 " + err.Error())
+	applyLinkRules(cmd, normalizedSecos[0])
+
+	if err := parseCreateOptions(); err != nil {
+		result := output.CreateResult{Success: false, Type: "硬链接", Error: err.Error()}
+		printCreateResult(format, result)
+		return err
+	}
+
+	if createMode != "" {
+		if _, err := fileperm.Parse(createMode); err != nil {
+			result := output.CreateResult{Success: false, Type: "硬链接", Error: err.Error()}
+			printCreateResult(format, result)
+			return err
+		}
+	}
+
+	for _, normalizedSeco := range normalizedSecos {
+		if err := checkStrict(normalizedPrim, normalizedSeco); err != nil {
+			result := output.CreateResult{Success: false, Type: "硬链接", Error: err.Error()}
+			printCreateResult(format, result)
+			return err
+		}
+		warnSelfReference(normalizedSeco)
+	}
+
+	if err := ensureHydrated(normalizedPrim); err != nil {
+		result := output.CreateResult{Success: false, Type: "硬链接", Error: err.Error()}
+		printCreateResult(format, result)
+		return err
+	}
+
+	if createForce {
+		for _, normalizedSeco := range normalizedSecos {
+			if !confirmForceDelete(normalizedSeco) {
+				result := output.CreateResult{Success: false, Type: "硬链接", Error: "已取消覆盖 " + normalizedSeco}
+				printCreateResult(format, result)
+				return errors.New(result.Error)
 			}
 		}
-		mgr := store.GlobalManager
-		if mgr != nil {
+	}
+
+	// 同一主文件的所有次要文件作为一组创建并记录为一条记录
+	var created []string
+	var txns []*createTxn
+	var replacedSecos []bool
+	var failures []string
+	for _, normalizedSeco := range normalizedSecos {
+		if err := checkHardlinkVolume(normalizedPrim, normalizedSeco); err != nil {
+			failures = append(failures, normalizedSeco+": "+err.Error())
+			continue
+		}
+		replaced := createForce && pathExists(normalizedSeco)
+		txn, err := beginCreate(normalizedSeco, createForce)
+		if err != nil {
+			failures = append(failures, normalizedSeco+": "+err.Error())
+			continue
+		}
+		if err := hardlink.Create(normalizedPrim, normalizedSeco, createForce); err != nil {
+			failures = append(failures, normalizedSeco+": "+txn.abort(interference.Explain(normalizedSeco, err)).Error())
+			continue
+		}
+		if err := fileperm.Apply(normalizedSeco, createMode); err != nil {
+			warnCreate("链接已创建，但设置权限失败：%v", err)
+		}
+		created = append(created, normalizedSeco)
+		txns = append(txns, txn)
+		replacedSecos = append(replacedSecos, replaced)
+	}
+
+	if len(created) > 0 {
+		absPrimPath, _ := pathutil.ToAbsolute(normalizedPrim)
+		fields := map[string]string{
+			"prim":          absPrimPath,
+			store.SourceKey: store.SourceCLI,
+		}
+		for i, normalizedSeco := range created {
 			absSecoPath, _ := pathutil.ToAbsolute(normalizedSeco)
-			fields := map[string]string{
-				"prim": normalizedPrim,
-				"seco": absSecoPath,
+			fields[store.SecoKeyAt(i)] = absSecoPath
+		}
+		if id, err := fileid.Get(normalizedPrim); err == nil {
+			fields[store.InodeKey] = id
+		}
+		addOptionFields(fields)
+		if err := saveRecord("hardlink", fields); err != nil {
+			// 整组撤销，避免留下未被记录的硬链接
+			for i, txn := range txns {
+				failures = append(failures, created[i]+": "+txn.abort(fmt.Errorf("写入存储失败，已撤销创建的链接: %v", err)).Error())
 			}
-			parentPath, _ := os.Getwd()
-			mgr.AddRecord(createDevice, "hardlink", parentPath, fields)
-			if err := mgr.Save(store.StorePath); err != nil {
-				logger.Error("持久化失败 " + err.Error())
+			created = nil
+		} else {
+			for i, txn := range txns {
+				txn.commit()
+				auditLink(replacedSecos[i], "hardlink", created[i], normalizedPrim)
 			}
 		}
 	}
-	output.PrintCreateResult(format, result)
+
+	var result output.CreateResult
+	switch {
+	case len(failures) == 0:
+		result = output.CreateResult{Success: true, Type: "硬链接", Message: "创建成功"}
+		if len(created) > 1 {
+			result.Message = fmt.Sprintf("创建成功，共 %d 个次要文件", len(created))
+		}
+	default:
+		result = output.CreateResult{Success: false, Type: "硬链接", Error: strings.Join(failures, "; ")}
+		if len(created) > 0 {
+			result.Message = fmt.Sprintf("已创建 %d 个，%d 个失败", len(created), len(failures))
+		}
+	}
+	result.Warnings = createWarnings
+	printCreateResult(format, result)
 	if result.Success {
 		return nil
 	}
 	return errors.New(result.Error)
 }
+
+// checkHardlinkVolume 创建前确认主文件和次要文件位于同一卷且该卷支持硬链接，给出比系统错误更明确的原因；无法探测时不拦截
+func checkHardlinkVolume(prim, seco string) error {
+	primInfo, err := fsinfo.For(prim)
+	if err != nil {
+		return nil
+	}
+	secoInfo, err := fsinfo.For(seco)
+	if err != nil {
+		return nil
+	}
+	if primInfo.Volume != secoInfo.Volume {
+		return fmt.Errorf("主文件位于卷 %s，次要文件位于卷 %s，硬链接只能在同一卷内创建，可改用 flk create symlink", primInfo.Volume, secoInfo.Volume)
+	}
+	if !secoInfo.Hardlink {
+		return fmt.Errorf("卷 %s（%s）不支持硬链接，可改用 flk create symlink", secoInfo.Volume, secoInfo.Type)
+	}
+	return nil
+}
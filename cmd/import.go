@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/manifest"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import [清单]...",
+	Short: "将 flk export 导出的清单或旧版 flk 的记录合并回存储",
+	Long: `读取 json、yaml 或 toml 格式的清单（按扩展名判断），将其中适用于当前平台的记录合并到存储中，只写入记录，不创建链接，之后可使用 flk apply 创建。
+存储中已有相同链接和目标的记录会被跳过；同一链接已记录了不同的目标时报告冲突并保留存储中的记录，使用 --force 以清单为准覆盖。
+--from-old 读取旧版 flk 数据目录中的 ` + manifest.OldFileName + `：Symlinks 和 Hardlinks 中每条记录的 RealRelative 基于该目录解析，
+FakeAbsolute 必须是绝对路径，Device 可用 --map-device 旧名称=新名称 改为新的设备名称；任何一条记录不符合要求时不写入任何记录`,
+	Args:         cobra.ArbitraryArgs,
+	RunE:         RunImport,
+	SilenceUsage: true,
+}
+
+var (
+	importDevice  string
+	importForce   bool
+	importDryRun  bool
+	importFromOld string
+	importDevices map[string]string
+)
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVarP(&importDevice, "device", "d", "", "写入的设备名称，默认使用记录中声明的设备，未声明时为 all")
+	importCmd.Flags().BoolVar(&importForce, "force", false, "发生冲突时以清单中的记录覆盖存储中的记录")
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "只显示将要合并的记录和冲突，不修改存储")
+	importCmd.Flags().StringVar(&importFromOld, "from-old", "", "从旧版 flk 的数据目录导入 "+manifest.OldFileName)
+	importCmd.Flags().StringToStringVar(&importDevices, "map-device", nil, "--from-old 时将旧记录的设备名称映射为新名称，如 laptop=work-laptop，可重复指定")
+}
+
+// RunImport 将清单中的记录合并到存储
+func RunImport(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 && importFromOld == "" {
+		return fmt.Errorf("需要指定清单文件或 --from-old")
+	}
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	var sources []importSource
+	if importFromOld != "" {
+		m, err := manifest.LoadOld(importFromOld, importDevices)
+		if err != nil {
+			return fmt.Errorf("读取旧版 flk 记录 %s 失败: %w", importFromOld, err)
+		}
+		sources = append(sources, importSource{m, "import:old:" + foldedAbs(filepath.Join(mustNormalize(importFromOld), manifest.OldFileName))})
+	}
+	for _, path := range args {
+		m, err := manifest.LoadFromFile(path)
+		if err != nil {
+			return fmt.Errorf("读取清单 %s 失败: %w", path, err)
+		}
+		sources = append(sources, importSource{m, "import:" + foldedAbs(mustNormalize(path))})
+	}
+
+	var items []output.PlanItem
+	conflicts := 0
+	for _, src := range sources {
+		m, source := src.manifest, src.source
+		for _, rec := range m.Records {
+			if rec.Platform != "" && rec.Platform != runtime.GOOS {
+				continue
+			}
+			item := importRecord(mgr, rec, source)
+			if item.Action == "none" {
+				continue
+			}
+			if item.Action == "conflict" {
+				conflicts++
+			}
+			items = append(items, item)
+		}
+	}
+
+	format := output.OutputFormat(outputFormat)
+	if len(items) == 0 {
+		logger.Info("清单中的记录均已存在于存储中")
+		return nil
+	}
+	if err := output.PrintPlan(format, items); err != nil {
+		logger.Error("输出失败 " + err.Error())
+	}
+	if !importDryRun {
+		if err := mgr.Save(store.StorePath); err != nil {
+			logger.Error("持久化失败 " + err.Error())
+			return err
+		}
+	}
+	if conflicts > 0 {
+		return fmt.Errorf("%d 条记录与存储冲突，已保留存储中的记录，使用 --force 以清单为准", conflicts)
+	}
+	return nil
+}
+
+// importSource 待合并的清单及写入记录的来源
+type importSource struct {
+	manifest *manifest.Manifest
+	source   string
+}
+
+// foldedAbs 返回 path 的绝对路径，主目录折叠为 ~
+func foldedAbs(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	folded, _ := pathutil.FoldHome(path)
+	return folded
+}
+
+// importRecord 将一条清单记录合并到存储，返回执行（或 --dry-run 时将要执行）的操作：
+// add 新增，modify 以清单覆盖冲突的记录，conflict 存在冲突且未覆盖，none 已存在相同记录
+func importRecord(mgr *store.Manager, rec manifest.Record, source string) output.PlanItem {
+	device := importDevice
+	if device == "" {
+		device = rec.Device
+	}
+	if device == "" {
+		device = "all"
+	}
+	item := output.PlanItem{Action: "add", Name: rec.Name, Type: rec.Type, Device: device, Link: rec.Link(), Target: rec.Target()}
+
+	foldedLink, _ := pathutil.FoldHome(rec.Link())
+	parentPath, entry, found := mgr.FindEntry(runtime.GOOS, device, rec.Type, linkEntry(rec.Type, foldedLink))
+	if found {
+		existing := rec.Target()
+		if basePath, err := recordBasePath(parentPath, entry); err == nil {
+			existing = resolveRecordPath(entry[targetKey(rec.Type)], basePath)
+		}
+		if samePath(existing, rec.Target()) {
+			item.Action = "none"
+			return item
+		}
+		if !importForce {
+			item.Action, item.Detail = "conflict", "存储中记录的目标为 "+existing
+			return item
+		}
+		item.Action, item.Detail = "modify", "目标由 "+existing+" 变为 "+rec.Target()
+	}
+	if importDryRun {
+		return item
+	}
+	if found {
+		mgr.RemoveMatchingEntry(runtime.GOOS, device, rec.Type, parentPath, entry)
+	}
+	mgr.AddRecord(device, rec.Type, filepath.Dir(rec.Link()), recordFields(rec, source))
+	return item
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jy-eggroll/flk/internal/create/junction"
+	"github.com/jy-eggroll/flk/internal/interference"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var (
+	junctionReal string
+	junctionFake string
+)
+
+var junctionCmd = &cobra.Command{
+	Use:   "junction",
+	Short: "创建目录联接（仅 Windows，仅目录）",
+	Long:  "创建 NTFS 目录联接。与目录符号链接不同，创建目录联接不需要管理员权限或开发者模式，但只能指向本机卷上的目录",
+	RunE:  Junction,
+}
+
+func init() {
+	createCmd.AddCommand(junctionCmd)
+	junctionCmd.Flags().StringVarP(&junctionReal, "real", "r", "", "真实目录路径")
+	junctionCmd.Flags().StringVarP(&junctionFake, "fake", "f", "", "目录联接路径")
+	junctionCmd.Flags().BoolVar(&createForce, "force", false, "强制覆盖已存在的文件或文件夹")
+	junctionCmd.Flags().StringVarP(&createDevice, "device", "d", "all", "设备名称，用于后续设备过滤")
+	junctionCmd.Flags().StringVar(&createEvery, "check-every", "", "检查频率提示，如 1h、7d，配合 check --due-only 减少对网络驱动器等昂贵路径的检查")
+	junctionCmd.Flags().StringVar(&createValidate, "validate", "", "修复前对真实目录的校验，语法同 flk create symlink --validate")
+	junctionCmd.Flags().StringVar(&createNote, "note", "", "记录的备注，如为什么链接指向非常规位置，检查时随结果显示")
+	junctionCmd.MarkFlagRequired("real")
+	junctionCmd.MarkFlagRequired("fake")
+}
+
+func Junction(cmd *cobra.Command, args []string) error {
+	format := output.OutputFormat(outputFormat)
+	fail := func(err error) error {
+		result := output.CreateResult{Success: false, Type: "目录联接", Error: err.Error(), Warnings: createWarnings}
+		printCreateResult(format, result)
+		return err
+	}
+	if !junction.Supported {
+		return fail(junction.ErrUnsupported)
+	}
+
+	normalizedReal, err := pathutil.NormalizePath(junctionReal)
+	if err != nil {
+		return fail(errors.New("真实目录路径标准化失败 " + err.Error()))
+	}
+	normalizedFake, err := pathutil.NormalizePath(junctionFake)
+	if err != nil {
+		return fail(errors.New("目录联接路径标准化失败 " + err.Error()))
+	}
+	applyLinkRules(cmd, normalizedFake)
+	if err := parseCreateOptions(); err != nil {
+		return fail(err)
+	}
+	if err := checkStrict(normalizedReal, normalizedFake); err != nil {
+		return fail(err)
+	}
+	warnSelfReference(normalizedFake)
+	logger.Info("创建目录联接 real=" + normalizedReal + ", fake=" + normalizedFake)
+
+	force := createForce
+	if force && !confirmForceDelete(normalizedFake) {
+		return fail(errors.New("已取消覆盖 " + normalizedFake))
+	}
+	replaced := force && pathExists(normalizedFake)
+	if err := createJunctionTxn(normalizedReal, normalizedFake, force); err != nil {
+		return fail(err)
+	}
+	auditLink(replaced, "junction", normalizedFake, normalizedReal)
+	printCreateResult(format, output.CreateResult{Success: true, Type: "目录联接", Message: "创建成功", Warnings: createWarnings})
+	return nil
+}
+
+// createJunctionTxn 创建目录联接并写入存储，写入存储失败时删除新链接并恢复被覆盖的原文件
+func createJunctionTxn(normalizedReal, normalizedFake string, force bool) error {
+	txn, err := beginCreate(normalizedFake, force)
+	if err != nil {
+		return err
+	}
+	if err := junction.Create(normalizedReal, normalizedFake, force); err != nil {
+		return txn.abort(interference.Explain(normalizedFake, err))
+	}
+	absRealPath, _ := pathutil.ToAbsolute(normalizedReal)
+	absFakePath, _ := pathutil.ToAbsolute(normalizedFake)
+	fields := map[string]string{
+		"real":          absRealPath,
+		"fake":          absFakePath,
+		store.SourceKey: store.SourceCLI,
+	}
+	addOptionFields(fields)
+	if err := saveRecord("junction", fields); err != nil {
+		return txn.abort(fmt.Errorf("写入存储失败，已撤销创建的链接: %v", err))
+	}
+	txn.commit()
+	return nil
+}
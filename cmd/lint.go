@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jy-eggroll/flk/internal/lint"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/manifest"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "静态检查存储和清单中不可移植或有歧义的写法",
+	Long: `不访问文件系统，只检查存储和清单本身：
+  ABSOLUTE_HOME    主目录下的路径未使用 ~（可修复）
+  FOREIGN_HOME     路径指向其他用户的主目录
+  UNKNOWN_PLATFORM 平台键不是有效的 GOOS 取值
+  DUPLICATE_LINK   同一平台和设备下重复记录的链接（完全相同时可修复）
+  DEVICE_DUPLICATE 同一链接同时记录在设备 all 和具体设备下
+  CHAINED_LINK     记录的真实文件本身是另一条记录的链接
+  MIXED_SEPARATORS 路径混用 / 和 \（Windows 记录可修复）
+  MISSING_DEVICE   设备名为空（可修复，合并到 all）
+  MISSING_FIELD    记录缺少必需字段
+  EMPTY_GROUP      父路径下没有记录（可修复）
+使用 --fix 修复存储中可安全修复的问题，清单需手动修改`,
+	SilenceUsage: true,
+	RunE:         RunLint,
+}
+
+var (
+	lintManifests []string
+	lintFix       bool
+)
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+	lintCmd.Flags().StringArrayVarP(&lintManifests, "manifest", "m", nil, "同时检查的清单文件，可重复指定")
+	lintCmd.Flags().BoolVar(&lintFix, "fix", false, "修复存储中可安全修复的问题并保存")
+}
+
+// RunLint 检查存储和指定的清单，存在未修复的 error 级问题时返回错误
+func RunLint(cmd *cobra.Command, args []string) error {
+	format := output.OutputFormat(outputFormat)
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	issues := lint.Store(mgr.Data, lintFix)
+
+	for _, path := range lintManifests {
+		m, err := manifest.ReadRaw(path)
+		if err != nil {
+			logger.Error("读取清单失败 " + err.Error())
+			return err
+		}
+		issues = append(issues, lint.Manifest(m, path)...)
+	}
+
+	fixed, remaining := 0, 0
+	for _, issue := range issues {
+		switch {
+		case issue.Fixed:
+			fixed++
+		case issue.Level == lint.LevelError:
+			remaining++
+		}
+	}
+	if fixed > 0 {
+		if err := mgr.Save(store.StorePath); err != nil {
+			logger.Error("持久化失败 " + err.Error())
+			return err
+		}
+	}
+
+	if err := output.PrintLintIssues(format, issues); err != nil {
+		return err
+	}
+	if remaining > 0 {
+		return fmt.Errorf("发现 %d 个 error 级问题", remaining)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/state"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出存储中的记录及其创建时间、主机和最后验证时间",
+	Long: `列出存储中的记录（默认当前平台），不访问文件系统，验证信息来自 check 写入的状态文件：
+  有效  最近一次检查时链接有效
+  无效  最近一次检查时链接无效
+  未知  从未检查过，或使用 --stale 时超过期限没有检查（如所在驱动器一直未挂载）
+创建时间和主机是记录首次写入存储的时间和所在主机，早期版本写入的记录显示为未记录。
+可按平台、设备、类型和路径子串筛选；其他平台的路径按本机的主目录和源根目录展开`,
+	Args: cobra.NoArgs,
+	RunE: RunList,
+}
+
+var (
+	listDevice   string
+	listStale    string
+	listPlatform string
+	listType     string
+	listPath     string
+)
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().StringVarP(&listDevice, "device", "d", "", "仅列出该设备的记录")
+	listCmd.Flags().StringVar(&listStale, "stale", "", "仅列出超过该时长没有验证为有效的记录，如 30d、12h")
+	listCmd.Flags().StringVar(&listPlatform, "platform", runtime.GOOS, "列出该平台的记录，如 linux、darwin、windows")
+	listCmd.Flags().StringVarP(&listType, "type", "t", "", "仅列出该类型的记录：symlink、hardlink、template、junction")
+	listCmd.Flags().StringVar(&listPath, "path", "", "仅列出链接路径或真实文件路径包含该子串的记录")
+	addRedactFlag(listCmd)
+}
+
+// recordItem 根据状态文件判断记录的验证状态，stale 大于 0 时超过该时长没有检查的记录视为未知
+func recordItem(l linkRecord, st *state.State, stale time.Duration, now time.Time) output.RecordItem {
+	rs := st.Records[state.Key(l.platform, l.Device, l.Type, l.key)]
+	item := output.RecordItem{
+		Type: l.Type, Device: l.Device, Link: l.Link, Target: l.Target, Status: output.RecordUnknown,
+		CreatedAt: parseEntryTime(l.created), UpdatedAt: parseEntryTime(l.updated), CreatedByHost: l.host, Version: l.version,
+	}
+	if !rs.LastVerified.IsZero() {
+		verified := rs.LastVerified
+		item.LastVerified = &verified
+	}
+	switch {
+	case stale > 0 && now.Sub(rs.LastChecked) > stale:
+		// 期限内没有检查过，无法判断当前状态
+	case rs.LastChecked.IsZero():
+	case rs.LastVerified.Before(rs.LastChecked):
+		item.Status = output.RecordBroken
+	default:
+		item.Status = output.RecordVerified
+	}
+	return item
+}
+
+// parseEntryTime 解析条目中的时间字段，早期版本写入的条目没有该字段时返回 nil
+func parseEntryTime(value string) *time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// loadRecordState 读取状态文件，失败时返回空状态，所有记录都将视为未知
+func loadRecordState() *state.State {
+	st, err := state.Load(state.PathFor(store.StorePath))
+	if err != nil {
+		logger.Warn("读取状态文件失败 " + err.Error())
+		return &state.State{Records: make(map[string]state.RecordState)}
+	}
+	return st
+}
+
+// RunList 列出筛选后的记录，指定 --stale 时只保留长期没有验证为有效的记录
+func RunList(cmd *cobra.Command, args []string) error {
+	stale, err := state.ParseFrequency(listStale)
+	if err != nil {
+		return err
+	}
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	st := loadRecordState()
+
+	now := time.Now()
+	var items []output.RecordItem
+	for _, l := range platformLinks(mgr, listPlatform, listDevice) {
+		if listType != "" && l.Type != listType {
+			continue
+		}
+		if listPath != "" && !strings.Contains(l.Link, listPath) && !strings.Contains(l.Target, listPath) {
+			continue
+		}
+		item := recordItem(l, st, stale, now)
+		if stale > 0 && item.LastVerified != nil && now.Sub(*item.LastVerified) <= stale {
+			continue
+		}
+		items = append(items, item)
+	}
+	return output.PrintRecords(output.OutputFormat(outputFormat), redactRecordItems(items))
+}
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/manifest"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pack"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/jy-eggroll/flk/internal/trace"
+	"github.com/spf13/cobra"
+)
+
+var packCmd = &cobra.Command{
+	Use:   "pack <归档>",
+	Short: "将选中记录的真实文件和记录一起打包，用于整体迁移到另一台机器",
+	Long: `将当前平台上选中记录的真实文件（文件或整个目录）连同记录本身打包为一个归档，在新机器上使用 flk unpack 恢复。
+按扩展名选择压缩方式：.tar.zst 使用 zstd，.tar.gz 使用 gzip，其他不压缩。
+真实文件位于配置的源根目录下时按源根目录名称和相对路径保存，恢复时放到新机器上同名源根目录下；否则按原路径恢复`,
+	Args: cobra.ExactArgs(1),
+	RunE: RunPack,
+}
+
+var unpackCmd = &cobra.Command{
+	Use:   "unpack <归档>",
+	Short: "恢复 flk pack 生成的归档中的真实文件，并重新创建其中的全部链接",
+	Args:  cobra.ExactArgs(1),
+	RunE:  RunUnpack,
+}
+
+var (
+	packDevice   string
+	packOnly     []string
+	packSkip     []string
+	unpackDevice string
+	unpackForce  bool
+)
+
+func init() {
+	rootCmd.AddCommand(packCmd)
+	rootCmd.AddCommand(unpackCmd)
+	packCmd.Flags().StringVarP(&packDevice, "device", "d", "", "仅打包该设备的记录")
+	packCmd.Flags().StringSliceVar(&packOnly, "only", nil, "仅打包被选中的记录，可重复，如 bundle:nvim、path:~/.config/*")
+	packCmd.Flags().StringSliceVar(&packSkip, "skip", nil, "跳过被选中的记录，语法同 --only")
+	unpackCmd.Flags().StringVarP(&unpackDevice, "device", "d", "all", "恢复的记录所属的设备")
+	unpackCmd.Flags().BoolVar(&unpackForce, "force", false, "真实文件的恢复位置已存在时将其覆盖")
+}
+
+// RunPack 收集选中的记录及其真实文件并写入归档
+func RunPack(cmd *cobra.Command, args []string) error {
+	only, err := parseSelectors(packOnly)
+	if err != nil {
+		return err
+	}
+	skip, err := parseSelectors(packSkip)
+	if err != nil {
+		return err
+	}
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+
+	index := &pack.Index{Version: 1}
+	var files []string
+	fileOf := make(map[string]string)
+	for _, e := range packEntries(mgr, packDevice) {
+		if (len(only) > 0 && !selectedByAny(e.Record, only)) || selectedByAny(e.Record, skip) {
+			continue
+		}
+		target := mustNormalize(e.Record.Target())
+		if _, err := trace.Stat(target); err != nil {
+			logger.Warn(fmt.Sprintf("跳过 %s：无法访问真实文件 %v", e.Record.Link(), err))
+			continue
+		}
+		if _, ok := fileOf[target]; !ok {
+			fileOf[target] = pack.FileName(len(files))
+			files = append(files, target)
+		}
+		e.File = fileOf[target]
+		index.Entries = append(index.Entries, e)
+	}
+	if len(index.Entries) == 0 {
+		return errors.New("没有可打包的记录")
+	}
+
+	archive := mustNormalize(args[0])
+	if err := pack.Pack(archive, index, files); err != nil {
+		return err
+	}
+	return output.PrintCreateResult(output.OutputFormat(outputFormat), output.CreateResult{
+		Success: true,
+		Type:    "打包",
+		Message: fmt.Sprintf("已将 %d 条记录和 %d 个真实文件打包到 %s", len(index.Entries), len(files), args[0]),
+	})
+}
+
+// packEntries 将当前平台的记录转换为归档条目，每个链接一条，真实文件位于源根目录下时记录其相对路径
+func packEntries(mgr *store.Manager, device string) []pack.Entry {
+	var entries []pack.Entry
+	for _, rec := range storeRecords(mgr) {
+		if device != "" && rec.Device != device {
+			continue
+		}
+		target := rec.Target()
+		foldedTarget := store.CanonicalPath(target, "")
+		if rec.Type == "hardlink" {
+			rec.Prim, rec.Seco = foldedTarget, store.CanonicalPath(rec.Seco, "")
+		} else {
+			rec.Real, rec.Fake = foldedTarget, store.CanonicalPath(rec.Fake, "")
+		}
+		e := pack.Entry{Record: rec, Path: foldedTarget}
+		if name, rel, ok := config.Global.SourceRoot(target); ok {
+			e.Root, e.Path = name, rel
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// RunUnpack 恢复归档中的真实文件，再按应用清单的方式创建链接并写入存储
+func RunUnpack(cmd *cobra.Command, args []string) error {
+	archive := mustNormalize(args[0])
+	index, err := pack.Unpack(archive, placePackedFiles)
+	if err != nil {
+		return err
+	}
+
+	m := &manifest.Manifest{Version: 1}
+	for _, e := range index.Entries {
+		rec := e.Record
+		rec.Device = ""
+		target, err := packedFilePath(e)
+		if err != nil {
+			return err
+		}
+		if rec.Type == "hardlink" {
+			rec.Prim, rec.Seco = target, mustNormalize(rec.Seco)
+		} else {
+			rec.Real, rec.Fake = target, mustNormalize(rec.Fake)
+		}
+		m.Records = append(m.Records, rec)
+	}
+
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	foldedArchive, _ := pathutil.FoldHome(archive)
+	source := "unpack:" + foldedArchive
+	var changes []applyStep
+	for _, step := range buildApplyPlan(mgr, m, source, unpackDevice, nil, nil) {
+		if step.item.Action != "none" {
+			changes = append(changes, step)
+		}
+	}
+	results, err := runApplySteps(mgr, changes, source)
+	if err != nil {
+		return err
+	}
+	if err := output.PrintApplyResults(output.OutputFormat(outputFormat), results); err != nil {
+		logger.Error("输出失败 " + err.Error())
+	}
+	if err := mgr.Save(store.StorePath); err != nil {
+		logger.Error("持久化失败 " + err.Error())
+		return err
+	}
+	failed := 0
+	for _, r := range results {
+		if !r.Success {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 项恢复失败", failed)
+	}
+	return nil
+}
+
+// packedFilePath 返回归档条目的真实文件在本机上的恢复位置
+func packedFilePath(e pack.Entry) (string, error) {
+	if e.Root == "" {
+		return mustNormalize(e.Path), nil
+	}
+	root, ok := config.Global.SourceRoots[e.Root]
+	if !ok {
+		return "", fmt.Errorf("源根目录 %s 未在本机的配置文件 source_roots 中声明，无法确定 %s 的恢复位置", e.Root, e.Path)
+	}
+	abs, err := pathutil.ToAbsolute(mustNormalize(root))
+	if err != nil {
+		return "", err
+	}
+	return resolveRecordPath(e.Path, abs), nil
+}
+
+// placePackedFiles 确定每个真实文件的恢复位置，位置已存在时需要 --force 才会覆盖
+func placePackedFiles(index *pack.Index) (map[string]string, error) {
+	places := make(map[string]string)
+	var conflicts []string
+	for _, e := range index.Entries {
+		if _, done := places[e.File]; done {
+			continue
+		}
+		dest, err := packedFilePath(e)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Lstat(dest); err == nil {
+			if !unpackForce {
+				conflicts = append(conflicts, dest)
+				continue
+			}
+			if !confirmForceDelete(dest) {
+				return nil, errors.New("已取消覆盖 " + dest)
+			}
+			if err := moveToTrash(dest); err != nil {
+				return nil, err
+			}
+			if err := trace.RemoveAll(dest); err != nil {
+				return nil, err
+			}
+		}
+		places[e.File] = dest
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("以下位置已存在，使用 --force 覆盖：%s", strings.Join(conflicts, "、"))
+	}
+	return places, nil
+}
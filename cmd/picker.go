@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+// 路径选择器中的特殊选项
+const (
+	pickHere = "[选择当前目录]"
+	pickNew  = "[在当前目录下输入新名称]"
+	pickUp   = ".."
+)
+
+// pickPath 以目录浏览的方式交互选择路径，方向键移动、直接输入可过滤；
+// forLink 为 true 时选择的是链接文件位置，允许输入尚不存在的名称，并检查所在目录是否可写
+func pickPath(title string, forLink bool) (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			pterm.Warning.Println("无法读取目录 " + dir + "：" + err.Error())
+		}
+		var options []string
+		if forLink {
+			options = append(options, pickNew)
+		}
+		options = append(options, pickHere, pickUp)
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() {
+				name += string(filepath.Separator)
+			}
+			options = append(options, name)
+		}
+
+		selected, err := pterm.DefaultInteractiveSelect.WithOptions(options).WithMaxHeight(15).Show(title + "（当前目录 " + dir + "）")
+		if err != nil {
+			return "", err
+		}
+
+		var path string
+		switch selected {
+		case pickUp:
+			dir = filepath.Dir(dir)
+			continue
+		case pickHere:
+			path = dir
+		case pickNew:
+			name, err := pterm.DefaultInteractiveTextInput.Show("名称")
+			if err != nil {
+				return "", err
+			}
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			path = filepath.Join(dir, name)
+		default:
+			path = filepath.Join(dir, strings.TrimSuffix(selected, string(filepath.Separator)))
+			if strings.HasSuffix(selected, string(filepath.Separator)) {
+				dir = path
+				continue
+			}
+		}
+
+		if !forLink {
+			return path, nil
+		}
+		if err := checkWritable(filepath.Dir(path)); err != nil {
+			pterm.Warning.Println(err.Error())
+			continue
+		}
+		if _, err := os.Lstat(path); err == nil {
+			if !confirm("pick", path+" 已存在，创建链接时需要覆盖它，是否仍选择该路径", false) {
+				continue
+			}
+		}
+		return path, nil
+	}
+}
+
+// checkWritable 检查能否在目录中创建文件，目录不存在时检查其最近的已存在的上级目录
+func checkWritable(dir string) error {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	f, err := os.CreateTemp(dir, ".flk-probe-*")
+	if err != nil {
+		return fmt.Errorf("目录 %s 不可写：%v", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
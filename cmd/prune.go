@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "移除检查失败且属于指定错误类型的记录",
+	Long: `检查当前平台上的全部记录，按错误类型汇总无效的记录，并移除属于 --type 指定类型的记录，如真实文件已被有意删除时的 TARGET_MISSING、EXPECTED_MISSING。
+只修改存储，不删除磁盘上的任何文件；未指定 --type 时只列出各错误类型的记录数。
+移除前需要确认（--yes 自动同意），清理后的存储先写入临时文件再整体替换，中断时原存储保持完整`,
+	Args:         cobra.NoArgs,
+	RunE:         RunPrune,
+	SilenceUsage: true,
+}
+
+var (
+	pruneTypes  []string
+	pruneDevice string
+)
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+	pruneCmd.Flags().StringSliceVarP(&pruneTypes, "type", "t", nil, "要移除的记录的错误类型，可重复或逗号分隔，如 EXPECTED_MISSING,LINK_MISSING")
+	pruneCmd.Flags().StringVarP(&pruneDevice, "device", "d", "", "只清理该设备的记录")
+}
+
+// RunPrune 检查记录并移除属于选中错误类型的无效记录
+func RunPrune(cmd *cobra.Command, args []string) error {
+	selected := make(map[string]bool)
+	for _, t := range pruneTypes {
+		t = strings.ToUpper(strings.TrimSpace(t))
+		if output.ErrorTypeLabel(t) == "" {
+			return fmt.Errorf("未知的错误类型 %s", t)
+		}
+		selected[t] = true
+	}
+
+	results, err := performCheck(CheckOptions{DeviceFilter: pruneDevice})
+	if err != nil {
+		return err
+	}
+	groups := make(map[string][]output.CheckResult)
+	for _, r := range results {
+		if !r.Valid && r.ErrorType != "" {
+			groups[r.ErrorType] = append(groups[r.ErrorType], r)
+		}
+	}
+	printPruneGroups(groups, selected)
+	if len(selected) == 0 {
+		if len(groups) > 0 {
+			logger.Info("使用 --type 选择要移除的错误类型")
+		}
+		return nil
+	}
+
+	var targets []output.CheckResult
+	for _, errorType := range slices.Sorted(maps.Keys(selected)) {
+		targets = append(targets, groups[errorType]...)
+	}
+	if len(targets) == 0 {
+		logger.Info("没有需要移除的记录")
+		return nil
+	}
+	sort.SliceStable(targets, func(i, j int) bool { return resultLink(targets[i]) < resultLink(targets[j]) })
+
+	var lines []string
+	for _, r := range targets {
+		lines = append(lines, fmt.Sprintf("  [%s] %s %s", r.ErrorType, r.Type, resultLink(r)))
+	}
+	if !confirm("prune", fmt.Sprintf("将从存储中移除以下 %d 条记录：\n%s\n是否继续", len(targets), strings.Join(lines, "\n")), false) {
+		return errors.New("已取消清理")
+	}
+
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	var applied []output.ApplyResult
+	for _, r := range targets {
+		result := output.ApplyResult{Action: "remove", Type: r.Type, Link: resultLink(r), Success: true}
+		if !pruneRecord(mgr, r) {
+			result.Success, result.Error = false, "存储中找不到该记录"
+		}
+		applied = append(applied, result)
+	}
+	if err := mgr.SaveAtomic(store.StorePath); err != nil {
+		logger.Error("持久化失败 " + err.Error())
+		return err
+	}
+	return output.PrintApplyResults(output.OutputFormat(outputFormat), applied)
+}
+
+// printPruneGroups 在表格输出时按错误类型列出无效记录的数量，选中的类型以 * 标记
+func printPruneGroups(groups map[string][]output.CheckResult, selected map[string]bool) {
+	if output.OutputFormat(outputFormat) != output.Table {
+		return
+	}
+	if len(groups) == 0 {
+		fmt.Println("所有记录均有效")
+		return
+	}
+	fmt.Println("无效记录：")
+	for _, t := range slices.Sorted(maps.Keys(groups)) {
+		mark := " "
+		if selected[t] {
+			mark = "*"
+		}
+		fmt.Printf(" %s %s（%s）: %d\n", mark, t, output.ErrorTypeLabel(t), len(groups[t]))
+	}
+	fmt.Println()
+}
+
+// pruneRecord 从存储中移除检查结果对应的记录，一组硬链接只移除其中无效的次要文件；返回是否找到记录
+func pruneRecord(mgr *store.Manager, r output.CheckResult) bool {
+	if r.Type != "hardlink" || len(r.Group) == 0 {
+		return mgr.RemoveRecord(runtime.GOOS, r.Device, r.Type, r.Path, resultEntry(r))
+	}
+	for _, entry := range mgr.Data[runtime.GOOS][r.Device][r.Type][r.Path] {
+		if entry["prim"] == r.Prim && slices.Contains(store.SecoPaths(entry), r.Seco) {
+			dropRecordLink(mgr, r.Device, r.Type, r.Path, entry, resolveRecordPath(r.Seco, mustNormalize(r.Path)))
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/fsinfo"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var queryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "查询文件是否由 flk 管理及其最近的检查状态",
+	Long: `查询文件是否是 flk 记录的链接文件或真实文件，给出对应的另一端路径和最近一次检查的状态。
+不访问被查询的文件，状态来自 check 写入的状态文件，适合编辑器状态栏插件频繁调用；
+JSON 输出的字段保持稳定，服务器模式下同样可通过 GET /api/query?file=<路径> 查询`,
+	Args: cobra.NoArgs,
+	RunE: RunQuery,
+}
+
+var queryFile string
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+	queryCmd.Flags().StringVar(&queryFile, "file", "", "要查询的文件路径")
+	queryCmd.MarkFlagRequired("file")
+}
+
+// RunQuery 查询 --file 指定的文件
+func RunQuery(cmd *cobra.Command, args []string) error {
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	result, err := queryPath(mgr, queryFile)
+	if err != nil {
+		return err
+	}
+	return output.PrintQueryResult(output.OutputFormat(outputFormat), result)
+}
+
+// queryPath 在当前平台的记录中查找以 file 为链接文件或真实文件的记录
+func queryPath(mgr *store.Manager, file string) (output.QueryResult, error) {
+	abs, err := pathutil.NormalizePath(file)
+	if err != nil {
+		return output.QueryResult{}, err
+	}
+	if abs, err = pathutil.ToAbsolute(abs); err != nil {
+		return output.QueryResult{}, err
+	}
+	result := output.QueryResult{File: abs, Links: []output.RecordItem{}, Targets: []output.RecordItem{}}
+	st := loadRecordState()
+	now := time.Now()
+	for _, l := range recordLinks(mgr, "") {
+		switch {
+		case samePath(l.Link, abs):
+			result.Links = append(result.Links, recordItem(l, st, 0, now))
+		case samePath(l.Target, abs):
+			result.Targets = append(result.Targets, recordItem(l, st, 0, now))
+		}
+	}
+	result.Tracked = len(result.Links) > 0 || len(result.Targets) > 0
+	return result, nil
+}
+
+// samePath 判断两个绝对路径是否指向同一位置，按所在卷是否区分大小写比较
+func samePath(a, b string) bool {
+	return fsinfo.SamePath(a, b)
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"github.com/jy-eggroll/flk/internal/manifest"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/redact"
+	"github.com/spf13/cobra"
+)
+
+// redactOutput 为 true 时输出中的主目录、用户名和主机名会被替换为占位符
+var redactOutput bool
+
+// addRedactFlag 为需要分享输出的命令注册 --redact
+func addRedactFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&redactOutput, "redact", false, "将输出路径中的主目录、用户名和主机名替换为占位符，便于分享截图和问题报告")
+}
+
+// redactCheckResults 返回脱敏后的检查结果副本，未指定 --redact 时原样返回
+func redactCheckResults(results []output.CheckResult) []output.CheckResult {
+	if !redactOutput {
+		return results
+	}
+	r := redact.New()
+	redacted := make([]output.CheckResult, len(results))
+	for i, res := range results {
+		for _, field := range []*string{&res.Path, &res.BasePath, &res.Real, &res.Fake, &res.Prim, &res.Seco, &res.Source, &res.Error} {
+			*field = r.String(*field)
+		}
+		if len(res.Group) > 0 {
+			group := make([]string, len(res.Group))
+			for j, seco := range res.Group {
+				group[j] = r.String(seco)
+			}
+			res.Group = group
+		}
+		redacted[i] = res
+	}
+	return redacted
+}
+
+// redactRecordItems 返回脱敏后的记录列表副本，未指定 --redact 时原样返回
+func redactRecordItems(items []output.RecordItem) []output.RecordItem {
+	if !redactOutput {
+		return items
+	}
+	r := redact.New()
+	redacted := make([]output.RecordItem, len(items))
+	for i, item := range items {
+		item.Link, item.Target = r.String(item.Link), r.String(item.Target)
+		item.CreatedByHost = r.String(item.CreatedByHost)
+		redacted[i] = item
+	}
+	return redacted
+}
+
+// redactApplyResults 返回脱敏后的执行结果副本，未指定 --redact 时原样返回
+func redactApplyResults(results []output.ApplyResult) []output.ApplyResult {
+	if !redactOutput {
+		return results
+	}
+	r := redact.New()
+	redacted := make([]output.ApplyResult, len(results))
+	for i, res := range results {
+		res.Link, res.Error = r.String(res.Link), r.String(res.Error)
+		redacted[i] = res
+	}
+	return redacted
+}
+
+// redactLinkRecords 返回脱敏后的链接列表副本，未指定 --redact 时原样返回
+func redactLinkRecords(links []linkRecord) []linkRecord {
+	if !redactOutput {
+		return links
+	}
+	r := redact.New()
+	redacted := make([]linkRecord, len(links))
+	for i, l := range links {
+		l.Link, l.Target, l.host = r.String(l.Link), r.String(l.Target), r.String(l.host)
+		redacted[i] = l
+	}
+	return redacted
+}
+
+// redactManifest 对清单中的路径和备注脱敏，未指定 --redact 时不做修改
+func redactManifest(m *manifest.Manifest) {
+	if !redactOutput {
+		return
+	}
+	r := redact.New()
+	for i := range m.Records {
+		rec := &m.Records[i]
+		for _, field := range []*string{&rec.Real, &rec.Fake, &rec.Prim, &rec.Seco, &rec.Note} {
+			*field = r.String(*field)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	remoteHost string
+	remoteFlk  string
+)
+
+// remoteArgs 返回去掉 --host 与 --remote-flk 后的原始命令行参数，用于在远程主机上原样执行
+func remoteArgs() []string {
+	var args []string
+	raw := os.Args[1:]
+	for i := 0; i < len(raw); i++ {
+		arg := raw[i]
+		switch {
+		case arg == "--host" || arg == "--remote-flk":
+			i++
+		case strings.HasPrefix(arg, "--host=") || strings.HasPrefix(arg, "--remote-flk="):
+		default:
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+// shellQuote 将参数用单引号包裹，避免远程 shell 对其再次解析
+func shellQuote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// runRemote 通过 SSH 在 remoteHost 上执行 flk，返回远程命令的退出码
+// check 命令会以 JSON 格式获取远程结果并在本地按 --output 输出，其他命令直接透传输入输出
+func runRemote(cmd *cobra.Command) int {
+	// 以 - 开头的值会被 ssh 当作选项（如 -oProxyCommand=...）执行任意命令
+	if strings.HasPrefix(remoteHost, "-") {
+		logger.Error("无效的主机 " + remoteHost + "，主机名不能以 - 开头")
+		return 1
+	}
+	args := remoteArgs()
+	isCheck := cmd.Name() == checkCmd.Name()
+	if isCheck {
+		args = append(args, "--output", "json")
+	}
+
+	quoted := make([]string, 0, len(args)+1)
+	quoted = append(quoted, shellQuote(remoteFlk))
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+
+	sshArgs := []string{}
+	if !isCheck && isInteractive() {
+		// 交互命令（如 fix）需要远程分配终端
+		sshArgs = append(sshArgs, "-t")
+	}
+	sshArgs = append(sshArgs, "--", remoteHost, strings.Join(quoted, " "))
+	// 命令行可能包含路径等敏感信息，只在调试级别记录
+	logger.Debug("在远程主机上执行 " + remoteHost + " " + strings.Join(quoted, " "))
+
+	ssh := exec.Command("ssh", sshArgs...)
+	ssh.Stdin = os.Stdin
+	ssh.Stderr = os.Stderr
+	var stdout bytes.Buffer
+	if isCheck {
+		ssh.Stdout = &stdout
+	} else {
+		ssh.Stdout = os.Stdout
+	}
+
+	code := 0
+	if err := ssh.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			logger.Error("执行 ssh 失败 " + err.Error())
+			return 1
+		}
+		code = exitErr.ExitCode()
+	}
+
+	if isCheck {
+		var results []output.CheckResult
+		if err := json.Unmarshal(stdout.Bytes(), &results); err != nil {
+			logger.Error("解析远程检查结果失败 " + err.Error())
+			os.Stdout.Write(stdout.Bytes())
+			return 1
+		}
+		if err := output.PrintCheckResults(output.OutputFormat(outputFormat), results); err != nil {
+			logger.Error("输出失败 " + err.Error())
+			return 1
+		}
+	}
+	return code
+}
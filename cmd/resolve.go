@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var resolveCmd = &cobra.Command{
+	Use:   "resolve <路径>",
+	Short: "解析路径最终指向的物理路径，并说明是否由 flk 管理",
+	Long: `展开 ~ 和环境变量（$VAR、${VAR}、%VAR%），逐个跟随符号链接和目录联接，输出经过的链接链和最终的物理路径，
+同时列出链上任一路径对应的 flk 记录，可代替各平台上的 readlink -f、realpath 和 Get-Item`,
+	Args: cobra.ExactArgs(1),
+	RunE: RunResolve,
+}
+
+func init() {
+	rootCmd.AddCommand(resolveCmd)
+}
+
+// RunResolve 解析路径并输出结果
+func RunResolve(cmd *cobra.Command, args []string) error {
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	result, err := resolvePath(mgr, args[0])
+	if err != nil {
+		return err
+	}
+	return output.PrintResolveResult(output.OutputFormat(outputFormat), result)
+}
+
+// resolvePath 解析路径的链接链和物理路径，并查找链上各路径对应的记录
+func resolvePath(mgr *store.Manager, path string) (output.ResolveResult, error) {
+	result := output.ResolveResult{Path: path, Records: []output.RecordItem{}}
+	abs, err := pathutil.NormalizePath(pathutil.ExpandEnv(path))
+	if err != nil {
+		return result, err
+	}
+	if abs, err = pathutil.ToAbsolute(abs); err != nil {
+		return result, err
+	}
+
+	result.Chain, err = pathutil.LinkChain(abs)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Resolved = result.Chain[len(result.Chain)-1]
+	// 链接链终点的上级目录中也可能有链接，完整解析后才是物理路径
+	if resolved, err := filepath.EvalSymlinks(result.Resolved); err == nil {
+		result.Resolved = resolved
+	}
+	_, statErr := os.Stat(result.Resolved)
+	result.Exists = statErr == nil
+
+	st := loadRecordState()
+	now := time.Now()
+	seen := make(map[linkRecord]bool)
+	candidates := append(append([]string{}, result.Chain...), result.Resolved)
+	for _, l := range recordLinks(mgr, "") {
+		for _, p := range candidates {
+			if !seen[l] && (samePath(l.Link, p) || samePath(l.Target, p)) {
+				seen[l] = true
+				result.Records = append(result.Records, recordItem(l, st, 0, now))
+			}
+		}
+	}
+	result.Tracked = len(result.Records) > 0
+	return result, nil
+}
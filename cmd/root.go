@@ -1,16 +1,28 @@
 package cmd
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
 
+	"github.com/jy-eggroll/flk/internal/audit"
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/contenthash"
 	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/progress"
 	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/jy-eggroll/flk/internal/trace"
 
 	"github.com/spf13/cobra"
 )
 
 var (
 	outputFormat string
+	configPath   string
+	sandboxDir   string
+	traceMode    bool
 )
 
 var rootCmd = &cobra.Command{
@@ -20,28 +32,190 @@ var rootCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 
 	},
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		// 在命令执行前初始化持久化存储，使用当前 storePath 配置
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// 远程模式下本机只负责转发，不加载本地配置和存储
+		if remoteHost != "" {
+			return abortRun(cmd, &exitCodeError{code: runRemote(cmd), err: errors.New("已在远程主机上执行")})
+		}
+		output.InitStyle()
+		logger.SetWriter(output.ASCIIWriter(os.Stderr))
+		if err := initSandbox(); err != nil {
+			logger.Error("启用沙盒失败 " + err.Error())
+			return abortRun(cmd, err)
+		}
+		resolveStorePath(cmd)
+		if err := config.Init(configPath); err != nil {
+			logger.Error("加载配置失败 " + err.Error())
+		}
+		initDevice(cmd)
+		initWorkspace(cmd)
+		initHash(cmd)
+		audit.Enabled = config.Global.Audit && !trace.DryRun
+		if trace.DryRun && !dryRunSupported(cmd) {
+			logger.Error(cmd.CommandPath() + " 不支持 --dry-run")
+			return abortRun(cmd, errors.New(cmd.CommandPath()+" 不支持 --dry-run"))
+		}
+		if traceMode {
+			initTrace()
+		}
+		if !trace.DryRun {
+			beginJournal(cmd, args)
+		}
+		// 在命令执行前初始化持久化存储，使用当前 storePath 配置；
+		// 存储无法读取时不执行命令，避免后续命令在不完整的状态下运行甚至覆盖原有存储
+		// flk shell 中存储已在内存中，只有切换到其他存储时才需要重新加载
+		if inShell && store.GlobalManager != nil && store.StorePath == loadedStorePath {
+			return nil
+		}
 		if err := store.InitStore(store.StorePath); err != nil {
 			logger.Error("初始化存储失败 " + err.Error())
+			// doctor 需要在存储损坏时报告原因
+			if cmd != doctorCmd {
+				return abortRun(cmd, err)
+			}
 		}
+		loadedStorePath = store.StorePath
+		return nil
 	},
 }
 
 func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+	registerCompletions()
+	if code := executeRoot(); code != ExitOK {
+		os.Exit(code)
+	}
+}
+
+// executeRoot 执行 rootCmd 并完成收尾（结束操作日志、停止跟踪、输出 --dry-run 的计划操作），返回退出码；
+// flk shell 中的每条命令也经过这里
+func executeRoot() int {
+	cmd, err := rootCmd.ExecuteC()
+	rootCmd.SilenceErrors, rootCmd.SilenceUsage = false, false
+	finishJournal()
+	trace.Stop()
+	if trace.DryRun {
+		printDryRun()
 	}
+	trace.ClearPlanned()
+	return exitCode(cmd, err)
+}
+
+// abortRun 在命令执行前终止本次执行：原因已经输出，不再让 cobra 重复输出错误和用法，executeRoot 结束后恢复
+func abortRun(cmd *cobra.Command, err error) error {
+	cmd.Root().SilenceErrors, cmd.Root().SilenceUsage = true, true
+	return err
 }
 
 func init() {
 	logger.Init(nil)
-	rootCmd.PersistentFlags().StringVar(
-		&store.StorePath,
-		"storePath",
-		store.DefaultStorePath,
-		"用于存放 flk-store.json 的路径",
-	)
+	rootCmd.PersistentFlags().StringVar(&store.StorePath, "store", store.DefaultStorePath, "本次运行使用的存储文件路径，未指定时依次使用环境变量 "+storeEnv+" 和默认路径；扩展名为 .yaml、.yml 或 .toml 时以对应格式读写")
+	// 旧名称，保留以兼容已有脚本
+	rootCmd.PersistentFlags().StringVar(&store.StorePath, "storePath", store.DefaultStorePath, "用于存放 flk-store.json 的路径")
+	rootCmd.PersistentFlags().MarkDeprecated("storePath", "请改用 --store")
 	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", "table", "输出格式：json/table")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", config.DefaultConfigPath, "配置文件路径")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "对所有确认提示自动回答是")
+	rootCmd.PersistentFlags().BoolVar(&assumeNo, "no", false, "对所有确认提示自动回答否")
+	rootCmd.MarkFlagsMutuallyExclusive("yes", "no")
+	rootCmd.PersistentFlags().StringVar(&remoteHost, "host", "", "通过 SSH 在远程主机上执行命令，如 user@box")
+	rootCmd.PersistentFlags().StringVar(&remoteFlk, "remote-flk", "flk", "远程主机上 flk 可执行文件的路径")
+	rootCmd.PersistentFlags().BoolVar(&progress.Enabled, "progress-json", false, "在标准错误中以 NDJSON 输出进度事件（started、record-checked、repaired、done），供图形界面展示进度")
+	rootCmd.PersistentFlags().BoolVar(&traceMode, "trace", false, "记录每次文件系统调用的参数、结果和耗时，同时写入存储目录下的 "+trace.LastTracePath)
+	rootCmd.PersistentFlags().BoolVar(&trace.DryRun, "dry-run", false, "只列出 create、delete、fix、check --fix、apply、prune 将要执行的文件系统操作和存储修改，不实际执行")
+	rootCmd.PersistentFlags().StringVar(&contenthash.Algorithm, "hash", contenthash.XXHash64, "比较文件内容时使用的摘要算法：xxhash64、sha256")
+	rootCmd.PersistentFlags().IntVar(&contenthash.Workers, "hash-workers", 0, "同时计算摘要的文件数，0 表示按 CPU 核数；文件位于机械硬盘时建议设为 1")
+	rootCmd.PersistentFlags().BoolVar(&output.ASCII, "ascii", false, "只输出 ASCII 字符，将框线、箭头等 Unicode 字符替换为 ASCII 近似字符")
+	rootCmd.PersistentFlags().BoolVar(&output.Linear, "linear", false, "表格改为逐行输出“列名: 值”且不截断路径，便于屏幕阅读器朗读和写入纯文本日志")
+	rootCmd.PersistentFlags().StringVar(&sandboxDir, "sandbox", "", "将所有路径（包括 ~ 和绝对路径）映射到该目录下，用于演示和测试，不会改动真实文件")
+}
+
+// storeEnv 指定存储文件路径的环境变量
+const storeEnv = "FLK_STORE"
+
+// storePathSource 本次运行所用存储路径的来源：flag、env 或 default
+var storePathSource = "default"
+
+// resolveStorePath 按 --store（或 --storePath）、FLK_STORE、默认路径的优先级确定存储文件路径
+func resolveStorePath(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	switch {
+	case flags.Changed("store") || flags.Changed("storePath"):
+		storePathSource = "flag"
+	case os.Getenv(storeEnv) != "":
+		store.StorePath = os.Getenv(storeEnv)
+		storePathSource = "env"
+	}
+	logger.Debug("使用存储 " + mustNormalize(store.StorePath) + "，来源：" + storePathSource)
+}
+
+// dryRunCommands 支持全局 --dry-run 的顶层命令，它们的文件系统操作和存储写入都经过 trace 包
+var dryRunCommands = map[string]bool{
+	"create": true,
+	"delete": true,
+	"fix":    true,
+	"check":  true,
+	"apply":  true,
+	"prune":  true,
+}
+
+// dryRunSupported 判断 cmd 所属的顶层命令是否支持 --dry-run
+func dryRunSupported(cmd *cobra.Command) bool {
+	for c := cmd; c.HasParent(); c = c.Parent() {
+		if !c.Parent().HasParent() {
+			return dryRunCommands[c.Name()]
+		}
+	}
+	return false
+}
+
+// printDryRun 输出 --dry-run 期间记录的计划操作
+func printDryRun() {
+	var ops []output.PlannedOp
+	for _, p := range trace.PlannedOps() {
+		ops = append(ops, output.PlannedOp{Op: p.Op, Args: p.Args})
+	}
+	if err := output.PrintDryRun(output.OutputFormat(outputFormat), ops); err != nil {
+		logger.Error("输出失败 " + err.Error())
+	}
+}
+
+// initTrace 开启文件系统调用跟踪，记录写入存储文件所在目录
+func initTrace() {
+	storePath, err := pathutil.NormalizePath(store.StorePath)
+	if err != nil {
+		logger.Warn("无法确定跟踪文件位置 " + err.Error())
+		return
+	}
+	dir := filepath.Dir(storePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		logger.Warn("无法创建跟踪文件目录 " + err.Error())
+		return
+	}
+	if err := trace.Start(filepath.Join(dir, trace.LastTracePath)); err != nil {
+		logger.Warn("无法写入跟踪文件 " + err.Error())
+		trace.Enabled = true
+	}
+}
+
+// initHash 未指定 --hash 时使用配置文件中的摘要算法，算法无效时回退到默认算法
+func initHash(cmd *cobra.Command) {
+	if !cmd.Flags().Changed("hash") && config.Global.Hash != "" {
+		contenthash.Algorithm = config.Global.Hash
+	}
+	if err := contenthash.Validate(contenthash.Algorithm); err != nil {
+		logger.Warn(err.Error() + "，改用 " + contenthash.XXHash64)
+		contenthash.Algorithm = contenthash.XXHash64
+	}
+}
+
+// initSandbox 按 --sandbox 启用沙盒模式，存储和配置文件也会位于沙盒内
+func initSandbox() error {
+	if sandboxDir == "" {
+		return nil
+	}
+	if err := pathutil.SetSandbox(sandboxDir); err != nil {
+		return err
+	}
+	logger.Warn("沙盒模式：所有路径都映射到 " + pathutil.Sandbox + " 下")
+	return nil
 }
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "管理定期执行 flk 的计划任务（Windows 任务计划程序）",
+	Long:  "管理定期执行 flk 的计划任务（Windows 任务计划程序）",
+}
+
+var scheduleInstallCmd = &cobra.Command{
+	Use:     "install <flk 参数>",
+	Short:   "注册计划任务",
+	Long:    "在 Windows 任务计划程序中注册一个定期执行 flk 的任务，例如 flk schedule install --daily 09:00 \"check --fix\"",
+	Args:    cobra.ExactArgs(1),
+	RunE:    RunScheduleInstall,
+	Example: `  flk schedule install --daily 09:00 "check --fix"`,
+}
+
+var scheduleRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "删除计划任务",
+	Long:  "删除之前注册的计划任务",
+	Args:  cobra.NoArgs,
+	RunE:  RunScheduleRemove,
+}
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+	scheduleCmd.AddCommand(scheduleInstallCmd)
+	scheduleCmd.AddCommand(scheduleRemoveCmd)
+	scheduleCmd.PersistentFlags().StringVar(&scheduleName, "name", "flk", "计划任务名称")
+	scheduleInstallCmd.Flags().StringVar(&scheduleDaily, "daily", "", "每天在该时间执行，格式 HH:MM")
+	scheduleInstallCmd.Flags().IntVar(&scheduleEvery, "every", 0, "每隔该分钟数执行一次")
+	scheduleInstallCmd.Flags().BoolVar(&scheduleHighest, "highest", true, "以最高权限运行（创建符号链接通常需要）")
+	scheduleInstallCmd.MarkFlagsOneRequired("daily", "every")
+	scheduleInstallCmd.MarkFlagsMutuallyExclusive("daily", "every")
+}
+
+var (
+	scheduleName    string
+	scheduleDaily   string
+	scheduleEvery   int
+	scheduleHighest bool
+)
+
+// RunScheduleInstall 通过 schtasks 注册计划任务
+func RunScheduleInstall(cmd *cobra.Command, args []string) error {
+	if runtime.GOOS != "windows" {
+		return errors.New("计划任务目前仅支持 Windows")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	schtasksArgs := []string{"/Create", "/F", "/TN", scheduleName, "/TR", fmt.Sprintf(`"%s" %s`, exe, args[0])}
+	if scheduleDaily != "" {
+		if _, err := time.Parse("15:04", scheduleDaily); err != nil {
+			return fmt.Errorf("无效的时间 %s，格式应为 HH:MM", scheduleDaily)
+		}
+		schtasksArgs = append(schtasksArgs, "/SC", "DAILY", "/ST", scheduleDaily)
+	} else {
+		if scheduleEvery < 1 || scheduleEvery > 1439 {
+			return fmt.Errorf("无效的间隔 %d，应在 1 到 1439 分钟之间", scheduleEvery)
+		}
+		schtasksArgs = append(schtasksArgs, "/SC", "MINUTE", "/MO", strconv.Itoa(scheduleEvery))
+	}
+	if scheduleHighest {
+		schtasksArgs = append(schtasksArgs, "/RL", "HIGHEST")
+	}
+
+	if err := runSchtasks(schtasksArgs...); err != nil {
+		return err
+	}
+	logger.Info("已注册计划任务 " + scheduleName)
+	return nil
+}
+
+// RunScheduleRemove 通过 schtasks 删除计划任务
+func RunScheduleRemove(cmd *cobra.Command, args []string) error {
+	if runtime.GOOS != "windows" {
+		return errors.New("计划任务目前仅支持 Windows")
+	}
+	if err := runSchtasks("/Delete", "/F", "/TN", scheduleName); err != nil {
+		return err
+	}
+	logger.Info("已删除计划任务 " + scheduleName)
+	return nil
+}
+
+func runSchtasks(args ...string) error {
+	c := exec.Command("schtasks", args...)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("执行 schtasks 失败 %w", err)
+	}
+	return nil
+}
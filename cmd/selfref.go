@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/fsinfo"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+)
+
+// flkDataDirs 返回 flk 自身读写的目录：存储文件所在目录（其中还有状态文件、快照、隔离区和命令历史）、配置文件所在目录和回收站
+func flkDataDirs() []string {
+	dirs := []string{filepath.Dir(mustNormalize(store.StorePath))}
+	if configDir := filepath.Dir(mustNormalize(configPath)); !fsinfo.SamePath(configDir, dirs[0]) {
+		dirs = append(dirs, configDir)
+	}
+	dirs = append(dirs, trashDir())
+	for i, d := range dirs {
+		if abs, err := pathutil.ToAbsolute(d); err == nil {
+			dirs[i] = abs
+		}
+	}
+	return dirs
+}
+
+// selfReference 判断 path 是否位于 flk 自身的数据目录中或包含该目录，返回命中的目录；
+// 这样的链接会让 flk 在遍历或修改文件时作用到自己的存储上，形成反馈循环
+func selfReference(path string) (string, bool) {
+	abs, err := pathutil.ToAbsolute(mustNormalize(path))
+	if err != nil {
+		return "", false
+	}
+	for _, dir := range flkDataDirs() {
+		if within(abs, dir) || within(dir, abs) {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// within 判断 path 是否为 dir 本身或位于 dir 之下
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// warnSelfReference 链接文件指向或包含 flk 的数据目录时给出警告，不阻止创建
+func warnSelfReference(link string) {
+	if dir, ok := selfReference(link); ok {
+		warnCreate("链接文件 %s 与 flk 的数据目录 %s 重叠，扫描未记录的链接时将跳过该位置，建议改用其他路径", link, dir)
+	}
+}
@@ -1,21 +1,395 @@
 package cmd
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jy-eggroll/flk/internal/config"
 	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/jy-eggroll/flk/internal/webui"
 
 	"github.com/spf13/cobra"
 )
 
 var serverCmd = &cobra.Command{
 	Use:   "server",
-	Short: "打开网页服务器（尚未实现）",
-	Long:  "打开网页服务器（尚未实现）",
-	Run: func(cmd *cobra.Command, args []string) {
-	},
+	Short: "以 HTTP 服务提供检查、创建、修复和列出记录的接口",
+	Long: `以 HTTP 服务提供与命令行相同的功能，供局域网内的其他机器或脚本调用，所有接口返回 JSON：
+  GET  /                     网页界面，通过下列接口列出、检查、修复记录和创建链接
+  GET  /api/check            检查链接，查询参数与 flk check 的选项同名：device、symlink、hardlink、template、junction、dir、source、untracked、backup-coverage
+  GET  /api/list             列出当前平台的记录，可用 device 过滤
+  GET  /api/query?file=<路径> 查询文件是否由 flk 管理，结果与 flk query --output json 相同
+  POST /api/create/symlink   创建符号链接，请求体字段：real、fake、device、force、mode、check_every、attrib、link_style、dir_link、validate、note
+  POST /api/create/hardlink  创建硬链接，请求体字段：prim、seco（数组）、device、force、mode、check_every、validate、note
+  POST /api/create/template  渲染模板，请求体字段：real、fake、device、force、mode、check_every、validate、note
+  POST /api/create/junction  创建目录联接（仅 Windows），请求体字段：real、fake、device、force、check_every、validate、note
+  POST /api/fix              修复可安全修复的无效链接（与 check --fix 相同，不会删除占据链接位置的文件），查询参数同 /api/check，
+                             另可用 max-risk 进一步限制允许的风险，默认使用配置文件中的 repair.max_risk
+所有接口都需要携带 Authorization: Bearer <令牌>，令牌通过 --token 或环境变量 ` + serverTokenEnv + ` 设置，未设置时启动时随机生成并显示；
+POST 接口只接受 Content-Type: application/json 的请求。Host 与监听地址不符或 Origin 与 Host 不同的请求会被拒绝，以防其他网页跨站调用或通过 DNS 重绑定访问`,
+	Args: cobra.NoArgs,
+	RunE: RunServer,
 }
 
+// serverTokenEnv 保存访问令牌的环境变量
+const serverTokenEnv = "FLK_SERVER_TOKEN"
+
+var (
+	serverPort  int
+	serverAddr  string
+	serverToken string
+
+	// serverMu 创建与修复函数通过包级变量传递选项，请求需逐个处理
+	serverMu sync.Mutex
+)
+
 func init() {
 	logger.Init(nil)
 	rootCmd.AddCommand(serverCmd)
-	serverCmd.Flags().IntP("port", "p", 8999, "指定端口号")
+	serverCmd.Flags().IntVarP(&serverPort, "port", "p", 8999, "指定端口号")
+	serverCmd.Flags().StringVar(&serverAddr, "addr", "127.0.0.1", "监听地址，使用 0.0.0.0 允许局域网内的其他机器访问")
+	serverCmd.Flags().StringVar(&serverToken, "token", "", "访问令牌，请求需携带 Authorization: Bearer <令牌>，默认读取环境变量 "+serverTokenEnv+"，仍未设置时随机生成")
+}
+
+// RunServer 启动 HTTP 服务
+func RunServer(cmd *cobra.Command, args []string) error {
+	if serverToken == "" {
+		serverToken = os.Getenv(serverTokenEnv)
+	}
+	generated := serverToken == ""
+	if generated {
+		token, err := randomToken()
+		if err != nil {
+			return fmt.Errorf("无法生成访问令牌：%v", err)
+		}
+		serverToken = token
+	}
+	headless = true
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", serverGuard(serveIndex))
+	mux.HandleFunc("GET /api/check", serverHandler(handleCheck))
+	mux.HandleFunc("GET /api/list", serverHandler(handleList))
+	mux.HandleFunc("GET /api/query", serverHandler(handleQuery))
+	mux.HandleFunc("POST /api/create/{type}", serverHandler(handleCreate))
+	mux.HandleFunc("POST /api/fix", serverHandler(handleFix))
+
+	addr := net.JoinHostPort(serverAddr, strconv.Itoa(serverPort))
+	logger.Info("flk 服务已启动 http://" + addr)
+	if generated {
+		// 令牌放在地址的片段中，网页界面读取后保存，不会随请求发送到服务端日志
+		// 只输出到终端，不写入日志文件
+		fmt.Println("访问令牌 " + serverToken + "，网页界面可直接打开 http://" + addr + "/#token=" + serverToken)
+	}
+	return http.ListenAndServe(addr, mux)
+}
+
+// randomToken 生成 32 位十六进制的随机访问令牌
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// serverGuard 拒绝 Host 与监听地址不符、Origin 与 Host 不同的请求，防止 DNS 重绑定和其他网页跨站调用
+func serverGuard(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowedHost(r.Host) {
+			writeServerJSON(w, http.StatusForbidden, map[string]string{"error": "不允许的 Host " + r.Host})
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || !strings.EqualFold(u.Host, r.Host) {
+				writeServerJSON(w, http.StatusForbidden, map[string]string{"error": "不允许的 Origin " + origin})
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// allowedHost 判断请求的 Host 是否指向监听地址：端口必须一致；监听本机地址时只接受本机地址和 localhost，
+// 监听 0.0.0.0 等全部地址时接受任意 IP 和本机主机名，其他情况只接受监听地址本身
+func allowedHost(hostport string) bool {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host, port = hostport, "80"
+	}
+	if port != strconv.Itoa(serverPort) {
+		return false
+	}
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, strings.Trim(serverAddr, "[]")) {
+		return true
+	}
+	listen := net.ParseIP(strings.Trim(serverAddr, "[]"))
+	ip := net.ParseIP(host)
+	switch {
+	case serverAddr == "localhost" || listen != nil && listen.IsLoopback():
+		return strings.EqualFold(host, "localhost") || ip != nil && ip.IsLoopback()
+	case serverAddr == "" || listen != nil && listen.IsUnspecified():
+		if ip != nil {
+			return true
+		}
+		name, err := os.Hostname()
+		return err == nil && (strings.EqualFold(host, name) || strings.EqualFold(host, "localhost"))
+	}
+	return false
+}
+
+// isJSONRequest 判断请求体是否声明为 JSON，浏览器跨站发送的简单请求无法设置该类型
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+// serverHandler 为接口处理函数加上来源检查、认证、串行化和存储重新加载，并将返回值编码为 JSON
+func serverHandler(handle func(r *http.Request) (any, error)) http.HandlerFunc {
+	return serverGuard(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+serverToken)) != 1 {
+			writeServerJSON(w, http.StatusUnauthorized, map[string]string{"error": "未授权"})
+			return
+		}
+		if r.Method == http.MethodPost && !isJSONRequest(r) {
+			writeServerJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "请求体必须为 application/json"})
+			return
+		}
+		serverMu.Lock()
+		defer serverMu.Unlock()
+		logger.Info(r.Method + " " + r.URL.String() + " 来自 " + r.RemoteAddr)
+		// 存储可能已被命令行修改，每个请求都重新加载
+		if err := store.InitStore(store.StorePath); err != nil {
+			writeServerJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+			return
+		}
+		v, err := handle(r)
+		if err != nil {
+			writeServerJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeServerJSON(w, http.StatusOK, v)
+	})
+}
+
+// serveIndex 返回内嵌的网页界面，页面本身不含数据，访问令牌由页面在调用接口时携带
+func serveIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(webui.Index)
+}
+
+func writeServerJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	enc.Encode(v)
+}
+
+// serverCheckOptions 从查询参数读取与 flk check 同名的过滤选项
+func serverCheckOptions(r *http.Request) CheckOptions {
+	q := r.URL.Query()
+	flag := func(name string) bool {
+		b, _ := strconv.ParseBool(q.Get(name))
+		return b
+	}
+	return CheckOptions{
+		DeviceFilter:   q.Get("device"),
+		CheckSymlink:   flag("symlink"),
+		CheckHardlink:  flag("hardlink"),
+		CheckTemplate:  flag("template"),
+		CheckJunction:  flag("junction"),
+		CheckDir:       q.Get("dir"),
+		Source:         q.Get("source"),
+		Untracked:      flag("untracked"),
+		BackupCoverage: flag("backup-coverage"),
+	}
+}
+
+func handleCheck(r *http.Request) (any, error) {
+	results, err := performCheck(serverCheckOptions(r))
+	if err != nil {
+		return nil, err
+	}
+	if results == nil {
+		results = []output.CheckResult{}
+	}
+	return results, nil
+}
+
+func handleList(r *http.Request) (any, error) {
+	mgr, err := store.Current()
+	if err != nil {
+		return nil, err
+	}
+	links := recordLinks(mgr, r.URL.Query().Get("device"))
+	if links == nil {
+		links = []linkRecord{}
+	}
+	return links, nil
+}
+
+func handleQuery(r *http.Request) (any, error) {
+	file := r.URL.Query().Get("file")
+	if file == "" {
+		return nil, errors.New("缺少 file")
+	}
+	mgr, err := store.Current()
+	if err != nil {
+		return nil, err
+	}
+	return queryPath(mgr, file)
+}
+
+// serverCreateRequest 创建接口的请求体，字段与命令行选项对应
+type serverCreateRequest struct {
+	Real       string   `json:"real"`
+	Fake       string   `json:"fake"`
+	Prim       string   `json:"prim"`
+	Seco       []string `json:"seco"`
+	Device     string   `json:"device"`
+	Force      bool     `json:"force"`
+	Mode       string   `json:"mode"`
+	CheckEvery string   `json:"check_every"`
+	Attrib     string   `json:"attrib"`
+	LinkStyle  string   `json:"link_style"`
+	DirLink    string   `json:"dir_link"`
+	Validate   string   `json:"validate"`
+	Note       string   `json:"note"`
+}
+
+func handleCreate(r *http.Request) (any, error) {
+	var req serverCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("无效的请求体：%v", err)
+	}
+	if req.Device == "" {
+		req.Device = "all"
+	}
+
+	var result *output.CreateResult
+	createResultHook = func(res output.CreateResult) { result = &res }
+	defer resetCreateOptions()
+	createForce, createDevice, createMode = req.Force, req.Device, req.Mode
+	createEvery, createAttrib, createValidate = req.CheckEvery, req.Attrib, req.Validate
+	createNote, createLinkStyle, createDirLink = req.Note, req.LinkStyle, req.DirLink
+
+	var link string
+	var create func() error
+	switch linkType := r.PathValue("type"); linkType {
+	case "symlink":
+		symlinkReal, symlinkFake, link = req.Real, req.Fake, req.Fake
+		create = func() error { return Symlink(nil, nil) }
+	case "hardlink":
+		if len(req.Seco) == 0 {
+			return nil, errors.New("缺少 seco")
+		}
+		hardlinkPrim, hardlinkSecos, link = req.Prim, req.Seco, req.Seco[0]
+		create = func() error { return Hardlink(nil, nil) }
+	case "template":
+		templateReal, templateFake, link = req.Real, req.Fake, req.Fake
+		create = func() error { return Template(nil, nil) }
+	case "junction":
+		junctionReal, junctionFake, link = req.Real, req.Fake, req.Fake
+		create = func() error { return Junction(nil, nil) }
+	default:
+		return nil, fmt.Errorf("不支持的链接类型 %s", linkType)
+	}
+	if link == "" {
+		return nil, errors.New("缺少链接文件路径")
+	}
+	applyServerRules(link)
+
+	err := create()
+	if result == nil {
+		if err == nil {
+			err = errors.New("创建函数没有返回结果")
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// applyServerRules 按配置中的目录规则为请求中未指定的选项填充默认值，与命令行行为一致
+func applyServerRules(link string) {
+	abs, err := pathutil.ToAbsolute(mustNormalize(link))
+	if err != nil {
+		return
+	}
+	d := config.Global.DefaultsFor(abs)
+	if createMode == "" {
+		createMode = d.Mode
+	}
+	if createAttrib == "" {
+		createAttrib = d.Attrib
+	}
+	if createEvery == "" {
+		createEvery = d.CheckEvery
+	}
+	if createLinkStyle == "" {
+		createLinkStyle = d.LinkStyle
+	}
+	if createDirLink == "" {
+		createDirLink = d.DirLink
+	}
+}
+
+// resetCreateOptions 恢复创建选项的默认值，避免影响下一个请求
+func resetCreateOptions() {
+	createResultHook = nil
+	createForce, createDevice, createMode, createEvery, createAttrib, createValidate = false, "all", "", "", "", ""
+	createNote, createLinkStyle, createDirLink = "", "", ""
+	createWarnings = nil
+	symlinkReal, symlinkFake = "", ""
+	hardlinkPrim, hardlinkSecos = "", nil
+	templateReal, templateFake = "", ""
+	junctionReal, junctionFake = "", ""
+}
+
+func handleFix(r *http.Request) (any, error) {
+	maxRisk, err := parseRisk(r.URL.Query().Get("max-risk"), config.Global.Repair.MaxRisk)
+	if err != nil {
+		return nil, err
+	}
+	results, err := performCheck(serverCheckOptions(r))
+	if err != nil {
+		return nil, err
+	}
+	// 修复时重新创建链接的结果已体现在返回值中，不再输出
+	createResultHook = func(output.CreateResult) {}
+	defer resetCreateOptions()
+	repaired := []output.ApplyResult{}
+	for i, res := range results {
+		if res.Valid {
+			continue
+		}
+		item := output.ApplyResult{Action: "repair", Type: res.Type, Link: resultLink(res), Success: true}
+		// 与 check --fix 相同，远程请求不能确认删除占据链接位置的文件
+		if err := unsafeToFix(res); err != nil {
+			item.Success, item.Error = false, err.Error()
+		} else if risk := riskOf(res); risk > maxRisk {
+			item.Success, item.Error = false, fmt.Sprintf("修复风险为 %s，超过允许的 %s", risk, maxRisk)
+		} else if err := repairResult(res, i); err != nil {
+			item.Success, item.Error = false, err.Error()
+		}
+		repaired = append(repaired, item)
+	}
+	return repaired, nil
 }
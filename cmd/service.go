@@ -0,0 +1,365 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "将定期执行 flk check --fix 注册为系统服务",
+	Long: `将定期执行 flk check --fix 注册为当前用户的后台服务：Linux 上为 systemd 用户服务和定时器，
+macOS 上为 launchd 代理，Windows 上为任务计划程序中的计划任务。服务使用本次运行的 --store 和 --config。
+Windows 上默认任务名称与 flk schedule install 的任务不同，同名的计划任务不是由 flk service 创建时不会被替换或删除`,
+}
+
+var serviceInstallCmd = &cobra.Command{
+	Use:     "install",
+	Short:   "注册并启用服务",
+	Args:    cobra.NoArgs,
+	RunE:    RunServiceInstall,
+	Example: `  flk service install --interval 30m`,
+}
+
+var serviceUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "停用并删除服务",
+	Args:  cobra.NoArgs,
+	RunE:  RunServiceUninstall,
+}
+
+var serviceStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "显示服务的状态",
+	Args:  cobra.NoArgs,
+	RunE:  RunServiceStatus,
+}
+
+var (
+	serviceName     string
+	serviceInterval time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(serviceCmd)
+	serviceCmd.AddCommand(serviceInstallCmd)
+	serviceCmd.AddCommand(serviceUninstallCmd)
+	serviceCmd.AddCommand(serviceStatusCmd)
+	serviceCmd.PersistentFlags().StringVar(&serviceName, "name", "flk-service", "服务名称，用作 systemd 单元名、launchd 标签（com.github.jy-eggroll.<名称>）或计划任务名称")
+	serviceInstallCmd.Flags().DurationVar(&serviceInterval, "interval", time.Hour, "执行 check --fix 的间隔，至少 1m")
+}
+
+// serviceArgs 返回服务执行的 flk 参数，显式指定的存储和配置文件以绝对路径传入
+func serviceArgs(cmd *cobra.Command) []string {
+	args := []string{"check", "--fix"}
+	if storePathSource != "default" {
+		if abs, err := pathutil.ToAbsolute(mustNormalize(store.StorePath)); err == nil {
+			args = append(args, "--store", abs)
+		}
+	}
+	if cmd.Flags().Changed("config") {
+		if abs, err := pathutil.ToAbsolute(mustNormalize(configPath)); err == nil {
+			args = append(args, "--config", abs)
+		}
+	}
+	return args
+}
+
+// RunServiceInstall 按当前平台注册服务
+func RunServiceInstall(cmd *cobra.Command, args []string) error {
+	if serviceInterval < time.Minute {
+		return fmt.Errorf("无效的间隔 %v，至少为 1m", serviceInterval)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	flkArgs := serviceArgs(cmd)
+	switch runtime.GOOS {
+	case "linux":
+		err = installSystemd(exe, flkArgs)
+	case "darwin":
+		err = installLaunchd(exe, flkArgs)
+	case "windows":
+		err = installScheduledTask(exe, flkArgs)
+	default:
+		return errors.New("服务目前仅支持 Linux、macOS 和 Windows")
+	}
+	if err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("已注册服务 %s，每 %v 执行一次 flk %s", serviceName, serviceInterval, strings.Join(flkArgs, " ")))
+	return nil
+}
+
+// RunServiceUninstall 按当前平台删除服务
+func RunServiceUninstall(cmd *cobra.Command, args []string) error {
+	var err error
+	switch runtime.GOOS {
+	case "linux":
+		err = uninstallSystemd()
+	case "darwin":
+		err = uninstallLaunchd()
+	case "windows":
+		err = uninstallScheduledTask()
+	default:
+		return errors.New("服务目前仅支持 Linux、macOS 和 Windows")
+	}
+	if err != nil {
+		return err
+	}
+	logger.Info("已删除服务 " + serviceName)
+	return nil
+}
+
+// RunServiceStatus 调用平台的服务管理工具显示服务状态
+func RunServiceStatus(cmd *cobra.Command, args []string) error {
+	switch runtime.GOOS {
+	case "linux":
+		// 定时器未运行时 systemctl status 以非零值退出，此时输出本身已说明状态
+		runServiceTool("systemctl", "--user", "status", "--no-pager", serviceName+".timer", serviceName+".service")
+		return nil
+	case "darwin":
+		return runServiceTool("launchctl", "list", launchdLabel())
+	case "windows":
+		return runSchtasks("/Query", "/V", "/FO", "LIST", "/TN", serviceName)
+	}
+	return errors.New("服务目前仅支持 Linux、macOS 和 Windows")
+}
+
+// systemdUnitDir 返回 systemd 用户单元的目录
+func systemdUnitDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "systemd", "user"), nil
+	}
+	return pathutil.NormalizePath("~/.config/systemd/user")
+}
+
+// systemdQuote 按 systemd 的规则为 ExecStart 中的参数加引号
+func systemdQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\%$") {
+		return arg
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `%`, `%%`, `$`, `$$`)
+	return `"` + r.Replace(arg) + `"`
+}
+
+// installSystemd 写入 systemd 用户服务和定时器并启用定时器
+func installSystemd(exe string, flkArgs []string) error {
+	dir, err := systemdUnitDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	execStart := []string{systemdQuote(exe)}
+	for _, a := range flkArgs {
+		execStart = append(execStart, systemdQuote(a))
+	}
+	unit := fmt.Sprintf(`[Unit]
+Description=flk: 检查并修复文件链接
+
+[Service]
+Type=oneshot
+ExecStart=%s
+`, strings.Join(execStart, " "))
+	seconds := int(serviceInterval.Seconds())
+	timer := fmt.Sprintf(`[Unit]
+Description=flk: 定期检查并修复文件链接
+
+[Timer]
+OnBootSec=%ds
+OnUnitActiveSec=%ds
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`, seconds, seconds)
+	if err := os.WriteFile(filepath.Join(dir, serviceName+".service"), []byte(unit), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, serviceName+".timer"), []byte(timer), 0644); err != nil {
+		return err
+	}
+	if err := runServiceTool("systemctl", "--user", "daemon-reload"); err != nil {
+		return err
+	}
+	return runServiceTool("systemctl", "--user", "enable", "--now", serviceName+".timer")
+}
+
+// uninstallSystemd 停用定时器并删除单元文件
+func uninstallSystemd() error {
+	dir, err := systemdUnitDir()
+	if err != nil {
+		return err
+	}
+	if err := runServiceTool("systemctl", "--user", "disable", "--now", serviceName+".timer"); err != nil {
+		logger.Warn(err.Error())
+	}
+	for _, name := range []string{serviceName + ".timer", serviceName + ".service"} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return runServiceTool("systemctl", "--user", "daemon-reload")
+}
+
+// launchdLabel 返回 launchd 代理的标签
+func launchdLabel() string {
+	return "com.github.jy-eggroll." + serviceName
+}
+
+// launchdPlist 返回 launchd 代理配置文件的路径
+func launchdPlist() (string, error) {
+	return pathutil.NormalizePath("~/Library/LaunchAgents/" + launchdLabel() + ".plist")
+}
+
+// installLaunchd 写入 launchd 代理配置并加载
+func installLaunchd(exe string, flkArgs []string) error {
+	path, err := launchdPlist()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var program strings.Builder
+	for _, a := range append([]string{exe}, flkArgs...) {
+		program.WriteString("\t\t<string>" + html.EscapeString(a) + "</string>\n")
+	}
+	plist := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+%s	</array>
+	<key>StartInterval</key>
+	<integer>%d</integer>
+	<key>RunAtLoad</key>
+	<true/>
+</dict>
+</plist>
+`, launchdLabel(), program.String(), int(serviceInterval.Seconds()))
+	// 重新安装时先卸载旧配置，否则 launchctl load 不会读取新内容
+	if _, err := os.Stat(path); err == nil {
+		runServiceTool("launchctl", "unload", path)
+	}
+	if err := os.WriteFile(path, []byte(plist), 0644); err != nil {
+		return err
+	}
+	return runServiceTool("launchctl", "load", "-w", path)
+}
+
+// uninstallLaunchd 卸载 launchd 代理并删除配置文件
+func uninstallLaunchd() error {
+	path, err := launchdPlist()
+	if err != nil {
+		return err
+	}
+	if err := runServiceTool("launchctl", "unload", "-w", path); err != nil {
+		logger.Warn(err.Error())
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// installScheduledTask 在任务计划程序中注册按分钟间隔执行的任务
+func installScheduledTask(exe string, flkArgs []string) error {
+	minutes := int(serviceInterval.Minutes())
+	if minutes > 1439 {
+		return fmt.Errorf("无效的间隔 %v，任务计划程序的分钟间隔不能超过 1439", serviceInterval)
+	}
+	tr := `"` + exe + `"`
+	for _, a := range flkArgs {
+		if strings.ContainsAny(a, " \t") {
+			a = `"` + a + `"`
+		}
+		tr += " " + a
+	}
+	if err := checkServiceTaskOwner(); err != nil {
+		return err
+	}
+	if err := runSchtasks("/Create", "/F", "/TN", serviceName, "/TR", tr, "/SC", "MINUTE", "/MO", strconv.Itoa(minutes), "/RL", "HIGHEST"); err != nil {
+		return err
+	}
+	marker, err := serviceTaskMarker()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(marker), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(marker, []byte(tr+"\n"), 0644)
+}
+
+// uninstallScheduledTask 删除由 flk service 创建的计划任务
+func uninstallScheduledTask() error {
+	if err := checkServiceTaskOwner(); err != nil {
+		return err
+	}
+	if err := runSchtasks("/Delete", "/F", "/TN", serviceName); err != nil {
+		return err
+	}
+	marker, err := serviceTaskMarker()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(marker); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// serviceTaskMarkerDir 记录由 flk service 创建的计划任务的目录，每个任务一个以任务名称命名的文件
+const serviceTaskMarkerDir = "~/.local/share/flk/services"
+
+// serviceTaskMarker 返回计划任务 serviceName 的记录文件路径
+func serviceTaskMarker() (string, error) {
+	return pathutil.NormalizePath(serviceTaskMarkerDir + "/" + serviceName)
+}
+
+// checkServiceTaskOwner 同名的计划任务已存在但不是由 flk service 创建时返回错误，
+// 避免替换或删除 flk schedule install 等注册的任务
+func checkServiceTaskOwner() error {
+	if exec.Command("schtasks", "/Query", "/TN", serviceName).Run() != nil {
+		return nil
+	}
+	marker, err := serviceTaskMarker()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(marker); err == nil {
+		return nil
+	}
+	return fmt.Errorf("计划任务 %s 已存在且不是由 flk service 创建的（可能来自 flk schedule install），请用 --name 指定其他名称", serviceName)
+}
+
+// runServiceTool 执行服务管理工具，输出直接显示给用户
+func runServiceTool(name string, args ...string) error {
+	c := exec.Command(name, args...)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("执行 %s %s 失败 %w", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
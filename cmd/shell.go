@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/jy-eggroll/flk/internal/trace"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+)
+
+var shellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "进入交互式命令行，存储只加载一次",
+	Long:  "进入交互式命令行，输入不带 flk 前缀的命令（如 check、fix 3、create symlink -r a -f b）立即执行，存储只在启动时加载一次；支持上下方向键浏览历史和 Tab 补全命令与选项，输入 exit 或按 Ctrl-D 退出",
+	Args:  cobra.NoArgs,
+	RunE:  RunShell,
+}
+
+// shellHistoryFile 历史记录文件名，位于存储文件所在目录
+const shellHistoryFile = "shell_history"
+
+// shellHistoryLimit 保留的历史记录条数
+const shellHistoryLimit = 1000
+
+var (
+	// inShell 为 true 时处于 flk shell 中，根命令不会为每条命令重新加载存储
+	inShell bool
+	// loadedStorePath 已加载到内存中的存储路径
+	loadedStorePath string
+)
+
+func init() {
+	rootCmd.AddCommand(shellCmd)
+}
+
+// RunShell 读取并执行命令直到用户退出
+func RunShell(cmd *cobra.Command, args []string) error {
+	if inShell {
+		return errors.New("已经在 flk shell 中")
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return errors.New("flk shell 需要在终端中运行")
+	}
+	inShell = true
+	loadedStorePath = store.StorePath
+	defer func() { inShell = false }()
+
+	// 进入 shell 时指定的全局选项（如 --store）对其中的每条命令都生效
+	initial := map[string]string{}
+	rootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed {
+			initial[f.Name] = f.Value.String()
+		}
+	})
+
+	// 复用同一个终端，保留一次读取中多出的输入（如粘贴的多行命令）
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, "flk> ")
+	t.History = loadShellHistory()
+	t.AutoCompleteCallback = completeShellLine
+	for {
+		line, err := readShellLine(fd, t)
+		if err == io.EOF {
+			fmt.Println()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		words, err := splitShellLine(line)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		if len(words) == 0 {
+			continue
+		}
+		switch words[0] {
+		case "exit", "quit":
+			return nil
+		case "flk":
+			words = words[1:]
+		}
+
+		resetShellState()
+		for name, value := range initial {
+			rootCmd.PersistentFlags().Set(name, value)
+		}
+		rootCmd.SetArgs(words)
+		if code := executeRoot(); code != ExitOK {
+			logger.Debug(fmt.Sprintf("命令执行失败，退出码 %d", code))
+		}
+		// --dry-run 只跳过写入，内存中的存储可能已被修改，下一条命令重新加载
+		if trace.DryRun {
+			loadedStorePath = ""
+		}
+	}
+}
+
+// readShellLine 以原始模式读取一行输入，返回前恢复终端模式，使命令的输出和交互提示正常显示
+func readShellLine(fd int, t *term.Terminal) (string, error) {
+	old, err := term.MakeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(fd, old)
+	return t.ReadLine()
+}
+
+// completeShellLine 在按下 Tab 时补全光标前的命令名或选项名，有多个候选时补全到公共前缀
+func completeShellLine(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+	before := line[:pos]
+	words := strings.Fields(before)
+	partial := ""
+	if len(words) > 0 && !strings.HasSuffix(before, " ") {
+		partial, words = words[len(words)-1], words[:len(words)-1]
+	}
+
+	c, _, err := rootCmd.Find(words)
+	if err != nil {
+		return "", 0, false
+	}
+	var candidates []string
+	if strings.HasPrefix(partial, "-") {
+		add := func(f *pflag.Flag) {
+			if !f.Hidden {
+				candidates = append(candidates, "--"+f.Name)
+			}
+		}
+		c.Flags().VisitAll(add)
+		c.InheritedFlags().VisitAll(add)
+	} else {
+		for _, sub := range c.Commands() {
+			if sub.IsAvailableCommand() {
+				candidates = append(candidates, sub.Name())
+			}
+		}
+	}
+
+	var matches []string
+	for _, cand := range candidates {
+		if strings.HasPrefix(cand, partial) {
+			matches = append(matches, cand)
+		}
+	}
+	if len(matches) == 0 {
+		return "", 0, false
+	}
+	completion := matches[0]
+	for _, m := range matches[1:] {
+		for !strings.HasPrefix(m, completion) {
+			completion = completion[:len(completion)-1]
+		}
+	}
+	if len(matches) == 1 {
+		completion += " "
+	}
+	newBefore := before[:len(before)-len(partial)] + completion
+	return newBefore + line[pos:], len(newBefore), true
+}
+
+// splitShellLine 按空白拆分命令行，支持单引号和双引号；不处理反斜杠转义，以便直接输入 Windows 路径
+func splitShellLine(line string) ([]string, error) {
+	var words []string
+	var current strings.Builder
+	inWord := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("引号未闭合")
+	}
+	if inWord {
+		words = append(words, current.String())
+	}
+	return words, nil
+}
+
+// resetShellState 将选项和命令执行期间设置的全局状态恢复为初始值，避免上一条命令影响下一条
+func resetShellState() {
+	resetShellFlags(rootCmd)
+	resetCreateOptions()
+	headless = false
+	storePathSource = "default"
+	pathutil.Sandbox = ""
+}
+
+// resetShellFlags 将所有命令的选项恢复为默认值；规则和配置文件（如 initDevice）可能不经命令行直接设置选项的值而不标记 Changed，
+// 因此无论是否标记都要恢复
+func resetShellFlags(c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			sv.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	c.Flags().VisitAll(reset)
+	c.PersistentFlags().VisitAll(reset)
+	for _, sub := range c.Commands() {
+		resetShellFlags(sub)
+	}
+}
+
+// shellHistory 持久化到存储目录的命令历史，实现 term.History
+type shellHistory struct {
+	entries []string // 由旧到新
+	path    string
+}
+
+func loadShellHistory() *shellHistory {
+	h := &shellHistory{path: filepath.Join(filepath.Dir(mustNormalize(store.StorePath)), shellHistoryFile)}
+	f, err := os.Open(h.path)
+	if err != nil {
+		return h
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if len(h.entries) > shellHistoryLimit {
+		h.entries = h.entries[len(h.entries)-shellHistoryLimit:]
+	}
+	return h
+}
+
+func (h *shellHistory) Add(entry string) {
+	if strings.TrimSpace(entry) == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry) {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, entry)
+}
+
+func (h *shellHistory) Len() int {
+	return len(h.entries)
+}
+
+func (h *shellHistory) At(idx int) string {
+	return h.entries[len(h.entries)-1-idx]
+}
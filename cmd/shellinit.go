@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var shellInitCmd = &cobra.Command{
+	Use:       "shell-init <powershell|bash|zsh>",
+	Short:     "输出可在 shell 配置文件中加载的辅助函数",
+	Long:      "输出 flk-here、flk-adopt 以及显示无效链接数量的提示符片段函数，在配置文件中加载即可使用，例如 eval \"$(flk shell-init bash)\"",
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"powershell", "bash", "zsh"},
+	RunE:      RunShellInit,
+}
+
+func init() {
+	rootCmd.AddCommand(shellInitCmd)
+}
+
+const posixShellInit = `# flk shell 辅助函数
+# flk-here <路径>：在当前目录创建指向该路径的同名符号链接
+flk-here() {
+    __flk_target="$1"
+    shift
+    %[1]s create symlink --real "$__flk_target" --fake "$PWD/$(basename "$__flk_target")" "$@"
+}
+# flk-adopt <路径>...：将已存在的符号链接登记到存储
+flk-adopt() {
+    %[1]s adopt "$@"
+}
+# __flk_prompt：根据最近一次检查的缓存输出无效链接标记，全部有效时不输出
+__flk_prompt() {
+    __flk_token=$(%[1]s status --prompt 2>/dev/null)
+    [ -n "$__flk_token" ] && printf '%%s ' "$__flk_token"
+}
+`
+
+const bashPromptHint = `# 在提示符中显示：PS1='$(__flk_prompt)'"$PS1"
+`
+
+const zshPromptHint = `# 在提示符中显示：setopt PROMPT_SUBST; PROMPT='$(__flk_prompt)'$PROMPT
+`
+
+const powershellInit = `# flk PowerShell 辅助函数
+# flk-here <路径>：在当前目录创建指向该路径的同名符号链接
+function flk-here {
+    param([Parameter(Mandatory = $true)][string]$Path)
+    & %[1]s create symlink --real $Path --fake (Join-Path $PWD (Split-Path $Path -Leaf)) @args
+}
+# flk-adopt <路径>...：将已存在的符号链接登记到存储
+function flk-adopt {
+    & %[1]s adopt @args
+}
+# Get-FlkPromptSegment：根据最近一次检查的缓存返回无效链接标记，全部有效时返回空字符串
+# 在提示符中显示：function prompt { "$(Get-FlkPromptSegment)PS $PWD> " }
+function Get-FlkPromptSegment {
+    $token = & %[1]s status --prompt 2>$null
+    if ($token) { "$token " } else { '' }
+}
+`
+
+// RunShellInit 输出指定 shell 的辅助函数
+func RunShellInit(cmd *cobra.Command, args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "bash":
+		fmt.Printf(posixShellInit, shellQuote(exe))
+		fmt.Print(bashPromptHint)
+	case "zsh":
+		fmt.Printf(posixShellInit, shellQuote(exe))
+		fmt.Print(zshPromptHint)
+	case "powershell":
+		fmt.Printf(powershellInit, "'"+strings.ReplaceAll(exe, "'", "''")+"'")
+	default:
+		return fmt.Errorf("不支持的 shell %s，可选 powershell、bash、zsh", args[0])
+	}
+	return nil
+}
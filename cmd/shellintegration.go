@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+// 由 shellintegration_windows.go 赋值，其他平台保持为 nil
+var (
+	installShellIntegration   func() error
+	uninstallShellIntegration func() error
+)
+
+var shellIntegrationCmd = &cobra.Command{
+	Use:   "shell-integration",
+	Short: "管理资源管理器右键菜单（仅 Windows）",
+	Long:  "在资源管理器的右键菜单中添加“flk: 在此处创建链接…”和“flk: 收编此符号链接”两项",
+}
+
+var shellIntegrationInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "注册右键菜单项",
+	Long:  "为当前用户注册右键菜单项，无需管理员权限",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if installShellIntegration == nil {
+			return errors.New("右键菜单集成仅支持 Windows")
+		}
+		return installShellIntegration()
+	},
+}
+
+var shellIntegrationUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "移除右键菜单项",
+	Long:  "移除由 install 注册的右键菜单项",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if uninstallShellIntegration == nil {
+			return errors.New("右键菜单集成仅支持 Windows")
+		}
+		return uninstallShellIntegration()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(shellIntegrationCmd)
+	shellIntegrationCmd.AddCommand(shellIntegrationInstallCmd)
+	shellIntegrationCmd.AddCommand(shellIntegrationUninstallCmd)
+}
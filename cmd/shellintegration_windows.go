@@ -0,0 +1,86 @@
+//go:build windows
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows/registry"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+)
+
+// shellMenuItem 描述一个右键菜单项，args 中的 %1 会被资源管理器替换为所选路径
+type shellMenuItem struct {
+	key   string
+	label string
+	args  string
+}
+
+var shellMenuItems = []shellMenuItem{
+	{key: "flk.link", label: "flk: 在此处创建链接…", args: `create symlink --real "%1"`},
+	{key: "flk.adopt", label: "flk: 收编此符号链接", args: `adopt "%1"`},
+}
+
+// 文件和文件夹各自的右键菜单注册位置
+var shellMenuRoots = []string{
+	`Software\Classes\*\shell`,
+	`Software\Classes\Directory\shell`,
+}
+
+func init() {
+	installShellIntegration = func() error {
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		for _, root := range shellMenuRoots {
+			for _, item := range shellMenuItems {
+				if err := writeShellMenuItem(root, item, exe); err != nil {
+					return err
+				}
+			}
+		}
+		logger.Info("已注册右键菜单")
+		return nil
+	}
+	uninstallShellIntegration = func() error {
+		for _, root := range shellMenuRoots {
+			for _, item := range shellMenuItems {
+				path := root + `\` + item.key
+				// 必须先删除子键
+				for _, key := range []string{path + `\command`, path} {
+					if err := registry.DeleteKey(registry.CURRENT_USER, key); err != nil && err != registry.ErrNotExist {
+						return err
+					}
+				}
+			}
+		}
+		logger.Info("已移除右键菜单")
+		return nil
+	}
+}
+
+func writeShellMenuItem(root string, item shellMenuItem, exe string) error {
+	path := root + `\` + item.key
+	k, _, err := registry.CreateKey(registry.CURRENT_USER, path, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	if err := k.SetStringValue("", item.label); err != nil {
+		return err
+	}
+	if err := k.SetStringValue("Icon", exe); err != nil {
+		return err
+	}
+
+	c, _, err := registry.CreateKey(registry.CURRENT_USER, path+`\command`, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	// 通过 cmd /k 保持窗口，便于在交互提示中输入路径并查看结果
+	return c.SetStringValue("", fmt.Sprintf(`cmd.exe /k ""%s" %s"`, exe, item.args))
+}
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/create/hardlink"
+	"github.com/jy-eggroll/flk/internal/create/template"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/snapshot"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/jy-eggroll/flk/internal/trace"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "保存和恢复整个链接状态的命名快照",
+	Long:  "快照包含存储内容和创建时文件系统上各链接的实际状态，恢复时按快照重新创建、重新指向或删除链接，并将存储恢复为快照中的内容",
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create <名称>",
+	Short: "创建快照，同名快照会被覆盖",
+	Args:  cobra.ExactArgs(1),
+	RunE:  RunSnapshotCreate,
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <名称>",
+	Short: "将链接和存储恢复为快照中的状态",
+	Args:  cobra.ExactArgs(1),
+	RunE:  RunSnapshotRestore,
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出全部快照",
+	RunE:  RunSnapshotList,
+}
+
+var snapshotForce bool
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotRestoreCmd.Flags().BoolVar(&snapshotForce, "force", false, "链接位置上存在快照之外的普通文件时也将其覆盖")
+}
+
+// snapshotDir 返回快照目录
+func snapshotDir() string {
+	return snapshot.Dir(mustNormalize(store.StorePath))
+}
+
+// RunSnapshotCreate 记录存储内容和当前平台上每个链接的实际状态
+func RunSnapshotCreate(cmd *cobra.Command, args []string) error {
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	data := mgr.Data
+	s := &snapshot.Snapshot{
+		Name:      args[0],
+		CreatedAt: time.Now(),
+		Platform:  runtime.GOOS,
+		Store:     data,
+		Links:     observeLinks(data),
+	}
+	if err := snapshot.Save(snapshotDir(), s); err != nil {
+		return err
+	}
+	output.PrintCreateResult(output.OutputFormat(outputFormat), output.CreateResult{Success: true, Type: "快照", Message: fmt.Sprintf("已创建快照 %s，包含 %d 个链接", s.Name, len(s.Links))})
+	return nil
+}
+
+// RunSnapshotRestore 按快照调整文件系统上的链接，再将存储替换为快照中的内容
+func RunSnapshotRestore(cmd *cobra.Command, args []string) error {
+	s, err := snapshot.Load(snapshotDir(), args[0])
+	if err != nil {
+		return err
+	}
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+
+	var results []output.ApplyResult
+	failed := 0
+	if s.Platform != runtime.GOOS {
+		pterm.Warning.Printf("快照 %s 创建于 %s，只恢复存储，不调整本机的链接\n", s.Name, s.Platform)
+	} else {
+		wanted := make(map[string]bool)
+		for _, l := range s.Links {
+			wanted[l.Type+"\x00"+l.Link] = true
+		}
+		// 删除快照之后才加入的链接
+		for _, l := range observeLinks(mgr.Data) {
+			if wanted[l.Type+"\x00"+l.Link] || l.State == snapshot.StateMissing || l.State == snapshot.StateFile {
+				continue
+			}
+			result := output.ApplyResult{Action: "remove", Type: l.Type, Link: l.Link, Success: true}
+			if err := removeManagedLink(l.Type, l.Target, l.Link, l.Device); err != nil {
+				result.Success, result.Error = false, err.Error()
+				failed++
+			}
+			results = append(results, result)
+		}
+		for _, l := range s.Links {
+			action, err := restoreSnapshotLink(l)
+			if action == "none" && err == nil {
+				continue
+			}
+			result := output.ApplyResult{Action: action, Type: l.Type, Link: l.Link, Success: err == nil}
+			if err != nil {
+				result.Error = err.Error()
+				failed++
+			}
+			results = append(results, result)
+		}
+	}
+
+	mgr.Data = s.Store
+	if mgr.Data == nil {
+		mgr.Data = make(store.RootConfig)
+	}
+	if err := mgr.Save(store.StorePath); err != nil {
+		logger.Error("持久化失败 " + err.Error())
+		return err
+	}
+
+	format := output.OutputFormat(outputFormat)
+	if len(results) == 0 {
+		output.PrintCreateResult(format, output.CreateResult{Success: true, Type: "快照", Message: "链接已与快照一致，已恢复存储"})
+		return nil
+	}
+	if err := output.PrintApplyResults(format, results); err != nil {
+		logger.Error("输出失败 " + err.Error())
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个链接未能恢复", failed)
+	}
+	return nil
+}
+
+// RunSnapshotList 列出全部快照
+func RunSnapshotList(cmd *cobra.Command, args []string) error {
+	snapshots, err := snapshot.List(snapshotDir())
+	if err != nil {
+		return err
+	}
+	if output.OutputFormat(outputFormat) == output.JSON {
+		type item struct {
+			Name      string    `json:"name"`
+			CreatedAt time.Time `json:"created_at"`
+			Platform  string    `json:"platform"`
+			Links     int       `json:"links"`
+		}
+		items := []item{}
+		for _, s := range snapshots {
+			items = append(items, item{s.Name, s.CreatedAt, s.Platform, len(s.Links)})
+		}
+		data, err := json.MarshalIndent(items, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	table := pterm.TableData{{"名称", "创建时间", "平台", "链接数"}}
+	for _, s := range snapshots {
+		table = append(table, []string{s.Name, s.CreatedAt.Format("2006-01-02 15:04:05"), s.Platform, fmt.Sprint(len(s.Links))})
+	}
+	return output.RenderTable(table)
+}
+
+// observeLinks 观察当前平台上所有记录的链接在文件系统上的状态
+func observeLinks(data store.RootConfig) []snapshot.Link {
+	var links []snapshot.Link
+	for device, types := range data[runtime.GOOS] {
+		for linkType, paths := range types {
+			for parentPath, entries := range paths {
+				for _, entry := range entries {
+					basePath, err := recordBasePath(parentPath, entry)
+					if err != nil {
+						logger.Warn(err.Error())
+						continue
+					}
+					target := resolveRecordPath(entry[targetKey(linkType)], basePath)
+					paths := []string{entry["fake"]}
+					if linkType == "hardlink" {
+						paths = store.SecoPaths(entry)
+					}
+					for _, p := range paths {
+						l := snapshot.Link{Type: linkType, Device: device, Link: resolveRecordPath(p, basePath), Target: target}
+						l.State, l.Readlink = observeLink(l)
+						links = append(links, l)
+					}
+				}
+			}
+		}
+	}
+	return links
+}
+
+// observeLink 返回链接的实际状态，符号链接同时返回其指向
+func observeLink(l snapshot.Link) (string, string) {
+	info, err := trace.Lstat(l.Link)
+	if err != nil {
+		return snapshot.StateMissing, ""
+	}
+	switch l.Type {
+	case "symlink":
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := trace.Readlink(l.Link)
+			if err == nil {
+				return snapshot.StateSymlink, target
+			}
+		}
+	case "hardlink":
+		if targetInfo, err := trace.Stat(l.Target); err == nil && os.SameFile(info, targetInfo) {
+			return snapshot.StateHardlink, ""
+		}
+	case "template":
+		if current, err := template.IsCurrent(l.Target, l.Link, template.DeviceVars(l.Device)); err == nil && current {
+			return snapshot.StateRendered, ""
+		}
+	}
+	return snapshot.StateFile, ""
+}
+
+// restoreSnapshotLink 使链接恢复为快照中观察到的状态，返回执行的操作
+func restoreSnapshotLink(l snapshot.Link) (string, error) {
+	state, readlink := observeLink(l)
+	if state == l.State && readlink == l.Readlink {
+		return "none", nil
+	}
+	switch l.State {
+	case snapshot.StateMissing:
+		return "remove", removeManagedLink(l.Type, l.Target, l.Link, l.Device)
+	case snapshot.StateFile:
+		// 快照时此处就不是受管理的链接，不做改动
+		return "none", nil
+	}
+
+	action := "add"
+	if state != snapshot.StateMissing {
+		action = "modify"
+		if state == snapshot.StateFile && !snapshotForce {
+			return action, fmt.Errorf("%s 已存在且不是受管理的链接，如需覆盖请指定 --force", l.Link)
+		}
+		if err := trace.RemoveAll(l.Link); err != nil {
+			return action, err
+		}
+	}
+	if err := pathutil.EnsureDirExists(l.Link); err != nil {
+		return action, err
+	}
+	switch l.State {
+	case snapshot.StateSymlink:
+		return action, trace.Symlink(l.Readlink, l.Link)
+	case snapshot.StateHardlink:
+		return action, hardlink.Create(l.Target, l.Link, false)
+	default:
+		return action, template.Create(l.Target, l.Link, template.DeviceVars(l.Device), false)
+	}
+}
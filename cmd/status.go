@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/status"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "显示最近一次检查的摘要",
+	Long:  "读取当前存储最近一次完整执行 flk check（未指定任何筛选条件）或 flk fix 后缓存的摘要，不访问文件系统中的链接",
+	Args:  cobra.NoArgs,
+	RunE:  RunStatus,
+	// 只读取缓存，跳过根命令中配置和存储的加载，保证嵌入提示符时足够快
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if remoteHost != "" {
+			return abortRun(cmd, &exitCodeError{code: runRemote(cmd), err: errors.New("已在远程主机上执行")})
+		}
+		if err := initSandbox(); err != nil {
+			logger.Error("启用沙盒失败 " + err.Error())
+			return abortRun(cmd, err)
+		}
+		resolveStorePath(cmd)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().BoolVar(&statusCount, "count", false, "仅输出无效链接数量")
+	statusCmd.Flags().BoolVar(&statusPrompt, "prompt", false, "输出适合嵌入 starship、powerlevel10k 等提示符的简短标记，如 ⛓2!，全部有效时不输出")
+	statusCmd.MarkFlagsMutuallyExclusive("count", "prompt")
+}
+
+var (
+	statusCount  bool
+	statusPrompt bool
+)
+
+// RunStatus 输出缓存的检查摘要
+func RunStatus(cmd *cobra.Command, args []string) error {
+	summary, err := status.Load(store.StorePath)
+	if statusPrompt {
+		// 提示符中不应出现错误信息，读取失败时静默
+		if err == nil && summary.Invalid > 0 {
+			fmt.Printf("⛓%d!\n", summary.Invalid)
+		}
+		return nil
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("尚未执行过检查，请先运行 flk check")
+		}
+		return err
+	}
+
+	if statusCount {
+		fmt.Println(summary.Invalid)
+		return nil
+	}
+
+	switch output.OutputFormat(outputFormat) {
+	case output.JSON:
+		data, err := json.MarshalIndent(summary, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case output.Table:
+		table := pterm.TableData{
+			{"检查时间", "总数", "无效"},
+			{summary.CheckedAt.Format("2006-01-02 15:04:05"), fmt.Sprintf("%d", summary.Total), fmt.Sprintf("%d", summary.Invalid)},
+		}
+		output.RenderTable(table)
+		for errorType, count := range summary.ErrorTypes {
+			fmt.Printf("  %s: %d\n", errorType, count)
+		}
+		if summary.GaveUp > 0 {
+			pterm.Warning.Printf("%d 条记录反复被改回，已放弃自动修复，请手动检查后执行 flk fix\n", summary.GaveUp)
+		}
+	}
+	return nil
+}
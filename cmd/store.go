@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/state"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var storeCmd = &cobra.Command{
+	Use:   "store",
+	Short: "维护存储文件本身",
+}
+
+var storeNormalizeCmd = &cobra.Command{
+	Use:   "normalize",
+	Short: "将当前平台记录中的路径批量改写为规范形式",
+	Long: `旧版本的不同命令写入的路径形式不一：有的以 ~ 折叠主目录，有的是未折叠的绝对路径，有的含 . 或 ..。
+这些形式仍可正常读取，本命令将其统一改写为规范形式：清理后的绝对路径，位于主目录下时以 ~ 开头，
+记录按链接文件所在目录分组，改写后完全相同的重复记录只保留一条；状态文件中的运行状态随链接路径一起迁移`,
+	Args: cobra.NoArgs,
+	RunE: RunStoreNormalize,
+}
+
+var storeNormalizeDryRun bool
+
+func init() {
+	rootCmd.AddCommand(storeCmd)
+	storeCmd.AddCommand(storeNormalizeCmd)
+	storeNormalizeCmd.Flags().BoolVar(&storeNormalizeDryRun, "dry-run", false, "只列出将要改写的路径，不写入存储")
+}
+
+// RunStoreNormalize 规范化存储中的路径并同步状态文件
+func RunStoreNormalize(cmd *cobra.Command, args []string) error {
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	changes := mgr.Normalize()
+	printed := make([]output.PathChange, len(changes))
+	for i, c := range changes {
+		printed[i] = output.PathChange{Device: c.Device, Type: c.Type, Field: c.Field, From: c.From, To: c.To}
+	}
+	if err := output.PrintPathChanges(output.OutputFormat(outputFormat), printed); err != nil {
+		return err
+	}
+	if storeNormalizeDryRun || len(changes) == 0 {
+		return nil
+	}
+
+	if err := mgr.Save(store.StorePath); err != nil {
+		logger.Error("持久化失败 " + err.Error())
+		return err
+	}
+	statePath := state.PathFor(store.StorePath)
+	if st, err := state.Load(statePath); err == nil {
+		for _, c := range changes {
+			if c.Field == "fake" || c.Field == store.SecoKey || strings.HasPrefix(c.Field, store.SecoKey+".") {
+				st.RenameKey(state.Key(runtime.GOOS, c.Device, c.Type, c.From), state.Key(runtime.GOOS, c.Device, c.Type, c.To))
+			}
+		}
+		if err := st.Save(statePath); err != nil {
+			logger.Warn("写入状态文件失败 " + err.Error())
+		}
+	}
+	logger.Info(fmt.Sprintf("已改写 %d 处路径", len(changes)))
+	return nil
+}
@@ -2,9 +2,16 @@ package cmd
 
 import (
 	"errors"
-	"os"
+	"fmt"
+	"runtime"
 
+	"github.com/jy-eggroll/flk/internal/config"
 	"github.com/jy-eggroll/flk/internal/create/symlink"
+	"github.com/jy-eggroll/flk/internal/elevate"
+	"github.com/jy-eggroll/flk/internal/fileattr"
+	"github.com/jy-eggroll/flk/internal/fileperm"
+	"github.com/jy-eggroll/flk/internal/fsinfo"
+	"github.com/jy-eggroll/flk/internal/interference"
 	"github.com/jy-eggroll/flk/internal/logger"
 	"github.com/jy-eggroll/flk/internal/output"
 	"github.com/jy-eggroll/flk/internal/pathutil"
@@ -20,68 +27,221 @@ var (
 var symlinkCmd = &cobra.Command{
 	Use:   "symlink",
 	Short: "创建符号链接（支持文件和文件夹）",
-	Long:  "创建符号链接（支持文件和文件夹）",
-	RunE:  Symlink,
+	Long: `创建符号链接（支持文件和文件夹）。
+使用 --fake-dir 批量创建：--real 作为通配符展开（需加引号避免被 shell 展开），在该目录下为每个匹配项创建同名链接，
+每个链接作为一条单独的记录，已指向对应真实文件的链接不会重建，没有记录时直接收编，如 flk create symlink --real 'configs/*.toml' --fake-dir ~/.config/app/；配合 --dry-run 预览`,
+	RunE: Symlink,
 }
 
 func init() {
 	createCmd.AddCommand(symlinkCmd)
-	symlinkCmd.Flags().StringVarP(&symlinkReal, "real", "r", "", "真实文件路径")
-	symlinkCmd.Flags().StringVarP(&symlinkFake, "fake", "f", "", "链接文件路径")
+	symlinkCmd.Flags().StringVarP(&symlinkReal, "real", "r", "", "真实文件路径，在终端中省略时会打开路径选择器")
+	symlinkCmd.Flags().StringVarP(&symlinkFake, "fake", "f", "", "链接文件路径，在终端中省略时会打开路径选择器")
 	symlinkCmd.Flags().BoolVar(&createForce, "force", false, "强制覆盖已存在的文件或文件夹")
 	symlinkCmd.Flags().StringVarP(&createDevice, "device", "d", "all", "设备名称，用于后续设备过滤")
-	symlinkCmd.MarkFlagRequired("real")
-	symlinkCmd.MarkFlagRequired("fake")
+	symlinkCmd.Flags().StringVar(&createMode, "mode", "", "创建后将目标文件权限设置为该值（八进制，如 600），Windows 上忽略")
+	symlinkCmd.Flags().StringVar(&createEvery, "check-every", "", "检查频率提示，如 1h、7d，配合 check --due-only 减少对网络驱动器等昂贵路径的检查")
+	symlinkCmd.Flags().StringVar(&createValidate, "validate", "", "修复前对真实文件的校验，逗号分隔，可选 nonempty、json、toml、yaml，最后可跟 cmd:<命令>（文件路径在环境变量 FLK_FILE 中）")
+	symlinkCmd.Flags().StringVar(&createNote, "note", "", "记录的备注，如为什么链接指向非常规位置，检查时随结果显示")
+	symlinkCmd.Flags().StringVar(&createAttrib, "attrib", "", "为链接自身设置 Windows 文件属性，可选 hidden、system，逗号分隔，其他平台忽略")
+	symlinkCmd.Flags().StringVar(&createLinkStyle, "link-style", "", "链接内容的写法：relative 为相对链接文件所在目录的路径（默认），absolute 为绝对路径")
+	symlinkCmd.Flags().StringVar(&createDirLink, "dir-link", "", "真实文件为目录时创建的链接类型：symlink（默认）或 junction（仅 Windows，其他平台仍创建符号链接）")
 }
 
 func Symlink(cmd *cobra.Command, args []string) error {
+	if symlinkFakeDir != "" {
+		return symlinkMap(cmd)
+	}
 	format := output.OutputFormat(outputFormat)
 
-	normalizedReal, err := pathutil.NormalizePath(symlinkReal)
+	realPath := symlinkReal
+	if realPath == "" {
+		if !isInteractive() {
+			result := output.CreateResult{Success: false, Type: "符号链接", Error: "必须指定真实文件路径 --real"}
+			printCreateResult(format, result)
+			return errors.New(result.Error)
+		}
+		var err error
+		if realPath, err = pickPath("选择真实文件", false); err != nil {
+			return err
+		}
+	}
+
+	normalizedReal, err := pathutil.NormalizePath(realPath)
 	if err != nil {
 		result := output.CreateResult{Success: false, Type: "符号链接", Error: "真实文件路径标准化失败 " + err.Error()}
-		output.PrintCreateResult(format, result)
+		printCreateResult(format, result)
 		return errors.New(result.Error)
 	}
 
+	fake := symlinkFake
+	if fake == "" {
+		// 从右键菜单等入口调用时只知道真实文件路径
+		if !isInteractive() {
+			result := output.CreateResult{Success: false, Type: "符号链接", Error: "必须指定链接文件路径 --fake"}
+			printCreateResult(format, result)
+			return errors.New(result.Error)
+		}
+		fake, err = pickPath("选择链接文件位置", true)
+		if err != nil {
+			return err
+		}
+	}
+
 	var normalizedFake string
-	normalizedFake, err = pathutil.NormalizePath(symlinkFake)
+	normalizedFake, err = pathutil.NormalizePath(fake)
 	if err != nil {
 		result := output.CreateResult{Success: false, Type: "符号链接", Error: "链接文件路径标准化失败 " + err.Error()}
-		output.PrintCreateResult(format, result)
+		printCreateResult(format, result)
 		return errors.New(result.Error)
 	}
 
+	applyLinkRules(cmd, normalizedFake)
+
+	if err := parseCreateOptions(); err != nil {
+		result := output.CreateResult{Success: false, Type: "符号链接", Error: err.Error()}
+		printCreateResult(format, result)
+		return err
+	}
+
+	if createDirLink == config.DirLinkJunction && junctionFor(normalizedReal) {
+		logger.Info("真实文件是目录，改为创建目录联接")
+		junctionReal, junctionFake = normalizedReal, normalizedFake
+		return Junction(nil, nil)
+	}
+
+	if createMode != "" {
+		if _, err := fileperm.Parse(createMode); err != nil {
+			result := output.CreateResult{Success: false, Type: "符号链接", Error: err.Error()}
+			printCreateResult(format, result)
+			return err
+		}
+	}
+
+	attrib, err := fileattr.Parse(createAttrib)
+	if err != nil {
+		result := output.CreateResult{Success: false, Type: "符号链接", Error: err.Error()}
+		printCreateResult(format, result)
+		return err
+	}
+
+	if err := checkStrict(normalizedReal, normalizedFake); err != nil {
+		result := output.CreateResult{Success: false, Type: "符号链接", Error: err.Error()}
+		printCreateResult(format, result)
+		return err
+	}
+	warnSelfReference(normalizedFake)
+
 	logger.Info("创建符号链接 real=" + normalizedReal + ", fake=" + normalizedFake)
 
+	force := createForce
+	if !force {
+		conflict, err := symlink.DetectConflict(normalizedReal, normalizedFake)
+		if err != nil {
+			logger.Warn("检测链接冲突失败 " + err.Error())
+		}
+		if conflict != nil {
+			switch resolveConflict(conflict) {
+			case conflictKeep:
+				result := output.CreateResult{Success: true, Type: "符号链接", Message: "已保留现有链接"}
+				printCreateResult(format, result)
+				return nil
+			case conflictOverwrite:
+				force = true
+			case conflictAdopt:
+				if err := recordSymlink(conflict.ExistingTarget, normalizedFake); err != nil {
+					result := output.CreateResult{Success: false, Type: "符号链接", Error: "写入存储失败 " + err.Error()}
+					printCreateResult(format, result)
+					return err
+				}
+				result := output.CreateResult{Success: true, Type: "符号链接", Message: "已收编现有链接"}
+				printCreateResult(format, result)
+				return nil
+			case conflictSkip:
+				result := output.CreateResult{Success: false, Type: "符号链接", Error: "链接文件已指向 " + conflict.ExistingTarget + "，已跳过"}
+				printCreateResult(format, result)
+				return errors.New(result.Error)
+			}
+		}
+	}
+
+	if force && !confirmForceDelete(normalizedFake) {
+		result := output.CreateResult{Success: false, Type: "符号链接", Error: "已取消覆盖 " + normalizedFake}
+		printCreateResult(format, result)
+		return errors.New(result.Error)
+	}
+
+	replaced := force && pathExists(normalizedFake)
+	if replaced {
+		// 覆盖时保留原有的隐藏、系统属性
+		if existing, err := fileattr.Get(normalizedFake); err == nil {
+			attrib |= existing
+		}
+	}
 	var result output.CreateResult
-	if err := symlink.Create(normalizedReal, normalizedFake, createForce); err != nil {
+	if err := createSymlinkTxn(normalizedReal, normalizedFake, force, attrib); err != nil {
 		result = output.CreateResult{Success: false, Type: "符号链接", Error: err.Error()}
 	} else {
 		result = output.CreateResult{Success: true, Type: "符号链接", Message: "创建成功"}
-		// 持久化数据
-		if store.GlobalManager == nil {
-			if err := store.InitStore(store.StorePath); err != nil {
-				logger.Error("初始化存储失败 " + err.Error())
-			}
-		}
-		mgr := store.GlobalManager
-		if mgr != nil {
-			absFakePath, _ := pathutil.ToAbsolute(normalizedFake)
-			fields := map[string]string{
-				"real": normalizedReal,
-				"fake": absFakePath,
-			}
-			parentPath, _ := os.Getwd()
-			mgr.AddRecord(createDevice, "symlink", parentPath, fields)
-			if err := mgr.Save(store.StorePath); err != nil {
-				logger.Error("持久化失败 " + err.Error())
-			}
-		}
+		auditLink(replaced, "symlink", normalizedFake, normalizedReal)
 	}
-	output.PrintCreateResult(format, result)
+	result.Warnings = createWarnings
+	printCreateResult(format, result)
 	if result.Success {
 		return nil
 	}
 	return errors.New(result.Error)
 }
+
+// createSymlinkTxn 创建符号链接并写入存储，写入存储失败时删除新链接并恢复被覆盖的原文件
+func createSymlinkTxn(normalizedReal, normalizedFake string, force bool, attrib fileattr.Attr) error {
+	// Windows 上缺少权限导致的失败由提权流程处理，只在其他平台上按探测结果提前拒绝
+	if info, err := fsinfo.For(normalizedFake); err == nil && info.Probed && !info.Symlink && runtime.GOOS != "windows" {
+		return fmt.Errorf("卷 %s（%s）不支持符号链接，可改用 flk create hardlink 或 flk create template", info.Volume, info.Type)
+	}
+	txn, err := beginCreate(normalizedFake, force)
+	if err != nil {
+		return err
+	}
+	absolute := createLinkStyle == config.LinkStyleAbsolute
+	if err := symlink.CreateWithStyle(normalizedReal, normalizedFake, force, absolute); err != nil {
+		if !elevate.Needed(err) {
+			return txn.abort(interference.Explain(normalizedFake, err))
+		}
+		// 子进程只创建链接，记录仍由本进程写入存储
+		if err := createSymlinkElevated(normalizedReal, normalizedFake, force, absolute); err != nil {
+			return txn.abort(err)
+		}
+	}
+	if err := fileperm.Apply(normalizedFake, createMode); err != nil {
+		warnCreate("链接已创建，但设置权限失败：%v", err)
+	}
+	if err := fileattr.Set(normalizedFake, attrib); err != nil {
+		warnCreate("链接已创建，但设置文件属性失败：%v", err)
+	}
+	createAttrib = attrib.String()
+	if err := recordSymlink(normalizedReal, normalizedFake); err != nil {
+		return txn.abort(fmt.Errorf("写入存储失败，已撤销创建的链接: %v", err))
+	}
+	txn.commit()
+	return nil
+}
+
+// recordSymlink 将符号链接记录持久化到存储
+func recordSymlink(normalizedReal, normalizedFake string) error {
+	absRealPath, _ := pathutil.ToAbsolute(normalizedReal)
+	absFakePath, _ := pathutil.ToAbsolute(normalizedFake)
+	fields := map[string]string{
+		"real":          absRealPath,
+		"fake":          absFakePath,
+		store.SourceKey: store.SourceCLI,
+	}
+	addOptionFields(fields)
+	if createAttrib != "" {
+		fields[store.AttribKey] = createAttrib
+	}
+	if createLinkStyle != "" {
+		fields[store.LinkStyleKey] = createLinkStyle
+	}
+	return saveRecord("symlink", fields)
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/create/symlink"
+	"github.com/jy-eggroll/flk/internal/fileattr"
+	"github.com/jy-eggroll/flk/internal/fileperm"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/jy-eggroll/flk/internal/trace"
+	"github.com/spf13/cobra"
+)
+
+var symlinkFakeDir string
+
+func init() {
+	symlinkCmd.Flags().StringVar(&symlinkFakeDir, "fake-dir", "", "批量创建：将 --real 作为通配符展开，在该目录下为每个匹配项创建同名链接")
+	symlinkCmd.MarkFlagsMutuallyExclusive("fake", "fake-dir")
+}
+
+// symlinkMapping 批量创建中的一个链接
+type symlinkMapping struct {
+	real string
+	fake string
+}
+
+// symlinkMap 展开 --real 通配符，在 --fake-dir 下为每个匹配项创建同名符号链接，每个链接作为一条单独的记录
+func symlinkMap(cmd *cobra.Command) error {
+	format := output.OutputFormat(outputFormat)
+	if symlinkReal == "" {
+		return errors.New("--fake-dir 需要同时用 --real 指定通配符，如 'configs/*.toml'")
+	}
+	pattern, err := pathutil.NormalizePath(symlinkReal)
+	if err != nil {
+		return fmt.Errorf("真实文件路径标准化失败 %v", err)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("无效的通配符 %s: %v", symlinkReal, err)
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("%s 没有匹配的文件", symlinkReal)
+	}
+	fakeDir, err := pathutil.NormalizePath(symlinkFakeDir)
+	if err != nil {
+		return fmt.Errorf("链接目录路径标准化失败 %v", err)
+	}
+
+	if err := parseCreateOptions(); err != nil {
+		return err
+	}
+	if createMode != "" {
+		if _, err := fileperm.Parse(createMode); err != nil {
+			return err
+		}
+	}
+	if _, err := fileattr.Parse(createAttrib); err != nil {
+		return err
+	}
+	// 目录规则按每个链接的位置分别生效，每项开始前恢复为命令行指定的选项，避免上一项匹配的规则带到后续链接
+	opts := mappedCreateOptions{mode: createMode, attrib: createAttrib, every: createEvery, linkStyle: createLinkStyle, dirLink: createDirLink}
+	defer opts.restore()
+
+	var mappings []symlinkMapping
+	for _, match := range matches {
+		real, _ := pathutil.ToAbsolute(match)
+		fake, _ := pathutil.ToAbsolute(filepath.Join(fakeDir, filepath.Base(match)))
+		mappings = append(mappings, symlinkMapping{real, fake})
+	}
+
+	mgr, err := store.Current()
+	if err != nil {
+		return err
+	}
+	tracked := make(map[string]bool)
+	for _, l := range recordLinks(mgr, "") {
+		tracked[l.Link] = true
+	}
+
+	var results []output.ApplyResult
+	failed := 0
+	for _, m := range mappings {
+		result := output.ApplyResult{Action: "add", Type: "symlink", Link: m.fake, Success: true}
+		var err error
+		switch {
+		case !mappedLinkExists(m):
+			err = createMappedSymlink(cmd, m, opts)
+		case tracked[m.fake]:
+			result.Action = "none"
+		default:
+			// 链接已正确指向真实文件但没有记录，与 adopt 相同地收编
+			result.Action = "adopt"
+			err = adoptMappedSymlink(cmd, m, opts)
+		}
+		if err != nil {
+			result.Success, result.Error = false, err.Error()
+			failed++
+		}
+		results = append(results, result)
+	}
+	if err := output.PrintApplyResults(format, results); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个链接创建失败", failed)
+	}
+	return nil
+}
+
+// mappedCreateOptions 批量创建时命令行指定的、可被目录规则补充的选项
+type mappedCreateOptions struct {
+	mode, attrib, every, linkStyle, dirLink string
+}
+
+// restore 将创建选项恢复为命令行指定的值
+func (o mappedCreateOptions) restore() {
+	createMode, createAttrib, createEvery = o.mode, o.attrib, o.every
+	createLinkStyle, createDirLink = o.linkStyle, o.dirLink
+}
+
+// createMappedSymlink 创建批量中的一个符号链接并写入存储，链接文件已存在且未指定 --force 时跳过
+func createMappedSymlink(cmd *cobra.Command, m symlinkMapping, opts mappedCreateOptions) error {
+	opts.restore()
+	applyLinkRules(cmd, m.fake)
+	if err := parseCreateOptions(); err != nil {
+		return err
+	}
+	if createMode != "" {
+		if _, err := fileperm.Parse(createMode); err != nil {
+			return err
+		}
+	}
+	attrib, err := fileattr.Parse(createAttrib)
+	if err != nil {
+		return err
+	}
+	if err := checkStrict(m.real, m.fake); err != nil {
+		return err
+	}
+	warnSelfReference(m.fake)
+	replaced := pathExists(m.fake)
+	if replaced {
+		if !createForce {
+			return errors.New("链接文件已存在，使用 --force 覆盖")
+		}
+		if !confirmForceDelete(m.fake) {
+			return errors.New("已取消覆盖 " + m.fake)
+		}
+		if existing, err := fileattr.Get(m.fake); err == nil {
+			attrib |= existing
+		}
+	}
+	if err := trace.MkdirAll(filepath.Dir(m.fake), 0755); err != nil {
+		return err
+	}
+	if createDirLink == config.DirLinkJunction && junctionFor(m.real) {
+		if err := createJunctionTxn(m.real, m.fake, createForce); err != nil {
+			return err
+		}
+		auditLink(replaced, "junction", m.fake, m.real)
+		return nil
+	}
+	if err := createSymlinkTxn(m.real, m.fake, createForce, attrib); err != nil {
+		return err
+	}
+	auditLink(replaced, "symlink", m.fake, m.real)
+	return nil
+}
+
+// adoptMappedSymlink 为已指向真实文件但没有记录的链接写入记录，选项按目录规则补充，与新建时相同
+func adoptMappedSymlink(cmd *cobra.Command, m symlinkMapping, opts mappedCreateOptions) error {
+	opts.restore()
+	applyLinkRules(cmd, m.fake)
+	if err := parseCreateOptions(); err != nil {
+		return err
+	}
+	logger.Info("收编符号链接 real=" + m.real + ", fake=" + m.fake)
+	return recordSymlink(m.real, m.fake)
+}
+
+// mappedLinkExists 判断链接文件是否已是指向该真实文件的符号链接，这样的项在批量创建时跳过
+func mappedLinkExists(m symlinkMapping) bool {
+	info, err := os.Lstat(m.fake)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return false
+	}
+	conflict, err := symlink.DetectConflict(m.real, m.fake)
+	return err == nil && conflict == nil
+}
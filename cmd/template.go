@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jy-eggroll/flk/internal/create/template"
+	"github.com/jy-eggroll/flk/internal/fileperm"
+	"github.com/jy-eggroll/flk/internal/interference"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var (
+	templateReal string
+	templateFake string
+)
+
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "渲染模板文件到目标路径（适用于不能在设备间原样共享的文件）",
+	Long:  "以设备变量渲染模板文件并写入目标路径，模板使用 Go text/template 语法，可用变量有 .Device .Hostname .Platform .User .Home；\n秘密可通过 {{ env \"NAME\" }} 读取环境变量或 {{ cmd \"op\" \"read\" \"op://...\" }} 读取外部命令输出，只写入渲染结果（权限 600），不会保存到存储中；\nflk check 不执行 cmd 中的命令，使用 cmd 的模板只检查渲染结果是否存在，需要更新时使用 flk create template --force 或 flk apply 重新渲染",
+	RunE:  Template,
+}
+
+func init() {
+	createCmd.AddCommand(templateCmd)
+	templateCmd.Flags().StringVarP(&templateReal, "real", "r", "", "模板文件路径")
+	templateCmd.Flags().StringVarP(&templateFake, "fake", "f", "", "渲染结果路径")
+	templateCmd.Flags().BoolVar(&createForce, "force", false, "强制覆盖已存在的文件或文件夹")
+	templateCmd.Flags().StringVarP(&createDevice, "device", "d", "all", "设备名称，用于后续设备过滤")
+	templateCmd.Flags().StringVar(&createMode, "mode", "", "创建后将目标文件权限设置为该值（八进制，如 600），Windows 上忽略")
+	templateCmd.Flags().BoolVar(&createHydrate, "hydrate", false, "源文件为云端占位文件时允许触发下载后继续")
+	templateCmd.Flags().StringVar(&createEvery, "check-every", "", "检查频率提示，如 1h、7d，配合 check --due-only 减少对网络驱动器等昂贵路径的检查")
+	templateCmd.Flags().StringVar(&createValidate, "validate", "", "修复前对真实文件的校验，逗号分隔，可选 nonempty、json、toml、yaml，最后可跟 cmd:<命令>（文件路径在环境变量 FLK_FILE 中）")
+	templateCmd.Flags().StringVar(&createNote, "note", "", "记录的备注，如为什么链接指向非常规位置，检查时随结果显示")
+	templateCmd.MarkFlagRequired("real")
+	templateCmd.MarkFlagRequired("fake")
+}
+
+func Template(cmd *cobra.Command, args []string) error {
+	format := output.OutputFormat(outputFormat)
+
+	normalizedReal, err := pathutil.NormalizePath(templateReal)
+	if err != nil {
+		result := output.CreateResult{Success: false, Type: "模板", Error: "模板文件路径标准化失败 " + err.Error()}
+		printCreateResult(format, result)
+		return errors.New(result.Error)
+	}
+
+	normalizedFake, err := pathutil.NormalizePath(templateFake)
+	if err != nil {
+		result := output.CreateResult{Success: false, Type: "模板", Error: "渲染结果路径标准化失败 " + err.Error()}
+		printCreateResult(format, result)
+		return errors.New(result.Error)
+	}
+
+	logger.Info("渲染模板 real=" + normalizedReal + ", fake=" + normalizedFake)
+
+	applyLinkRules(cmd, normalizedFake)
+
+	if err := parseCreateOptions(); err != nil {
+		result := output.CreateResult{Success: false, Type: "模板", Error: err.Error()}
+		printCreateResult(format, result)
+		return err
+	}
+
+	if createMode != "" {
+		if _, err := fileperm.Parse(createMode); err != nil {
+			result := output.CreateResult{Success: false, Type: "模板", Error: err.Error()}
+			printCreateResult(format, result)
+			return err
+		}
+	}
+
+	if err := checkStrict(normalizedReal, normalizedFake); err != nil {
+		result := output.CreateResult{Success: false, Type: "模板", Error: err.Error()}
+		printCreateResult(format, result)
+		return err
+	}
+	warnSelfReference(normalizedFake)
+
+	if err := ensureHydrated(normalizedReal); err != nil {
+		result := output.CreateResult{Success: false, Type: "模板", Error: err.Error()}
+		printCreateResult(format, result)
+		return err
+	}
+
+	if createForce && !confirmForceDelete(normalizedFake) {
+		result := output.CreateResult{Success: false, Type: "模板", Error: "已取消覆盖 " + normalizedFake}
+		printCreateResult(format, result)
+		return errors.New(result.Error)
+	}
+
+	replaced := createForce && pathExists(normalizedFake)
+	var result output.CreateResult
+	if err := createTemplateTxn(normalizedReal, normalizedFake); err != nil {
+		result = output.CreateResult{Success: false, Type: "模板", Error: err.Error()}
+	} else {
+		result = output.CreateResult{Success: true, Type: "模板", Message: "渲染成功"}
+		auditLink(replaced, "template", normalizedFake, normalizedReal)
+	}
+	result.Warnings = createWarnings
+	printCreateResult(format, result)
+	if result.Success {
+		return nil
+	}
+	return errors.New(result.Error)
+}
+
+// createTemplateTxn 渲染模板并写入存储，写入存储失败时删除渲染结果并恢复被覆盖的原文件
+func createTemplateTxn(normalizedReal, normalizedFake string) error {
+	txn, err := beginCreate(normalizedFake, createForce)
+	if err != nil {
+		return err
+	}
+	if err := template.Create(normalizedReal, normalizedFake, template.DeviceVars(createDevice), createForce); err != nil {
+		return txn.abort(interference.Explain(normalizedFake, err))
+	}
+	if err := fileperm.Apply(normalizedFake, createMode); err != nil {
+		warnCreate("渲染结果已写入，但设置权限失败：%v", err)
+	}
+	absRealPath, _ := pathutil.ToAbsolute(normalizedReal)
+	absFakePath, _ := pathutil.ToAbsolute(normalizedFake)
+	fields := map[string]string{
+		"real":          absRealPath,
+		"fake":          absFakePath,
+		store.SourceKey: store.SourceCLI,
+	}
+	addOptionFields(fields)
+	if err := saveRecord("template", fields); err != nil {
+		return txn.abort(fmt.Errorf("写入存储失败，已撤销渲染结果: %v", err))
+	}
+	txn.commit()
+	return nil
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/trash"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "管理 --force 覆盖前移走的文件",
+	Long: `create、fix、apply 等使用 --force 覆盖已存在的文件或文件夹时，原文件不会被删除，而是移到 ` + trash.DefaultDir + ` 下以删除时间命名的目录中，
+可以用 flk trash restore 恢复到原位置；被覆盖的符号链接不会进入回收站`,
+}
+
+var trashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出回收站中的文件",
+	Args:  cobra.NoArgs,
+	RunE:  RunTrashList,
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:          "restore <编号>...",
+	Short:        "将回收站中的文件恢复到原位置，原位置已存在文件时跳过",
+	Args:         cobra.MinimumNArgs(1),
+	RunE:         RunTrashRestore,
+	SilenceUsage: true,
+}
+
+var trashEmptyCmd = &cobra.Command{
+	Use:          "empty",
+	Short:        "永久删除回收站中的文件",
+	Args:         cobra.NoArgs,
+	RunE:         RunTrashEmpty,
+	SilenceUsage: true,
+}
+
+var trashOlderThan time.Duration
+
+func init() {
+	rootCmd.AddCommand(trashCmd)
+	trashCmd.AddCommand(trashListCmd)
+	trashCmd.AddCommand(trashRestoreCmd)
+	trashCmd.AddCommand(trashEmptyCmd)
+	trashEmptyCmd.Flags().DurationVar(&trashOlderThan, "older-than", 0, "只删除移入回收站超过该时长的文件，如 720h")
+}
+
+// RunTrashList 列出回收站中的文件
+func RunTrashList(cmd *cobra.Command, args []string) error {
+	items, err := trash.List(trashDir())
+	if err != nil {
+		return err
+	}
+	if output.OutputFormat(outputFormat) == output.JSON {
+		if items == nil {
+			items = []trash.Item{}
+		}
+		data, err := json.MarshalIndent(items, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	if len(items) == 0 {
+		fmt.Println("回收站是空的")
+		return nil
+	}
+	table := pterm.TableData{{"编号", "原位置", "移入时间"}}
+	for _, item := range items {
+		table = append(table, []string{item.ID, item.Path, item.DeletedAt.Format("2006-01-02 15:04:05")})
+	}
+	return output.RenderTable(table)
+}
+
+// RunTrashRestore 将指定编号的文件移回原位置
+func RunTrashRestore(cmd *cobra.Command, args []string) error {
+	failed := 0
+	for _, id := range args {
+		item, err := trash.Restore(trashDir(), id)
+		if err != nil {
+			logger.Error("无法恢复 " + id + " " + err.Error())
+			failed++
+			continue
+		}
+		logger.Info("已恢复 " + item.Path)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个文件未能恢复", failed)
+	}
+	return nil
+}
+
+// RunTrashEmpty 确认后永久删除回收站中的文件
+func RunTrashEmpty(cmd *cobra.Command, args []string) error {
+	var before time.Time
+	msg := "将永久删除回收站中的全部文件，是否继续"
+	if trashOlderThan > 0 {
+		before = time.Now().Add(-trashOlderThan)
+		msg = fmt.Sprintf("将永久删除移入回收站超过 %s 的文件，是否继续", trashOlderThan)
+	}
+	if !confirm("trash", msg, false) {
+		return fmt.Errorf("已取消清空回收站")
+	}
+	n, err := trash.Empty(trashDir(), before)
+	if err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("已永久删除 %d 个文件", n))
+	return nil
+}
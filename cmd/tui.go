@@ -0,0 +1,377 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"atomicgo.dev/keyboard"
+	"atomicgo.dev/keyboard/keys"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "全屏交互式查看和处理链接记录",
+	Long: `全屏列出当前平台上的全部记录及其有效性，并按 --interval 定期重新检查。
+方向键选择记录，d、t、e 分别按设备、类型、状态（全部、无效或某一错误类型）循环筛选；
+f 修复选中的记录，x 删除选中的链接及其记录，o 在文件管理器中打开链接所在的文件夹，r 立即重新检查，q 退出。
+需要删除或合并文件的修复和删除操作需要再按 y 确认，每次修复和删除都会写入操作日志，可用 flk undo 撤销`,
+	Args:         cobra.NoArgs,
+	RunE:         RunTUI,
+	SilenceUsage: true,
+}
+
+var (
+	tuiDevice   string
+	tuiInterval time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+	tuiCmd.Flags().StringVarP(&tuiDevice, "device", "d", "", "启动时只显示该设备的记录，之后可按 d 切换")
+	tuiCmd.Flags().DurationVar(&tuiInterval, "interval", 5*time.Second, "重新检查全部记录的间隔")
+}
+
+// tuiModel flk tui 的状态，检查、按键处理和渲染都在 mu 保护下进行
+type tuiModel struct {
+	mu      sync.Mutex
+	area    *pterm.AreaPrinter
+	results []output.CheckResult
+	checked time.Time
+	// device、linkType、status 当前的筛选条件，空表示全部；status 为 invalid 表示全部无效记录，否则为错误类型
+	device, linkType, status string
+	cursor, offset           int
+	message                  string
+	// pending 等待按 y 确认的操作
+	pending func()
+}
+
+// RunTUI 启动全屏界面，直到按下 q、Esc 或 Ctrl+C
+func RunTUI(cmd *cobra.Command, args []string) error {
+	if !isInteractive() {
+		return errors.New("flk tui 需要在交互式终端中运行")
+	}
+	interval := max(tuiInterval, time.Second)
+
+	// 日志会打乱全屏画面，操作结果改为显示在状态行中
+	logger.SetWriter(io.Discard)
+	defer logger.SetWriter(output.ASCIIWriter(os.Stderr))
+
+	area, err := pterm.DefaultArea.WithFullscreen().WithRemoveWhenDone().Start()
+	if err != nil {
+		return err
+	}
+	defer area.Stop()
+
+	m := &tuiModel{area: area, device: tuiDevice}
+	m.mu.Lock()
+	m.refresh()
+	m.render()
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				m.mu.Lock()
+				m.refresh()
+				m.render()
+				m.mu.Unlock()
+			}
+		}
+	}()
+
+	return keyboard.Listen(func(key keys.Key) (bool, error) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		stop := m.handle(key)
+		if !stop {
+			m.render()
+		}
+		return stop, nil
+	})
+}
+
+// refresh 重新读取存储并检查全部记录，按链接路径排序使画面稳定
+func (m *tuiModel) refresh() {
+	if err := store.InitStore(store.StorePath); err != nil {
+		m.message = pterm.Red("读取存储失败 " + err.Error())
+		return
+	}
+	results, err := performCheck(CheckOptions{})
+	if err != nil {
+		m.message = pterm.Red("检查失败 " + err.Error())
+		return
+	}
+	sort.SliceStable(results, func(i, j int) bool { return resultLink(results[i]) < resultLink(results[j]) })
+	m.results = results
+	m.checked = time.Now()
+}
+
+// visible 返回符合筛选条件的记录在 results 中的下标
+func (m *tuiModel) visible() []int {
+	var idx []int
+	for i, r := range m.results {
+		if (m.device != "" && r.Device != m.device) || (m.linkType != "" && r.Type != m.linkType) {
+			continue
+		}
+		switch m.status {
+		case "":
+		case "invalid":
+			if r.Valid {
+				continue
+			}
+		default:
+			if r.ErrorType != m.status {
+				continue
+			}
+		}
+		idx = append(idx, i)
+	}
+	return idx
+}
+
+// selected 返回选中的记录及其在 results 中的下标
+func (m *tuiModel) selected() (output.CheckResult, int, bool) {
+	idx := m.visible()
+	if m.cursor < 0 || m.cursor >= len(idx) {
+		return output.CheckResult{}, 0, false
+	}
+	return m.results[idx[m.cursor]], idx[m.cursor], true
+}
+
+func (m *tuiModel) render() {
+	idx := m.visible()
+	rows := max(pterm.GetTerminalHeight()-10, 3)
+	m.cursor = max(min(m.cursor, len(idx)-1), 0)
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+rows {
+		m.offset = m.cursor - rows + 1
+	}
+	d := output.Dashboard{
+		Total: len(m.results), Cursor: m.cursor, Offset: m.offset, Rows: rows, Width: pterm.GetTerminalWidth(),
+		Device: m.device, Type: m.linkType, Status: m.status, Checked: m.checked, Message: m.message,
+	}
+	for _, r := range m.results {
+		if !r.Valid {
+			d.Invalid++
+		}
+	}
+	for _, i := range idx {
+		r := m.results[i]
+		target := r.Real
+		if r.Type == "hardlink" {
+			target = r.Prim
+		}
+		d.Results = append(d.Results, r)
+		d.Links = append(d.Links, resolveRecordPath(resultLink(r), r.BasePath))
+		d.Targets = append(d.Targets, resolveRecordPath(target, r.BasePath))
+	}
+	m.area.Update(output.RenderDashboard(d))
+}
+
+// handle 处理一次按键，返回 true 表示退出
+func (m *tuiModel) handle(key keys.Key) bool {
+	if pending := m.pending; pending != nil {
+		m.pending = nil
+		if key.Code == keys.RuneKey && string(key.Runes) == "y" {
+			pending()
+		} else {
+			m.message = "已取消"
+		}
+		return false
+	}
+	switch key.Code {
+	case keys.CtrlC, keys.Escape:
+		return true
+	case keys.Up:
+		m.cursor--
+	case keys.Down:
+		m.cursor++
+	case keys.PgUp:
+		m.cursor -= max(pterm.GetTerminalHeight()-10, 3)
+	case keys.PgDown:
+		m.cursor += max(pterm.GetTerminalHeight()-10, 3)
+	case keys.Home:
+		m.cursor = 0
+	case keys.End:
+		m.cursor = len(m.results)
+	case keys.Delete:
+		m.confirmDelete()
+	case keys.RuneKey:
+		switch string(key.Runes) {
+		case "q":
+			return true
+		case "k":
+			m.cursor--
+		case "j":
+			m.cursor++
+		case "d":
+			m.device = cycleFilter(m.options(func(r output.CheckResult) string { return r.Device }), m.device)
+			m.cursor = 0
+		case "t":
+			m.linkType = cycleFilter(m.options(func(r output.CheckResult) string { return r.Type }), m.linkType)
+			m.cursor = 0
+		case "e":
+			errorTypes := m.options(func(r output.CheckResult) string { return r.ErrorType })
+			m.status = cycleFilter(append([]string{"invalid"}, errorTypes...), m.status)
+			m.cursor = 0
+		case "f":
+			m.confirmFix()
+		case "x":
+			m.confirmDelete()
+		case "o":
+			m.open()
+		case "r":
+			m.refresh()
+			m.message = "已重新检查"
+		}
+	}
+	return false
+}
+
+// options 返回记录中 field 出现过的非空取值，按字母顺序排列
+func (m *tuiModel) options(field func(output.CheckResult) string) []string {
+	var values []string
+	for _, r := range m.results {
+		if v := field(r); v != "" && !slices.Contains(values, v) {
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// cycleFilter 返回筛选条件的下一个取值，依次为全部（空）和 options 中的各项
+func cycleFilter(options []string, current string) string {
+	i := slices.Index(options, current)
+	if i+1 < len(options) {
+		return options[i+1]
+	}
+	return ""
+}
+
+// confirmFix 修复选中的记录，修复需要删除或合并文件时先请求确认
+func (m *tuiModel) confirmFix() {
+	r, idx, ok := m.selected()
+	if !ok {
+		return
+	}
+	if r.Valid {
+		m.message = "记录有效，无需修复"
+		return
+	}
+	link := resolveRecordPath(resultLink(r), r.BasePath)
+	if err := unsafeToFix(r); err != nil {
+		m.message = pterm.Yellow(err.Error() + "，按 y 仍然修复 " + link + "，其他键取消")
+		m.pending = func() { m.fix(r, idx, link) }
+		return
+	}
+	m.fix(r, idx, link)
+}
+
+func (m *tuiModel) fix(r output.CheckResult, idx int, link string) {
+	// 需要确认的修复已在界面中确认过，修复过程中的提示不能在全屏界面中显示
+	oldYes := assumeYes
+	assumeYes = true
+	createResultHook = func(output.CreateResult) {}
+	defer func() {
+		assumeYes = oldYes
+		resetCreateOptions()
+	}()
+	err := journalAction("flk tui fix "+link, func() error { return repairResult(r, idx) })
+	if err != nil {
+		m.message = pterm.Red("修复 " + link + " 失败：" + err.Error())
+		return
+	}
+	m.refresh()
+	m.message = pterm.Green("已修复 " + link)
+}
+
+// confirmDelete 请求确认后删除选中的链接及其记录
+func (m *tuiModel) confirmDelete() {
+	r, _, ok := m.selected()
+	if !ok {
+		return
+	}
+	link := resolveRecordPath(resultLink(r), r.BasePath)
+	m.message = pterm.Yellow("按 y 删除 " + link + " 及其记录，其他键取消")
+	m.pending = func() { m.delete(r, link) }
+}
+
+func (m *tuiModel) delete(r output.CheckResult, link string) {
+	err := journalAction("flk tui delete "+link, func() error {
+		mgr, err := store.Current()
+		if err != nil {
+			return err
+		}
+		for _, t := range selectDeleteTargets(mgr, []string{link}) {
+			if t.device != r.Device || t.linkType != r.Type {
+				continue
+			}
+			if err := deleteLink(mgr, t); err != nil {
+				return err
+			}
+			return mgr.Save(store.StorePath)
+		}
+		return errors.New("存储中没有该记录")
+	})
+	if err != nil {
+		m.message = pterm.Red("删除 " + link + " 失败：" + err.Error())
+		return
+	}
+	m.refresh()
+	m.message = pterm.Green("已删除 " + link)
+}
+
+// open 在文件管理器中打开选中记录的链接所在的文件夹
+func (m *tuiModel) open() {
+	r, _, ok := m.selected()
+	if !ok {
+		return
+	}
+	dir := filepath.Dir(resolveRecordPath(resultLink(r), r.BasePath))
+	if err := openFolder(dir); err != nil {
+		m.message = pterm.Red("无法打开 " + dir + "：" + err.Error())
+		return
+	}
+	m.message = "已打开 " + dir
+}
+
+// openFolder 用系统的文件管理器打开 dir，不等待其退出
+func openFolder(dir string) error {
+	var c *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		c = exec.Command("explorer", dir)
+	case "darwin":
+		c = exec.Command("open", dir)
+	default:
+		c = exec.Command("xdg-open", dir)
+	}
+	if err := c.Start(); err != nil {
+		return fmt.Errorf("%s: %v", c.Path, err)
+	}
+	go c.Wait()
+	return nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/journal"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "撤销最近一次修改链接的操作",
+	Long: `create、delete、fix、check --fix、apply、prune 执行时会把对文件系统和存储的改动记录到配置文件所在目录的 ` + journal.FileName + `，
+flk undo 按相反顺序撤销最近一次尚未撤销的操作：删除新建的链接、从备份恢复被覆盖或删除的文件，并将存储恢复为操作前的内容；多次执行可依次撤销更早的操作。
+被删除的文件与配置目录不在同一卷上时无法备份，对应的改动不能撤销。已被其他程序改动过的位置不会被覆盖`,
+	Args:         cobra.NoArgs,
+	RunE:         RunUndo,
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+}
+
+// journalRecorder 当前命令的操作记录，命令不修改链接时为 nil
+var journalRecorder *journal.Recorder
+
+// journalPath 返回操作日志路径
+func journalPath() string {
+	return filepath.Join(filepath.Dir(mustNormalize(configPath)), journal.FileName)
+}
+
+// beginJournal 为会修改链接的命令开始记录操作，--dry-run 时不记录
+func beginJournal(cmd *cobra.Command, args []string) {
+	if !dryRunSupported(cmd) {
+		return
+	}
+	words := append([]string{cmd.CommandPath()}, args...)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		// 全局参数与操作内容无关
+		if cmd.Root().PersistentFlags().Lookup(f.Name) == nil {
+			words = append(words, "--"+f.Name+"="+f.Value.String())
+		}
+	})
+	command := strings.Join(words, " ")
+	journalRecorder = journal.Begin(journalPath(), command, mustNormalize(store.StorePath))
+}
+
+// finishJournal 结束当前命令的操作记录，写入失败只记录警告
+func finishJournal() {
+	if journalRecorder == nil {
+		return
+	}
+	if err := journalRecorder.Finish(); err != nil {
+		logger.Warn("写入操作日志失败 " + err.Error())
+	}
+	journalRecorder = nil
+}
+
+// journalAction 将 fn 作为一次单独的操作记录到操作日志，用于 flk tui 等在一条命令中执行多次修改的场景
+func journalAction(command string, fn func() error) error {
+	journalRecorder = journal.Begin(journalPath(), command, mustNormalize(store.StorePath))
+	defer finishJournal()
+	return fn()
+}
+
+// RunUndo 撤销最近一次尚未撤销的操作
+func RunUndo(cmd *cobra.Command, args []string) error {
+	path := journalPath()
+	entries, err := journal.Load(path)
+	if err != nil {
+		return err
+	}
+	i := journal.Last(entries)
+	if i < 0 {
+		return fmt.Errorf("没有可以撤销的操作")
+	}
+	e := entries[i]
+	if e.StoreModified() && !confirm("undo", fmt.Sprintf("存储 %s 在 %s 之后又被修改过，撤销将丢失这些修改，是否继续", e.StorePath, e.Command), false) {
+		return fmt.Errorf("已取消撤销")
+	}
+	errs := journal.Revert(e)
+	for _, err := range errs {
+		logger.Warn("无法撤销 " + err.Error())
+	}
+	if err := journal.MarkUndone(path, entries, i); err != nil {
+		return err
+	}
+	// flk shell 中下一条命令重新加载已恢复的存储
+	loadedStorePath = ""
+	msg := fmt.Sprintf("已撤销 %s（%s）", e.Command, e.Time.Format("2006-01-02 15:04:05"))
+	if len(errs) > 0 {
+		msg += fmt.Sprintf("，%d 项改动未能撤销", len(errs))
+	}
+	return output.PrintCreateResult(output.OutputFormat(outputFormat), output.CreateResult{Success: len(errs) == 0, Type: "撤销", Message: msg})
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/trace"
+	"github.com/pterm/pterm"
+)
+
+// findUntrackedLinks 扫描已有记录的链接文件所在目录（不递归），找出指向已记录源文件但本身没有记录的符号链接，
+// 如手动创建或在其他工作目录下由提权子进程创建的链接
+func findUntrackedLinks(results []output.CheckResult) []output.CheckResult {
+	tracked := make(map[string]bool)   // 已记录的链接文件
+	targets := make(map[string]string) // 已记录的源文件 -> 所属设备
+	dirs := make(map[string]bool)
+	for _, r := range results {
+		if r.ErrorType == "ROOT_UNDEFINED" {
+			continue
+		}
+		link, target := r.Fake, r.Real
+		if r.Type == "hardlink" {
+			link, target = r.Seco, r.Prim
+		}
+		// 链接文件的相对路径始终相对于父路径，源根目录只作用于源文件
+		absLink := resolveRecordPath(link, mustNormalize(r.Path))
+		tracked[absLink] = true
+		dirs[filepath.Dir(absLink)] = true
+		if r.Type == "symlink" {
+			abs := resolveRecordPath(target, r.BasePath)
+			if _, ok := targets[abs]; !ok {
+				targets[abs] = r.Device
+			}
+		}
+	}
+
+	var sorted []string
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	sort.Strings(sorted)
+
+	var untracked []output.CheckResult
+	for _, dir := range sorted {
+		if _, ok := selfReference(dir); ok {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.Type()&os.ModeSymlink == 0 {
+				continue
+			}
+			link := filepath.Join(dir, e.Name())
+			if tracked[link] {
+				continue
+			}
+			target, err := trace.Readlink(link)
+			if err != nil {
+				continue
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(dir, target)
+			}
+			target = filepath.Clean(target)
+			device, ok := targets[target]
+			if !ok {
+				continue
+			}
+			folded, _ := pathutil.FoldHome(dir)
+			untracked = append(untracked, output.CheckResult{
+				Type:      "symlink",
+				Device:    device,
+				Path:      folded,
+				BasePath:  dir,
+				Real:      target,
+				Fake:      link,
+				Error:     "符号链接 " + link + " 指向已记录的 " + target + "，但本身没有记录",
+				ErrorType: "UNTRACKED",
+			})
+		}
+	}
+	return untracked
+}
+
+// offerAdoption 逐个询问是否收编未记录的符号链接，收编时使用指向同一源文件的记录所属的设备
+func offerAdoption(results []output.CheckResult) {
+	oldDevice := createDevice
+	defer func() { createDevice = oldDevice }()
+	for _, r := range results {
+		if r.ErrorType != "UNTRACKED" {
+			continue
+		}
+		if !confirm("adopt", "收编 "+r.Fake+" -> "+r.Real+"？", false) {
+			continue
+		}
+		createDevice = r.Device
+		if _, err := adoptSymlink(r.Fake); err != nil {
+			logger.Error("收编失败 " + err.Error())
+			continue
+		}
+		pterm.Success.Println("已收编 " + r.Fake)
+	}
+}
@@ -0,0 +1,339 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/state"
+	"github.com/jy-eggroll/flk/internal/status"
+	"github.com/jy-eggroll/flk/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "持续监视记录中的链接，在链接被删除或真实文件移回时自动重建",
+	Long: `持续监视当前平台记录中的链接文件和真实文件，发现变化并在 --debounce 内不再变化后检查这些记录，
+自动修复可安全修复的链接（与 check --fix 相同：重建缺失的链接、替换指向错误的链接），真实文件暂时缺失的记录会在它移回后修复。
+监视通过文件系统通知监听链接文件和真实文件所在的目录（所在目录不存在时监听最近的上级目录），存储文件变化时重新加载记录，
+flk 自身数据目录中的变化（状态文件、修复记录等）不会触发检查；网络驱动器等不发送通知的位置可用 --interval 定期检查。
+与 check --due-only 相同，声明了检查频率的记录只在到期后检查；修复遵循配置文件中的 repair 限流策略和 blackouts 静默时段，
+每轮检查后按配置更新链接状态镜像和 MQTT，每次修复以 JSON 行追加到存储文件所在目录的 ` + watchLogName + `。按 Ctrl+C 停止`,
+	Args: cobra.NoArgs,
+	RunE: RunWatch,
+}
+
+// watchLogName 修复记录文件的名称，位于存储文件所在目录
+const watchLogName = "watch.jsonl"
+
+var (
+	watchDevice   string
+	watchInterval time.Duration
+	watchDebounce time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().StringVarP(&watchDevice, "device", "d", "", "只监视该设备的记录，默认监视全部设备")
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 0, "除文件系统通知外定期比较链接状态的间隔，用于不发送通知的网络驱动器，0 表示只依赖通知")
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", time.Second, "发现变化后等待文件不再变化的时间，避免在编辑器保存或同步过程中修复")
+}
+
+// watchEntry 修复记录文件中的一行
+type watchEntry struct {
+	Time      string `json:"time"`
+	Type      string `json:"type"`
+	Device    string `json:"device"`
+	Link      string `json:"link"`
+	ErrorType string `json:"error_type"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RunWatch 监听记录中的路径所在的目录，变化稳定后修复可安全修复的链接
+func RunWatch(cmd *cobra.Command, args []string) error {
+	if watchInterval < 0 || watchDebounce < 0 {
+		return fmt.Errorf("--interval 和 --debounce 不能为负数")
+	}
+	headless = true
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("无法监视文件变化：%v", err)
+	}
+	defer w.Close()
+	storeFile, err := pathutil.ToAbsolute(mustNormalize(store.StorePath))
+	if err != nil {
+		return err
+	}
+
+	logPath := filepath.Join(filepath.Dir(storeFile), watchLogName)
+	paths := watchPaths()
+	syncWatches(w, storeFile, paths)
+	logger.Info(fmt.Sprintf("开始监视 %d 个目录，修复记录写入 %s", len(w.WatchList()), logPath))
+
+	// 启动时先修复一次已有的问题
+	watchRepair(logPath)
+	syncWatches(w, storeFile, paths)
+	// 修复本身会引起文件变化，以修复后的状态为基准，状态未变时不再检查
+	stamps := watchStamps(paths)
+
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	var tick <-chan time.Time
+	if watchInterval > 0 {
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	dataDirs := flkDataDirs()
+	storeChanged := false
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("已停止监视")
+			return nil
+		case err := <-w.Errors:
+			logger.Warn(err.Error())
+		case <-tick:
+			debounce.Reset(0)
+		case e, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			switch {
+			case samePath(e.Name, storeFile):
+				storeChanged = true
+			case inDirs(e.Name, dataDirs), !watchRelevant(paths, e.Name):
+				continue
+			}
+			debounce.Reset(watchDebounce)
+		case <-debounce.C:
+			if storeChanged {
+				storeChanged = false
+				paths = watchPaths()
+			} else if maps.Equal(watchStamps(paths), stamps) {
+				continue
+			}
+			watchRepair(logPath)
+			// 记录变化或修复都可能让之前不存在的目录出现
+			syncWatches(w, storeFile, paths)
+			stamps = watchStamps(paths)
+		}
+	}
+}
+
+// watchPaths 重新加载存储并返回需要监视的链接文件和真实文件的绝对路径，位于 flk 自身数据目录中的路径除外
+func watchPaths() []string {
+	if err := store.InitStore(store.StorePath); err != nil {
+		logger.Warn(err.Error())
+		return nil
+	}
+	mgr, err := store.Current()
+	if err != nil {
+		return nil
+	}
+	dataDirs := flkDataDirs()
+	var paths []string
+	for _, l := range recordLinks(mgr, watchDevice) {
+		for _, p := range []string{l.Link, l.Target} {
+			if abs, err := pathutil.ToAbsolute(p); err == nil && !inDirs(abs, dataDirs) {
+				paths = append(paths, abs)
+			}
+		}
+	}
+	return paths
+}
+
+// inDirs 判断 path 是否位于 dirs 中的某个目录之下
+func inDirs(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if within(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileStamp 一个路径在某一时刻的状态，不存在时为零值
+type fileStamp struct {
+	exists  bool
+	mode    os.FileMode
+	size    int64
+	modTime time.Time
+	target  string // 符号链接的指向
+}
+
+// watchStamps 返回 paths 当前的状态，不跟随符号链接
+func watchStamps(paths []string) map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(paths))
+	for _, p := range paths {
+		info, err := os.Lstat(p)
+		if err != nil {
+			stamps[p] = fileStamp{}
+			continue
+		}
+		s := fileStamp{exists: true, mode: info.Mode(), size: info.Size(), modTime: info.ModTime()}
+		if info.Mode()&os.ModeSymlink != 0 {
+			s.target, _ = os.Readlink(p)
+		}
+		stamps[p] = s
+	}
+	return stamps
+}
+
+// watchRelevant 判断发生变化的 name 是否为被监视的路径或其上级目录
+func watchRelevant(paths []string, name string) bool {
+	for _, p := range paths {
+		if samePath(p, name) || within(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// syncWatches 使监视器监听存储文件和 paths 所在的目录，所在目录不存在时监听最近的已存在的上级目录，
+// 并停止监听不再需要的目录
+func syncWatches(w *fsnotify.Watcher, storeFile string, paths []string) {
+	wanted := map[string]bool{existingDir(filepath.Dir(storeFile)): true}
+	for _, p := range paths {
+		wanted[existingDir(filepath.Dir(p))] = true
+	}
+	watched := make(map[string]bool)
+	for _, dir := range w.WatchList() {
+		watched[dir] = true
+		if !wanted[dir] {
+			w.Remove(dir)
+		}
+	}
+	for dir := range wanted {
+		if watched[dir] {
+			continue
+		}
+		if err := w.Add(dir); err != nil {
+			logger.Warn(fmt.Sprintf("无法监视 %s：%v", dir, err))
+		}
+	}
+}
+
+// existingDir 返回 dir 或其最近的已存在的上级目录
+func existingDir(dir string) string {
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return filepath.Clean(dir)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// watchRepair 检查监视范围内已到检查时间的记录，修复可安全修复的无效链接并写入修复记录，最后按配置发布本轮的链接状态
+func watchRepair(logPath string) {
+	statePath := state.PathFor(store.StorePath)
+	st, err := state.Load(statePath)
+	if err != nil {
+		logger.Warn("读取状态文件失败，本次不按检查频率跳过记录，也不限制自动修复 " + err.Error())
+		st = &state.State{Records: make(map[string]state.RecordState)}
+	}
+	results, err := performCheck(CheckOptions{DeviceFilter: watchDevice, IncludeShared: implicitDevice, DueOnly: true, State: st})
+	if err != nil {
+		logger.Error("检查失败 " + err.Error())
+		return
+	}
+	now := time.Now()
+	var pending []int
+	for i, r := range results {
+		if r.ErrorType == "UNTRACKED" {
+			continue
+		}
+		st.MarkChecked(resultKey(r), now)
+		if r.Valid {
+			st.MarkValid(resultKey(r), now)
+		} else if unsafeToFix(r) == nil {
+			pending = append(pending, i)
+		}
+	}
+	defer func() {
+		if err := st.Save(statePath); err != nil {
+			logger.Warn("写入状态文件失败 " + err.Error())
+		}
+		summary := status.Summarize(results)
+		summary.GaveUp = st.GaveUpCount()
+		publishMirror(summary, results)
+		publishMQTT(summary, results)
+	}()
+	if len(pending) == 0 {
+		return
+	}
+	if b, ok := config.Global.ActiveBlackout(time.Now()); ok {
+		logger.Info(fmt.Sprintf("当前处于静默时段 %s-%s，%d 条无效链接暂不修复", b.Start, b.End, len(pending)))
+		return
+	}
+
+	policy := repairPolicy()
+	createResultHook = func(output.CreateResult) {}
+	defer resetCreateOptions()
+
+	var entries []watchEntry
+	for _, i := range pending {
+		r := results[i]
+		now := time.Now()
+		key := resultKey(r)
+		if err := st.AllowRepair(key, policy, now); err != nil {
+			logger.Debug("跳过 " + resultLink(r) + " " + err.Error())
+			continue
+		}
+		entry := watchEntry{Time: now.Format(time.RFC3339), Type: r.Type, Device: r.Device, Link: resultLink(r), ErrorType: r.ErrorType, Success: true}
+		if err := repairResult(r, i); err != nil {
+			entry.Success, entry.Error = false, err.Error()
+			logger.Warn("自动修复失败 " + entry.Link + " " + err.Error())
+		} else {
+			logger.Info("已自动修复 " + entry.Link + "（" + output.ErrorTypeLabel(r.ErrorType) + "）")
+			// 发布的状态反映修复后的结果
+			results[i].Valid, results[i].Error, results[i].ErrorType = true, "", ""
+			st.MarkValid(key, now)
+			if st.RecordRepair(key, policy, now) {
+				logger.Warn(fmt.Sprintf("%s 连续 %d 次修复后仍被改回，已放弃自动修复", entry.Link, policy.GiveUpAfter))
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err := appendWatchLog(logPath, entries); err != nil {
+		logger.Warn("写入修复记录失败 " + err.Error())
+	}
+}
+
+// appendWatchLog 将修复记录以 JSON 行追加到 path
+func appendWatchLog(path string, entries []watchEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
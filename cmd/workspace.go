@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// currentWorkspace 当前目录所在的工作区，不在任何工作区内时为 nil
+	currentWorkspace *workspace.Workspace
+	// workspaceRootAdded 表示工作区的源根目录尚未写入配置文件，仅在本次运行中生效
+	workspaceRootAdded bool
+)
+
+// initWorkspace 查找当前目录所在的工作区，将其注册为源根目录并为创建命令应用默认值
+func initWorkspace(cmd *cobra.Command) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	ws, err := workspace.Find(cwd)
+	if err != nil {
+		logger.Warn(err.Error())
+		return
+	}
+	if ws == nil {
+		return
+	}
+	currentWorkspace = ws
+	logger.Debug("位于工作区 " + ws.Dir)
+
+	cfg := config.Global
+	if existing, ok := cfg.SourceRoots[ws.Root]; ok {
+		if dir, err := pathutil.ToAbsolute(mustNormalize(existing)); err == nil && dir != ws.Dir {
+			logger.Warn("工作区 " + ws.Dir + " 的源根目录名称 " + ws.Root + " 已在配置文件中指向 " + existing + "，以配置文件为准")
+		}
+	} else {
+		if cfg.SourceRoots == nil {
+			cfg.SourceRoots = make(map[string]string)
+		}
+		cfg.SourceRoots[ws.Root] = ws.Dir
+		workspaceRootAdded = true
+	}
+	if ws.Strict {
+		cfg.Strict = true
+	}
+
+	if cmd.Parent() == createCmd && ws.Device != "" {
+		if f := cmd.Flags().Lookup("device"); f != nil && !f.Changed {
+			f.Value.Set(ws.Device)
+		}
+	}
+}
+
+// persistWorkspaceRoot 首次以工作区为源根目录写入记录时，将其写入配置文件，使在工作区外也能解析这些记录
+func persistWorkspaceRoot() {
+	if !workspaceRootAdded {
+		return
+	}
+	workspaceRootAdded = false
+	c, err := config.LoadFromFile(configPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Error("加载配置失败 " + err.Error())
+			return
+		}
+		c = &config.Config{}
+	}
+	if c.SourceRoots == nil {
+		c.SourceRoots = make(map[string]string)
+	}
+	folded, _ := pathutil.FoldHome(currentWorkspace.Dir)
+	c.SourceRoots[currentWorkspace.Root] = folded
+	if err := c.Save(configPath); err != nil {
+		logger.Error("保存配置失败 " + err.Error())
+		return
+	}
+	logger.Info("已将工作区 " + currentWorkspace.Dir + " 作为源根目录 " + currentWorkspace.Root + " 写入配置文件")
+}
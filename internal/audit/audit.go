@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"fmt"
+	"os/user"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+)
+
+// 审计事件的操作类型
+const (
+	ActionCreate  = "create"  // 新建链接
+	ActionReplace = "replace" // 删除已存在的文件后新建链接（--force）
+	ActionDelete  = "delete"  // 删除链接
+)
+
+// Enabled 控制是否向系统安全日志写入审计事件，由配置文件中的 audit 开启
+var Enabled = false
+
+// Event 一次链接变更的审计信息
+type Event struct {
+	Action string
+	Type   string
+	Link   string
+	Target string
+}
+
+// String 返回写入系统日志的单行文本
+func (e Event) String() string {
+	username := "unknown"
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	return fmt.Sprintf("flk action=%s type=%s link=%q target=%q user=%s elevated=%t",
+		e.Action, e.Type, e.Link, e.Target, username, elevated())
+}
+
+// Emit 在启用审计时写入系统安全日志（Windows 事件日志或 syslog 的 authpriv），写入失败只记录警告
+func Emit(e Event) {
+	if !Enabled {
+		return
+	}
+	if err := write(e.String()); err != nil {
+		logger.Warn("写入审计日志失败 " + err.Error())
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package audit
+
+import (
+	"log/syslog"
+	"os"
+)
+
+func elevated() bool {
+	return os.Geteuid() == 0
+}
+
+// write 写入 authpriv 设施，通常会进入 /var/log/auth.log 或 /var/log/secure，并可被 auditd 等工具转发
+func write(msg string) error {
+	w, err := syslog.New(syslog.LOG_AUTHPRIV|syslog.LOG_NOTICE, "flk")
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	return w.Notice(msg)
+}
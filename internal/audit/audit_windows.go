@@ -0,0 +1,26 @@
+//go:build windows
+
+package audit
+
+import (
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// auditEventID 写入事件日志时使用的事件 ID
+const auditEventID = 1000
+
+func elevated() bool {
+	return windows.GetCurrentProcessToken().IsElevated()
+}
+
+// write 以 flk 为来源写入应用程序事件日志，首次使用时尝试注册事件来源（需要管理员权限，失败不影响写入）
+func write(msg string) error {
+	_ = eventlog.InstallAsEventCreate("flk", eventlog.Info|eventlog.Warning|eventlog.Error)
+	l, err := eventlog.Open("flk")
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return l.Info(auditEventID, msg)
+}
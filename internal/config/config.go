@@ -0,0 +1,340 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/pathutil"
+)
+
+// 确认策略的取值
+const (
+	ConfirmAsk = "ask" // 交互询问，非交互环境下使用调用方给出的默认值
+	ConfirmYes = "yes" // 总是同意
+	ConfirmNo  = "no"  // 总是拒绝
+)
+
+// Config 用户配置，存放在 DefaultConfigPath 指定的 JSON 文件中
+type Config struct {
+	// Confirm 按命令配置确认策略，键为命令名（如 fix、force），值为 ask/yes/no
+	Confirm map[string]string `json:"confirm,omitempty"`
+	// Audit 为 true 时，每次创建、替换或删除链接都会写入系统安全日志
+	Audit bool `json:"audit,omitempty"`
+	// SourceRoots 源根目录，键为名称（如 dotfiles），值为该机器上的路径（如 ~/dotfiles）
+	SourceRoots map[string]string `json:"source_roots,omitempty"`
+	// Strict 为 true 时，创建链接会拒绝源文件不在任何源根目录下或链接文件位于非系统盘符的记录
+	Strict bool `json:"strict,omitempty"`
+	// Rules 按链接文件位置自动应用的默认选项，命令行或清单中显式给出的值优先
+	Rules []Rule `json:"rules,omitempty"`
+	// Mirror 非空时每次检查后将链接状态发布到一个固定位置，供其他工具只读使用
+	Mirror *Mirror `json:"mirror,omitempty"`
+	// MQTT 非空时每次检查后向该 MQTT 服务器发布各设备的链接健康状况
+	MQTT *MQTT `json:"mqtt,omitempty"`
+	// Repair 无人值守自动修复（fix --yes、计划任务）的限流策略，未设置的字段使用默认值
+	Repair RepairPolicy `json:"repair,omitempty"`
+	// Blackouts 静默时段，期间无人值守的检查只报告不修复，如备份或系统更新运行时
+	Blackouts []Blackout `json:"blackouts,omitempty"`
+	// BackupRoots 被备份工具覆盖的目录，真实文件应位于其中之一，否则链接的内容没有被备份
+	BackupRoots []string `json:"backup_roots,omitempty"`
+	// Columns 检查结果表格默认显示的列及其顺序，如 ["type","device","fake","status"]，--columns 优先
+	Columns []string `json:"columns,omitempty"`
+	// Hash 比较文件内容时使用的摘要算法 xxhash64（默认）或 sha256，--hash 优先
+	Hash string `json:"hash,omitempty"`
+	// Device 本机的设备名称，create、check、fix 未指定 --device 时使用，由 flk device set 或 flk device auto 写入
+	Device string `json:"device,omitempty"`
+}
+
+// Blackout 一个按本地时间每天（或每周指定几天）重复的静默时段
+type Blackout struct {
+	// Start、End 起止时间，格式 HH:MM；End 早于 Start 时表示跨越午夜，如 23:00 到 02:00
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Days 生效的星期（时段开始的那一天），如 ["sat", "sun"]，为空表示每天
+	Days []string `json:"days,omitempty"`
+	// Reason 说明，会在跳过修复时输出
+	Reason string `json:"reason,omitempty"`
+}
+
+// RepairPolicy 自动修复的限流设置
+type RepairPolicy struct {
+	// MaxPerHour 单条记录每小时最多自动修复的次数，默认 3
+	MaxPerHour int `json:"max_per_hour,omitempty"`
+	// Backoff 连续修复之间的初始等待时间，每次修复后翻倍，如 1m（默认）
+	Backoff string `json:"backoff,omitempty"`
+	// GiveUpAfter 连续修复该次数仍被改回时放弃自动修复，默认 5
+	GiveUpAfter int `json:"give_up_after,omitempty"`
+	// MaxRisk 自动修复允许的最高风险 low、medium、high（默认），fix --max-risk 优先
+	MaxRisk string `json:"max_risk,omitempty"`
+}
+
+// MQTT 链接健康状况的 MQTT 发布设置
+type MQTT struct {
+	// Broker 服务器地址，如 tcp://nas.local:1883、ssl://nas.local:8883
+	Broker string `json:"broker"`
+	// Topic 主题前缀，默认为 flk
+	Topic string `json:"topic,omitempty"`
+	// Username、Password 认证信息，支持 $VAR 形式引用环境变量，避免在配置文件中保存明文密码
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// ClientID 客户端标识，默认为 flk-<主机名>
+	ClientID string `json:"client_id,omitempty"`
+	// Retain 为 true 时以保留消息发布，新订阅者可立即得到最近的状态
+	Retain bool `json:"retain,omitempty"`
+	// Discovery 为 true 时发布 Home Assistant 自动发现配置
+	Discovery bool `json:"discovery,omitempty"`
+}
+
+// Mirror 链接状态镜像的发布设置
+type Mirror struct {
+	// Path 镜像文件路径，为空时使用 /run/user/<uid>/flk/links.json 或临时目录下的 flk/links.json
+	Path string `json:"path,omitempty"`
+	// Format 为 json（默认）或 ndjson
+	Format string `json:"format,omitempty"`
+}
+
+// Rule 一条按目录模式生效的默认选项规则，如 ~/.ssh 下的链接目标权限为 600
+type Rule struct {
+	// Pattern 链接文件所在位置，可以是目录（匹配其下所有路径）或通配模式（如 ~/.config/*/secrets），
+	// 支持 ~、$VAR 和 %VAR% 形式的环境变量
+	Pattern string `json:"pattern"`
+	// Mode 目标文件权限（八进制，如 600）
+	Mode string `json:"mode,omitempty"`
+	// Attrib 链接自身的 Windows 文件属性，如 hidden,system
+	Attrib string `json:"attrib,omitempty"`
+	// CheckEvery 检查频率提示，如 1h、7d
+	CheckEvery string `json:"check_every,omitempty"`
+	// DirLink 链接目录时创建的链接类型 symlink 或 junction（仅 Windows），如 %APPDATA% 下的目录使用目录联接
+	DirLink string `json:"dir_link,omitempty"`
+	// LinkStyle 符号链接的写法 relative（相对链接文件所在目录，默认）或 absolute
+	LinkStyle string `json:"link_style,omitempty"`
+}
+
+// 规则中 DirLink 和 LinkStyle 的取值
+const (
+	DirLinkSymlink    = "symlink"
+	DirLinkJunction   = "junction"
+	LinkStyleRelative = "relative"
+	LinkStyleAbsolute = "absolute"
+)
+
+// RuleDefaults 按规则合并后对某个链接生效的默认选项，未被任何规则设置的字段为空
+type RuleDefaults struct {
+	Mode       string
+	Attrib     string
+	CheckEvery string
+	DirLink    string
+	LinkStyle  string
+}
+
+// DefaultConfigPath 默认的配置文件路径
+const DefaultConfigPath = "~/.config/flk/config.json"
+
+// Global 是全局共享的配置实例，未加载配置文件时为空配置
+var Global = &Config{}
+
+// ConfirmPolicy 返回指定命令在配置文件中的确认策略，未配置或取值非法时返回 ConfirmAsk
+func (c *Config) ConfirmPolicy(command string) string {
+	switch policy := c.Confirm[command]; policy {
+	case ConfirmYes, ConfirmNo:
+		return policy
+	default:
+		return ConfirmAsk
+	}
+}
+
+// SourceRoot 返回包含 path 的源根目录名称及 path 相对于它的路径，path 需为绝对路径；
+// 多个源根目录嵌套时取最深的一个
+func (c *Config) SourceRoot(path string) (name, rel string, ok bool) {
+	best := ""
+	for n, root := range c.SourceRoots {
+		dir, err := pathutil.NormalizePath(root)
+		if err != nil {
+			continue
+		}
+		if dir, err = pathutil.ToAbsolute(dir); err != nil {
+			continue
+		}
+		r, err := filepath.Rel(dir, path)
+		if err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if len(dir) > len(best) {
+			best, name, rel, ok = dir, n, filepath.ToSlash(r), true
+		}
+	}
+	return name, rel, ok
+}
+
+// BackupRoot 返回包含 path（绝对路径）的备份目录，不在任何备份目录下时 ok 为 false
+func (c *Config) BackupRoot(path string) (root string, ok bool) {
+	for _, r := range c.BackupRoots {
+		dir, err := pathutil.NormalizePath(r)
+		if err != nil {
+			continue
+		}
+		if dir, err = pathutil.ToAbsolute(dir); err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return r, true
+		}
+	}
+	return "", false
+}
+
+// DefaultsFor 返回对链接文件 link（绝对路径）生效的默认选项，多条规则匹配时后面的规则覆盖前面的同名选项
+func (c *Config) DefaultsFor(link string) RuleDefaults {
+	var d RuleDefaults
+	for _, r := range c.Rules {
+		if !r.matches(link) {
+			continue
+		}
+		if r.Mode != "" {
+			d.Mode = r.Mode
+		}
+		if r.Attrib != "" {
+			d.Attrib = r.Attrib
+		}
+		if r.CheckEvery != "" {
+			d.CheckEvery = r.CheckEvery
+		}
+		if r.DirLink != "" {
+			d.DirLink = r.DirLink
+		}
+		if r.LinkStyle != "" {
+			d.LinkStyle = r.LinkStyle
+		}
+	}
+	return d
+}
+
+// weekdays 静默时段中星期的写法
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ActiveBlackout 返回 now 所处的静默时段，不在任何静默时段内时 ok 为 false；格式无效的时段会被忽略
+func (c *Config) ActiveBlackout(now time.Time) (b Blackout, ok bool) {
+	for _, b := range c.Blackouts {
+		if b.covers(now) {
+			return b, true
+		}
+	}
+	return Blackout{}, false
+}
+
+// covers 判断 now 是否位于该时段内
+func (b Blackout) covers(now time.Time) bool {
+	start, err1 := time.Parse("15:04", b.Start)
+	end, err2 := time.Parse("15:04", b.End)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	from, to := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+	day := now.Weekday()
+	switch {
+	case from <= to:
+		if minute < from || minute >= to {
+			return false
+		}
+	case minute >= from:
+		// 跨越午夜的时段，当前处于开始当天
+	case minute < to:
+		// 跨越午夜的时段，当前处于次日，按开始那天判断星期
+		day = (day + 6) % 7
+	default:
+		return false
+	}
+	if len(b.Days) == 0 {
+		return true
+	}
+	for _, d := range b.Days {
+		if wd, ok := weekdays[strings.ToLower(d)]; ok && wd == day {
+			return true
+		}
+	}
+	return false
+}
+
+// windowsEnvPattern 匹配 %APPDATA% 形式的环境变量
+var windowsEnvPattern = regexp.MustCompile(`%([A-Za-z0-9_]+)%`)
+
+// matches 判断链接文件是否位于规则的模式下
+func (r Rule) matches(link string) bool {
+	pattern := windowsEnvPattern.ReplaceAllStringFunc(r.Pattern, func(s string) string {
+		return os.Getenv(strings.Trim(s, "%"))
+	})
+	pattern, err := pathutil.NormalizePath(os.ExpandEnv(pattern))
+	if err != nil || pattern == "" {
+		return false
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		rel, err := filepath.Rel(pattern, link)
+		return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	}
+	// 通配模式与链接文件本身或它的任一上级目录匹配即可
+	for p := link; ; p = filepath.Dir(p) {
+		if ok, _ := filepath.Match(pattern, p); ok {
+			return true
+		}
+		if filepath.Dir(p) == p {
+			return false
+		}
+	}
+}
+
+// Init 加载配置文件到 Global，文件不存在时使用空配置
+func Init(configPath string) error {
+	c, err := LoadFromFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			c = &Config{}
+		} else {
+			return err
+		}
+	}
+	Global = c
+	return nil
+}
+
+// LoadFromFile 从指定路径加载配置
+func LoadFromFile(filePath string) (*Config, error) {
+	expanded, err := pathutil.NormalizePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(expanded)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Save 将配置写入指定路径
+func (c *Config) Save(filePath string) error {
+	data, err := json.MarshalIndent(c, "", "    ")
+	if err != nil {
+		return err
+	}
+	expanded, err := pathutil.NormalizePath(filePath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(expanded), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(expanded, data, 0644)
+}
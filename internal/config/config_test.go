@@ -0,0 +1,64 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultsForDirectoryPattern(t *testing.T) {
+	home := t.TempDir()
+	ssh := filepath.Join(home, ".ssh")
+	c := &Config{Rules: []Rule{{Pattern: ssh, Mode: "600", LinkStyle: LinkStyleAbsolute}}}
+
+	d := c.DefaultsFor(filepath.Join(ssh, "config"))
+	if d.Mode != "600" || d.LinkStyle != LinkStyleAbsolute {
+		t.Errorf("~/.ssh/config 的默认选项为 %+v", d)
+	}
+	if d := c.DefaultsFor(ssh); d.Mode != "600" {
+		t.Errorf("目录本身也应匹配，得到 %+v", d)
+	}
+	// 名称以目录名开头的兄弟目录不在目录下
+	if d := c.DefaultsFor(filepath.Join(home, ".ssh2", "config")); d != (RuleDefaults{}) {
+		t.Errorf("~/.ssh2/config 不应匹配，得到 %+v", d)
+	}
+}
+
+func TestDefaultsForGlobPattern(t *testing.T) {
+	base := t.TempDir()
+	c := &Config{Rules: []Rule{{Pattern: filepath.Join(base, "*", "secrets"), Mode: "600"}}}
+
+	if d := c.DefaultsFor(filepath.Join(base, "app", "secrets", "token")); d.Mode != "600" {
+		t.Errorf("通配模式应匹配上级目录，得到 %+v", d)
+	}
+	if d := c.DefaultsFor(filepath.Join(base, "app", "config")); d.Mode != "" {
+		t.Errorf("不应匹配，得到 %+v", d)
+	}
+}
+
+func TestDefaultsForEnvPattern(t *testing.T) {
+	appdata := t.TempDir()
+	t.Setenv("FLK_TEST_APPDATA", appdata)
+	c := &Config{Rules: []Rule{
+		{Pattern: "%FLK_TEST_APPDATA%", DirLink: DirLinkJunction},
+		{Pattern: "$FLK_TEST_APPDATA/Code", Attrib: "hidden"},
+	}}
+
+	d := c.DefaultsFor(filepath.Join(appdata, "Code", "User"))
+	if d.DirLink != DirLinkJunction || d.Attrib != "hidden" {
+		t.Errorf("两种环境变量写法都应展开，得到 %+v", d)
+	}
+}
+
+func TestDefaultsForLaterRulesOverride(t *testing.T) {
+	base := t.TempDir()
+	c := &Config{Rules: []Rule{
+		{Pattern: base, Mode: "644", CheckEvery: "1h", LinkStyle: LinkStyleAbsolute},
+		{Pattern: filepath.Join(base, "private"), Mode: "600", LinkStyle: LinkStyleRelative},
+	}}
+
+	d := c.DefaultsFor(filepath.Join(base, "private", "key"))
+	want := RuleDefaults{Mode: "600", CheckEvery: "1h", LinkStyle: LinkStyleRelative}
+	if d != want {
+		t.Errorf("得到 %+v，期望 %+v", d, want)
+	}
+}
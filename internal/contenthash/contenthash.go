@@ -0,0 +1,121 @@
+// Package contenthash 计算文件内容的摘要，用于比较两个文件内容是否相同
+package contenthash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// 可选的摘要算法
+const (
+	XXHash64 = "xxhash64" // 速度快，适合日常的内容比较，默认使用
+	SHA256   = "sha256"   // 抗碰撞，适合对完整性要求较高的场景
+)
+
+// Algorithm 本次运行使用的摘要算法，由 --hash 或配置文件中的 hash 设置
+var Algorithm = XXHash64
+
+// Workers 同时计算摘要的文件数，由 --hash-workers 设置；0 表示按 CPU 核数，适合固态硬盘，机械硬盘上设为 1 可避免来回寻道
+var Workers = 0
+
+// Algorithms 返回全部可选的摘要算法
+func Algorithms() []string {
+	return []string{XXHash64, SHA256}
+}
+
+// newHash 返回算法对应的摘要计算器
+func newHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case XXHash64:
+		return xxhash.New(), nil
+	case SHA256:
+		return sha256.New(), nil
+	}
+	return nil, fmt.Errorf("不支持的摘要算法 %s，可选 xxhash64、sha256", algorithm)
+}
+
+// Validate 检查算法名称是否受支持
+func Validate(algorithm string) error {
+	_, err := newHash(algorithm)
+	return err
+}
+
+// File 以流式读取的方式计算文件内容的摘要，返回十六进制字符串
+func File(path string) (string, error) {
+	h, err := newHash(Algorithm)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Result 一个文件的摘要结果
+type Result struct {
+	Path string
+	Sum  string
+	Err  error
+}
+
+// Files 并发计算多个文件的摘要，结果顺序与 paths 相同，并发数由 Workers 决定
+func Files(paths []string) []Result {
+	results := make([]Result, len(paths))
+	workers := Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, p := range paths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			sum, err := File(p)
+			results[i] = Result{Path: p, Sum: sum, Err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// Same 判断两个文件内容是否相同，大小不同时直接返回 false，不读取内容
+func Same(a, b string) (bool, error) {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if aInfo.Size() != bInfo.Size() {
+		return false, nil
+	}
+	if os.SameFile(aInfo, bInfo) {
+		return true, nil
+	}
+	results := Files([]string{a, b})
+	for _, r := range results {
+		if r.Err != nil {
+			return false, r.Err
+		}
+	}
+	return results[0].Sum == results[1].Sum, nil
+}
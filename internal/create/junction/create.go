@@ -0,0 +1,60 @@
+// Package junction 创建和读取 NTFS 目录联接（junction），目录联接只能指向本机卷上的目录，但创建时不需要管理员权限
+package junction
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/trace"
+)
+
+// ErrUnsupported 当前平台不支持目录联接
+var ErrUnsupported = errors.New("目录联接仅在 Windows 上可用，其他平台请使用 flk create symlink")
+
+// Create 在 fakePath 创建指向目录 realPath 的目录联接，force 为 true 时先删除 fakePath 上已存在的文件；
+// 目录联接总是记录目标的绝对路径
+func Create(realPath, fakePath string, force bool) error {
+	if !Supported {
+		return ErrUnsupported
+	}
+	info, err := trace.Stat(realPath)
+	if err != nil {
+		logger.Error("realPath 对应的目录不存在，中止执行")
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s 不是目录，目录联接只能指向目录", realPath)
+	}
+	if force {
+		if _, err := trace.Lstat(fakePath); err == nil {
+			if err := trace.RemoveAll(fakePath); err != nil {
+				logger.Error("删除失败 " + err.Error())
+				return err
+			}
+			logger.Info("已成功删除 fakePath")
+		}
+	}
+	if err := pathutil.EnsureDirExists(fakePath); err != nil {
+		return err
+	}
+	absRealPath, err := filepath.Abs(realPath)
+	if err != nil {
+		return err
+	}
+	absFakePath, err := filepath.Abs(fakePath)
+	if err != nil {
+		return err
+	}
+	return trace.Do("junction", func() error { return create(absRealPath, absFakePath) }, absRealPath, absFakePath)
+}
+
+// Target 返回目录联接指向的绝对路径，path 不是目录联接时返回错误
+func Target(path string) (string, error) {
+	if !Supported {
+		return "", ErrUnsupported
+	}
+	return target(path)
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package junction
+
+// Supported 表示当前平台是否支持目录联接
+const Supported = false
+
+func create(target, link string) error {
+	return ErrUnsupported
+}
+
+func target(path string) (string, error) {
+	return "", ErrUnsupported
+}
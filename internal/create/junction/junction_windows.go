@@ -0,0 +1,119 @@
+package junction
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/trace"
+	"golang.org/x/sys/windows"
+)
+
+// Supported 表示当前平台是否支持目录联接
+const Supported = true
+
+// nonInterpretedPrefix 目录联接的替代名称使用的 NT 路径前缀
+const nonInterpretedPrefix = `\??\`
+
+// create 先创建空目录，再通过 FSCTL_SET_REPARSE_POINT 将其设为挂载点；失败时改用 mklink /J
+func create(target, link string) error {
+	err := setMountPoint(target, link)
+	if err == nil {
+		return nil
+	}
+	logger.Warn(fmt.Sprintf("设置重分析点失败（%v），改用 mklink /J", err))
+	if _, statErr := os.Lstat(link); statErr == nil {
+		trace.Remove(link)
+	}
+	out, mklinkErr := exec.Command("cmd", "/c", "mklink", "/J", link, target).CombinedOutput()
+	if mklinkErr != nil {
+		return fmt.Errorf("mklink /J 失败: %v %s", mklinkErr, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// setMountPoint 在 link 创建空目录并写入指向 target 的挂载点重分析数据
+func setMountPoint(target, link string) error {
+	if err := os.Mkdir(link, 0755); err != nil {
+		return err
+	}
+	path, err := windows.UTF16PtrFromString(link)
+	if err != nil {
+		return err
+	}
+	h, err := windows.CreateFile(path, windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING,
+		windows.FILE_FLAG_OPEN_REPARSE_POINT|windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	buf := mountPointBuffer(target)
+	var returned uint32
+	return windows.DeviceIoControl(h, windows.FSCTL_SET_REPARSE_POINT, &buf[0], uint32(len(buf)), nil, 0, &returned, nil)
+}
+
+// mountPointBuffer 构造 REPARSE_DATA_BUFFER 的挂载点形式：替代名称为 \??\<目标>，显示名称为目标本身
+func mountPointBuffer(target string) []byte {
+	substitute := utf16.Encode([]rune(nonInterpretedPrefix + target))
+	print := utf16.Encode([]rune(target))
+	// 两个名称各自以 NUL 结尾
+	names := make([]uint16, 0, len(substitute)+len(print)+2)
+	names = append(names, substitute...)
+	names = append(names, 0)
+	names = append(names, print...)
+	names = append(names, 0)
+
+	const headerSize = 8  // ReparseTag、ReparseDataLength、Reserved
+	const offsetsSize = 8 // 替代名称和显示名称的偏移与长度
+	buf := make([]byte, headerSize+offsetsSize+len(names)*2)
+	le := binary.LittleEndian
+	le.PutUint32(buf[0:], windows.IO_REPARSE_TAG_MOUNT_POINT)
+	le.PutUint16(buf[4:], uint16(offsetsSize+len(names)*2))
+	le.PutUint16(buf[8:], 0)
+	le.PutUint16(buf[10:], uint16(len(substitute)*2))
+	le.PutUint16(buf[12:], uint16((len(substitute)+1)*2))
+	le.PutUint16(buf[14:], uint16(len(print)*2))
+	for i, c := range names {
+		le.PutUint16(buf[headerSize+offsetsSize+i*2:], c)
+	}
+	return buf
+}
+
+// target 读取挂载点重分析数据中的目标，去掉 NT 路径前缀
+func target(path string) (string, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return "", err
+	}
+	h, err := windows.CreateFile(p, 0, windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE, nil,
+		windows.OPEN_EXISTING, windows.FILE_FLAG_OPEN_REPARSE_POINT|windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return "", err
+	}
+	defer windows.CloseHandle(h)
+
+	buf := make([]byte, windows.MAXIMUM_REPARSE_DATA_BUFFER_SIZE)
+	var returned uint32
+	if err := windows.DeviceIoControl(h, windows.FSCTL_GET_REPARSE_POINT, nil, 0, &buf[0], uint32(len(buf)), &returned, nil); err != nil {
+		return "", fmt.Errorf("%s 不是目录联接: %w", path, err)
+	}
+	le := binary.LittleEndian
+	if le.Uint32(buf[0:]) != windows.IO_REPARSE_TAG_MOUNT_POINT {
+		return "", fmt.Errorf("%s 不是目录联接", path)
+	}
+	offset, length := int(le.Uint16(buf[8:])), int(le.Uint16(buf[10:]))
+	names := buf[16:returned]
+	if offset+length > len(names) {
+		return "", fmt.Errorf("%s 的重分析数据无效", path)
+	}
+	name := make([]uint16, length/2)
+	for i := range name {
+		name[i] = le.Uint16(names[offset+i*2:])
+	}
+	return strings.TrimPrefix(windows.UTF16ToString(name), nonInterpretedPrefix), nil
+}
@@ -7,12 +7,17 @@ import (
 
 	"github.com/jy-eggroll/flk/internal/logger"
 	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/trace"
 )
 
 // 该函数只处理创建逻辑，需要保证传入的路径一定是最正确、最简洁的，函数被调用时，应该优先处理字符串
 func Create(realPath, fakePath string, force bool) error {
-	logger.Init(nil)
-	if _, err := os.Stat(realPath); err == nil {
+	return CreateWithStyle(realPath, fakePath, force, false)
+}
+
+// CreateWithStyle 与 Create 相同，absolute 为 true 时链接内容为真实文件的绝对路径，否则尽量使用相对链接文件所在目录的路径
+func CreateWithStyle(realPath, fakePath string, force, absolute bool) error {
+	if _, err := trace.Stat(realPath); err == nil {
 		logger.Debug("realPath 对应的文件存在，允许继续执行")
 	} else {
 		logger.Error("realPath 对应的文件不存在，中止执行")
@@ -21,9 +26,9 @@ func Create(realPath, fakePath string, force bool) error {
 	if force {
 		logger.Info("检测到 force 选项，将会尝试删除已存在的链接文件或冲突的非目录文件")
 		// 使用 Lstat 而不是 Stat，因为 Stat 会跟随符号链接
-		if _, err := os.Lstat(fakePath); err == nil { // 文件/链接/文件夹存在
+		if _, err := trace.Lstat(fakePath); err == nil { // 文件/链接/文件夹存在
 			logger.Debug("fakePath 存在")
-			if err := os.RemoveAll(fakePath); err == nil {
+			if err := trace.RemoveAll(fakePath); err == nil {
 				logger.Info("已成功删除 fakePath")
 			} else {
 				logger.Error("删除失败 " + err.Error())
@@ -35,7 +40,7 @@ func Create(realPath, fakePath string, force bool) error {
 		if err := pathutil.EnsureDirExists(fakePath); err != nil {
 			if errors.Is(err, &pathutil.ExistsButNotDirectoryError{}) {
 				// fakePath 的父路径存在但不是目录（是文件），删除它
-				if removeErr := os.Remove(filepath.Dir(fakePath)); removeErr == nil {
+				if removeErr := trace.Remove(filepath.Dir(fakePath)); removeErr == nil {
 					logger.Info("已成功删除非目录文件")
 				} else {
 					logger.Error("删除非目录文件失败 " + removeErr.Error())
@@ -55,14 +60,58 @@ func Create(realPath, fakePath string, force bool) error {
 		return err
 	}
 
-	fakeDir := filepath.Dir(fakePath)
-	linkTarget, err := filepath.Rel(fakeDir, absRealPath)
-	if err != nil || linkTarget == "." {
-		linkTarget = absRealPath
+	linkTarget := absRealPath
+	if !absolute {
+		rel, err := filepath.Rel(filepath.Dir(fakePath), absRealPath)
+		if err == nil && rel != "." {
+			linkTarget = rel
+		}
 	}
 
-	if err := os.Symlink(linkTarget, fakePath); err != nil {
+	if err := trace.Symlink(linkTarget, fakePath); err != nil {
 		return err
 	}
 	return nil
 }
+
+// Conflict 描述 fakePath 已被一个指向其他目标的符号链接占用的情况
+type Conflict struct {
+	FakePath       string
+	ExistingTarget string // 现有符号链接实际指向的绝对路径
+}
+
+// DetectConflict 检查 fakePath 是否为指向 realPath 以外目标的符号链接，不存在冲突时返回 nil
+func DetectConflict(realPath, fakePath string) (*Conflict, error) {
+	info, err := trace.Lstat(fakePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return nil, nil
+	}
+
+	target, err := trace.Readlink(fakePath)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(fakePath), target)
+	}
+	target = filepath.Clean(target)
+
+	absRealPath, err := filepath.Abs(realPath)
+	if err != nil {
+		return nil, err
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return nil, err
+	}
+	if absTarget == absRealPath {
+		return nil, nil
+	}
+	return &Conflict{FakePath: fakePath, ExistingTarget: absTarget}, nil
+}
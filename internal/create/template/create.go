@@ -0,0 +1,144 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/trace"
+)
+
+// Vars 渲染模板时可用的设备变量，模板中以 {{ .Device }} 等形式引用
+type Vars struct {
+	Device   string
+	Hostname string
+	Platform string
+	User     string
+	Home     string
+}
+
+// DeviceVars 返回当前机器上指定设备的模板变量
+func DeviceVars(device string) Vars {
+	vars := Vars{Device: device, Platform: runtime.GOOS}
+	vars.Hostname, _ = os.Hostname()
+	vars.Home, _ = pathutil.HomeDir()
+	if u, err := user.Current(); err == nil {
+		vars.User = u.Username
+	}
+	return vars
+}
+
+// Render 读取 realPath 处的模板并使用 vars 渲染
+// 模板可通过 env 和 cmd 函数在渲染时读取环境变量或外部命令（如 op read）的输出，这些值只写入渲染结果，不会进入存储
+// sensitive 表示渲染过程中使用了这类秘密来源，调用方应以更严格的权限写入结果
+func Render(realPath string, vars Vars) (rendered []byte, sensitive bool, err error) {
+	return render(realPath, vars, true)
+}
+
+// ErrUsesCommand 模板通过 cmd 函数读取外部命令的输出，不执行命令就无法得到当前的渲染结果
+var ErrUsesCommand = errors.New("模板使用 cmd 函数读取外部命令的输出")
+
+// render 渲染模板，runCommands 为 false 时不执行 cmd 函数中的命令，模板调用 cmd 即返回 ErrUsesCommand
+func render(realPath string, vars Vars, runCommands bool) (rendered []byte, sensitive bool, err error) {
+	b, err := os.ReadFile(realPath)
+	if err != nil {
+		return nil, false, err
+	}
+	funcs := template.FuncMap{
+		"env": func(name string) (string, error) {
+			sensitive = true
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				return "", fmt.Errorf("环境变量 %s 未设置", name)
+			}
+			return value, nil
+		},
+		"cmd": func(name string, args ...string) (string, error) {
+			sensitive = true
+			if !runCommands {
+				return "", ErrUsesCommand
+			}
+			out, err := exec.Command(name, args...).Output()
+			if err != nil {
+				return "", fmt.Errorf("执行命令 %s 失败 %w", name, err)
+			}
+			return strings.TrimRight(string(out), "\r\n"), nil
+		},
+	}
+	tmpl, err := template.New(filepath.Base(realPath)).Funcs(funcs).Option("missingkey=error").Parse(string(b))
+	if err != nil {
+		return nil, false, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), sensitive, nil
+}
+
+// 该函数只处理创建逻辑，需要保证传入的路径一定是最正确、最简洁的，函数被调用时，应该优先处理字符串
+func Create(realPath, fakePath string, vars Vars, force bool) error {
+	rendered, sensitive, err := Render(realPath, vars)
+	if err != nil {
+		logger.Error("渲染模板失败 " + err.Error())
+		return err
+	}
+	if force {
+		logger.Info("检测到 force 选项，将会尝试删除已存在的文件")
+		if info, err := os.Lstat(fakePath); err == nil && (info.IsDir() || info.Mode()&os.ModeSymlink != 0) {
+			// 渲染结果总是普通文件，已存在的目录或链接需要先删除，普通文件直接覆盖写入即可
+			if err := trace.RemoveAll(fakePath); err != nil {
+				logger.Error("删除失败 " + err.Error())
+				return err
+			}
+		}
+	} else if _, err := os.Lstat(fakePath); err == nil {
+		return &os.PathError{Op: "render", Path: fakePath, Err: os.ErrExist}
+	}
+
+	if err := pathutil.EnsureDirExists(fakePath); err != nil {
+		return err
+	}
+	// 含有秘密的渲染结果仅允许所有者读写
+	perm := os.FileMode(0644)
+	if sensitive {
+		perm = 0600
+	}
+	if err := trace.WriteFile(fakePath, rendered, perm); err != nil {
+		return err
+	}
+	// WriteFile 不会修改已存在文件的权限
+	return trace.Chmod(fakePath, perm)
+}
+
+// IsCurrent 判断 fakePath 的内容是否与模板当前的渲染结果一致
+func IsCurrent(realPath, fakePath string, vars Vars) (bool, error) {
+	return isCurrent(realPath, fakePath, vars, true)
+}
+
+// IsCurrentWithoutCommands 与 IsCurrent 相同但不执行模板中的外部命令，模板调用了 cmd 函数时返回 ErrUsesCommand；
+// 供 flk check 这类频繁且并发执行的检查使用，避免每次检查都调用 op read 等命令
+func IsCurrentWithoutCommands(realPath, fakePath string, vars Vars) (bool, error) {
+	return isCurrent(realPath, fakePath, vars, false)
+}
+
+func isCurrent(realPath, fakePath string, vars Vars, runCommands bool) (bool, error) {
+	rendered, _, err := render(realPath, vars, runCommands)
+	if err != nil {
+		return false, err
+	}
+	existing, err := os.ReadFile(fakePath)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(rendered, existing), nil
+}
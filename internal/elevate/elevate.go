@@ -0,0 +1,129 @@
+// Package elevate 在当前进程权限不足时以管理员权限启动子进程执行文件系统操作。
+// 子进程只负责文件系统操作，不读写存储，结果通过请求文件旁的结果文件交还父进程，由父进程统一写入存储，
+// 避免两个进程以不同的工作目录各自写入存储
+package elevate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ChildCommand 提权子进程执行的隐藏子命令名称
+const ChildCommand = "__elevated"
+
+// Op 交由提权子进程执行的一次文件系统操作，路径均为绝对路径，子进程的工作目录与父进程无关
+type Op struct {
+	Type   string `json:"type"` // 目前仅支持 symlink
+	Target string `json:"target"`
+	Link   string `json:"link"`
+	Force  bool   `json:"force,omitempty"`
+	// Absolute 为 true 时符号链接写入真实文件的绝对路径
+	Absolute bool `json:"absolute,omitempty"`
+}
+
+// Result 单个操作的执行结果，Error 为空表示成功
+type Result struct {
+	Error string `json:"error,omitempty"`
+}
+
+// ErrUnsupported 当前平台不支持提权执行
+var ErrUnsupported = errors.New("当前平台不支持提权执行")
+
+// Command 提权子进程的完整命令行，用于在弹出 UAC 前向用户展示
+type Command struct {
+	Exe  string   `json:"exe"`
+	Args []string `json:"args"`
+	Dir  string   `json:"dir"` // 子进程的工作目录，为请求文件所在目录
+}
+
+// Request 已写入请求文件、等待执行的一组提权操作
+type Request struct {
+	Command Command
+	Ops     []Op
+	file    string
+}
+
+// Prepare 将 ops 写入临时请求文件并生成子进程命令行，调用方负责在完成后调用 Close
+func Prepare(exe string, ops []Op) (*Request, error) {
+	for _, op := range ops {
+		if !filepath.IsAbs(op.Target) || !filepath.IsAbs(op.Link) {
+			return nil, fmt.Errorf("提权操作要求绝对路径：%s -> %s", op.Link, op.Target)
+		}
+	}
+	f, err := os.CreateTemp("", "flk-elevate-*.json")
+	if err != nil {
+		return nil, err
+	}
+	r := &Request{
+		Command: Command{Exe: exe, Args: []string{ChildCommand, f.Name()}, Dir: filepath.Dir(f.Name())},
+		Ops:     ops,
+		file:    f.Name(),
+	}
+	data, err := json.Marshal(ops)
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Execute 以管理员权限启动子进程执行请求，等待其退出并返回与 Ops 一一对应的结果
+func (r *Request) Execute() ([]Result, error) {
+	if err := runElevated(r.Command); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(ResultPath(r.file))
+	if err != nil {
+		return nil, fmt.Errorf("提权子进程未返回结果：%v", err)
+	}
+	var results []Result
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+	if len(results) != len(r.Ops) {
+		return nil, fmt.Errorf("提权子进程返回了 %d 个结果，期望 %d 个", len(results), len(r.Ops))
+	}
+	return results, nil
+}
+
+// Close 删除请求文件和结果文件
+func (r *Request) Close() {
+	os.Remove(r.file)
+	os.Remove(ResultPath(r.file))
+}
+
+// ResultPath 返回请求文件对应的结果文件路径
+func ResultPath(request string) string {
+	return request + ".result"
+}
+
+// ReadRequest 子进程读取父进程写入的操作列表
+func ReadRequest(request string) ([]Op, error) {
+	data, err := os.ReadFile(request)
+	if err != nil {
+		return nil, err
+	}
+	var ops []Op
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// WriteResults 子进程写入执行结果
+func WriteResults(request string, results []Result) error {
+	data, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(ResultPath(request), data, 0600)
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package elevate
+
+// Needed 判断 err 是否为可通过提权解决的权限不足错误，仅 Windows 上创建符号链接时会出现
+func Needed(err error) bool {
+	return false
+}
+
+func runElevated(c Command) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,85 @@
+//go:build windows
+
+package elevate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Needed 判断 err 是否为可通过提权解决的权限不足错误，未开启开发者模式时创建符号链接需要管理员权限
+func Needed(err error) bool {
+	return errors.Is(err, windows.ERROR_PRIVILEGE_NOT_HELD) && !windows.GetCurrentProcessToken().IsElevated()
+}
+
+var procShellExecuteExW = windows.NewLazySystemDLL("shell32.dll").NewProc("ShellExecuteExW")
+
+// shellExecuteInfo 对应 SHELLEXECUTEINFOW
+type shellExecuteInfo struct {
+	cbSize       uint32
+	fMask        uint32
+	hwnd         windows.Handle
+	lpVerb       *uint16
+	lpFile       *uint16
+	lpParameters *uint16
+	lpDirectory  *uint16
+	nShow        int32
+	hInstApp     windows.Handle
+	lpIDList     uintptr
+	lpClass      *uint16
+	hkeyClass    windows.Handle
+	dwHotKey     uint32
+	hIcon        windows.Handle
+	hProcess     windows.Handle
+}
+
+const seeMaskNoCloseProcess = 0x00000040
+
+// runElevated 通过 UAC 以管理员权限启动子进程并等待其退出
+func runElevated(c Command) error {
+	quoted := make([]string, len(c.Args))
+	for i, a := range c.Args {
+		quoted[i] = windows.EscapeArg(a)
+	}
+	verb, _ := windows.UTF16PtrFromString("runas")
+	file, err := windows.UTF16PtrFromString(c.Exe)
+	if err != nil {
+		return err
+	}
+	dir, err := windows.UTF16PtrFromString(c.Dir)
+	if err != nil {
+		return err
+	}
+	params, err := windows.UTF16PtrFromString(strings.Join(quoted, " "))
+	if err != nil {
+		return err
+	}
+	info := shellExecuteInfo{
+		fMask:        seeMaskNoCloseProcess,
+		lpVerb:       verb,
+		lpFile:       file,
+		lpParameters: params,
+		lpDirectory:  dir,
+		nShow:        windows.SW_HIDE,
+	}
+	info.cbSize = uint32(unsafe.Sizeof(info))
+	if ok, _, err := procShellExecuteExW.Call(uintptr(unsafe.Pointer(&info))); ok == 0 {
+		return fmt.Errorf("无法以管理员权限启动 flk：%v", err)
+	}
+	defer windows.CloseHandle(info.hProcess)
+	if _, err := windows.WaitForSingleObject(info.hProcess, windows.INFINITE); err != nil {
+		return err
+	}
+	var code uint32
+	if err := windows.GetExitCodeProcess(info.hProcess, &code); err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("提权子进程退出码 %d", code)
+	}
+	return nil
+}
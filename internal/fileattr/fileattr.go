@@ -0,0 +1,84 @@
+package fileattr
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Attr Windows 文件属性位，取值与 FILE_ATTRIBUTE_* 一致
+type Attr uint32
+
+const (
+	Hidden Attr = 0x2
+	System Attr = 0x4
+)
+
+var attrNames = []struct {
+	name string
+	attr Attr
+}{
+	{"hidden", Hidden},
+	{"system", System},
+}
+
+// known 所有可管理的属性位，其余属性（如 ARCHIVE、REPARSE_POINT）不做比较
+const known = Hidden | System
+
+// Parse 解析逗号分隔的属性名，如 hidden、hidden,system
+func Parse(raw string) (Attr, error) {
+	var a Attr
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(strings.ToLower(part))
+		if part == "" {
+			continue
+		}
+		found := false
+		for _, n := range attrNames {
+			if n.name == part {
+				a |= n.attr
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("无效的文件属性 %s，可选 hidden、system", part)
+		}
+	}
+	return a, nil
+}
+
+// String 返回逗号分隔的属性名，无属性时返回空字符串
+func (a Attr) String() string {
+	var names []string
+	for _, n := range attrNames {
+		if a&n.attr != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Check 判断 path 自身（不跟随符号链接）是否设置了 want 中的全部属性，返回实际的可管理属性
+func Check(path string, want Attr) (bool, Attr, error) {
+	if !Supported || want == 0 {
+		return true, 0, nil
+	}
+	actual, err := Get(path)
+	if err != nil {
+		return false, 0, err
+	}
+	return actual&want == want, actual, nil
+}
+
+// Hydrate 完整读取 path 的内容，迫使云同步客户端将占位文件下载到本地
+func Hydrate(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(io.Discard, f)
+	return err
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+
+package fileattr
+
+// Supported 表示当前平台是否支持文件属性，非 Windows 平台以文件名前缀点号表示隐藏，没有对应属性
+const Supported = false
+
+// IsPlaceholder 非 Windows 平台总是返回 false
+func IsPlaceholder(path string) (bool, error) {
+	return false, nil
+}
+
+// Get 非 Windows 平台总是返回无属性
+func Get(path string) (Attr, error) {
+	return 0, nil
+}
+
+// Set 非 Windows 平台不做任何操作
+func Set(path string, a Attr) error {
+	return nil
+}
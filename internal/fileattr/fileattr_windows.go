@@ -0,0 +1,58 @@
+//go:build windows
+
+package fileattr
+
+import (
+	"github.com/jy-eggroll/flk/internal/trace"
+	"golang.org/x/sys/windows"
+)
+
+// Supported 表示当前平台是否支持文件属性
+const Supported = true
+
+// placeholderAttrs 云同步客户端（OneDrive、Dropbox 等）用于标记仅在线占位文件的属性
+const placeholderAttrs = windows.FILE_ATTRIBUTE_RECALL_ON_DATA_ACCESS | windows.FILE_ATTRIBUTE_RECALL_ON_OPEN | windows.FILE_ATTRIBUTE_OFFLINE
+
+// IsPlaceholder 判断 path 是否为仅在线的云端占位文件，读取其内容会触发下载
+func IsPlaceholder(path string) (bool, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return false, err
+	}
+	attrs, err := windows.GetFileAttributes(p)
+	if err != nil {
+		return false, err
+	}
+	return attrs&placeholderAttrs != 0, nil
+}
+
+// Get 返回 path 自身（不跟随符号链接）的可管理属性
+func Get(path string) (Attr, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	attrs, err := windows.GetFileAttributes(p)
+	if err != nil {
+		return 0, err
+	}
+	return Attr(attrs) & known, nil
+}
+
+// Set 在 path 自身（不跟随符号链接）上追加设置属性 a，不会清除已有属性
+func Set(path string, a Attr) error {
+	if a == 0 {
+		return nil
+	}
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	return trace.Do("set_attrib", func() error {
+		attrs, err := windows.GetFileAttributes(p)
+		if err != nil {
+			return err
+		}
+		return windows.SetFileAttributes(p, attrs|uint32(a))
+	}, path, a.String())
+}
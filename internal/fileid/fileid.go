@@ -0,0 +1,8 @@
+// Package fileid 读取文件在文件系统中的唯一标识（Unix 上为设备号与 inode，NTFS 上为卷序列号与 FileID），
+// 用于判断硬链接是否因编辑器以“写入临时文件再重命名”的方式保存而断开
+package fileid
+
+// Get 返回 path（跟随符号链接）的文件标识，同一文件的所有硬链接标识相同
+func Get(path string) (string, error) {
+	return get(path)
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+
+package fileid
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func get(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", fmt.Errorf("无法读取 %s 的 inode", path)
+	}
+	return fmt.Sprintf("%d:%d", st.Dev, st.Ino), nil
+}
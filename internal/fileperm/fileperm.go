@@ -0,0 +1,51 @@
+package fileperm
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+
+	"github.com/jy-eggroll/flk/internal/trace"
+)
+
+// Supported 表示当前平台是否支持 Unix 权限位，Windows 上 chmod 只能切换只读属性，因此不做权限管理
+var Supported = runtime.GOOS != "windows"
+
+// Parse 解析八进制权限字符串，如 600、0644
+func Parse(mode string) (os.FileMode, error) {
+	v, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || v > 0o777 {
+		return 0, fmt.Errorf("无效的权限 %s，应为八进制形式如 600", mode)
+	}
+	return os.FileMode(v), nil
+}
+
+// Apply 将 path（跟随符号链接）的权限设置为 mode
+func Apply(path, mode string) error {
+	if !Supported || mode == "" {
+		return nil
+	}
+	perm, err := Parse(mode)
+	if err != nil {
+		return err
+	}
+	return trace.Chmod(path, perm)
+}
+
+// Check 判断 path（跟随符号链接）的权限是否与 mode 一致，返回实际权限的八进制字符串
+func Check(path, mode string) (bool, string, error) {
+	if !Supported || mode == "" {
+		return true, "", nil
+	}
+	perm, err := Parse(mode)
+	if err != nil {
+		return false, "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, "", err
+	}
+	actual := info.Mode().Perm()
+	return actual == perm, fmt.Sprintf("%03o", actual), nil
+}
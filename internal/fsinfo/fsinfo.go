@@ -0,0 +1,123 @@
+// Package fsinfo 按卷探测并缓存文件系统的特性，同一卷上的多条记录只探测一次
+package fsinfo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Info 一个卷的文件系统特性
+type Info struct {
+	// Volume 卷的挂载点（Windows 上为卷根目录，如 C:\）
+	Volume string `json:"volume"`
+	// Type 文件系统类型，如 ext4、btrfs、NTFS、apfs，无法识别时为空
+	Type string `json:"type,omitempty"`
+	// CaseSensitive 文件名是否区分大小写
+	CaseSensitive bool `json:"case_sensitive"`
+	// Symlink、Hardlink 当前用户能否在该卷上创建符号链接、硬链接
+	Symlink  bool `json:"symlink"`
+	Hardlink bool `json:"hardlink"`
+	// Reflink 是否支持写时复制的文件克隆（btrfs、xfs、APFS、ReFS 等）
+	Reflink bool `json:"reflink"`
+	// Network 是否为网络文件系统（NFS、SMB 等），其上的链接可能因服务端而失效
+	Network bool `json:"network"`
+	// ClusterSize 分配单元大小（字节），无法获取时为 0
+	ClusterSize int64 `json:"cluster_size,omitempty"`
+	// Probed 是否通过在卷上创建临时文件实际探测了大小写和链接支持；为 false 时这些字段为按平台推测的默认值
+	Probed bool `json:"probed"`
+}
+
+var (
+	mu    sync.Mutex
+	cache = make(map[string]*Info)
+)
+
+// For 返回路径所在卷的文件系统特性，路径不存在时使用其最近的已存在的上级目录；结果按卷缓存
+func For(path string) (*Info, error) {
+	dir, err := existingDir(path)
+	if err != nil {
+		return nil, err
+	}
+	volume, err := volumeOf(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if info, ok := cache[volume]; ok {
+		return info, nil
+	}
+	info := &Info{Volume: volume}
+	describe(info)
+	probe(info, dir)
+	cache[volume] = info
+	return info, nil
+}
+
+// SameVolume 判断两个路径是否位于同一卷，硬链接只能在同一卷内创建
+func SameVolume(a, b string) (bool, error) {
+	ia, err := For(a)
+	if err != nil {
+		return false, err
+	}
+	ib, err := For(b)
+	if err != nil {
+		return false, err
+	}
+	return ia.Volume == ib.Volume, nil
+}
+
+// SamePath 判断两个绝对路径是否指向同一位置，按 a 所在卷是否区分大小写比较
+func SamePath(a, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	if a == b {
+		return true
+	}
+	if info, err := For(a); err == nil && !info.CaseSensitive {
+		return strings.EqualFold(a, b)
+	}
+	return false
+}
+
+// existingDir 返回 path 本身（若为目录）或其最近的已存在的上级目录
+func existingDir(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if info, err := os.Stat(abs); err == nil && info.IsDir() {
+			return abs, nil
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return abs, nil
+		}
+		abs = parent
+	}
+}
+
+// probe 在 dir 中创建临时文件，实际测试大小写敏感性和链接支持；dir 不可写时保留 describe 给出的默认值
+func probe(info *Info, dir string) {
+	f, err := os.CreateTemp(dir, ".flk-probe-*")
+	if err != nil {
+		return
+	}
+	name := f.Name()
+	f.Close()
+	defer os.Remove(name)
+
+	info.Probed = true
+	upper := filepath.Join(dir, strings.ToUpper(filepath.Base(name)))
+	_, err = os.Stat(upper)
+	info.CaseSensitive = err != nil
+
+	link := name + "-link"
+	info.Symlink = os.Symlink(name, link) == nil
+	os.Remove(link)
+	info.Hardlink = os.Link(name, link) == nil
+	os.Remove(link)
+}
@@ -0,0 +1,21 @@
+package fsinfo
+
+import "golang.org/x/sys/unix"
+
+// describe 通过 statfs 识别文件系统类型、是否为本地卷和分配单元大小
+func describe(info *Info) {
+	info.Symlink = true
+	info.Hardlink = true
+	var st unix.Statfs_t
+	if err := unix.Statfs(info.Volume, &st); err != nil {
+		return
+	}
+	info.Type = unix.ByteSliceToString(st.Fstypename[:])
+	info.Network = st.Flags&unix.MNT_LOCAL == 0
+	info.Reflink = info.Type == "apfs"
+	info.ClusterSize = int64(st.Bsize)
+	if info.Type == "msdos" || info.Type == "exfat" {
+		info.Symlink = false
+		info.Hardlink = false
+	}
+}
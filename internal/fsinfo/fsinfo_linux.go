@@ -0,0 +1,52 @@
+package fsinfo
+
+import "golang.org/x/sys/unix"
+
+// fsTypes statfs 返回的文件系统魔数对应的名称
+var fsTypes = map[int64]string{
+	0xEF53:     "ext4",
+	0x9123683E: "btrfs",
+	0x58465342: "xfs",
+	0x2FC12FC1: "zfs",
+	0xF2F52010: "f2fs",
+	0xCA451A4E: "bcachefs",
+	0x01021994: "tmpfs",
+	0x794C7630: "overlay",
+	0x4D44:     "vfat",
+	0x2011BAB0: "exfat",
+	0x7366746E: "ntfs3",
+	0x65735546: "fuse",
+	0x6969:     "nfs",
+	0xFF534D42: "cifs",
+	0xFE534D42: "smb2",
+	0x517B:     "smb",
+	0x01021997: "9p",
+	0x00C36400: "ceph",
+	0x5346414F: "afs",
+}
+
+// networkTypes 网络文件系统
+var networkTypes = map[string]bool{"nfs": true, "cifs": true, "smb2": true, "smb": true, "9p": true, "ceph": true, "afs": true}
+
+// reflinkTypes 支持 FICLONE 克隆文件的文件系统
+var reflinkTypes = map[string]bool{"btrfs": true, "xfs": true, "bcachefs": true}
+
+// describe 通过 statfs 识别文件系统类型和分配单元大小
+func describe(info *Info) {
+	info.CaseSensitive = true
+	info.Symlink = true
+	info.Hardlink = true
+	var st unix.Statfs_t
+	if err := unix.Statfs(info.Volume, &st); err != nil {
+		return
+	}
+	info.Type = fsTypes[int64(st.Type)]
+	info.Network = networkTypes[info.Type]
+	info.Reflink = reflinkTypes[info.Type]
+	info.ClusterSize = int64(st.Bsize)
+	if info.Type == "vfat" || info.Type == "exfat" {
+		info.CaseSensitive = false
+		info.Symlink = false
+		info.Hardlink = false
+	}
+}
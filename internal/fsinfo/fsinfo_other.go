@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !windows
+
+package fsinfo
+
+// describe 其他平台上不识别文件系统类型，按常见的类 Unix 文件系统推测
+func describe(info *Info) {
+	info.CaseSensitive = true
+	info.Symlink = true
+	info.Hardlink = true
+}
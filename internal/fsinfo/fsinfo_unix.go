@@ -0,0 +1,29 @@
+//go:build !windows
+
+package fsinfo
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// volumeOf 向上查找设备号不变的最上层目录作为挂载点
+func volumeOf(dir string) (string, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", err
+	}
+	dev := info.Sys().(*syscall.Stat_t).Dev
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir, nil
+		}
+		pinfo, err := os.Stat(parent)
+		if err != nil || pinfo.Sys().(*syscall.Stat_t).Dev != dev {
+			return dir, nil
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,52 @@
+package fsinfo
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procGetDiskFreeSpace = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetDiskFreeSpaceW")
+
+// fileSupportsBlockRefcounting 对应 FILE_SUPPORTS_BLOCK_REFCOUNTING，ReFS 支持块克隆时设置
+const fileSupportsBlockRefcounting = 0x08000000
+
+// volumeOf 返回目录所在卷的根目录，如 C:\ 或挂载到文件夹的卷的挂载点
+func volumeOf(dir string) (string, error) {
+	p, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumePathName(p, &buf[0], uint32(len(buf))); err != nil {
+		return "", err
+	}
+	return windows.UTF16ToString(buf), nil
+}
+
+// describe 通过 GetVolumeInformation、GetDriveType 和 GetDiskFreeSpace 识别卷的特性
+func describe(info *Info) {
+	root, err := windows.UTF16PtrFromString(info.Volume)
+	if err != nil {
+		return
+	}
+	info.Network = windows.GetDriveType(root) == windows.DRIVE_REMOTE
+
+	var flags uint32
+	name := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumeInformation(root, nil, 0, nil, nil, &flags, &name[0], uint32(len(name))); err == nil {
+		info.Type = windows.UTF16ToString(name)
+		info.Hardlink = flags&windows.FILE_SUPPORTS_HARD_LINKS != 0
+		// 符号链接还需要管理员权限或开发人员模式，默认值只反映卷本身是否支持，实际能力以探测结果为准
+		info.Symlink = flags&windows.FILE_SUPPORTS_REPARSE_POINTS != 0
+		info.Reflink = flags&fileSupportsBlockRefcounting != 0
+	}
+
+	var sectorsPerCluster, bytesPerSector, freeClusters, totalClusters uint32
+	r, _, _ := procGetDiskFreeSpace.Call(uintptr(unsafe.Pointer(root)),
+		uintptr(unsafe.Pointer(&sectorsPerCluster)), uintptr(unsafe.Pointer(&bytesPerSector)),
+		uintptr(unsafe.Pointer(&freeClusters)), uintptr(unsafe.Pointer(&totalClusters)))
+	if r != 0 {
+		info.ClusterSize = int64(sectorsPerCluster) * int64(bytesPerSector)
+	}
+}
@@ -0,0 +1,30 @@
+// Package interference 识别杀毒软件、同步客户端等外部程序造成的拒绝访问和重命名失败，给出可操作的说明而不是原始系统错误
+package interference
+
+import "strings"
+
+// Error 附带可能原因的文件操作错误
+type Error struct {
+	Err    error
+	Causes []string
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error() + "（可能原因：" + strings.Join(e.Causes, "；") + "）"
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Explain 在 err 可能由外部程序干扰造成时返回附带原因说明的错误，否则原样返回 err；path 为操作失败的文件
+func Explain(path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	causes := detect(path, err)
+	if len(causes) == 0 {
+		return err
+	}
+	return &Error{Err: err, Causes: causes}
+}
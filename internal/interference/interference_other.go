@@ -0,0 +1,8 @@
+//go:build !windows
+
+package interference
+
+// detect 其他平台上没有需要识别的常见干扰来源
+func detect(path string, err error) []string {
+	return nil
+}
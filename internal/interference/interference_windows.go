@@ -0,0 +1,155 @@
+//go:build windows
+
+package interference
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+var (
+	modRstrtmgr             = windows.NewLazySystemDLL("rstrtmgr.dll")
+	procRmStartSession      = modRstrtmgr.NewProc("RmStartSession")
+	procRmRegisterResources = modRstrtmgr.NewProc("RmRegisterResources")
+	procRmGetList           = modRstrtmgr.NewProc("RmGetList")
+	procRmEndSession        = modRstrtmgr.NewProc("RmEndSession")
+)
+
+// rmProcessInfo 对应 RM_PROCESS_INFO
+type rmProcessInfo struct {
+	ProcessID        uint32
+	ProcessStartTime windows.Filetime
+	AppName          [256]uint16
+	ServiceShortName [64]uint16
+	ApplicationType  uint32
+	AppStatus        uint32
+	TSSessionID      uint32
+	Restartable      int32
+}
+
+// controlledFolderKey Defender 受控文件夹访问的设置
+const controlledFolderKey = `SOFTWARE\Microsoft\Windows Defender\Windows Defender Exploit Guard\Controlled Folder Access`
+
+// detect 对拒绝访问、共享冲突类错误查找占用文件的进程，并检查受控文件夹访问和 OneDrive 同步目录
+func detect(path string, err error) []string {
+	if !errors.Is(err, windows.ERROR_ACCESS_DENIED) && !errors.Is(err, windows.ERROR_SHARING_VIOLATION) &&
+		!errors.Is(err, windows.ERROR_LOCK_VIOLATION) && !errors.Is(err, os.ErrPermission) {
+		return nil
+	}
+	abs, absErr := filepath.Abs(path)
+	if absErr != nil {
+		abs = path
+	}
+
+	var causes []string
+	if holders := lockingProcesses(abs); len(holders) > 0 {
+		causes = append(causes, fmt.Sprintf("文件正被 %s 占用，关闭这些程序后重试", strings.Join(holders, "、")))
+	}
+	if controlledFolder(abs) {
+		causes = append(causes, "文件位于 Windows Defender 受控文件夹访问保护的目录中，请在 Windows 安全中心的“允许应用通过受控文件夹访问”中添加 flk")
+	}
+	if dir := oneDriveRoot(abs); dir != "" {
+		causes = append(causes, "文件位于 OneDrive 同步目录 "+dir+" 中，同步客户端可能正在上传或锁定该文件，请暂停同步后重试")
+	}
+	return causes
+}
+
+// lockingProcesses 通过 Restart Manager 查询占用文件的进程，返回“程序名(PID)”列表
+func lockingProcesses(path string) []string {
+	if procRmStartSession.Find() != nil {
+		return nil
+	}
+	var session uint32
+	key := make([]uint16, 33)
+	if r, _, _ := procRmStartSession.Call(uintptr(unsafe.Pointer(&session)), 0, uintptr(unsafe.Pointer(&key[0]))); r != 0 {
+		return nil
+	}
+	defer procRmEndSession.Call(uintptr(session))
+
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil
+	}
+	if r, _, _ := procRmRegisterResources.Call(uintptr(session), 1, uintptr(unsafe.Pointer(&name)), 0, 0, 0, 0); r != 0 {
+		return nil
+	}
+
+	var needed, count, reasons uint32
+	var infos []rmProcessInfo
+	for {
+		var p *rmProcessInfo
+		if len(infos) > 0 {
+			p = &infos[0]
+		}
+		count = uint32(len(infos))
+		r, _, _ := procRmGetList.Call(uintptr(session), uintptr(unsafe.Pointer(&needed)), uintptr(unsafe.Pointer(&count)), uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&reasons)))
+		if r == uintptr(windows.ERROR_MORE_DATA) {
+			infos = make([]rmProcessInfo, needed)
+			continue
+		}
+		if r != 0 {
+			return nil
+		}
+		break
+	}
+
+	var holders []string
+	for _, info := range infos[:count] {
+		holders = append(holders, fmt.Sprintf("%s(%d)", windows.UTF16ToString(info.AppName[:]), info.ProcessID))
+	}
+	return holders
+}
+
+// controlledFolder 判断受控文件夹访问已开启且路径位于受保护的目录中
+func controlledFolder(path string) bool {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, controlledFolderKey, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer k.Close()
+	enabled, _, err := k.GetIntegerValue("EnableControlledFolderAccess")
+	if err != nil || enabled != 1 {
+		return false
+	}
+	protected := []string{}
+	if home, err := os.UserHomeDir(); err == nil {
+		for _, dir := range []string{"Documents", "Pictures", "Videos", "Music", "Desktop", "Favorites"} {
+			protected = append(protected, filepath.Join(home, dir))
+		}
+	}
+	if pk, err := registry.OpenKey(registry.LOCAL_MACHINE, controlledFolderKey+`\ProtectedFolders`, registry.QUERY_VALUE); err == nil {
+		if names, err := pk.ReadValueNames(0); err == nil {
+			protected = append(protected, names...)
+		}
+		pk.Close()
+	}
+	for _, dir := range protected {
+		if within(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// oneDriveRoot 返回包含路径的 OneDrive 同步目录，不在其中时返回空字符串
+func oneDriveRoot(path string) string {
+	for _, env := range []string{"OneDrive", "OneDriveConsumer", "OneDriveCommercial"} {
+		if dir := os.Getenv(env); dir != "" && within(path, dir) {
+			return dir
+		}
+	}
+	return ""
+}
+
+// within 判断 path 是否为 dir 或位于 dir 之下，不区分大小写
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(strings.ToLower(filepath.Clean(dir)), strings.ToLower(filepath.Clean(path)))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, `..\`)
+}
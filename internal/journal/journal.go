@@ -0,0 +1,179 @@
+// Package journal 记录每次修改链接的操作（create、delete、fix、apply 等）对文件系统和存储的改动，
+// 供 flk undo 按相反顺序撤销最近一次操作
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/trace"
+)
+
+// FileName 操作日志的文件名，位于配置文件所在目录
+const FileName = "journal.jsonl"
+
+// Keep 最多保留的操作数，更早的操作及其备份会被删除
+const Keep = 50
+
+// Change 一项已执行的文件系统修改及撤销它所需的原状态
+type Change = trace.Change
+
+// Entry 一次操作的记录
+type Entry struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Changes []Change  `json:"changes,omitempty"`
+	// StorePath 操作使用的存储文件
+	StorePath string `json:"store_path"`
+	// StoreBackup 操作前存储文件的副本，为空且 StoreChanged 为 true 表示操作前没有存储文件
+	StoreBackup  string `json:"store_backup,omitempty"`
+	StoreChanged bool   `json:"store_changed,omitempty"`
+	// StoreDigest 操作完成后存储文件的摘要，撤销时据此判断存储是否又被改动过
+	StoreDigest string `json:"store_digest,omitempty"`
+	Undone      bool   `json:"undone,omitempty"`
+}
+
+// Recorder 一次正在进行的操作
+type Recorder struct {
+	path        string
+	entry       Entry
+	storeBefore []byte
+	storeExists bool
+}
+
+// Dir 返回操作日志 path 对应的备份目录
+func Dir(path string) string {
+	return filepath.Join(filepath.Dir(path), "journal")
+}
+
+// Begin 开始记录一次操作：读取当前存储内容，并让 trace 包记录文件系统修改，被删除的文件备份到该操作自己的目录下
+func Begin(path, command, storePath string) *Recorder {
+	id := strconv.FormatInt(time.Now().UnixNano(), 10)
+	r := &Recorder{path: path, entry: Entry{ID: id, Time: time.Now(), Command: command, StorePath: storePath}}
+	if data, err := os.ReadFile(storePath); err == nil {
+		r.storeBefore, r.storeExists = data, true
+	}
+	trace.JournalDir = filepath.Join(Dir(path), id)
+	return r
+}
+
+// Finish 结束记录，操作改动了文件系统或存储时追加到操作日志；没有任何改动时不记录
+func (r *Recorder) Finish() error {
+	trace.JournalDir = ""
+	r.entry.Changes = trace.TakeChanges()
+	after, err := os.ReadFile(r.entry.StorePath)
+	r.entry.StoreChanged = r.storeExists != (err == nil) || !bytes.Equal(r.storeBefore, after)
+	if err == nil {
+		r.entry.StoreDigest = digest(after)
+	}
+	if len(r.entry.Changes) == 0 && !r.entry.StoreChanged {
+		os.RemoveAll(filepath.Join(Dir(r.path), r.entry.ID))
+		return nil
+	}
+	if r.entry.StoreChanged && r.storeExists {
+		dir := filepath.Join(Dir(r.path), r.entry.ID)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		r.entry.StoreBackup = filepath.Join(dir, "store"+filepath.Ext(r.entry.StorePath))
+		if err := os.WriteFile(r.entry.StoreBackup, r.storeBefore, 0644); err != nil {
+			return err
+		}
+	}
+	entries, err := Load(r.path)
+	if err != nil {
+		return err
+	}
+	return save(r.path, append(entries, r.entry))
+}
+
+// Load 读取操作日志，按时间先后排列；文件不存在时返回空
+func Load(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("操作日志 %s 已损坏: %v", path, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// save 写入操作日志，超出 Keep 的旧操作连同其备份一起删除
+func save(path string, entries []Entry) error {
+	if len(entries) > Keep {
+		for _, e := range entries[:len(entries)-Keep] {
+			os.RemoveAll(filepath.Join(Dir(path), e.ID))
+		}
+		entries = entries[len(entries)-Keep:]
+	}
+	var buf bytes.Buffer
+	for _, e := range entries {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(data, '\n'))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// Last 返回最近一次尚未撤销的操作在 entries 中的下标，没有时返回 -1
+func Last(entries []Entry) int {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !entries[i].Undone {
+			return i
+		}
+	}
+	return -1
+}
+
+// StoreModified 判断存储文件在操作之后是否又被改动过，此时恢复操作前的存储会丢失之后的改动
+func (e Entry) StoreModified() bool {
+	if !e.StoreChanged {
+		return false
+	}
+	data, err := os.ReadFile(e.StorePath)
+	if err != nil {
+		return e.StoreDigest != ""
+	}
+	return digest(data) != e.StoreDigest
+}
+
+// MarkUndone 将第 i 项操作标记为已撤销并删除其备份
+func MarkUndone(path string, entries []Entry, i int) error {
+	entries[i].Undone = true
+	os.RemoveAll(filepath.Join(Dir(path), entries[i].ID))
+	return save(path, entries)
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
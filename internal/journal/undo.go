@@ -0,0 +1,106 @@
+package journal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Revert 按相反顺序撤销 e 对文件系统的修改并恢复操作前的存储，返回未能撤销的各项原因；
+// 已被其他程序改动过的位置不会被覆盖
+func Revert(e Entry) []error {
+	var errs []error
+	for i := len(e.Changes) - 1; i >= 0; i-- {
+		c := e.Changes[i]
+		if err := revertChange(c); err != nil {
+			errs = append(errs, fmt.Errorf("%s %v: %v", c.Op, c.Args, err))
+		}
+	}
+	if err := restoreStore(e); err != nil {
+		errs = append(errs, fmt.Errorf("恢复存储 %s: %v", e.StorePath, err))
+	}
+	return errs
+}
+
+func revertChange(c Change) error {
+	switch c.Op {
+	case "symlink":
+		if target, err := os.Readlink(c.Args[1]); err != nil || target != c.Args[0] {
+			return fmt.Errorf("%s 已不是指向 %s 的符号链接", c.Args[1], c.Args[0])
+		}
+		return os.Remove(c.Args[1])
+	case "link":
+		a, errA := os.Stat(c.Args[0])
+		b, errB := os.Lstat(c.Args[1])
+		if errA != nil || errB != nil || !os.SameFile(a, b) {
+			return fmt.Errorf("%s 已不是 %s 的硬链接", c.Args[1], c.Args[0])
+		}
+		return os.Remove(c.Args[1])
+	case "junction":
+		if target, err := os.Readlink(c.Args[1]); err != nil || !samePath(target, c.Args[0]) {
+			return fmt.Errorf("%s 已不是指向 %s 的目录联接", c.Args[1], c.Args[0])
+		}
+		return os.Remove(c.Args[1])
+	case "rename":
+		if err := os.Rename(c.Args[1], c.Args[0]); err != nil {
+			return err
+		}
+		if c.Backup != "" {
+			return os.Rename(c.Backup, c.Args[1])
+		}
+		return nil
+	case "remove", "remove_all":
+		name := c.Args[0]
+		if _, err := os.Lstat(name); err == nil {
+			return fmt.Errorf("%s 已存在", name)
+		}
+		switch {
+		case c.Backup != "":
+			return os.Rename(c.Backup, name)
+		case c.Target != "":
+			return os.Symlink(c.Target, name)
+		case c.Dir:
+			return os.Mkdir(name, 0755)
+		}
+		return fmt.Errorf("删除时未能备份 %s", name)
+	case "write_file":
+		if c.Backup != "" {
+			os.Remove(c.Args[0])
+			return os.Rename(c.Backup, c.Args[0])
+		}
+		return os.Remove(c.Args[0])
+	case "chmod":
+		return os.Chmod(c.Args[0], c.Mode)
+	case "mkdir_all":
+		// 目录中还有其他文件时保留
+		for i := len(c.Created) - 1; i >= 0; i-- {
+			if err := os.Remove(c.Created[i]); err != nil {
+				break
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("不支持撤销该操作")
+}
+
+// restoreStore 将存储恢复为操作前的内容，操作前没有存储文件时删除它
+func restoreStore(e Entry) error {
+	if !e.StoreChanged {
+		return nil
+	}
+	if e.StoreBackup == "" {
+		if err := os.Remove(e.StorePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := os.ReadFile(e.StoreBackup)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(e.StorePath, data, 0644)
+}
+
+func samePath(a, b string) bool {
+	return filepath.Clean(a) == filepath.Clean(b)
+}
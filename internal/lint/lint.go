@@ -0,0 +1,307 @@
+// Package lint 静态分析存储和清单中不可移植或有歧义的写法，并自动修复其中安全的部分
+package lint
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/manifest"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/store"
+)
+
+// 问题级别
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+)
+
+// knownPlatforms 为 runtime.GOOS 的可能取值，其他平台键下的记录永远不会生效
+var knownPlatforms = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+	"illumos": true, "ios": true, "js": true, "linux": true, "netbsd": true,
+	"openbsd": true, "plan9": true, "solaris": true, "wasip1": true, "windows": true,
+}
+
+// homePattern 匹配各平台用户主目录形式的绝对路径
+var homePattern = regexp.MustCompile(`^(/home/[^/]+|/Users/[^/]+|[A-Za-z]:[\\/]Users[\\/][^\\/]+)`)
+
+// requiredKeys 各类型记录必须包含的字段
+var requiredKeys = map[string][]string{
+	"symlink":  {"real", "fake"},
+	"hardlink": {"prim", store.SecoKey},
+	"template": {"real", "fake"},
+	"junction": {"real", "fake"},
+}
+
+// Store 分析存储数据，fix 为 true 时原地修复安全的问题并将其标记为已修复
+func Store(data store.RootConfig, fix bool) []output.LintIssue {
+	home, _ := pathutil.HomeDir()
+	var issues []output.LintIssue
+
+	for _, platform := range slices.Sorted(maps.Keys(data)) {
+		devices := data[platform]
+		if !knownPlatforms[platform] {
+			issues = append(issues, output.LintIssue{
+				Level: LevelWarning, Code: "UNKNOWN_PLATFORM", Location: platform,
+				Detail: fmt.Sprintf("平台键 %s 不是有效的 GOOS 取值，其中的记录永远不会生效", platform),
+			})
+		}
+
+		if types, ok := devices[""]; ok {
+			issue := output.LintIssue{
+				Level: LevelWarning, Code: "MISSING_DEVICE", Location: platform,
+				Detail: "存在设备名为空的记录，应使用 all 表示适用于所有设备", Fixable: true,
+			}
+			if fix {
+				mergeDevice(devices, "", "all", types)
+				issue.Fixed = true
+			}
+			issues = append(issues, issue)
+		}
+
+		var recorded []recordedPath
+		for _, device := range slices.Sorted(maps.Keys(devices)) {
+			links := make(map[string]string)
+			for _, linkType := range slices.Sorted(maps.Keys(devices[device])) {
+				paths := devices[device][linkType]
+				// 父路径本身同样需要可移植，修复时先将记录移到新的父路径下
+				for _, parentPath := range slices.Sorted(maps.Keys(paths)) {
+					location := strings.Join([]string{platform, device, linkType, parentPath}, "/")
+					parent := store.Entry{"parent": parentPath}
+					issues = append(issues, checkPath(parent, "parent", platform, location, home, fix)...)
+					if parent["parent"] != parentPath {
+						paths[parent["parent"]] = append(paths[parent["parent"]], paths[parentPath]...)
+						delete(paths, parentPath)
+					}
+				}
+				for _, parentPath := range slices.Sorted(maps.Keys(paths)) {
+					location := strings.Join([]string{platform, device, linkType, parentPath}, "/")
+					entries := paths[parentPath]
+					if len(entries) == 0 {
+						issue := output.LintIssue{Level: LevelWarning, Code: "EMPTY_GROUP", Location: location, Detail: "父路径下没有任何记录", Fixable: true}
+						if fix {
+							delete(paths, parentPath)
+							issue.Fixed = true
+						}
+						issues = append(issues, issue)
+						continue
+					}
+
+					var kept []store.Entry
+					for _, entry := range entries {
+						for _, key := range requiredKeys[linkType] {
+							if entry[key] == "" {
+								issues = append(issues, output.LintIssue{Level: LevelError, Code: "MISSING_FIELD", Location: location, Detail: fmt.Sprintf("记录缺少字段 %s", key)})
+							}
+						}
+
+						for _, key := range slices.Sorted(maps.Keys(entry)) {
+							if store.IsMetadataKey(key) {
+								continue
+							}
+							issues = append(issues, checkPath(entry, key, platform, location, home, fix)...)
+						}
+
+						if target := entryTarget(linkType, entry, parentPath); target != "" {
+							recorded = append(recorded, recordedPath{device: device, location: location, path: foldHome(target, home), target: true})
+						}
+						duplicate := false
+						for _, link := range entryLinks(linkType, entry) {
+							if !filepath.IsAbs(link) && !strings.HasPrefix(link, "~") {
+								link = parentPath + "/" + link
+							}
+							recorded = append(recorded, recordedPath{device: device, location: location, path: foldHome(link, home)})
+							if prev, ok := links[link]; ok {
+								issue := output.LintIssue{Level: LevelError, Code: "DUPLICATE_LINK", Location: location, Detail: fmt.Sprintf("链接 %s 已在 %s 中记录", link, prev)}
+								if slices.ContainsFunc(kept, func(e store.Entry) bool { return store.EqualIgnoringStamps(e, entry) }) {
+									// 除记录时间外完全相同的重复记录可以安全删除
+									issue.Fixable = true
+									if fix {
+										issue.Fixed = true
+										duplicate = true
+									}
+								}
+								issues = append(issues, issue)
+								continue
+							}
+							links[link] = location
+						}
+						if !duplicate {
+							kept = append(kept, entry)
+						}
+					}
+					if fix {
+						paths[parentPath] = kept
+					}
+				}
+			}
+		}
+		issues = append(issues, overlaps(recorded)...)
+	}
+	return issues
+}
+
+// recordedPath 同一平台下某条记录中的链接或目标路径
+type recordedPath struct {
+	device   string
+	location string
+	path     string
+	target   bool // 为 true 时是记录的真实文件而不是链接文件
+}
+
+// overlaps 查找同一台机器上会同时生效的记录之间的冲突：设备 all 与具体设备记录了同一个链接，
+// 或者一条记录的链接是另一条记录的真实文件（链式管理），修复其中一条会破坏另一条。
+// 同一设备内的重复链接已由 DUPLICATE_LINK 报告
+func overlaps(recorded []recordedPath) []output.LintIssue {
+	var issues []output.LintIssue
+	links := make(map[string][]recordedPath)
+	for _, r := range recorded {
+		if !r.target {
+			links[r.path] = append(links[r.path], r)
+		}
+	}
+	for _, path := range slices.Sorted(maps.Keys(links)) {
+		rs := links[path]
+		for i, a := range rs {
+			for _, b := range rs[i+1:] {
+				if a.device != b.device && (a.device == "all" || b.device == "all") {
+					issues = append(issues, output.LintIssue{Level: LevelWarning, Code: "DEVICE_DUPLICATE", Location: b.location, Detail: fmt.Sprintf("链接 %s 同时记录在 %s 中，在设备 %s 上两条记录都会生效", path, a.location, b.device)})
+				}
+			}
+		}
+	}
+	for _, r := range recorded {
+		if !r.target {
+			continue
+		}
+		for _, l := range links[r.path] {
+			if l.device == r.device || l.device == "all" || r.device == "all" {
+				issues = append(issues, output.LintIssue{Level: LevelWarning, Code: "CHAINED_LINK", Location: r.location, Detail: fmt.Sprintf("真实文件 %s 本身是 %s 中记录的链接，修复时两条记录会相互影响", r.path, l.location)})
+			}
+		}
+	}
+	return issues
+}
+
+// entryTarget 返回条目中的真实文件路径，相对于源根目录的路径无法静态解析，返回空字符串
+func entryTarget(linkType string, entry store.Entry, parentPath string) string {
+	if entry[store.RootKey] != "" {
+		return ""
+	}
+	key := "real"
+	if linkType == "hardlink" {
+		key = "prim"
+	}
+	target := entry[key]
+	if target == "" {
+		return ""
+	}
+	if !filepath.IsAbs(target) && !strings.HasPrefix(target, "~") {
+		target = parentPath + "/" + target
+	}
+	return target
+}
+
+// foldHome 将主目录下的绝对路径改写为 ~ 开头，使两种写法的同一路径可以比较
+func foldHome(path, home string) string {
+	if home != "" && (path == home || strings.HasPrefix(path, home+"/") || strings.HasPrefix(path, home+`\`)) {
+		return "~" + path[len(home):]
+	}
+	return path
+}
+
+// Manifest 分析清单中记录的原始写法，清单文件的修复需要手动完成
+func Manifest(m *manifest.Manifest, name string) []output.LintIssue {
+	home, _ := pathutil.HomeDir()
+	var issues []output.LintIssue
+	links := make(map[string]int)
+	for i, rec := range m.Records {
+		location := fmt.Sprintf("%s#%d", name, i+1)
+		if rec.Name != "" {
+			location += "(" + rec.Name + ")"
+		}
+		if rec.Platform != "" && !knownPlatforms[rec.Platform] {
+			issues = append(issues, output.LintIssue{Level: LevelWarning, Code: "UNKNOWN_PLATFORM", Location: location, Detail: fmt.Sprintf("platform %s 不是有效的 GOOS 取值，该记录永远不会生效", rec.Platform)})
+		}
+		entry := store.Entry{"real": rec.Real, "fake": rec.Fake, "prim": rec.Prim, store.SecoKey: rec.Seco}
+		for _, key := range []string{"real", "fake", "prim", store.SecoKey} {
+			if entry[key] != "" {
+				issues = append(issues, checkPath(entry, key, rec.Platform, location, home, false)...)
+			}
+		}
+		link := rec.Link()
+		if link == "" {
+			continue
+		}
+		key := rec.Platform + "|" + rec.Device + "|" + link
+		if prev, ok := links[key]; ok {
+			issues = append(issues, output.LintIssue{Level: LevelError, Code: "DUPLICATE_LINK", Location: location, Detail: fmt.Sprintf("链接 %s 与第 %d 条记录重复", link, prev)})
+			continue
+		}
+		links[key] = i + 1
+	}
+	return issues
+}
+
+// checkPath 检查条目中单个路径字段的可移植性
+func checkPath(entry store.Entry, key, platform, location, home string, fix bool) []output.LintIssue {
+	var issues []output.LintIssue
+	value := entry[key]
+	where := location + " " + key
+
+	if home != "" && (value == home || strings.HasPrefix(value, home+"/") || strings.HasPrefix(value, home+`\`)) {
+		issue := output.LintIssue{Level: LevelWarning, Code: "ABSOLUTE_HOME", Location: where, Detail: fmt.Sprintf("%s 位于主目录下，使用 ~ 可在其他机器上复用", value), Fixable: true}
+		if fix {
+			entry[key] = "~" + value[len(home):]
+			value = entry[key]
+			issue.Fixed = true
+		}
+		issues = append(issues, issue)
+	} else if homePattern.MatchString(value) {
+		issues = append(issues, output.LintIssue{Level: LevelWarning, Code: "FOREIGN_HOME", Location: where, Detail: fmt.Sprintf("%s 指向某个用户的主目录，在其他机器上可能不存在，建议改为 ~", value)})
+	}
+
+	if strings.Contains(value, "/") && strings.Contains(value, `\`) {
+		issue := output.LintIssue{Level: LevelWarning, Code: "MIXED_SEPARATORS", Location: where, Detail: fmt.Sprintf("%s 混用了 / 和 \\", value)}
+		// 只有 Windows 上 \ 一定是分隔符，其他平台上它可能是文件名的一部分
+		if platform == "windows" {
+			issue.Fixable = true
+			if fix {
+				entry[key] = strings.ReplaceAll(value, "/", `\`)
+				issue.Fixed = true
+			}
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// entryLinks 返回条目中的链接文件路径
+func entryLinks(linkType string, entry store.Entry) []string {
+	if linkType == "hardlink" {
+		return store.SecoPaths(entry)
+	}
+	return []string{entry["fake"]}
+}
+
+// mergeDevice 将设备 from 下的全部记录合并到设备 to
+func mergeDevice(devices store.DeviceGroup, from, to string, types store.TypeGroup) {
+	if devices[to] == nil {
+		devices[to] = make(store.TypeGroup)
+	}
+	for linkType, paths := range types {
+		if devices[to][linkType] == nil {
+			devices[to][linkType] = make(store.PathGroup)
+		}
+		for parentPath, entries := range paths {
+			devices[to][linkType][parentPath] = append(devices[to][linkType][parentPath], entries...)
+		}
+	}
+	delete(devices, from)
+}
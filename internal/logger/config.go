@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/pterm/pterm"
@@ -40,5 +41,13 @@ func FromEnv() *Config { // 定义函数，无入参，返回指向 Config 结
 		config.FilePath = filePath // 将读取到的文件路径赋值给配置实例的 FilePath 字段，指定日志文件的存储路径
 	}
 
+	if maxSize := os.Getenv("FLK_LOG_MAX_SIZE"); maxSize != "" { // 读取日志文件大小上限，如 10MB、512K 或字节数
+		if size, err := ParseSize(maxSize); err == nil {
+			config.MaxSize = size
+		} else {
+			fmt.Fprintln(os.Stderr, "忽略环境变量 FLK_LOG_MAX_SIZE: "+err.Error())
+		}
+	}
+
 	return config // 返回加载了环境变量配置的 Config 结构体指针，这实现了环境变量比约定配置有更高的优先级
 }
@@ -0,0 +1,116 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxSize 日志文件的默认大小上限（字节）
+const DefaultMaxSize = 10 << 20
+
+// rotatingFile 按大小轮转的日志文件：写入后超过上限时将当前文件重命名为 <路径>.1（覆盖旧的备份）并重新创建
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	file    *os.File
+	size    int64
+}
+
+// openRotatingFile 以追加方式打开日志文件，maxSize 不大于 0 时不轮转
+func openRotatingFile(path string, maxSize int64) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	if dir := filepath.Dir(r.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file, r.size = f, info.Size()
+	return nil
+}
+
+// Write 写入一条日志，写入后超过大小上限时轮转
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return len(p), nil
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	if err == nil && r.maxSize > 0 && r.size >= r.maxSize {
+		r.rotate()
+	}
+	return n, err
+}
+
+// rotate 将当前文件移动为备份并重新创建，调用方需持有锁
+func (r *rotatingFile) rotate() {
+	r.file.Close()
+	// Windows 上重命名不会覆盖已存在的文件，先删除旧的备份
+	backup := r.path + ".1"
+	os.Remove(backup)
+	renameErr := os.Rename(r.path, backup)
+	if err := r.open(); err != nil {
+		// 无法重新打开时丢弃之后的文件日志，终端日志不受影响
+		fmt.Fprintf(os.Stderr, "重新打开日志文件 %s 失败: %v\n", r.path, err)
+		r.file = nil
+		return
+	}
+	if renameErr != nil {
+		fmt.Fprintf(os.Stderr, "轮转日志文件 %s 失败: %v\n", r.path, renameErr)
+	}
+}
+
+// Close 关闭日志文件
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Close()
+}
+
+// ParseSize 解析日志文件大小，支持纯字节数和 K、M、G 后缀（可带 B，不区分大小写），如 512K、10MB
+func ParseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(s, "B")
+	unit := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		unit = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		unit = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		unit = 1 << 30
+	}
+	if unit > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("无效的日志文件大小 %q", s)
+	}
+	return n * unit, nil
+}
@@ -1,5 +1,7 @@
 package logger // 声明当前代码所属的包名为 logger
 import (       // 导入代码依赖的外部包列表
+	"fmt"
+	"io"
 	"log/slog" // 导入 Go 标准库的 slog 包，用于实现结构化日志记录功能
 	"os"       // 导入 os 包，用于操作系统交互（如程序退出、文件操作）
 
@@ -9,6 +11,8 @@ import (       // 导入代码依赖的外部包列表
 var ( // 声明包级别的全局变量组
 	globalLogger *slog.Logger  // 声明全局的 slog.Logger 类型指针，作为应用核心日志实例
 	ptermLogger  *pterm.Logger // 声明全局的 pterm.Logger 类型指针，用于配置 pterm 日志行为
+	logFile      *rotatingFile // 启用文件输出时的日志文件，重新初始化时关闭
+	fileLevel    slog.LevelVar // 文件日志的级别，随 SetLevel 与终端日志同步
 )
 
 // Config 日志配置
@@ -17,9 +21,10 @@ type Config struct { // 定义日志配置结构体，封装所有日志相关
 	ShowCaller bool           // 是否显示日志调用方信息（包含文件路径、行号等）
 	ShowTime   bool           // 是否在日志中显示时间戳
 	TimeFormat string         // 时间戳的格式化字符串，遵循 Go 语言的时间格式化规则
-	// 文件输出配置（预留）
-	FileOutput bool   // 是否启用日志文件输出功能（预留配置项，暂未实现完整逻辑）
-	FilePath   string // 日志文件的存储路径（预留配置项，暂未实现完整逻辑）
+	// 文件输出配置
+	FileOutput bool   // 是否同时将 JSON 格式的结构化日志写入文件
+	FilePath   string // 日志文件的存储路径
+	MaxSize    int64  // 日志文件的大小上限（字节），超过后轮转为 <路径>.1，不大于 0 时不轮转
 }
 
 // DefaultConfig 默认配置
@@ -31,21 +36,40 @@ func DefaultConfig() *Config { // 定义函数，无入参，返回指向 Config
 		TimeFormat: "01-02 15:04:05.000", // 默认时间格式，包含月-日 时:分:秒.毫秒
 		FileOutput: false,                // 默认关闭文件输出功能
 		FilePath:   "flk.log",            // 默认日志文件路径为当前目录下的 flk.log 文件
+		MaxSize:    DefaultMaxSize,       // 默认日志文件超过 10MB 后轮转
 	}
 }
 
 // Init 初始化全局 logger
 func Init(config *Config) { // 定义初始化函数，入参为 Config 结构体指针，无返回值，用于初始化全局日志实例
 	if config == nil { // 检查入参配置是否为空指针
-		config = DefaultConfig() // 若配置为空，则使用默认配置初始化
+		config = FromEnv() // 若配置为空，则使用默认配置并应用环境变量
+	}
+	if logFile != nil { // 重新初始化时关闭上一次打开的日志文件
+		logFile.Close()
+		logFile = nil
+	}
+	if config.FileOutput {
+		f, err := openRotatingFile(config.FilePath, config.MaxSize)
+		if err != nil {
+			// 日志文件不可用时只输出到终端，不影响命令执行
+			fmt.Fprintf(os.Stderr, "打开日志文件 %s 失败: %v\n", config.FilePath, err)
+		} else {
+			logFile = f
+		}
+	}
+	callerOffset := 4
+	if logFile != nil {
+		callerOffset++ // 多路 handler 在调用栈中多出一层
 	}
 
 	// 正确的配置方式：分步骤配置 PTerm logger
 	ptermLogger = pterm.DefaultLogger. // 获取 pterm 库的默认 Logger 实例作为配置基础
-						WithLevel(config.Level).       // 设置日志级别为配置项中指定的 Level 值
-						WithCaller(config.ShowCaller). // 设置是否显示调用方信息为配置项中指定的 ShowCaller 值
-						WithCallerOffset(4).           // 设置调用方信息的栈偏移量为 4，这将显示正确的源代码行号
-						WithTime(config.ShowTime)      // 设置是否显示时间戳为配置项中指定的 ShowTime 值（WithTime 方法接收布尔值）
+						WithLevel(config.Level).        // 设置日志级别为配置项中指定的 Level 值
+						WithCaller(config.ShowCaller).  // 设置是否显示调用方信息为配置项中指定的 ShowCaller 值
+						WithCallerOffset(callerOffset). // 设置调用方信息的栈偏移量，这将显示正确的源代码行号
+						WithTime(config.ShowTime).      // 设置是否显示时间戳为配置项中指定的 ShowTime 值（WithTime 方法接收布尔值）
+						WithWriter(os.Stderr)           // 日志写入标准错误，保证标准输出只包含命令结果，便于脚本和远程模式解析
 
 	// 如果需要自定义时间格式，使用 WithTimeFormat
 	if config.TimeFormat != "" { // 检查配置项中的时间格式字符串是否非空
@@ -53,7 +77,12 @@ func Init(config *Config) { // 定义初始化函数，入参为 Config 结构
 	}
 
 	// 创建 slog handler
-	handler := pterm.NewSlogHandler(ptermLogger) // 使用 ptermLogger 作为底层，创建适配 slog 库的 Handler 实例
+	var handler slog.Handler = pterm.NewSlogHandler(ptermLogger) // 使用 ptermLogger 作为底层，创建适配 slog 库的 Handler 实例
+	if logFile != nil {
+		fileLevel.Set(slogLevel(config.Level))
+		fileHandler := slog.NewJSONHandler(logFile, &slog.HandlerOptions{Level: &fileLevel})
+		handler = slog.NewMultiHandler(handler, fileHandler) // 终端和文件各自按级别过滤后输出
+	}
 
 	globalLogger = slog.New(handler) // 使用创建好的 handler 初始化 slog.Logger 实例，并赋值给全局变量
 	slog.SetDefault(globalLogger)    // 将全局 slog.Logger 实例设为 Go 标准库 slog 的默认日志实例
@@ -84,4 +113,25 @@ func Fatal(msg string, args ...any) { // 定义 Fatal 级别日志的便捷函
 // 设置日志级别
 func SetLevel(level pterm.LogLevel) { // 定义动态修改日志级别的函数，入参为 pterm.LogLevel 类型的级别值
 	ptermLogger.Level = level // 直接修改全局 ptermLogger 的 Level 字段，动态调整日志级别
+	fileLevel.Set(slogLevel(level))
+}
+
+// slogLevel 将 pterm 日志级别转换为 slog 日志级别，trace 对应比 debug 更低的级别
+func slogLevel(level pterm.LogLevel) slog.Level {
+	switch level {
+	case pterm.LogLevelTrace:
+		return slog.LevelDebug - 4
+	case pterm.LogLevelDebug:
+		return slog.LevelDebug
+	case pterm.LogLevelWarn:
+		return slog.LevelWarn
+	case pterm.LogLevelError, pterm.LogLevelFatal:
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// SetWriter 修改日志的输出位置，如 --ascii 模式下替换 Unicode 字符的标准错误
+func SetWriter(w io.Writer) {
+	ptermLogger.Writer = w
 }
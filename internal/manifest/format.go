@@ -0,0 +1,69 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// 清单文件支持的格式
+const (
+	JSON = "json"
+	YAML = "yaml"
+	TOML = "toml"
+)
+
+// FormatOf 按扩展名判断清单文件的格式，.yaml、.yml 为 YAML，.toml 为 TOML，其他均按 JSON 读取
+func FormatOf(filePath string) string {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		return YAML
+	case ".toml":
+		return TOML
+	}
+	return JSON
+}
+
+// Marshal 将清单编码为指定格式
+func Marshal(format string, m *Manifest) ([]byte, error) {
+	switch format {
+	case JSON:
+		data, err := json.MarshalIndent(m, "", "    ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case YAML:
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(m); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), enc.Close()
+	case TOML:
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(m); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("不支持的清单格式 %s，可选 json、yaml、toml", format)
+}
+
+// Unmarshal 按指定格式解码清单
+func Unmarshal(format string, data []byte, m *Manifest) error {
+	switch format {
+	case YAML:
+		return yaml.Unmarshal(data, m)
+	case TOML:
+		_, err := toml.Decode(string(data), m)
+		return err
+	}
+	return json.Unmarshal(data, m)
+}
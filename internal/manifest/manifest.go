@@ -0,0 +1,282 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/fileattr"
+	"github.com/jy-eggroll/flk/internal/fileperm"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/state"
+)
+
+// Record 清单中的一条链接声明，相对路径以清单文件所在目录为基准
+type Record struct {
+	Name     string   `json:"name,omitempty" yaml:"name,omitempty" toml:"name,omitempty"`
+	Type     string   `json:"type" yaml:"type" toml:"type"`                                           // symlink、hardlink、template 或 junction
+	Platform string   `json:"platform,omitempty" yaml:"platform,omitempty" toml:"platform,omitempty"` // 为空表示适用于所有平台
+	Device   string   `json:"device,omitempty" yaml:"device,omitempty" toml:"device,omitempty"`       // 为空表示适用于所有设备
+	Real     string   `json:"real,omitempty" yaml:"real,omitempty" toml:"real,omitempty"`
+	Fake     string   `json:"fake,omitempty" yaml:"fake,omitempty" toml:"fake,omitempty"`
+	Prim     string   `json:"prim,omitempty" yaml:"prim,omitempty" toml:"prim,omitempty"`
+	Seco     string   `json:"seco,omitempty" yaml:"seco,omitempty" toml:"seco,omitempty"`
+	Bundle   string   `json:"bundle,omitempty" yaml:"bundle,omitempty" toml:"bundle,omitempty"` // 所属的应用或分组，如 nvim
+	Tags     []string `json:"tags,omitempty" yaml:"tags,omitempty" toml:"tags,omitempty"`
+	Mode     string   `json:"mode,omitempty" yaml:"mode,omitempty" toml:"mode,omitempty"` // 创建后目标文件应有的权限（八进制，如 600）
+	// Frequency 检查频率提示，如 1h、7d
+	Frequency string `json:"frequency,omitempty" yaml:"frequency,omitempty" toml:"frequency,omitempty"`
+	// Attrib 符号链接自身的 Windows 文件属性，如 hidden,system
+	Attrib string `json:"attrib,omitempty" yaml:"attrib,omitempty" toml:"attrib,omitempty"`
+	// LinkStyle 符号链接的写法 relative（默认）或 absolute
+	LinkStyle string `json:"link_style,omitempty" yaml:"link_style,omitempty" toml:"link_style,omitempty"`
+	// Validate 修复前对真实文件的校验，如 nonempty,json 或 cmd:<命令>
+	Validate string `json:"validate,omitempty" yaml:"validate,omitempty" toml:"validate,omitempty"`
+	// Note 随检查结果显示的备注
+	Note string `json:"note,omitempty" yaml:"note,omitempty" toml:"note,omitempty"`
+	// DependsOn 列出必须先于本记录应用的记录名称，如先创建目录联接再创建其中的文件链接
+	DependsOn []string `json:"depends_on,omitempty" yaml:"depends_on,omitempty" toml:"depends_on,omitempty"`
+}
+
+// Manifest 可移植的链接清单
+type Manifest struct {
+	Version int      `json:"version" yaml:"version" toml:"version"`
+	Records []Record `json:"records" yaml:"records" toml:"records"`
+}
+
+// Target 返回链接指向的真实文件路径（符号链接的 real 或硬链接的 prim）
+func (r Record) Target() string {
+	if r.Type == "hardlink" {
+		return r.Prim
+	}
+	return r.Real
+}
+
+// Link 返回链接文件路径（符号链接的 fake 或硬链接的 seco）
+func (r Record) Link() string {
+	if r.Type == "hardlink" {
+		return r.Seco
+	}
+	return r.Fake
+}
+
+// Matches 判断记录是否适用于指定的平台和设备
+func (r Record) Matches(platform, device string) bool {
+	if r.Platform != "" && r.Platform != platform {
+		return false
+	}
+	return r.Device == "" || r.Device == device
+}
+
+// Selector 用于从清单中挑选记录，Kind 为 name、bundle、tag 或 path
+type Selector struct {
+	Kind  string
+	Value string
+}
+
+// ParseSelector 解析形如 bundle:nvim、tag:shell、path:~/.config/*、name:git 的选择器，省略前缀时按名称匹配
+func ParseSelector(raw string) (Selector, error) {
+	kind, value, found := strings.Cut(raw, ":")
+	if !found {
+		return Selector{Kind: "name", Value: raw}, nil
+	}
+	switch kind {
+	case "name", "bundle", "tag":
+		return Selector{Kind: kind, Value: value}, nil
+	case "path":
+		expanded, err := pathutil.NormalizePath(value)
+		if err != nil {
+			return Selector{}, err
+		}
+		return Selector{Kind: kind, Value: expanded}, nil
+	}
+	return Selector{}, fmt.Errorf("未知的选择器类型 %s", kind)
+}
+
+// Selected 判断记录是否被选择器选中，path 选择器以通配符匹配链接路径或目标路径
+func (r Record) Selected(sel Selector) bool {
+	switch sel.Kind {
+	case "name":
+		return r.Name == sel.Value
+	case "bundle":
+		return r.Bundle == sel.Value
+	case "tag":
+		return slices.Contains(r.Tags, sel.Value)
+	case "path":
+		for _, p := range []string{r.Link(), r.Target()} {
+			if ok, _ := filepath.Match(sel.Value, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LoadFromFile 读取清单，并将其中的路径展开为绝对路径
+func LoadFromFile(filePath string) (*Manifest, error) {
+	m, err := ReadRaw(filePath)
+	if err != nil {
+		return nil, err
+	}
+	expanded, err := pathutil.NormalizePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	absPath, err := filepath.Abs(expanded)
+	if err != nil {
+		return nil, err
+	}
+	baseDir := filepath.Dir(absPath)
+	for i := range m.Records {
+		if err := m.Records[i].resolve(baseDir); err != nil {
+			return nil, fmt.Errorf("清单第 %d 条记录无效 %w", i+1, err)
+		}
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ReadRaw 读取清单文件但不解析路径、不做校验，保留文件中的原始写法，供 lint 等静态分析使用
+func ReadRaw(filePath string) (*Manifest, error) {
+	expanded, err := pathutil.NormalizePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(expanded)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err := Unmarshal(FormatOf(filePath), b, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// validate 检查记录名称唯一且依赖的记录均存在
+func (m *Manifest) validate() error {
+	names := make(map[string]bool)
+	for _, r := range m.Records {
+		if r.Name == "" {
+			continue
+		}
+		if names[r.Name] {
+			return fmt.Errorf("记录名称 %s 重复", r.Name)
+		}
+		names[r.Name] = true
+	}
+	for _, r := range m.Records {
+		for _, dep := range r.DependsOn {
+			if !names[dep] {
+				return fmt.Errorf("记录 %s 依赖的 %s 不存在", r.Name, dep)
+			}
+		}
+	}
+	return nil
+}
+
+// Levels 按依赖关系将记录分层，同一层内的记录互不依赖，可以并发应用
+// 返回值为每层记录在 records 中的下标，依赖不在 records 中的记录视为已满足
+func Levels(records []Record) ([][]int, error) {
+	index := make(map[string]int)
+	for i, r := range records {
+		if r.Name != "" {
+			index[r.Name] = i
+		}
+	}
+
+	pending := make([]int, len(records)) // 每条记录尚未满足的依赖数
+	dependents := make(map[int][]int)
+	for i, r := range records {
+		for _, dep := range r.DependsOn {
+			if j, ok := index[dep]; ok {
+				pending[i]++
+				dependents[j] = append(dependents[j], i)
+			}
+		}
+	}
+
+	var levels [][]int
+	var current []int
+	for i := range records {
+		if pending[i] == 0 {
+			current = append(current, i)
+		}
+	}
+	done := 0
+	for len(current) > 0 {
+		levels = append(levels, current)
+		done += len(current)
+		var next []int
+		for _, i := range current {
+			for _, j := range dependents[i] {
+				pending[j]--
+				if pending[j] == 0 {
+					next = append(next, j)
+				}
+			}
+		}
+		slices.Sort(next)
+		current = next
+	}
+	if done != len(records) {
+		return nil, fmt.Errorf("记录之间存在循环依赖")
+	}
+	return levels, nil
+}
+
+func (r *Record) resolve(baseDir string) error {
+	switch r.Type {
+	case "symlink", "template", "junction":
+		if r.Real == "" || r.Fake == "" {
+			return fmt.Errorf("%s 需要同时指定 real 和 fake", r.Type)
+		}
+	case "hardlink":
+		if r.Prim == "" || r.Seco == "" {
+			return fmt.Errorf("硬链接需要同时指定 prim 和 seco")
+		}
+	default:
+		return fmt.Errorf("未知类型 %s", r.Type)
+	}
+
+	if r.Mode != "" {
+		if _, err := fileperm.Parse(r.Mode); err != nil {
+			return err
+		}
+	}
+
+	if _, err := state.ParseFrequency(r.Frequency); err != nil {
+		return err
+	}
+
+	if _, err := fileattr.Parse(r.Attrib); err != nil {
+		return err
+	}
+
+	switch r.LinkStyle {
+	case "", config.LinkStyleRelative, config.LinkStyleAbsolute:
+	default:
+		return fmt.Errorf("无效的 link_style %s，可选 %s、%s", r.LinkStyle, config.LinkStyleRelative, config.LinkStyleAbsolute)
+	}
+
+	for _, p := range []*string{&r.Real, &r.Fake, &r.Prim, &r.Seco} {
+		if *p == "" {
+			continue
+		}
+		normalized, err := pathutil.NormalizePath(*p)
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(normalized) {
+			normalized = filepath.Join(baseDir, normalized)
+		}
+		*p = normalized
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/pathutil"
+)
+
+// OldFileName 旧版 flk 保存链接记录的文件名
+const OldFileName = "file-link-manager-links.json"
+
+// oldRecord 旧版 flk 的 SymlinkRecord 和 HardlinkRecord，真实文件（硬链接为主文件）以相对数据目录的路径保存，
+// 链接文件（硬链接为次要文件）以绝对路径保存；字段名按 encoding/json 的规则不区分大小写
+type oldRecord struct {
+	RealRelative string `json:"RealRelative"`
+	FakeAbsolute string `json:"FakeAbsolute"`
+	Device       string `json:"Device"`
+}
+
+// oldLinks file-link-manager-links.json 的内容
+type oldLinks struct {
+	Symlinks  []oldRecord `json:"Symlinks"`
+	Hardlinks []oldRecord `json:"Hardlinks"`
+}
+
+// LoadOld 读取旧版 flk 数据目录 dir 中的 file-link-manager-links.json 并转换为清单：
+// 相对路径基于 dir 解析，链接文件必须是绝对路径，记录的设备按 devices 映射为新名称，未映射的保持原名
+func LoadOld(dir string, devices map[string]string) (*Manifest, error) {
+	expanded, err := pathutil.NormalizePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	baseDir, err := filepath.Abs(expanded)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(filepath.Join(baseDir, OldFileName))
+	if err != nil {
+		return nil, err
+	}
+	var old oldLinks
+	if err := json.Unmarshal(b, &old); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败 %w", OldFileName, err)
+	}
+	if old.Symlinks == nil && old.Hardlinks == nil {
+		return nil, fmt.Errorf("%s 中没有 Symlinks 或 Hardlinks 记录", OldFileName)
+	}
+
+	m := &Manifest{Version: 1, Records: []Record{}}
+	add := func(linkType string, records []oldRecord) error {
+		for i, o := range records {
+			link := o.FakeAbsolute
+			if !filepath.IsAbs(link) && !strings.HasPrefix(link, "~") {
+				return fmt.Errorf("%s 第 %d 条记录的 FakeAbsolute %q 不是绝对路径", linkType, i+1, o.FakeAbsolute)
+			}
+			// NormalizePath 会把相对路径解析为相对当前目录，先按数据目录拼接
+			real := o.RealRelative
+			if real != "" && !filepath.IsAbs(real) && !strings.HasPrefix(real, "~") {
+				real = filepath.Join(baseDir, real)
+			}
+			device := o.Device
+			if mapped, ok := devices[device]; ok {
+				device = mapped
+			}
+			rec := Record{Type: linkType, Device: device}
+			if linkType == "hardlink" {
+				rec.Prim, rec.Seco = real, link
+			} else {
+				rec.Real, rec.Fake = real, link
+			}
+			if err := rec.resolve(baseDir); err != nil {
+				return fmt.Errorf("%s 第 %d 条记录无效 %w", linkType, i+1, err)
+			}
+			m.Records = append(m.Records, rec)
+		}
+		return nil
+	}
+	if err := add("symlink", old.Symlinks); err != nil {
+		return nil, err
+	}
+	if err := add("hardlink", old.Hardlinks); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
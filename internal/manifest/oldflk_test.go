@@ -0,0 +1,59 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeOld(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, OldFileName), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestLoadOld(t *testing.T) {
+	link := filepath.Join(t.TempDir(), "link")
+	dir := writeOld(t, `{
+		"Symlinks": [{"RealRelative": "dots/vimrc", "FakeAbsolute": `+quote(link+".vim")+`, "Device": "laptop"}],
+		"hardlinks": [{"realRelative": "dots/gitconfig", "fakeAbsolute": `+quote(link+".git")+`}]
+	}`)
+
+	m, err := LoadOld(dir, map[string]string{"laptop": "work"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Records) != 2 {
+		t.Fatalf("got %d records, want 2", len(m.Records))
+	}
+	sym, hard := m.Records[0], m.Records[1]
+	if sym.Type != "symlink" || sym.Real != filepath.Join(dir, "dots", "vimrc") || sym.Fake != link+".vim" || sym.Device != "work" {
+		t.Errorf("symlink record = %+v", sym)
+	}
+	if hard.Type != "hardlink" || hard.Prim != filepath.Join(dir, "dots", "gitconfig") || hard.Seco != link+".git" || hard.Device != "" {
+		t.Errorf("hardlink record = %+v", hard)
+	}
+}
+
+func TestLoadOldRejectsRelativeLink(t *testing.T) {
+	dir := writeOld(t, `{"Symlinks": [{"RealRelative": "a", "FakeAbsolute": "rel/b"}]}`)
+	if _, err := LoadOld(dir, nil); err == nil || !strings.Contains(err.Error(), "FakeAbsolute") {
+		t.Fatalf("err = %v, want FakeAbsolute error", err)
+	}
+}
+
+func TestLoadOldRejectsUnknownLayout(t *testing.T) {
+	dir := writeOld(t, `{"links": []}`)
+	if _, err := LoadOld(dir, nil); err == nil {
+		t.Fatal("expected error for file without Symlinks or Hardlinks")
+	}
+}
+
+// quote 将路径写成 JSON 字符串，Windows 路径中的反斜杠需要转义
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `\`, `\\`) + `"`
+}
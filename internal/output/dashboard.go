@@ -0,0 +1,99 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Dashboard flk tui 一帧画面的内容
+type Dashboard struct {
+	// Results 经过筛选后显示的记录
+	Results []CheckResult
+	// Links、Targets 与 Results 一一对应的链接文件和真实文件的绝对路径
+	Links, Targets []string
+	Total, Invalid int
+	// Cursor 选中的记录在 Results 中的下标，Offset 第一行显示的记录下标，Rows 最多显示的记录数
+	Cursor, Offset, Rows int
+	Width                int
+	// Device、Type、Status 当前的筛选条件，空表示全部
+	Device, Type, Status string
+	Checked              time.Time
+	Message              string
+}
+
+// DashboardKeys flk tui 底部显示的按键说明
+const DashboardKeys = "↑/↓ 移动  d 设备  t 类型  e 状态  f 修复  x 删除  o 打开所在文件夹  r 刷新  q 退出"
+
+// RenderDashboard 将 flk tui 的一帧画面渲染为字符串：顶部是统计和筛选条件，中间是记录表格，底部是最近一次操作的结果和按键说明
+func RenderDashboard(d Dashboard) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "共 %d 条记录，无效 %d 条，显示 %d 条  最后检查 %s\n", d.Total, d.Invalid, len(d.Results), d.Checked.Format("15:04:05"))
+	fmt.Fprintf(&b, "设备 [%s]  类型 [%s]  状态 [%s]\n\n", filterLabel(d.Device), filterLabel(d.Type), statusLabel(d.Status))
+
+	pathWidth := max((d.Width-40)/2, 12)
+	table := pterm.TableData{{" ", "状态", "类型", "设备", "链接文件", "真实文件"}}
+	end := min(len(d.Results), d.Offset+d.Rows)
+	for i := d.Offset; i < end; i++ {
+		r := d.Results[i]
+		marker := " "
+		if i == d.Cursor {
+			marker = ">"
+		}
+		status := pterm.Green("有效")
+		if !r.Valid {
+			status = pterm.Red(r.ErrorType)
+		}
+		row := []string{marker, status, truncateString(r.Type, 8), truncateString(r.Device, 10), truncateString(d.Links[i], pathWidth), truncateString(d.Targets[i], pathWidth)}
+		if i == d.Cursor {
+			for j := range row {
+				row[j] = pterm.Bold.Sprint(row[j])
+			}
+		}
+		table = append(table, row)
+	}
+	if len(d.Results) == 0 {
+		b.WriteString("没有符合筛选条件的记录\n")
+	} else if s, err := pterm.DefaultTable.WithHasHeader().WithBoxed(false).WithData(table).Srender(); err == nil {
+		b.WriteString(s)
+		b.WriteString("\n")
+	}
+	for i := end - d.Offset; i < d.Rows; i++ {
+		b.WriteString("\n")
+	}
+
+	if len(d.Results) > 0 && d.Cursor < len(d.Results) && !d.Results[d.Cursor].Valid {
+		b.WriteString(pterm.Red(d.Results[d.Cursor].Error))
+	}
+	b.WriteString("\n")
+	b.WriteString(d.Message + "\n")
+	b.WriteString(pterm.Gray(DashboardKeys))
+	// 区域打印器直接写入终端，不经过 InitStyle 设置的输出
+	if ASCII {
+		return asciiReplacer.Replace(b.String())
+	}
+	return b.String()
+}
+
+func filterLabel(value string) string {
+	if value == "" {
+		return "全部"
+	}
+	return value
+}
+
+// statusLabel 返回状态筛选条件的显示名称：空表示全部，invalid 表示全部无效记录，其他为错误类型
+func statusLabel(status string) string {
+	switch status {
+	case "":
+		return "全部"
+	case "invalid":
+		return "无效"
+	}
+	if label := ErrorTypeLabel(status); label != "" {
+		return status + " " + label
+	}
+	return status
+}
@@ -3,7 +3,14 @@ package output
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/mattn/go-runewidth"
 	"github.com/pterm/pterm"
 )
 
@@ -17,17 +24,26 @@ const (
 
 // CheckResult 单个链接的检查结果
 type CheckResult struct {
-	Type      string `json:"type"`
-	Device    string `json:"device"`
-	Path      string `json:"path"`
-	BasePath  string `json:"base_path,omitempty"`
-	Real      string `json:"real,omitempty"`
-	Fake      string `json:"fake,omitempty"`
-	Prim      string `json:"prim,omitempty"`
-	Seco      string `json:"seco,omitempty"`
-	Valid     bool   `json:"valid"`
-	Error     string `json:"error,omitempty"`
-	ErrorType string `json:"error_type,omitempty"`
+	Type      string   `json:"type"`
+	Device    string   `json:"device"`
+	Path      string   `json:"path"`
+	BasePath  string   `json:"base_path,omitempty"`
+	Real      string   `json:"real,omitempty"`
+	Fake      string   `json:"fake,omitempty"`
+	Prim      string   `json:"prim,omitempty"`
+	Seco      string   `json:"seco,omitempty"`
+	Root      string   `json:"root,omitempty"`
+	Source    string   `json:"source,omitempty"`
+	Mode      string   `json:"mode,omitempty"`
+	Attrib    string   `json:"attrib,omitempty"`
+	LinkStyle string   `json:"link_style,omitempty"`
+	Validate  string   `json:"validate,omitempty"`
+	Note      string   `json:"note,omitempty"`
+	Inode     string   `json:"inode,omitempty"`
+	Group     []string `json:"group,omitempty"`
+	Valid     bool     `json:"valid"`
+	Error     string   `json:"error,omitempty"`
+	ErrorType string   `json:"error_type,omitempty"`
 }
 
 // CreateResult 创建结果
@@ -36,37 +52,600 @@ type CreateResult struct {
 	Type    string `json:"type"`
 	Message string `json:"message,omitempty"`
 	Error   string `json:"error,omitempty"`
+	// Warnings 操作成功或部分成功时仍需调用方注意的问题，如链接已创建但写入存储失败
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// PlanItem 应用清单时计划执行的一项变更
+type PlanItem struct {
+	Action string `json:"action"` // add、modify、remove、link（记录已存在，只创建链接）、conflict（与存储冲突，未执行）或 none
+	Name   string `json:"name,omitempty"`
+	Type   string `json:"type"`
+	Device string `json:"device"`
+	Link   string `json:"link"`
+	Target string `json:"target"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// PrintPlan 打印应用计划
+func PrintPlan(format OutputFormat, items []PlanItem) error {
+	switch format {
+	case JSON:
+		data, err := json.MarshalIndent(items, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case Table:
+		table := pterm.TableData{{"操作", "类型", "设备", "链接路径", "目标路径", "说明"}}
+		for _, item := range items {
+			row := []string{item.Action, item.Type, item.Device, item.Link, item.Target, item.Detail}
+			switch item.Action {
+			case "add", "link":
+				for i := range row {
+					row[i] = pterm.Green(row[i])
+				}
+			case "modify":
+				for i := range row {
+					row[i] = pterm.Yellow(row[i])
+				}
+			case "remove", "conflict":
+				for i := range row {
+					row[i] = pterm.Red(row[i])
+				}
+			}
+			table = append(table, row)
+		}
+		RenderTable(table)
+	}
+	return nil
+}
+
+// DeviceDiff 两个设备之间针对同一目标文件的记录差异
+type DeviceDiff struct {
+	Type   string   `json:"type"`
+	Target string   `json:"target"`
+	Left   []string `json:"left"`
+	Right  []string `json:"right"`
+	Status string   `json:"status"` // only-left、only-right 或 different
+	Copied bool     `json:"copied,omitempty"`
+}
+
+// PrintDeviceDiffs 打印设备差异，left 和 right 为两个设备名称
+func PrintDeviceDiffs(format OutputFormat, left, right string, diffs []DeviceDiff) error {
+	switch format {
+	case JSON:
+		data, err := json.MarshalIndent(diffs, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case Table:
+		statusText := map[string]string{
+			"only-left":  "仅 " + left,
+			"only-right": "仅 " + right,
+			"different":  "链接不同",
+		}
+		table := pterm.TableData{{"类型", "目标", left + " 的链接", right + " 的链接", "差异", "已复制"}}
+		for _, d := range diffs {
+			copied := ""
+			if d.Copied {
+				copied = "是"
+			}
+			row := []string{d.Type, d.Target, strings.Join(d.Left, ", "), strings.Join(d.Right, ", "), statusText[d.Status], copied}
+			if d.Copied {
+				for i := range row {
+					row[i] = pterm.Green(row[i])
+				}
+			}
+			table = append(table, row)
+		}
+		RenderTable(table)
+	}
+	return nil
+}
+
+// DeviceSummary 一个设备在各平台下的记录数
+type DeviceSummary struct {
+	Device  string         `json:"device"`
+	Counts  map[string]int `json:"counts"`
+	Total   int            `json:"total"`
+	Current bool           `json:"current,omitempty"` // 是否为配置文件中本机的设备名称
+}
+
+// PrintDevices 打印设备列表，platforms 为表格中依次显示的平台列
+func PrintDevices(format OutputFormat, platforms []string, devices []DeviceSummary) error {
+	switch format {
+	case JSON:
+		data, err := json.MarshalIndent(devices, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case Table:
+		header := append([]string{"设备"}, platforms...)
+		table := pterm.TableData{append(header, "合计", "本机")}
+		for _, d := range devices {
+			row := []string{d.Device}
+			for _, p := range platforms {
+				row = append(row, fmt.Sprint(d.Counts[p]))
+			}
+			current := ""
+			if d.Current {
+				current = "是"
+			}
+			table = append(table, append(row, fmt.Sprint(d.Total), current))
+		}
+		RenderTable(table)
+	}
+	return nil
+}
+
+// 诊断项的状态
+const (
+	DoctorOK   = "ok"
+	DoctorWarn = "warn"
+	DoctorFail = "fail"
+)
+
+// DoctorCheck flk doctor 的一项诊断结果
+type DoctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // ok、warn 或 fail
+	Detail string `json:"detail,omitempty"`
+}
+
+// PrintDoctorChecks 打印诊断结果
+func PrintDoctorChecks(format OutputFormat, checks []DoctorCheck) error {
+	switch format {
+	case JSON:
+		data, err := json.MarshalIndent(checks, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case Table:
+		statusText := map[string]string{
+			DoctorOK:   pterm.Green("正常"),
+			DoctorWarn: pterm.Yellow("警告"),
+			DoctorFail: pterm.Red("失败"),
+		}
+		table := pterm.TableData{{"项目", "状态", "说明"}}
+		for _, c := range checks {
+			table = append(table, []string{c.Name, statusText[c.Status], c.Detail})
+		}
+		RenderTable(table)
+	}
+	return nil
+}
+
+// LintIssue lint 发现的一个问题
+type LintIssue struct {
+	Level    string `json:"level"`
+	Code     string `json:"code"`
+	Location string `json:"location"`
+	Detail   string `json:"detail"`
+	Fixable  bool   `json:"fixable"`
+	Fixed    bool   `json:"fixed,omitempty"`
+}
+
+// PrintLintIssues 打印 lint 结果
+func PrintLintIssues(format OutputFormat, issues []LintIssue) error {
+	switch format {
+	case JSON:
+		data, err := json.MarshalIndent(issues, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case Table:
+		table := pterm.TableData{{"级别", "代码", "位置", "说明", "修复"}}
+		for _, issue := range issues {
+			fix := ""
+			switch {
+			case issue.Fixed:
+				fix = "已修复"
+			case issue.Fixable:
+				fix = "可修复"
+			}
+			row := []string{issue.Level, issue.Code, issue.Location, issue.Detail, fix}
+			for i := range row {
+				switch {
+				case issue.Fixed:
+					row[i] = pterm.Green(row[i])
+				case issue.Level == "error":
+					row[i] = pterm.Red(row[i])
+				default:
+					row[i] = pterm.Yellow(row[i])
+				}
+			}
+			table = append(table, row)
+		}
+		RenderTable(table)
+	}
+	return nil
+}
+
+// 记录的验证状态
+const (
+	RecordVerified = "verified" // 最近一次检查时有效
+	RecordBroken   = "broken"   // 最近一次检查时无效
+	RecordUnknown  = "unknown"  // 从未检查过，或超过期限没有检查（如所在驱动器一直未挂载）
+)
+
+// RecordItem 列出的一条记录，不访问文件系统，验证信息来自状态文件
+type RecordItem struct {
+	Type         string     `json:"type"`
+	Device       string     `json:"device"`
+	Link         string     `json:"link"`
+	Target       string     `json:"target"`
+	LastVerified *time.Time `json:"last_verified,omitempty"`
+	Status       string     `json:"status"`
+	// CreatedAt、CreatedByHost 记录首次写入存储的时间和主机，UpdatedAt、Version 最近一次写入的时间和 flk 版本
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+	CreatedByHost string     `json:"created_by_host,omitempty"`
+	Version       string     `json:"flk_version,omitempty"`
+}
+
+// PrintRecords 打印记录列表
+func PrintRecords(format OutputFormat, items []RecordItem) error {
+	switch format {
+	case JSON:
+		data, err := json.MarshalIndent(items, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case Table:
+		statusNames := map[string]string{RecordVerified: "有效", RecordBroken: "无效", RecordUnknown: "未知"}
+		termWidth := pterm.GetTerminalWidth()
+		pathWidth := (termWidth-4-6-8-10-10-16-4-8*3)/2 - 3
+		table := pterm.TableData{{"编号", "类型", "设备", "链接文件", "真实文件", "创建时间", "主机", "最后验证", "状态"}}
+		for i, item := range items {
+			verified := "从未"
+			if item.LastVerified != nil {
+				verified = item.LastVerified.Local().Format("2006-01-02 15:04")
+			}
+			created, host := "未记录", item.CreatedByHost
+			if item.CreatedAt != nil {
+				created = item.CreatedAt.Local().Format("2006-01-02")
+			}
+			row := []string{fmt.Sprintf("%d", i+1), truncateString(item.Type, 6), truncateString(item.Device, 8), truncateString(item.Link, pathWidth), truncateString(item.Target, pathWidth), created, truncateString(host, 10), verified, statusNames[item.Status]}
+			for j := 1; j < len(row); j++ {
+				switch item.Status {
+				case RecordBroken:
+					row[j] = pterm.Red(row[j])
+				case RecordUnknown:
+					row[j] = pterm.Yellow(row[j])
+				}
+			}
+			table = append(table, row)
+		}
+		RenderTable(table)
+	}
+	return nil
+}
+
+// PathChange 规范化存储时改写的一个路径字段
+type PathChange struct {
+	Device string `json:"device"`
+	Type   string `json:"type"`
+	Field  string `json:"field"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// PrintPathChanges 打印规范化存储时的改动
+func PrintPathChanges(format OutputFormat, changes []PathChange) error {
+	switch format {
+	case JSON:
+		data, err := json.MarshalIndent(changes, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case Table:
+		if len(changes) == 0 {
+			pterm.Success.Println("存储中的路径均已是规范形式")
+			return nil
+		}
+		pathWidth := (pterm.GetTerminalWidth()-8-8-8-4*3)/2 - 3
+		table := pterm.TableData{{"设备", "类型", "字段", "原路径", "规范路径"}}
+		for _, c := range changes {
+			table = append(table, []string{truncateString(c.Device, 8), truncateString(c.Type, 8), c.Field, truncateString(c.From, pathWidth), truncateString(c.To, pathWidth)})
+		}
+		RenderTable(table)
+	}
+	return nil
+}
+
+// QueryResult 查询单个文件是否由 flk 管理的结果
+type QueryResult struct {
+	File    string `json:"file"`
+	Tracked bool   `json:"tracked"`
+	// Links 以该文件为链接文件的记录
+	Links []RecordItem `json:"links"`
+	// Targets 以该文件为真实文件的记录
+	Targets []RecordItem `json:"targets"`
+}
+
+// PrintQueryResult 打印查询结果
+func PrintQueryResult(format OutputFormat, result QueryResult) error {
+	switch format {
+	case JSON:
+		data, err := json.MarshalIndent(result, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case Table:
+		if !result.Tracked {
+			pterm.Info.Println(result.File + " 不由 flk 管理")
+			return nil
+		}
+		items := append(append([]RecordItem{}, result.Links...), result.Targets...)
+		return PrintRecords(format, items)
+	}
+	return nil
+}
+
+// ResolveResult 解析路径得到的物理路径及其管理情况
+type ResolveResult struct {
+	Path string `json:"path"`
+	// Chain 从展开后的绝对路径开始依次经过的链接，最后一项为链接链的终点
+	Chain    []string `json:"chain"`
+	Resolved string   `json:"resolved"`
+	Exists   bool     `json:"exists"`
+	Tracked  bool     `json:"tracked"`
+	// Records 链接链上任一路径作为链接文件或真实文件出现的记录
+	Records []RecordItem `json:"records"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// PrintResolveResult 打印路径解析结果
+func PrintResolveResult(format OutputFormat, result ResolveResult) error {
+	switch format {
+	case JSON:
+		data, err := json.MarshalIndent(result, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case Table:
+		exists, tracked := "否", "否"
+		if result.Exists {
+			exists = "是"
+		}
+		if result.Tracked {
+			tracked = fmt.Sprintf("是，%d 条记录", len(result.Records))
+		}
+		table := pterm.TableData{
+			{"输入", result.Path},
+			{"链接链", strings.Join(result.Chain, " -> ")},
+			{"物理路径", result.Resolved},
+			{"存在", exists},
+			{"由 flk 管理", tracked},
+		}
+		if result.Error != "" {
+			table = append(table, []string{"错误", pterm.Red(result.Error)})
+		}
+		RenderPairs(table)
+		if result.Tracked {
+			return PrintRecords(format, result.Records)
+		}
+	}
+	return nil
+}
+
+// ApplyResult 应用计划中单项变更的执行结果
+type ApplyResult struct {
+	Name    string `json:"name,omitempty"`
+	Action  string `json:"action"`
+	Type    string `json:"type"`
+	Link    string `json:"link"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// PrintApplyResults 打印应用结果
+func PrintApplyResults(format OutputFormat, results []ApplyResult) error {
+	switch format {
+	case JSON:
+		data, err := json.MarshalIndent(results, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case Table:
+		table := pterm.TableData{{"编号", "名称", "操作", "类型", "链接路径", "成功", "错误"}}
+		for i, r := range results {
+			success := "是"
+			if !r.Success {
+				success = "否"
+			}
+			row := []string{fmt.Sprintf("%d", i+1), r.Name, r.Action, r.Type, r.Link, success, r.Error}
+			if !r.Success {
+				for i := range row {
+					row[i] = pterm.Red(row[i])
+				}
+			}
+			table = append(table, row)
+		}
+		RenderTable(table)
+	}
+	return nil
+}
+
+// PlannedOp 因 --dry-run 未执行的一项文件系统操作或存储修改
+type PlannedOp struct {
+	Op   string   `json:"op"`
+	Args []string `json:"args"`
+}
+
+// dryRunOps 计划操作在表格中显示的名称
+var dryRunOps = map[string]string{
+	"symlink":      "创建符号链接",
+	"link":         "创建硬链接",
+	"junction":     "创建目录联接",
+	"rename":       "重命名",
+	"remove":       "删除",
+	"remove_all":   "递归删除",
+	"write_file":   "写入文件",
+	"chmod":        "修改权限",
+	"mkdir_all":    "创建目录",
+	"set_attrib":   "设置文件属性",
+	"store_add":    "新增记录",
+	"store_remove": "移除记录",
+}
+
+// PrintDryRun 打印 --dry-run 记录的计划操作；JSON 格式写入标准错误，使标准输出仍只包含命令本身的 JSON 结果
+func PrintDryRun(format OutputFormat, ops []PlannedOp) error {
+	switch format {
+	case JSON:
+		data, err := json.MarshalIndent(map[string][]PlannedOp{"dry_run": ops}, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, string(data))
+	case Table:
+		pterm.Println()
+		if len(ops) == 0 {
+			pterm.Println("--dry-run：没有需要执行的操作")
+			return nil
+		}
+		pterm.Println("--dry-run：以下操作均未执行")
+		table := pterm.TableData{{"编号", "操作", "参数"}}
+		for i, op := range ops {
+			name := dryRunOps[op.Op]
+			if name == "" {
+				name = op.Op
+			}
+			table = append(table, []string{fmt.Sprintf("%d", i+1), name, strings.Join(op.Args, " ")})
+		}
+		return RenderTable(table)
+	}
+	return nil
+}
+
+// ShowNotes 为 true 时表格中完整显示记录备注，否则截断到 noteWidth
+var ShowNotes bool
+
+// noteWidth 表格中备注列的默认宽度
+const noteWidth = 16
+
+// Columns 检查结果表格显示的列及其顺序，为空时显示默认列；JSON 输出不受影响
+var Columns []string
+
+// checkColumn 检查结果表格中的一列，width 为 0 表示与其他路径列平分剩余宽度
+type checkColumn struct {
+	header string
+	width  int
+	value  func(r CheckResult) string
+}
+
+// checkColumns 检查结果表格中可选的列，键为 --columns 中使用的名称
+var checkColumns = map[string]checkColumn{
+	"type":   {"类型", 6, func(r CheckResult) string { return r.Type }},
+	"device": {"设备", 8, func(r CheckResult) string { return r.Device }},
+	"parent": {"父路径", 0, func(r CheckResult) string { return r.Path }},
+	"real": {"相对路径", 0, func(r CheckResult) string {
+		if r.Real == "" {
+			return r.Prim
+		}
+		return r.Real
+	}},
+	"fake": {"绝对路径", 0, func(r CheckResult) string {
+		if r.Fake == "" {
+			return r.Seco
+		}
+		return r.Fake
+	}},
+	"status": {"有效", 4, func(r CheckResult) string {
+		if r.Valid {
+			return "是"
+		}
+		return "否"
+	}},
+	"error": {"错误类型", 10, func(r CheckResult) string { return r.ErrorType }},
+	"note":  {"备注", noteWidth, func(r CheckResult) string { return r.Note }},
+}
+
+// defaultColumns 未指定 Columns 时显示的列，存在备注时追加 note
+var defaultColumns = []string{"type", "device", "parent", "real", "fake", "status", "error"}
+
+// ParseColumns 校验列名并去除空白，可用的列名见 ColumnNames
+func ParseColumns(names []string) ([]string, error) {
+	var columns []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := checkColumns[name]; !ok {
+			return nil, fmt.Errorf("未知的列 %s，可选 %s", name, strings.Join(ColumnNames(), "、"))
+		}
+		columns = append(columns, name)
+	}
+	return columns, nil
+}
+
+// ColumnNames 返回检查结果表格可选的列名
+func ColumnNames() []string {
+	return append(append([]string{}, defaultColumns...), "note")
+}
+
+// errorTypes 检查结果错误类型的说明
+var errorTypes = map[string]string{
+	"PATH_EXPAND_FAIL":     "路径展开失败",
+	"LINK_MISSING":         "链接文件缺失",
+	"LINK_ACCESS_FAIL":     "链接访问失败",
+	"NOT_SYMLINK":          "不是符号链接",
+	"NOT_JUNCTION":         "不是目录联接",
+	"READLINK_FAIL":        "读取链接失败",
+	"TARGET_MISSING":       "目标文件缺失",
+	"TARGET_ACCESS_FAIL":   "目标访问失败",
+	"EXPECTED_MISSING":     "期望文件缺失",
+	"EXPECTED_ACCESS_FAIL": "期望访问失败",
+	"TARGET_MISMATCH":      "目标不匹配",
+	"PRIM_MISSING":         "主文件缺失",
+	"PRIM_ACCESS_FAIL":     "主文件访问失败",
+	"SECO_MISSING":         "次文件缺失",
+	"SECO_ACCESS_FAIL":     "次文件访问失败",
+	"NOT_SAME_FILE":        "不是同一文件",
+	"RENDERED_MISSING":     "渲染结果缺失",
+	"RENDERED_STALE":       "渲染结果过期",
+	"TEMPLATE_RENDER_FAIL": "模板渲染失败",
+	"MODE_DRIFT":           "权限与声明不一致",
+	"ATTRIB_DRIFT":         "文件属性与声明不一致",
+	"PLACEHOLDER":          "云端占位文件，未下载到本地",
+	"PRIM_REPLACED":        "主文件被保存操作替换",
+	"SECO_REPLACED":        "硬链接文件被保存操作替换",
+	"HARDLINK_BROKEN":      "两侧均被替换，硬链接已断开",
+	"ROOT_UNDEFINED":       "源根目录未在本机配置",
+	"UNTRACKED":            "指向已记录源文件但没有记录的符号链接",
+	"NOT_BACKED_UP":        "真实文件不在任何备份目录下",
+}
+
+// ErrorTypeLabel 返回检查结果错误类型的说明，未知类型返回空字符串
+func ErrorTypeLabel(errorType string) string {
+	return errorTypes[errorType]
 }
 
 // PrintCheckResults 打印检查结果
 func PrintCheckResults(format OutputFormat, results []CheckResult) error {
-	// 收集错误类型并打印解释
-	errorTypes := map[string]string{
-		"PATH_EXPAND_FAIL":     "路径展开失败",
-		"LINK_MISSING":         "链接文件缺失",
-		"LINK_ACCESS_FAIL":     "链接访问失败",
-		"NOT_SYMLINK":          "不是符号链接",
-		"READLINK_FAIL":        "读取链接失败",
-		"TARGET_MISSING":       "目标文件缺失",
-		"TARGET_ACCESS_FAIL":   "目标访问失败",
-		"EXPECTED_MISSING":     "期望文件缺失",
-		"EXPECTED_ACCESS_FAIL": "期望访问失败",
-		"TARGET_MISMATCH":      "目标不匹配",
-		"PRIM_MISSING":         "主文件缺失",
-		"PRIM_ACCESS_FAIL":     "主文件访问失败",
-		"SECO_MISSING":         "次文件缺失",
-		"SECO_ACCESS_FAIL":     "次文件访问失败",
-		"NOT_SAME_FILE":        "不是同一文件",
-	}
-	usedTypes := make(map[string]bool)
+	// 收集错误类型并按名称顺序打印解释，使相同的结果输出相同
+	var usedTypes []string
 	for _, r := range results {
-		if r.ErrorType != "" {
-			usedTypes[r.ErrorType] = true
+		if r.ErrorType != "" && !slices.Contains(usedTypes, r.ErrorType) {
+			usedTypes = append(usedTypes, r.ErrorType)
 		}
 	}
-	if len(usedTypes) > 0 {
+	sort.Strings(usedTypes)
+	// 错误类型说明只面向阅读表格的用户，JSON 输出需要保持可被直接解析
+	if len(usedTypes) > 0 && format == Table {
 		fmt.Println("Error Types:")
-		for et := range usedTypes {
+		for _, et := range usedTypes {
 			fmt.Printf("  %s: %s\n", et, errorTypes[et])
 		}
 		fmt.Println()
@@ -80,51 +659,91 @@ func PrintCheckResults(format OutputFormat, results []CheckResult) error {
 		}
 		fmt.Println(string(data))
 	case Table:
-		// 动态调整列宽，截断长路径
+		columns := Columns
+		if len(columns) == 0 {
+			columns = defaultColumns
+			// 只有存在备注时才默认显示备注列
+			for _, r := range results {
+				if r.Note != "" {
+					columns = append(columns[:len(columns):len(columns)], "note")
+					break
+				}
+			}
+		}
+
+		// 动态调整列宽，固定宽度的列之外的宽度由路径列平分，截断长路径
 		termWidth := pterm.GetTerminalWidth()
-		table := pterm.TableData{{"编号", "类型", "设备", "父路径", "相对路径", "绝对路径", "有效", "错误类型"}}
+		rest, flexible := termWidth-4, 0
+		header := []string{"编号"}
+		for _, name := range columns {
+			col := checkColumns[name]
+			header = append(header, col.header)
+			rest -= 3
+			switch {
+			case name == "note" && ShowNotes:
+			case col.width == 0:
+				flexible++
+			default:
+				rest -= col.width
+			}
+		}
+		pathWidth := rest
+		if flexible > 0 {
+			pathWidth = rest / flexible
+		}
+		table := pterm.TableData{header}
 		for i, r := range results {
-			num := fmt.Sprintf("%d", i+1)
-			valid := "是"
-			if !r.Valid {
-				valid = "否"
-			}
-			relPath := truncateString(r.Real, (termWidth-7*3-4-8-4-10)/3-3)
-			if relPath == "" {
-				relPath = truncateString(r.Prim, (termWidth-7*3-4-8-4-10)/3-3)
-			}
-			absPath := truncateString(r.Fake, (termWidth-7*3-4-8-4-10)/3-3)
-			if absPath == "" {
-				absPath = truncateString(r.Seco, (termWidth-7*3-4-8-4-10)/3-3)
-			}
-			row := []string{num, truncateString(r.Type, 6), truncateString(r.Device, 8), truncateString(r.Path, (termWidth-7*3-4-8-4-10)/3-3), relPath, absPath, valid, truncateString(r.ErrorType, 10)}
-			if r.Valid {
-				table = append(table, row)
-			} else {
-				table = append(table, []string{
-					num,
-					pterm.Red(truncateString(r.Type, 6)),
-					pterm.Red(truncateString(r.Device, 8)),
-					pterm.Red(truncateString(r.Path, (termWidth-7*3-4-8-4-10)/3-3)),
-					pterm.Red(relPath),
-					pterm.Red(absPath),
-					pterm.Red(valid),
-					pterm.Red(truncateString(r.ErrorType, 10)),
-				})
-			}
-		}
-		pterm.DefaultTable.WithHasHeader().WithBoxed(false).WithData(table).Render()
+			row := []string{fmt.Sprintf("%d", i+1)}
+			for _, name := range columns {
+				col := checkColumns[name]
+				value := col.value(r)
+				switch {
+				case name == "note":
+					if !ShowNotes {
+						value = truncateString(value, col.width)
+					}
+					value = pterm.Gray(value)
+				case col.width == 0:
+					value = truncateString(value, pathWidth)
+				default:
+					value = truncateString(value, col.width)
+				}
+				if !r.Valid && name != "note" {
+					value = pterm.Red(value)
+				}
+				row = append(row, value)
+			}
+			table = append(table, row)
+		}
+		RenderTable(table)
 	}
 	return nil
 }
 
-// truncateString 截断路径，如果超过 maxLen
+// WriteCheckResults 将检查结果以 JSON 写入文件，与终端输出格式无关，供计划任务归档
+func WriteCheckResults(path string, results []CheckResult) error {
+	if results == nil {
+		results = []CheckResult{}
+	}
+	data, err := json.MarshalIndent(results, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// truncateString 截断路径，如果显示宽度超过 maxLen；中日韩等宽字符按两列计算，保证表格对齐
 func truncateString(raw string, maxLen int) string {
-	runes := []rune(raw)
-	if len(runes) <= maxLen {
+	if Linear {
 		return raw
 	}
-	return string(runes[:maxLen-3]) + "..."
+	if runewidth.StringWidth(raw) <= maxLen {
+		return raw
+	}
+	return runewidth.Truncate(raw, maxLen, "...")
 }
 
 // PrintCreateResult 打印创建结果
@@ -143,7 +762,10 @@ func PrintCreateResult(format OutputFormat, result CreateResult) error {
 			success = "否"
 		}
 		table = append(table, []string{success, result.Type, result.Message, result.Error})
-		pterm.DefaultTable.WithHasHeader().WithData(table).Render()
+		RenderTable(table)
+		for _, w := range result.Warnings {
+			pterm.Warning.Println(w)
+		}
 	}
 	return nil
 }
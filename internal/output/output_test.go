@@ -0,0 +1,144 @@
+package output
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// update 为 true 时用当前输出重写 testdata 下的期望文件，用法：go test ./internal/output -update
+var update = flag.Bool("update", false, "重写 testdata 下的期望输出")
+
+// capture 返回 fn 写到标准输出的内容，表格按固定的 120 列终端宽度、无颜色渲染
+func capture(t *testing.T, fn func() error) []byte {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	pterm.SetDefaultOutput(ASCIIWriter(w))
+	pterm.DisableColor()
+	pterm.SetForcedTerminalSize(120, 40)
+	defer func() {
+		os.Stdout = stdout
+		pterm.SetDefaultOutput(stdout)
+	}()
+
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	if err := fn(); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return <-done
+}
+
+// golden 将 got 与 testdata/name 比较
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v，可用 go test ./internal/output -update 生成", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s 的输出已改变，确认改动是有意的后用 -update 更新：\n得到：\n%s\n期望：\n%s", name, got, want)
+	}
+}
+
+// sampleCheckResults 包含中文路径、有效记录和每一种错误类型的检查结果
+func sampleCheckResults() []CheckResult {
+	results := []CheckResult{
+		{Type: "symlink", Device: "all", Path: "~", BasePath: "/home/me", Real: "~/dotfiles/.bashrc", Fake: "~/.bashrc", Valid: true},
+		{Type: "symlink", Device: "笔记本", Path: "~/文档", BasePath: "/home/me/文档", Real: "~/同步盘/笔记/日记.md", Fake: "~/文档/日记.md", Note: "原件 → 同步盘", Valid: true},
+		{Type: "hardlink", Device: "desktop", Path: "/data", BasePath: "/data", Prim: "/data/主文件.conf", Seco: "/data/副本.conf", Group: []string{"/data/副本.conf"}, Valid: true},
+	}
+	types := make([]string, 0, len(errorTypes))
+	for et := range errorTypes {
+		types = append(types, et)
+	}
+	sort.Strings(types)
+	for _, et := range types {
+		results = append(results, CheckResult{
+			Type: "symlink", Device: "all", Path: "~/配置", BasePath: "/home/me/配置",
+			Real: "~/dotfiles/" + et, Fake: "~/配置/" + et, Valid: false, Error: errorTypes[et], ErrorType: et,
+		})
+	}
+	return results
+}
+
+func sampleRecords() []RecordItem {
+	verified := time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC)
+	created := time.Date(2025, 12, 24, 20, 0, 0, 0, time.UTC)
+	return []RecordItem{
+		{Type: "symlink", Device: "all", Link: "/home/me/.bashrc", Target: "/home/me/dotfiles/.bashrc", LastVerified: &verified, Status: RecordVerified,
+			CreatedAt: &created, UpdatedAt: &verified, CreatedByHost: "laptop", Version: "v1.2.0"},
+		{Type: "template", Device: "笔记本", Link: "/home/me/文档/配置.toml", Target: "/home/me/模板/配置.toml.tmpl", LastVerified: &verified, Status: RecordBroken},
+		{Type: "hardlink", Device: "desktop", Link: "/data/副本.conf", Target: "/data/主文件.conf", Status: RecordUnknown},
+	}
+}
+
+func TestPrintCheckResultsGolden(t *testing.T) {
+	for _, tc := range []struct {
+		format OutputFormat
+		file   string
+	}{{Table, "check.table.golden"}, {JSON, "check.json.golden"}} {
+		t.Run(string(tc.format), func(t *testing.T) {
+			got := capture(t, func() error { return PrintCheckResults(tc.format, sampleCheckResults()) })
+			golden(t, tc.file, got)
+		})
+	}
+}
+
+func TestPrintRecordsGolden(t *testing.T) {
+	local := time.Local
+	time.Local = time.UTC
+	defer func() { time.Local = local }()
+	for _, tc := range []struct {
+		format OutputFormat
+		file   string
+	}{{Table, "records.table.golden"}, {JSON, "records.json.golden"}} {
+		t.Run(string(tc.format), func(t *testing.T) {
+			got := capture(t, func() error { return PrintRecords(tc.format, sampleRecords()) })
+			golden(t, tc.file, got)
+		})
+	}
+}
+
+// TestPrintStylesGolden 覆盖 --linear 和 --ascii 下检查结果和记录的表格输出
+func TestPrintStylesGolden(t *testing.T) {
+	local := time.Local
+	time.Local = time.UTC
+	defer func() { time.Local = local }()
+	for _, tc := range []struct {
+		name          string
+		ascii, linear bool
+	}{{"linear", false, true}, {"ascii", true, false}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ASCII, Linear = tc.ascii, tc.linear
+			defer func() { ASCII, Linear = false, false }()
+			got := capture(t, func() error { return PrintCheckResults(Table, sampleCheckResults()) })
+			golden(t, "check."+tc.name+".golden", got)
+			got = capture(t, func() error { return PrintRecords(Table, sampleRecords()) })
+			golden(t, "records."+tc.name+".golden", got)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package output
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+// ASCII 为 true 时将终端输出中的 Unicode 框线、箭头等字符替换为 ASCII 字符，由 --ascii 设置
+var ASCII bool
+
+// Linear 为 true 时表格改为按“列名: 值”逐行输出、记录之间空一行，且不截断路径，
+// 便于屏幕阅读器朗读和写入纯文本日志，由 --linear 设置
+var Linear bool
+
+// asciiReplacer 将 pterm 和日志中出现的 Unicode 字符替换为 ASCII 近似字符
+var asciiReplacer = strings.NewReplacer(
+	"├", "|", "└", "`", "│", "|", "─", "-", "┌", "+", "┐", "+", "┘", "+", "┤", "|", "┬", "+", "┴", "+", "┼", "+",
+	"…", "...", "→", "->", "↑", "^", "↓", "v", "✓", "OK", "✗", "X", "✔", "OK", "✘", "X", "⚠", "!",
+)
+
+// asciiWriter 在写入前替换 Unicode 字符
+type asciiWriter struct {
+	w io.Writer
+}
+
+func (a asciiWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(a.w, asciiReplacer.Replace(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// ASCIIWriter 在 --ascii 模式下返回替换 Unicode 字符的 w，否则原样返回 w
+func ASCIIWriter(w io.Writer) io.Writer {
+	if !ASCII {
+		return w
+	}
+	return asciiWriter{w}
+}
+
+func init() {
+	// https://no-color.org 和 FORCE_COLOR 约定，FORCE_COLOR 优先
+	if os.Getenv("NO_COLOR") != "" {
+		pterm.DisableColor()
+	}
+	if v := os.Getenv("FORCE_COLOR"); v != "" && v != "0" && v != "false" {
+		pterm.EnableColor()
+	}
+}
+
+// InitStyle 在解析命令行参数后应用 --ascii
+func InitStyle() {
+	pterm.SetDefaultOutput(ASCIIWriter(os.Stdout))
+}
+
+// RenderTable 输出第一行为列名的表格，线性模式下逐条记录输出“列名: 值”
+func RenderTable(table pterm.TableData) error {
+	if !Linear {
+		return pterm.DefaultTable.WithHasHeader().WithBoxed(false).WithData(table).Render()
+	}
+	if len(table) == 0 {
+		return nil
+	}
+	header := table[0]
+	for i, row := range table[1:] {
+		if i > 0 {
+			pterm.Println()
+		}
+		for j, cell := range row {
+			if cell == "" || j >= len(header) {
+				continue
+			}
+			pterm.Printfln("%s: %s", header[j], cell)
+		}
+	}
+	return nil
+}
+
+// RenderPairs 输出每行为“名称, 值”的两列表格，线性模式下输出“名称: 值”
+func RenderPairs(table pterm.TableData) error {
+	if !Linear {
+		return pterm.DefaultTable.WithBoxed(false).WithData(table).Render()
+	}
+	for _, row := range table {
+		if len(row) == 2 && row[1] != "" {
+			pterm.Printfln("%s: %s", row[0], row[1])
+		}
+	}
+	return nil
+}
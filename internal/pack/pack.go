@@ -0,0 +1,301 @@
+// Package pack 读写包含记录清单和真实文件的归档，用于将一组链接连同其内容整体迁移到另一台机器
+package pack
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/manifest"
+	"github.com/klauspost/compress/zstd"
+)
+
+// IndexName 归档中索引文件的名称，总是归档的第一个成员
+const IndexName = "flk-pack.json"
+
+// Entry 归档中的一条记录及其真实文件
+type Entry struct {
+	manifest.Record
+	// Root 真实文件所在的源根目录名称，为空表示按原路径恢复
+	Root string `json:"root,omitempty"`
+	// Path Root 非空时为真实文件相对于源根目录的路径，否则为打包时的路径（主目录折叠为 ~）
+	Path string `json:"path"`
+	// File 真实文件在归档中的位置，多条记录指向同一真实文件时共用
+	File string `json:"file"`
+}
+
+// Index 归档的索引
+type Index struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+// FileName 返回第 i 个真实文件在归档中的位置
+func FileName(i int) string {
+	return fmt.Sprintf("files/%d", i)
+}
+
+// Pack 将索引和真实文件写入归档，files[i] 为归档中 FileName(i) 对应的本机文件或目录；
+// 按扩展名选择压缩方式：.tar.zst/.tzst 使用 zstd，.tar.gz/.tgz 使用 gzip，其他不压缩
+func Pack(archivePath string, index *Index, files []string) (err error) {
+	f, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(archivePath)
+		}
+	}()
+
+	var w io.Writer = f
+	switch compression(archivePath) {
+	case "zstd":
+		zw, err := zstd.NewWriter(f)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := zw.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = zw
+	case "gzip":
+		gw := gzip.NewWriter(f)
+		defer func() {
+			if closeErr := gw.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = gw
+	}
+
+	tw := tar.NewWriter(w)
+	data, err := json.MarshalIndent(index, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: IndexName, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg, ModTime: time.Now()}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+	for i, src := range files {
+		if err := addTree(tw, FileName(i), src); err != nil {
+			return fmt.Errorf("打包 %s 失败: %w", src, err)
+		}
+	}
+	return tw.Close()
+}
+
+// addTree 将文件或目录 src 以 name 为根写入归档，目录中的符号链接按链接本身保存
+func addTree(tw *tar.Writer, name, src string) error {
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		linkTarget := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if linkTarget, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, linkTarget)
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(name, filepath.ToSlash(rel))
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		in, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(tw, in)
+		return err
+	})
+}
+
+// Unpack 读取归档，先解析索引并由 place 返回每个真实文件（以 Entry.File 为键）的恢复位置，再将文件写入这些位置；
+// place 未给出位置的文件会被跳过
+func Unpack(archivePath string, place func(*Index) (map[string]string, error)) (*Index, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch compression(archivePath) {
+	case "zstd":
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	case "gzip":
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	tr := tar.NewReader(r)
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("读取归档失败: %w", err)
+	}
+	if hdr.Name != IndexName {
+		return nil, fmt.Errorf("%s 不是 flk pack 生成的归档，缺少 %s", archivePath, IndexName)
+	}
+	index := &Index{}
+	if err := json.NewDecoder(tr).Decode(index); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", IndexName, err)
+	}
+	places, err := place(index)
+	if err != nil {
+		return nil, err
+	}
+
+	// 符号链接在其他成员写入后再创建，避免后续成员经由归档中的符号链接写到恢复位置之外
+	var links []*tar.Header
+	var linkDests, linkRoots []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取归档失败: %w", err)
+		}
+		root, dest, ok := destination(hdr.Name, places)
+		if !ok {
+			continue
+		}
+		if hdr.Typeflag == tar.TypeSymlink {
+			links = append(links, hdr)
+			linkDests, linkRoots = append(linkDests, dest), append(linkRoots, root)
+			continue
+		}
+		if err := extract(tr, hdr, root, dest); err != nil {
+			return nil, fmt.Errorf("恢复 %s 失败: %w", dest, err)
+		}
+	}
+	for i, hdr := range links {
+		if err := extract(tr, hdr, linkRoots[i], linkDests[i]); err != nil {
+			return nil, fmt.Errorf("恢复 %s 失败: %w", linkDests[i], err)
+		}
+	}
+	return index, nil
+}
+
+// destination 返回归档成员所属真实文件的恢复位置 root 及成员在本机上的恢复位置，拒绝包含 .. 的成员名称
+func destination(name string, places map[string]string) (root, dest string, ok bool) {
+	name = path.Clean(name)
+	if strings.HasPrefix(name, "../") || path.IsAbs(name) {
+		return "", "", false
+	}
+	parts := strings.SplitN(name, "/", 3)
+	if len(parts) < 2 {
+		return "", "", false
+	}
+	root, ok = places[parts[0]+"/"+parts[1]]
+	if !ok {
+		return "", "", false
+	}
+	if len(parts) == 2 {
+		return root, root, true
+	}
+	return root, filepath.Join(root, filepath.FromSlash(parts[2])), true
+}
+
+// checkNoSymlink 确认 root 之下、到 dest 为止（含 dest）的各级路径都不是符号链接，
+// 否则写入会经由符号链接落到恢复位置之外
+func checkNoSymlink(root, dest string) error {
+	rel, err := filepath.Rel(root, dest)
+	if err != nil || rel == "." {
+		return err
+	}
+	p := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		p = filepath.Join(p, part)
+		info, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s 是符号链接，拒绝经由它写入", p)
+		}
+	}
+	return nil
+}
+
+// extract 将一个归档成员写入 root 之下的 dest
+func extract(tr *tar.Reader, hdr *tar.Header, root, dest string) error {
+	if err := checkNoSymlink(root, dest); err != nil {
+		return err
+	}
+	mode := os.FileMode(hdr.Mode).Perm()
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		return os.MkdirAll(dest, mode|0700)
+	case tar.TypeSymlink:
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		return os.Symlink(hdr.Linkname, dest)
+	case tar.TypeReg:
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, tr); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	}
+	return nil
+}
+
+// compression 按扩展名判断压缩方式
+func compression(archivePath string) string {
+	lower := strings.ToLower(archivePath)
+	switch {
+	case strings.HasSuffix(lower, ".tar.zst"), strings.HasSuffix(lower, ".tzst"):
+		return "zstd"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "gzip"
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package pack
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/jy-eggroll/flk/internal/manifest"
+)
+
+// member 写入测试归档的一个成员
+type member struct {
+	name, link, content string
+	typeflag            byte
+}
+
+// writeArchive 写入以索引开头、随后为 members 的未压缩归档
+func writeArchive(t *testing.T, members []member) string {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), "test.tar")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tw := tar.NewWriter(f)
+	index, _ := json.Marshal(Index{Version: 1, Entries: []Entry{{File: FileName(0)}}})
+	members = append([]member{{name: IndexName, content: string(index), typeflag: tar.TypeReg}}, members...)
+	for _, m := range members {
+		hdr := &tar.Header{Name: m.name, Linkname: m.link, Typeflag: m.typeflag, Mode: 0644, Size: int64(len(m.content))}
+		if m.typeflag == tar.TypeDir {
+			hdr.Mode = 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(m.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
+
+func placeAt(dir string) func(*Index) (map[string]string, error) {
+	return func(*Index) (map[string]string, error) {
+		return map[string]string{FileName(0): dir}, nil
+	}
+}
+
+func TestPackUnpackRoundTrip(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "nvim")
+	if err := os.MkdirAll(filepath.Join(src, "lua"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "lua", "init.lua"), []byte("print(1)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "out.tar.zst")
+	index := &Index{Version: 1, Entries: []Entry{{Record: manifest.Record{Type: "symlink"}, File: FileName(0)}}}
+	if err := Pack(archive, index, []string{src}); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "restored")
+	got, err := Unpack(archive, placeAt(dest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Entries) != 1 || got.Entries[0].Type != "symlink" {
+		t.Errorf("索引为 %+v", got)
+	}
+	b, err := os.ReadFile(filepath.Join(dest, "lua", "init.lua"))
+	if err != nil || string(b) != "print(1)" {
+		t.Fatalf("恢复的文件内容为 %q, %v", b, err)
+	}
+}
+
+func TestUnpackRefusesWritingThroughArchivedSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("创建符号链接需要权限")
+	}
+	outside := t.TempDir()
+	archive := writeArchive(t, []member{
+		{name: FileName(0), typeflag: tar.TypeDir},
+		{name: FileName(0) + "/a", link: outside, typeflag: tar.TypeSymlink},
+		{name: FileName(0) + "/a/passwd", content: "pwned", typeflag: tar.TypeReg},
+	})
+	dest := filepath.Join(t.TempDir(), "restored")
+	Unpack(archive, placeAt(dest))
+	if _, err := os.Stat(filepath.Join(outside, "passwd")); err == nil {
+		t.Fatal("归档成员经由符号链接写到了恢复位置之外")
+	}
+}
+
+func TestUnpackRefusesExistingSymlinkInDestination(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("创建符号链接需要权限")
+	}
+	outside := t.TempDir()
+	dest := filepath.Join(t.TempDir(), "restored")
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dest, "a")); err != nil {
+		t.Fatal(err)
+	}
+	archive := writeArchive(t, []member{
+		{name: FileName(0) + "/a/passwd", content: "pwned", typeflag: tar.TypeReg},
+	})
+	if _, err := Unpack(archive, placeAt(dest)); err == nil {
+		t.Fatal("期望拒绝经由已存在的符号链接写入")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "passwd")); err == nil {
+		t.Fatal("归档成员经由符号链接写到了恢复位置之外")
+	}
+}
+
+func TestUnpackRestoresSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("创建符号链接需要权限")
+	}
+	archive := writeArchive(t, []member{
+		{name: FileName(0) + "/target", content: "x", typeflag: tar.TypeReg},
+		{name: FileName(0) + "/link", link: "target", typeflag: tar.TypeSymlink},
+	})
+	dest := filepath.Join(t.TempDir(), "restored")
+	if _, err := Unpack(archive, placeAt(dest)); err != nil {
+		t.Fatal(err)
+	}
+	if target, err := os.Readlink(filepath.Join(dest, "link")); err != nil || target != "target" {
+		t.Fatalf("恢复的符号链接指向 %q, %v", target, err)
+	}
+}
+
+func TestDestinationRejectsParentTraversal(t *testing.T) {
+	places := map[string]string{FileName(0): "/restore"}
+	for _, name := range []string{"../etc/passwd", "/etc/passwd", FileName(0) + "/../../etc"} {
+		if _, _, ok := destination(name, places); ok {
+			t.Errorf("destination(%q) 应被拒绝", name)
+		}
+	}
+}
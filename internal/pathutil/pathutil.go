@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-
-	// "runtime"
+	"regexp"
+	"runtime"
 	"strings"
+
+	"github.com/jy-eggroll/flk/internal/trace"
 )
 
 type ExistsButNotDirectoryError struct {
@@ -25,16 +27,59 @@ func (e *ExistsButNotDirectoryError) Is(target error) bool {
 	return true
 }
 
+// Sandbox 非空时所有路径解析都被限制在该目录下，~ 和绝对路径都会映射到其中，用于演示和测试清单
+var Sandbox string
+
+// SetSandbox 启用沙盒模式，dir 不存在时自动创建
+func SetSandbox(dir string) error {
+	expanded, err := ExpandHome(dir)
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(expanded)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(abs, 0755); err != nil {
+		return err
+	}
+	Sandbox = abs
+	return nil
+}
+
+// Confine 将绝对路径映射到沙盒目录下，如 /home/u/.bashrc 映射为 <沙盒>/home/u/.bashrc、C:\Users 映射为 <沙盒>\C\Users；
+// 未启用沙盒、相对路径或已位于沙盒内的路径原样返回
+func Confine(path string) string {
+	if Sandbox == "" || !filepath.IsAbs(path) {
+		return path
+	}
+	if path == Sandbox || strings.HasPrefix(path, Sandbox+string(filepath.Separator)) {
+		return path
+	}
+	volume := filepath.VolumeName(path)
+	return filepath.Join(Sandbox, strings.TrimSuffix(volume, ":"), path[len(volume):])
+}
+
+// HomeDir 返回用户主目录，沙盒模式下返回其在沙盒内的对应路径
+func HomeDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return Confine(home), nil
+}
+
 // FoldHome 函数，接收原始路径字符串，返回将用户主目录替换为~的简化路径
 func FoldHome(path string) (string, error) { // 定义 fold
 
-	home, err := os.UserHomeDir()
+	home, err := HomeDir()
 	if err != nil {
 		return "", err
 	}
 	normPath, _ := NormalizePath(path)
-	if strings.HasPrefix(normPath, home) { // 判断传入的原始路径是否以用户主目录路径为前缀
-		return strings.Replace(normPath, home, "~", 1), nil // 若路径包含主目录前缀，将第一个主目录子串替换为~后返回
+	// 只折叠主目录本身及其下的路径，/home/user2 这类仅名称前缀相同的兄弟目录保持原样
+	if normPath == home || strings.HasPrefix(normPath, home+string(filepath.Separator)) {
+		return "~" + normPath[len(home):], nil
 	}
 	return normPath, nil
 }
@@ -47,8 +92,8 @@ func ExpandHome(path string) (string, error) {
 	}
 
 	// 获取用户主目录
-	home, err := os.UserHomeDir() // 调用 os 包的 UserHomeDir 函数获取当前用户的主目录路径，返回主目录字符串和错误对象
-	if err != nil {               // 判断获取用户主目录的操作是否产生错误
+	home, err := HomeDir() // 获取当前用户的主目录路径（沙盒模式下为沙盒内的对应路径），返回主目录字符串和错误对象
+	if err != nil {        // 判断获取用户主目录的操作是否产生错误
 		return "", err // 若获取主目录出错，返回空字符串和该错误对象
 	}
 
@@ -74,6 +119,15 @@ func NormalizePath(path string) (string, error) { // 定义 NormalizePath 函数
 
 	cleaned := filepath.Clean(expanded) // 调用 filepath.Clean 函数清理展开后的路径，解析路径中的.和..、合并冗余分隔符，生成最简路径
 
+	if Sandbox != "" {
+		// 沙盒模式下相对路径也需要先定位到绝对路径，才能映射到沙盒内
+		abs, err := filepath.Abs(cleaned)
+		if err != nil {
+			return "", err
+		}
+		return Confine(abs), nil
+	}
+
 	return cleaned, nil // 返回清理后的规范化路径和 nil
 }
 
@@ -82,7 +136,7 @@ func ToAbsolute(normalizePath string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return absPath, nil
+	return Confine(absPath), nil
 }
 
 // EnsureDirExists 确保目录存在，如果不存在则创建
@@ -102,9 +156,56 @@ func EnsureDirExists(path string) error {
 
 	// 目录不存在，创建目录（包括所有必要的父目录）
 	// 0755 权限：所有者可读写执行，组和其他用户可读执行，属于泛用权限
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := trace.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// envPattern 匹配 Windows 风格的 %VAR% 环境变量引用
+var envPattern = regexp.MustCompile(`%([A-Za-z_][A-Za-z0-9_()]*)%`)
+
+// ExpandEnv 展开路径中的 $VAR、${VAR} 和 %VAR% 形式的环境变量，未定义的 %VAR% 保持原样
+func ExpandEnv(path string) string {
+	path = envPattern.ReplaceAllStringFunc(path, func(m string) string {
+		if v, ok := os.LookupEnv(m[1 : len(m)-1]); ok {
+			return v
+		}
+		return m
+	})
+	return os.ExpandEnv(path)
+}
+
+// maxLinkHops 解析链接链时最多跟随的次数，超过时视为循环
+const maxLinkHops = 40
+
+// LinkChain 从 path 开始逐个跟随符号链接（Windows 上包括目录联接），返回依次经过的路径，第一项为 path 本身。
+// 最后一项不存在或不是链接时停止；出现循环时返回错误
+func LinkChain(path string) ([]string, error) {
+	chain := []string{path}
+	for len(chain) <= maxLinkHops {
+		current := chain[len(chain)-1]
+		info, err := os.Lstat(current)
+		if err != nil || !isLink(info) {
+			return chain, nil
+		}
+		target, err := os.Readlink(current)
+		if err != nil {
+			return chain, err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(current), target)
+		}
+		chain = append(chain, filepath.Clean(target))
+	}
+	return chain, fmt.Errorf("跟随 %d 次后仍未到达真实文件，可能存在循环链接", maxLinkHops)
+}
+
+// isLink 判断文件是否为符号链接或 Windows 目录联接
+func isLink(info os.FileInfo) bool {
+	if info.Mode()&os.ModeSymlink != 0 {
+		return true
+	}
+	return runtime.GOOS == "windows" && info.Mode()&os.ModeIrregular != 0
+}
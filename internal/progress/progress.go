@@ -0,0 +1,55 @@
+// Package progress 以 NDJSON 向标准错误输出结构化的进度事件，供外部图形界面和编辑器插件展示进度，无需解析终端输出
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	Started  = "started"        // 命令开始执行
+	Checked  = "record-checked" // 检查完一条记录
+	Repaired = "repaired"       // 尝试修复了一条记录，Error 非空表示失败
+	Done     = "done"           // 命令执行结束，Counts 中为各类计数
+)
+
+// Enabled 为 true 时才输出事件，由 --progress-json 设置
+var Enabled bool
+
+var (
+	mu     sync.Mutex
+	writer io.Writer = os.Stderr
+)
+
+// Event 一条进度事件，每条占一行
+type Event struct {
+	Event     string         `json:"event"`
+	Time      time.Time      `json:"time"`
+	Command   string         `json:"command,omitempty"`
+	Type      string         `json:"type,omitempty"`
+	Device    string         `json:"device,omitempty"`
+	Link      string         `json:"link,omitempty"`
+	Valid     *bool          `json:"valid,omitempty"`
+	ErrorType string         `json:"error_type,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	Counts    map[string]int `json:"counts,omitempty"`
+}
+
+// Emit 输出一条事件，未启用时不做任何事
+func Emit(e Event) {
+	if !Enabled {
+		return
+	}
+	e.Time = time.Now()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	writer.Write(append(data, '\n'))
+}
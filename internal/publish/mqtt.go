@@ -0,0 +1,114 @@
+// Package publish 将链接健康状况发布到外部系统，供仪表盘和自动化使用
+package publish
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/jy-eggroll/flk/internal/config"
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/status"
+)
+
+// DefaultTopic 未配置主题前缀时使用的前缀
+const DefaultTopic = "flk"
+
+// timeout 连接和发布的超时时间，服务器不可用时不应长时间阻塞检查
+const timeout = 10 * time.Second
+
+// Health 按设备汇总检查结果
+func Health(results []output.CheckResult) map[string]status.Summary {
+	byDevice := make(map[string][]output.CheckResult)
+	for _, r := range results {
+		byDevice[r.Device] = append(byDevice[r.Device], r)
+	}
+	devices := make(map[string]status.Summary, len(byDevice))
+	for device, rs := range byDevice {
+		devices[device] = status.Summarize(rs)
+	}
+	return devices
+}
+
+// MQTT 向 cfg 指定的服务器发布链接健康状况，主题为：
+//
+//	<前缀>/<主机名>/summary          全部记录的汇总
+//	<前缀>/<主机名>/device/<设备>    每个设备的汇总
+//
+// 开启 Discovery 时同时发布 Home Assistant 自动发现配置，为每台主机创建一个无效链接数传感器
+func MQTT(cfg *config.MQTT, summary status.Summary, results []output.CheckResult) error {
+	if cfg.Broker == "" {
+		return fmt.Errorf("未配置 MQTT 服务器地址")
+	}
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "unknown"
+	}
+	prefix := strings.TrimSuffix(cfg.Topic, "/")
+	if prefix == "" {
+		prefix = DefaultTopic
+	}
+	clientID := cfg.ClientID
+	if clientID == "" {
+		clientID = "flk-" + host
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(clientID).
+		SetUsername(os.ExpandEnv(cfg.Username)).
+		SetPassword(os.ExpandEnv(cfg.Password)).
+		SetConnectTimeout(timeout).
+		SetAutoReconnect(false)
+	client := mqtt.NewClient(opts)
+	if err := wait(client.Connect()); err != nil {
+		return fmt.Errorf("无法连接 MQTT 服务器 %s：%v", cfg.Broker, err)
+	}
+	defer client.Disconnect(250)
+
+	base := prefix + "/" + topicSegment(host)
+	devices := Health(results)
+	messages := map[string]any{base + "/summary": summary}
+	for device, s := range devices {
+		messages[base+"/device/"+topicSegment(device)] = s
+	}
+	if cfg.Discovery {
+		id := "flk_" + topicSegment(host) + "_invalid"
+		messages["homeassistant/sensor/"+id+"/config"] = map[string]any{
+			"name":                  "flk " + host + " 无效链接",
+			"unique_id":             id,
+			"state_topic":           base + "/summary",
+			"value_template":        "{{ value_json.invalid }}",
+			"json_attributes_topic": base + "/summary",
+			"icon":                  "mdi:link-variant-off",
+		}
+	}
+
+	for topic, v := range messages {
+		payload, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if err := wait(client.Publish(topic, 1, cfg.Retain, payload)); err != nil {
+			return fmt.Errorf("发布到 %s 失败：%v", topic, err)
+		}
+	}
+	return nil
+}
+
+// wait 等待 MQTT 操作完成，超时视为失败
+func wait(t mqtt.Token) error {
+	if !t.WaitTimeout(timeout) {
+		return fmt.Errorf("操作超时")
+	}
+	return t.Error()
+}
+
+// topicSegment 将名称中 MQTT 主题的特殊字符替换为下划线
+func topicSegment(s string) string {
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_", " ", "_").Replace(s)
+}
@@ -0,0 +1,73 @@
+// Package redact 将输出中的主目录、用户名和主机名替换为占位符，避免截图和问题报告泄露个人信息
+package redact
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 占位符
+const (
+	HomePlaceholder = "~"
+	UserPlaceholder = "<user>"
+	HostPlaceholder = "<host>"
+)
+
+// Redactor 按顺序替换敏感字符串，较长的字符串优先替换
+type Redactor struct {
+	replacements [][2]string
+}
+
+// New 根据当前用户的主目录、用户名和主机名创建 Redactor
+func New() *Redactor {
+	r := &Redactor{}
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		r.add(home, HomePlaceholder)
+		// 同时处理另一种分隔符的写法，如 Windows 路径出现在 JSON 或 WSL 输出中
+		r.add(filepath.ToSlash(home), HomePlaceholder)
+	}
+	if u, err := user.Current(); err == nil {
+		name := u.Username
+		// Windows 上为 DOMAIN\user
+		if i := strings.LastIndex(name, `\`); i >= 0 {
+			name = name[i+1:]
+		}
+		// 过短的用户名容易误伤普通文本
+		if len(name) >= 3 {
+			r.add(name, UserPlaceholder)
+		}
+	}
+	if host, err := os.Hostname(); err == nil && len(host) >= 3 {
+		r.add(host, HostPlaceholder)
+	}
+	sort.SliceStable(r.replacements, func(i, j int) bool {
+		return len(r.replacements[i][0]) > len(r.replacements[j][0])
+	})
+	return r
+}
+
+func (r *Redactor) add(old, placeholder string) {
+	r.replacements = append(r.replacements, [2]string{old, placeholder})
+}
+
+// String 返回替换后的字符串
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, rep := range r.replacements {
+		s = strings.ReplaceAll(s, rep[0], rep[1])
+	}
+	return s
+}
+
+// Bytes 返回替换后的内容
+func (r *Redactor) Bytes(b []byte) []byte {
+	if r == nil {
+		return b
+	}
+	return []byte(r.String(string(b)))
+}
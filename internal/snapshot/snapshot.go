@@ -0,0 +1,107 @@
+// Package snapshot 保存和读取整个链接状态的命名快照：存储内容加上创建快照时文件系统上观察到的链接状态
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/store"
+)
+
+// 链接在文件系统上的状态
+const (
+	StateSymlink  = "symlink"  // 符号链接，目标见 Readlink
+	StateHardlink = "hardlink" // 与目标文件是同一文件
+	StateRendered = "rendered" // 模板渲染结果与模板一致
+	StateFile     = "file"     // 存在但不是记录所描述的链接
+	StateMissing  = "missing"  // 不存在
+)
+
+// Link 创建快照时观察到的一个链接
+type Link struct {
+	Type     string `json:"type"`
+	Device   string `json:"device"`
+	Link     string `json:"link"`
+	Target   string `json:"target"`
+	State    string `json:"state"`
+	Readlink string `json:"readlink,omitempty"`
+}
+
+// Snapshot 命名快照
+type Snapshot struct {
+	Name      string           `json:"name"`
+	CreatedAt time.Time        `json:"created_at"`
+	Platform  string           `json:"platform"`
+	Store     store.RootConfig `json:"store"`
+	Links     []Link           `json:"links"`
+}
+
+// Dir 返回与存储文件对应的快照目录
+func Dir(storePath string) string {
+	return filepath.Join(filepath.Dir(storePath), "snapshots")
+}
+
+// ValidateName 检查快照名称能否安全地用作文件名
+func ValidateName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\:*?"<>|`) {
+		return fmt.Errorf("无效的快照名称 %q", name)
+	}
+	return nil
+}
+
+// Save 将快照写入 dir，同名快照会被覆盖
+func Save(dir string, s *Snapshot) error {
+	if err := ValidateName(s.Name); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, s.Name+".json"), data, 0644)
+}
+
+// Load 读取 dir 中名为 name 的快照
+func Load(dir, name string) (*Snapshot, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("快照 %s 不存在", name)
+		}
+		return nil, err
+	}
+	s := &Snapshot{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// List 返回 dir 中的全部快照，按创建时间排序
+func List(dir string) ([]*Snapshot, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []*Snapshot
+	for _, f := range files {
+		s, err := Load(dir, strings.TrimSuffix(filepath.Base(f), ".json"))
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, s)
+	}
+	slices.SortFunc(snapshots, func(a, b *Snapshot) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return snapshots, nil
+}
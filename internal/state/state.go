@@ -0,0 +1,246 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/pathutil"
+)
+
+// RecordState 单条记录的运行状态，与存储分开保存，避免每次检查都改写存储文件
+type RecordState struct {
+	LastChecked time.Time `json:"last_checked"`
+	// LastVerified 最近一次检查结果为有效的时间，早于 LastChecked 说明最近一次检查发现链接无效
+	LastVerified time.Time `json:"last_verified,omitzero"`
+	// Repairs 最近一小时内的自动修复时间
+	Repairs []time.Time `json:"repairs,omitempty"`
+	// Streak 未能稳定下来的连续自动修复次数，链接在最后一次修复后保持有效一小时即清零
+	Streak int `json:"repair_streak,omitempty"`
+	// GaveUp 连续自动修复次数达到上限后放弃，之后只报告不修复，手动修复后恢复
+	GaveUp bool `json:"gave_up,omitempty"`
+}
+
+// RepairPolicy 无人值守自动修复的限流策略，避免与反复改写同一路径的程序（如安装程序）无休止地互相覆盖
+type RepairPolicy struct {
+	MaxPerHour  int           // 单条记录每小时最多自动修复的次数
+	Backoff     time.Duration // 连续修复之间的初始等待时间，每次修复后翻倍
+	GiveUpAfter int           // 连续修复该次数仍未稳定时放弃
+}
+
+// DefaultRepairPolicy 未配置时使用的限流策略
+var DefaultRepairPolicy = RepairPolicy{MaxPerHour: 3, Backoff: time.Minute, GiveUpAfter: 5}
+
+// stableAfter 链接在最后一次修复后保持有效多久视为已稳定
+const stableAfter = time.Hour
+
+// State 所有记录的运行状态，键由 Key 生成
+type State struct {
+	Records map[string]RecordState `json:"records"`
+}
+
+// Key 根据平台、设备、类型和链接路径生成记录的唯一标识
+func Key(platform, device, linkType, link string) string {
+	return strings.Join([]string{platform, device, linkType, link}, "|")
+}
+
+// PathFor 返回与存储文件对应的状态文件路径，如 flk-store.json 对应 flk-store.state.json；
+// 该文件总是 JSON 格式，与存储文件的格式无关
+func PathFor(storePath string) string {
+	ext := filepath.Ext(storePath)
+	return strings.TrimSuffix(storePath, ext) + ".state.json"
+}
+
+// Load 读取状态文件，文件不存在时返回空状态
+func Load(filePath string) (*State, error) {
+	s := &State{Records: make(map[string]RecordState)}
+	expanded, err := pathutil.NormalizePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(expanded)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	if s.Records == nil {
+		s.Records = make(map[string]RecordState)
+	}
+	return s, nil
+}
+
+// Save 写入状态文件
+func (s *State) Save(filePath string) error {
+	data, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return err
+	}
+	expanded, err := pathutil.NormalizePath(filePath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(expanded), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(expanded, data, 0644)
+}
+
+// RenameDevice 将设备 from 的运行状态改为属于设备 to
+func (s *State) RenameDevice(from, to string) {
+	for key, rs := range s.Records {
+		parts := strings.SplitN(key, "|", 4)
+		if len(parts) == 4 && parts[1] == from {
+			delete(s.Records, key)
+			parts[1] = to
+			s.Records[strings.Join(parts, "|")] = rs
+		}
+	}
+}
+
+// RemoveDevice 删除设备 device 下全部记录的运行状态
+func (s *State) RemoveDevice(device string) {
+	for key := range s.Records {
+		parts := strings.SplitN(key, "|", 4)
+		if len(parts) == 4 && parts[1] == device {
+			delete(s.Records, key)
+		}
+	}
+}
+
+// RenameKey 将键 from 的运行状态移到键 to 下，to 已有状态时保留 to 的状态
+func (s *State) RenameKey(from, to string) {
+	rs, ok := s.Records[from]
+	if !ok || from == to {
+		return
+	}
+	delete(s.Records, from)
+	if _, exists := s.Records[to]; !exists {
+		s.Records[to] = rs
+	}
+}
+
+// Due 判断记录在给定检查频率下是否到期，未声明频率或从未检查过的记录总是到期
+func (s *State) Due(key string, frequency time.Duration, now time.Time) bool {
+	if frequency <= 0 {
+		return true
+	}
+	rs, ok := s.Records[key]
+	if !ok {
+		return true
+	}
+	return !now.Before(rs.LastChecked.Add(frequency))
+}
+
+// MarkChecked 记录一次检查
+func (s *State) MarkChecked(key string, now time.Time) {
+	rs := s.Records[key]
+	rs.LastChecked = now
+	s.Records[key] = rs
+}
+
+// AllowRepair 判断按策略此时是否允许自动修复该记录，不允许时返回说明原因的错误
+func (s *State) AllowRepair(key string, p RepairPolicy, now time.Time) error {
+	rs := s.Records[key]
+	if rs.GaveUp {
+		return fmt.Errorf("连续自动修复 %d 次后仍被改回，已放弃自动修复，请手动检查后执行 flk fix", rs.Streak)
+	}
+	recent := recentRepairs(rs.Repairs, now)
+	if p.MaxPerHour > 0 && len(recent) >= p.MaxPerHour {
+		return fmt.Errorf("最近一小时已自动修复 %d 次，达到上限", len(recent))
+	}
+	if rs.Streak > 0 && len(recent) > 0 && p.Backoff > 0 {
+		wait := p.Backoff << min(rs.Streak-1, 20)
+		if next := recent[len(recent)-1].Add(wait); now.Before(next) {
+			return fmt.Errorf("退避中，%s 后再尝试自动修复", next.Format("15:04:05"))
+		}
+	}
+	return nil
+}
+
+// RecordRepair 记录一次自动修复，返回该记录是否因此进入放弃状态
+func (s *State) RecordRepair(key string, p RepairPolicy, now time.Time) bool {
+	rs := s.Records[key]
+	rs.Repairs = append(recentRepairs(rs.Repairs, now), now)
+	rs.Streak++
+	if p.GiveUpAfter > 0 && rs.Streak >= p.GiveUpAfter && !rs.GaveUp {
+		rs.GaveUp = true
+		s.Records[key] = rs
+		return true
+	}
+	s.Records[key] = rs
+	return false
+}
+
+// MarkValid 记录一次有效的检查结果，链接在最后一次自动修复后已稳定时清除修复记录和放弃状态
+func (s *State) MarkValid(key string, now time.Time) {
+	rs := s.Records[key]
+	rs.LastVerified = now
+	s.Records[key] = rs
+	if rs.Streak == 0 {
+		return
+	}
+	if len(rs.Repairs) > 0 && now.Sub(rs.Repairs[len(rs.Repairs)-1]) < stableAfter {
+		return
+	}
+	rs.Repairs, rs.Streak, rs.GaveUp = nil, 0, false
+	s.Records[key] = rs
+}
+
+// ResetRepairs 清除记录的自动修复历史，用于用户手动修复之后
+func (s *State) ResetRepairs(key string) {
+	rs, ok := s.Records[key]
+	if !ok {
+		return
+	}
+	rs.Repairs, rs.Streak, rs.GaveUp = nil, 0, false
+	s.Records[key] = rs
+}
+
+// GaveUpCount 返回处于放弃自动修复状态的记录数量
+func (s *State) GaveUpCount() int {
+	n := 0
+	for _, rs := range s.Records {
+		if rs.GaveUp {
+			n++
+		}
+	}
+	return n
+}
+
+// recentRepairs 返回一小时内的修复时间
+func recentRepairs(repairs []time.Time, now time.Time) []time.Time {
+	var recent []time.Time
+	for _, t := range repairs {
+		if now.Sub(t) < time.Hour {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
+
+// ParseFrequency 解析检查频率，除 time.ParseDuration 支持的格式外还支持以 d 表示天，如 7d
+func ParseFrequency(raw string) (time.Duration, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		d, err := time.ParseDuration(days + "h")
+		if err != nil {
+			return 0, fmt.Errorf("无效的检查频率 %s", raw)
+		}
+		return d * 24, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("无效的检查频率 %s", raw)
+	}
+	return d, nil
+}
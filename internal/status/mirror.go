@@ -0,0 +1,95 @@
+package status
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+)
+
+// 镜像文件格式
+const (
+	MirrorJSON   = "json"   // 一个包含摘要和全部链接的 JSON 对象
+	MirrorNDJSON = "ndjson" // 每行一个链接，便于逐行读取的工具使用
+)
+
+// MirrorLink 镜像中单个链接的简化状态
+type MirrorLink struct {
+	Type      string `json:"type"`
+	Device    string `json:"device"`
+	Link      string `json:"link"`
+	Target    string `json:"target"`
+	Valid     bool   `json:"valid"`
+	ErrorType string `json:"error_type,omitempty"`
+}
+
+// Mirror 发布给状态栏、Home Assistant 等外部工具读取的只读链接状态
+type Mirror struct {
+	Summary
+	Platform string       `json:"platform"`
+	Links    []MirrorLink `json:"links"`
+}
+
+// DefaultMirrorPath 返回镜像文件的默认位置：Linux 上优先为 $XDG_RUNTIME_DIR（通常是 /run/user/<uid>），
+// 其他情况为系统临时目录（Windows 上即 %TEMP%）
+func DefaultMirrorPath() string {
+	dir := os.TempDir()
+	if runtime.GOOS != "windows" {
+		if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+			dir = runtimeDir
+		}
+	}
+	return pathutil.Confine(filepath.Join(dir, "flk", "links.json"))
+}
+
+// NewMirror 由检查摘要和结果生成镜像，links、targets 与 results 一一对应，为各结果链接和目标的绝对路径
+func NewMirror(summary Summary, results []output.CheckResult, links, targets []string) Mirror {
+	m := Mirror{Summary: summary, Platform: runtime.GOOS, Links: make([]MirrorLink, 0, len(results))}
+	for i, r := range results {
+		m.Links = append(m.Links, MirrorLink{
+			Type:      r.Type,
+			Device:    r.Device,
+			Link:      links[i],
+			Target:    targets[i],
+			Valid:     r.Valid,
+			ErrorType: r.ErrorType,
+		})
+	}
+	return m
+}
+
+// WriteMirror 以 format 格式将镜像写入 path，先写入临时文件再重命名，读取方不会看到写了一半的内容
+func WriteMirror(path, format string, m Mirror) error {
+	var buf bytes.Buffer
+	if format == MirrorNDJSON {
+		enc := json.NewEncoder(&buf)
+		for _, l := range m.Links {
+			if err := enc.Encode(l); err != nil {
+				return err
+			}
+		}
+	} else {
+		data, err := json.MarshalIndent(m, "", "    ")
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp-" + time.Now().Format("150405.000000000")
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package status
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/output"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+)
+
+// Summary 最近一次检查的摘要，供提示符、编辑器插件等无需重新检查的场景读取
+type Summary struct {
+	CheckedAt  time.Time      `json:"checked_at"`
+	Total      int            `json:"total"`
+	Invalid    int            `json:"invalid"`
+	ErrorTypes map[string]int `json:"error_types,omitempty"`
+	// GaveUp 因反复被改回而放弃自动修复的记录数量
+	GaveUp int `json:"gave_up,omitempty"`
+}
+
+// Summarize 汇总检查结果
+func Summarize(results []output.CheckResult) Summary {
+	s := Summary{CheckedAt: time.Now(), Total: len(results)}
+	for _, r := range results {
+		if r.Valid {
+			continue
+		}
+		s.Invalid++
+		if r.ErrorType != "" {
+			if s.ErrorTypes == nil {
+				s.ErrorTypes = make(map[string]int)
+			}
+			s.ErrorTypes[r.ErrorType]++
+		}
+	}
+	return s
+}
+
+// CachePath 返回存储 storePath 的摘要缓存文件路径，位于用户缓存目录下；
+// 每个存储单独缓存，按存储文件的绝对路径区分，避免切换 --store 时互相覆盖
+func CachePath(storePath string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	if expanded, err := pathutil.NormalizePath(storePath); err == nil {
+		storePath = expanded
+	}
+	if abs, err := filepath.Abs(storePath); err == nil {
+		storePath = abs
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(storePath)))
+	name := "status-" + hex.EncodeToString(sum[:8]) + ".json"
+	return pathutil.Confine(filepath.Join(dir, "flk", name)), nil
+}
+
+// Save 将存储 storePath 的摘要写入缓存
+func Save(storePath string, s Summary) error {
+	path, err := CachePath(storePath)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Load 读取存储 storePath 缓存的摘要，从未检查过时返回的错误满足 os.IsNotExist
+func Load(storePath string) (*Summary, error) {
+	path, err := CachePath(storePath)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Summary{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"path/filepath"
+	"runtime"
+	"slices"
+
+	"github.com/jy-eggroll/flk/internal/pathutil"
+)
+
+// 存储中路径的规范形式：
+//   - 父路径和路径字段均为清理后的绝对路径，位于用户主目录下时以 ~ 开头
+//   - 记录了源根目录（root 字段）的 real、prim 相对于源根目录，只做清理
+//   - 父路径为链接文件所在目录
+// 旧版本写入的未折叠绝对路径、相对路径和含 . 或 .. 的路径仍可正常读取，
+// flk store normalize 会将其批量改写为规范形式
+
+// CanonicalPath 返回路径的规范形式，相对路径先按 base 解析，base 为空时按当前工作目录解析；无法处理时返回原值
+func CanonicalPath(path, base string) string {
+	if path == "" {
+		return path
+	}
+	expanded, err := pathutil.ExpandHome(path)
+	if err != nil {
+		return path
+	}
+	if !filepath.IsAbs(expanded) && base != "" {
+		if b, err := pathutil.ExpandHome(base); err == nil {
+			expanded = filepath.Join(b, expanded)
+		}
+	}
+	abs, err := pathutil.ToAbsolute(filepath.Clean(expanded))
+	if err != nil {
+		return path
+	}
+	folded, err := pathutil.FoldHome(abs)
+	if err != nil {
+		return path
+	}
+	return folded
+}
+
+// canonicalField 返回条目中字段 k 的规范值，元数据原样返回
+func canonicalField(e Entry, k, base string) string {
+	v := e[k]
+	if metadataKeys[k] || v == "" {
+		return v
+	}
+	if e[RootKey] != "" && (k == "real" || k == "prim") {
+		return filepath.Clean(v)
+	}
+	return CanonicalPath(v, base)
+}
+
+// PathChange 规范化时改写的一个字段，Field 为 parent 时表示记录所在的父路径
+type PathChange struct {
+	Device string
+	Type   string
+	Field  string
+	From   string
+	To     string
+}
+
+// Normalize 将当前平台全部记录的路径改写为规范形式，并按链接文件所在目录重新分组，
+// 改写后完全相同的重复记录只保留一条；返回改动列表
+func (m *Manager) Normalize() []PathChange {
+	var changes []PathChange
+	for device, types := range m.Data[runtime.GOOS] {
+		for linkType, paths := range types {
+			normalized := make(PathGroup)
+			for parentPath, entries := range paths {
+				for _, e := range entries {
+					canonical := make(Entry, len(e))
+					for k, v := range e {
+						canonical[k] = canonicalField(e, k, parentPath)
+						if canonical[k] != v {
+							changes = append(changes, PathChange{device, linkType, k, v, canonical[k]})
+						}
+					}
+					key := CanonicalPath(parentPath, "")
+					if link := LinkPath(canonical); link != "" {
+						if expanded, err := pathutil.ExpandHome(link); err == nil {
+							key = CanonicalPath(filepath.Dir(expanded), "")
+						}
+					}
+					if key != parentPath {
+						changes = append(changes, PathChange{device, linkType, "parent", parentPath, key})
+					}
+					if !slices.ContainsFunc(normalized[key], func(x Entry) bool { return EqualIgnoringStamps(x, canonical) }) {
+						normalized[key] = append(normalized[key], canonical)
+					}
+				}
+			}
+			types[linkType] = normalized
+		}
+	}
+	return changes
+}
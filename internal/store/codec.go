@@ -0,0 +1,69 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Codec 存储文件的编码方式，由存储文件的扩展名决定
+type Codec interface {
+	Marshal(data RootConfig) ([]byte, error)
+	Unmarshal(b []byte, data *RootConfig) error
+}
+
+// CodecFor 按扩展名返回存储文件的编码方式，.yaml、.yml 为 YAML，.toml 为 TOML，其他均为 JSON
+func CodecFor(filePath string) Codec {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		return yamlCodec{}
+	case ".toml":
+		return tomlCodec{}
+	}
+	return jsonCodec{}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(data RootConfig) ([]byte, error) {
+	return json.MarshalIndent(data, "", "    ")
+}
+
+func (jsonCodec) Unmarshal(b []byte, data *RootConfig) error {
+	return json.Unmarshal(b, data)
+}
+
+type yamlCodec struct{}
+
+func (yamlCodec) Marshal(data RootConfig) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), enc.Close()
+}
+
+func (yamlCodec) Unmarshal(b []byte, data *RootConfig) error {
+	return yaml.Unmarshal(b, data)
+}
+
+type tomlCodec struct{}
+
+func (tomlCodec) Marshal(data RootConfig) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (tomlCodec) Unmarshal(b []byte, data *RootConfig) error {
+	_, err := toml.Decode(string(b), data)
+	return err
+}
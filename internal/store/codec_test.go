@@ -0,0 +1,64 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	data := RootConfig{
+		"linux": DeviceGroup{
+			"all": TypeGroup{
+				"symlink": PathGroup{
+					"~": {{"real": "~/dotfiles/.bashrc", "fake": "~/.bashrc", "source": "cli"}},
+				},
+				"hardlink": PathGroup{
+					"~/.config": {{"prim": "~/dotfiles/a.conf", "seco": "~/.config/a.conf", "seco.1": "~/.config/b.conf", "seco.2": "~/.config/c.conf"}},
+				},
+			},
+		},
+		"windows": DeviceGroup{
+			"desktop": TypeGroup{
+				"symlink": PathGroup{
+					`C:\Users\me`: {{"real": `D:\dotfiles\profile.ps1`, "fake": `C:\Users\me\profile.ps1`}},
+					"D:":          {{"real": `D:\tools\a`, "fake": `D:\a`}},
+				},
+			},
+		},
+	}
+
+	for _, ext := range []string{".json", ".yaml", ".yml", ".toml"} {
+		t.Run(ext, func(t *testing.T) {
+			codec := CodecFor("flk-store" + ext)
+			b, err := codec.Marshal(data)
+			if err != nil {
+				t.Fatalf("编码失败：%v", err)
+			}
+			var got RootConfig
+			if err := codec.Unmarshal(b, &got); err != nil {
+				t.Fatalf("解码失败：%v\n%s", err, b)
+			}
+			if !reflect.DeepEqual(got, data) {
+				t.Fatalf("往返后内容不同：\n得到 %v\n期望 %v\n编码结果：\n%s", got, data, b)
+			}
+			if paths := SecoPaths(got["linux"]["all"]["hardlink"]["~/.config"][0]); len(paths) != 3 {
+				t.Fatalf("一组硬链接的次要文件为 %v，期望 3 个", paths)
+			}
+		})
+	}
+}
+
+func TestCodecForExtension(t *testing.T) {
+	cases := map[string]Codec{
+		"store.json": jsonCodec{},
+		"store.YAML": yamlCodec{},
+		"store.yml":  yamlCodec{},
+		"store.toml": tomlCodec{},
+		"store":      jsonCodec{},
+	}
+	for path, want := range cases {
+		if got := CodecFor(path); reflect.TypeOf(got) != reflect.TypeOf(want) {
+			t.Errorf("CodecFor(%q) = %T，期望 %T", path, got, want)
+		}
+	}
+}
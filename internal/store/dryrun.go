@@ -0,0 +1,63 @@
+package store
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/trace"
+)
+
+// planSave 在 --dry-run 模式下代替写入存储文件：将相对存储文件（或本次运行中上一次保存）新增和移除的条目记录为计划操作，
+// 修改一个条目表现为移除旧条目并新增新条目
+func (m *Manager) planSave(filePath string) {
+	before := m.dryRunBase
+	if before == nil {
+		before = make(map[string][]string)
+		if expanded, err := pathutil.NormalizePath(filePath); err == nil {
+			if data, err := os.ReadFile(expanded); err == nil {
+				var disk RootConfig
+				if CodecFor(filePath).Unmarshal(data, &disk) == nil {
+					before = flattenEntries(disk)
+				}
+			}
+		}
+	}
+	after := flattenEntries(m.Data)
+	for _, k := range slices.Sorted(maps.Keys(before)) {
+		if _, ok := after[k]; !ok {
+			trace.Plan("store_remove", before[k]...)
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(after)) {
+		if _, ok := before[k]; !ok {
+			trace.Plan("store_add", after[k]...)
+		}
+	}
+	m.dryRunBase = after
+}
+
+// flattenEntries 将全部条目展开为以平台、设备、类型、父路径和条目内容为键的集合，值为这些字段
+func flattenEntries(data RootConfig) map[string][]string {
+	flat := make(map[string][]string)
+	for platform, devices := range data {
+		for device, types := range devices {
+			for linkType, paths := range types {
+				for parentPath, entries := range paths {
+					for _, e := range entries {
+						content, err := json.Marshal(e)
+						if err != nil {
+							continue
+						}
+						fields := []string{platform, device, linkType, parentPath, string(content)}
+						flat[strings.Join(fields, "\x00")] = fields
+					}
+				}
+			}
+		}
+	}
+	return flat
+}
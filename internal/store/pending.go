@@ -0,0 +1,118 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+	"github.com/jy-eggroll/flk/internal/pathutil"
+)
+
+// saveRetryDelays 写入存储文件失败后每次重试前的等待时间，依次翻倍，共约 3 秒
+var saveRetryDelays = []time.Duration{
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	400 * time.Millisecond,
+	800 * time.Millisecond,
+	1600 * time.Millisecond,
+}
+
+// pendingFile 未能写入存储文件的更改，Base 为产生这些更改时存储文件内容的摘要
+type pendingFile struct {
+	Base string     `json:"base"`
+	Data RootConfig `json:"data"`
+}
+
+// PendingPath 返回与存储文件对应的待写入文件路径，如 flk-store.json 对应 flk-store.pending.json；
+// 该文件总是 JSON 格式，与存储文件的格式无关
+func PendingPath(storePath string) string {
+	ext := filepath.Ext(storePath)
+	return strings.TrimSuffix(storePath, ext) + ".pending.json"
+}
+
+// digest 返回存储文件内容的摘要，文件不存在时为空字符串
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// retryable 判断写入失败是否可能是文件被其他程序暂时占用
+func retryable(err error) bool {
+	if errors.Is(err, os.ErrPermission) || errors.Is(err, syscall.EBUSY) {
+		return true
+	}
+	var errno syscall.Errno
+	// ERROR_SHARING_VIOLATION、ERROR_LOCK_VIOLATION
+	return runtime.GOOS == "windows" && errors.As(err, &errno) && (errno == 32 || errno == 33)
+}
+
+// writeWithRetry 写入文件，文件被暂时占用时按 saveRetryDelays 退避重试
+func writeWithRetry(path string, data []byte) error {
+	err := os.WriteFile(path, data, 0644)
+	for _, delay := range saveRetryDelays {
+		if err == nil || !retryable(err) {
+			return err
+		}
+		logger.Debug(fmt.Sprintf("写入存储文件失败，%v 后重试 %v", delay, err))
+		time.Sleep(delay)
+		err = os.WriteFile(path, data, 0644)
+	}
+	return err
+}
+
+// savePending 将当前数据写入待写入文件
+func (m *Manager) savePending(storePath string) error {
+	data, err := json.MarshalIndent(pendingFile{Base: m.base, Data: m.Data}, "", "    ")
+	if err != nil {
+		return err
+	}
+	expanded, err := pathutil.NormalizePath(PendingPath(storePath))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(expanded, data, 0600)
+}
+
+// applyPending 将上次未能写入的更改写回存储文件。存储文件在此期间被修改过时不自动应用，
+// 避免覆盖其他更改，并在之后的保存中保留该文件等待手动合并
+func (m *Manager) applyPending(storePath string) {
+	expanded, err := pathutil.NormalizePath(PendingPath(storePath))
+	if err != nil {
+		return
+	}
+	b, err := os.ReadFile(expanded)
+	if err != nil {
+		return
+	}
+	var p pendingFile
+	if err := json.Unmarshal(b, &p); err != nil || p.Data == nil {
+		logger.Warn("待写入文件 " + expanded + " 无法解析，已忽略")
+		m.pendingBlocked = true
+		return
+	}
+	if p.Base != m.base {
+		logger.Warn("存储文件在上次保存失败后被修改，未自动应用 " + expanded + " 中的更改，请手动合并后删除该文件")
+		m.pendingBlocked = true
+		return
+	}
+	m.Data = p.Data
+	queued, err := m.save(storePath)
+	if err != nil {
+		logger.Warn("写回上次未能保存的更改失败 " + err.Error())
+		return
+	}
+	// 仍无法写入时更改已重新保存到待写入文件，保留它
+	if !queued {
+		os.Remove(expanded)
+		logger.Info("已写入上次未能保存的更改")
+	}
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"slices"
+)
+
+// RemoveRecord 移除指定父路径下第一个包含 match 中所有字段的条目，并清理因此变空的父路径、类型、设备和平台分组；
+// 返回是否找到并移除了条目
+func (m *Manager) RemoveRecord(platform, device, linkType, parentPath string, match Entry) bool {
+	entries := m.Data[platform][device][linkType][parentPath]
+	for i, e := range entries {
+		if entryMatches(e, match) {
+			m.Data[platform][device][linkType][parentPath] = append(entries[:i], entries[i+1:]...)
+			m.prune(platform, device, linkType, parentPath)
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveByPath 移除指定平台下链接文件或真实文件为 path 的全部条目，一组硬链接中任一文件匹配时移除整组；
+// 路径按规范形式比较，记录了源根目录的真实文件路径不参与比较；返回移除的条目数
+func (m *Manager) RemoveByPath(platform, path string) int {
+	want := CanonicalPath(path, "")
+	removed := 0
+	for device, types := range m.Data[platform] {
+		for linkType, paths := range types {
+			for parentPath, entries := range paths {
+				kept := slices.DeleteFunc(entries, func(e Entry) bool {
+					return entryHasPath(e, parentPath, want)
+				})
+				removed += len(entries) - len(kept)
+				paths[parentPath] = kept
+				m.prune(platform, device, linkType, parentPath)
+			}
+		}
+	}
+	return removed
+}
+
+// entryHasPath 判断条目的任一路径字段的规范形式是否为 want
+func entryHasPath(e Entry, parentPath, want string) bool {
+	for k := range e {
+		if metadataKeys[k] || (e[RootKey] != "" && (k == "real" || k == "prim")) {
+			continue
+		}
+		if canonicalField(e, k, parentPath) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveByDevice 移除设备 device 的全部记录，platform 为空时作用于所有平台；返回移除的条目数
+func (m *Manager) RemoveByDevice(platform, device string) int {
+	removed := 0
+	for p, devices := range m.Data {
+		if platform != "" && p != platform {
+			continue
+		}
+		for _, paths := range devices[device] {
+			for _, entries := range paths {
+				removed += len(entries)
+			}
+		}
+		delete(devices, device)
+		if len(devices) == 0 {
+			delete(m.Data, p)
+		}
+	}
+	return removed
+}
+
+// prune 自内向外删除已经为空的父路径、类型、设备和平台分组，使存储文件中不留下空的嵌套对象
+func (m *Manager) prune(platform, device, linkType, parentPath string) {
+	devices := m.Data[platform]
+	types := devices[device]
+	paths := types[linkType]
+	if len(paths[parentPath]) > 0 {
+		return
+	}
+	delete(paths, parentPath)
+	if len(paths) > 0 {
+		return
+	}
+	delete(types, linkType)
+	if len(types) > 0 {
+		return
+	}
+	delete(devices, device)
+	if len(devices) > 0 {
+		return
+	}
+	delete(m.Data, platform)
+}
@@ -0,0 +1,156 @@
+package store
+
+import "testing"
+
+func testManager() *Manager {
+	return &Manager{Data: RootConfig{
+		"linux": DeviceGroup{
+			"laptop": TypeGroup{
+				"symlink": PathGroup{
+					"~": {
+						{"real": "~/dotfiles/a", "fake": "~/.a"},
+						{"real": "~/dotfiles/b", "fake": "~/.b"},
+					},
+				},
+			},
+			"desktop": TypeGroup{
+				"hardlink": PathGroup{
+					"/data": {{"prim": "/data/p", "seco": "/data/s"}},
+				},
+			},
+		},
+		"windows": DeviceGroup{
+			"laptop": TypeGroup{
+				"symlink": PathGroup{
+					"C:\\Users\\me": {{"real": "C:\\dotfiles\\a", "fake": "C:\\Users\\me\\.a"}},
+				},
+			},
+		},
+	}}
+}
+
+func TestRemoveRecordKeepsNonEmptyGroups(t *testing.T) {
+	m := testManager()
+	if !m.RemoveRecord("linux", "laptop", "symlink", "~", Entry{"fake": "~/.a"}) {
+		t.Fatal("未找到 ~/.a 的记录")
+	}
+	entries := m.Data["linux"]["laptop"]["symlink"]["~"]
+	if len(entries) != 1 || entries[0]["fake"] != "~/.b" {
+		t.Fatalf("剩余记录为 %v，期望只剩 ~/.b", entries)
+	}
+}
+
+func TestRemoveRecordPrunesEmptyGroups(t *testing.T) {
+	m := testManager()
+	m.RemoveRecord("linux", "laptop", "symlink", "~", Entry{"fake": "~/.a"})
+	m.RemoveRecord("linux", "laptop", "symlink", "~", Entry{"fake": "~/.b"})
+	if _, ok := m.Data["linux"]["laptop"]; ok {
+		t.Fatalf("设备 laptop 已没有记录但仍保留：%v", m.Data["linux"])
+	}
+	if _, ok := m.Data["linux"]["desktop"]; !ok {
+		t.Fatal("其他设备的记录不应被移除")
+	}
+
+	m.RemoveRecord("linux", "desktop", "hardlink", "/data", Entry{"seco": "/data/s"})
+	if _, ok := m.Data["linux"]; ok {
+		t.Fatalf("平台 linux 已没有记录但仍保留：%v", m.Data["linux"])
+	}
+	if _, ok := m.Data["windows"]; !ok {
+		t.Fatal("其他平台的记录不应被移除")
+	}
+}
+
+func TestRemoveRecordPrunesParentAndType(t *testing.T) {
+	m := testManager()
+	m.Data["linux"]["laptop"]["symlink"]["/etc"] = []Entry{{"real": "~/dotfiles/hosts", "fake": "/etc/hosts"}}
+	m.Data["linux"]["laptop"]["template"] = PathGroup{"~": {{"real": "~/tpl/c", "fake": "~/.c"}}}
+
+	m.RemoveRecord("linux", "laptop", "symlink", "/etc", Entry{"fake": "/etc/hosts"})
+	if _, ok := m.Data["linux"]["laptop"]["symlink"]["/etc"]; ok {
+		t.Fatal("空的父路径 /etc 仍保留")
+	}
+	m.RemoveRecord("linux", "laptop", "template", "~", Entry{"fake": "~/.c"})
+	if _, ok := m.Data["linux"]["laptop"]["template"]; ok {
+		t.Fatal("空的类型 template 仍保留")
+	}
+	if len(m.Data["linux"]["laptop"]["symlink"]["~"]) != 2 {
+		t.Fatal("同一设备下其他类型的记录不应被移除")
+	}
+}
+
+func TestRemoveRecordNoMatch(t *testing.T) {
+	m := testManager()
+	if m.RemoveRecord("linux", "laptop", "symlink", "~", Entry{"fake": "~/.missing"}) {
+		t.Fatal("不存在的记录不应被移除")
+	}
+	if m.RemoveRecord("darwin", "laptop", "symlink", "~", Entry{"fake": "~/.a"}) {
+		t.Fatal("不存在的平台不应被移除")
+	}
+	if _, ok := m.Data["darwin"]; ok {
+		t.Fatal("查找不存在的平台时不应创建空分组")
+	}
+}
+
+func TestRemoveByDevice(t *testing.T) {
+	m := testManager()
+	if n := m.RemoveByDevice("linux", "laptop"); n != 2 {
+		t.Fatalf("移除了 %d 条记录，期望 2", n)
+	}
+	if _, ok := m.Data["linux"]["laptop"]; ok {
+		t.Fatal("设备 laptop 仍保留在 linux 下")
+	}
+	if _, ok := m.Data["windows"]["laptop"]; !ok {
+		t.Fatal("指定平台时不应移除其他平台的同名设备")
+	}
+
+	if n := m.RemoveByDevice("", "laptop"); n != 1 {
+		t.Fatalf("移除了 %d 条记录，期望 1", n)
+	}
+	if _, ok := m.Data["windows"]; ok {
+		t.Fatal("平台 windows 已没有记录但仍保留")
+	}
+	if n := m.RemoveByDevice("", "desktop"); n != 1 || len(m.Data) != 0 {
+		t.Fatalf("移除全部设备后存储应为空，实际为 %v", m.Data)
+	}
+}
+
+func TestRemoveByPathMatchesLinkAndTarget(t *testing.T) {
+	m := testManager()
+	if n := m.RemoveByPath("linux", "~/.a"); n != 1 {
+		t.Fatalf("按链接文件移除了 %d 条记录，期望 1", n)
+	}
+	if n := m.RemoveByPath("linux", "~/dotfiles/b"); n != 1 {
+		t.Fatalf("按真实文件移除了 %d 条记录，期望 1", n)
+	}
+	if _, ok := m.Data["linux"]["laptop"]; ok {
+		t.Fatalf("设备 laptop 已没有记录但仍保留：%v", m.Data["linux"])
+	}
+	if _, ok := m.Data["windows"]["laptop"]; !ok {
+		t.Fatal("其他平台的记录不应被移除")
+	}
+}
+
+func TestRemoveByPathPrunesHardlinkGroups(t *testing.T) {
+	m := testManager()
+	if n := m.RemoveByPath("linux", "/data/s"); n != 1 {
+		t.Fatalf("移除了 %d 条记录，期望整组硬链接 1 条", n)
+	}
+	if _, ok := m.Data["linux"]["desktop"]; ok {
+		t.Fatalf("设备 desktop 已没有记录但仍保留：%v", m.Data["linux"])
+	}
+	m.RemoveByPath("linux", "~/.a")
+	m.RemoveByPath("linux", "~/.b")
+	if _, ok := m.Data["linux"]; ok {
+		t.Fatalf("平台 linux 已没有记录但仍保留：%v", m.Data["linux"])
+	}
+}
+
+func TestRemoveByPathNoMatch(t *testing.T) {
+	m := testManager()
+	if n := m.RemoveByPath("linux", "~/.missing"); n != 0 {
+		t.Fatalf("移除了 %d 条记录，期望 0", n)
+	}
+	if len(m.Data["linux"]["laptop"]["symlink"]["~"]) != 2 || len(m.Data["linux"]["desktop"]) != 1 {
+		t.Fatalf("没有匹配时不应修改存储：%v", m.Data["linux"])
+	}
+}
@@ -2,12 +2,19 @@ package store
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"time"
 
+	"github.com/jy-eggroll/flk/internal/interference"
 	"github.com/jy-eggroll/flk/internal/logger"
 	"github.com/jy-eggroll/flk/internal/pathutil"
+	"github.com/jy-eggroll/flk/internal/trace"
+	"github.com/jy-eggroll/flk/internal/version"
 )
 
 // BaseEntry 用于承载通用的 JSON 序列化逻辑
@@ -17,8 +24,160 @@ type TypeGroup map[string]PathGroup    // 定义 TypeGroup 类型，按链接类
 type DeviceGroup map[string]TypeGroup  // 定义 DeviceGroup 类型，按设备标识字符串为键，存储对应设备下的多个 TypeGroup 实例
 type RootConfig map[string]DeviceGroup // 定义 RootConfig 类型，按操作系统平台字符串为键，存储对应平台下的多个 DeviceGroup 实例
 
+// SourceKey 是条目中记录来源的字段名，取值如 cli、apply:<清单路径>、import:stow、web
+const SourceKey = "source"
+
+// SourceCLI 表示条目由命令行直接创建
+const SourceCLI = "cli"
+
+// ModeKey 是条目中声明的目标文件权限（八进制，如 600）的字段名
+const ModeKey = "mode"
+
+// FrequencyKey 是条目中声明的检查频率（如 1h、7d）的字段名，未声明时每次检查都会检查该条目
+const FrequencyKey = "frequency"
+
+// AttribKey 是条目中声明的链接自身 Windows 文件属性（如 hidden,system）的字段名
+const AttribKey = "attrib"
+
+// LinkStyleKey 是符号链接条目中声明的链接写法（relative 或 absolute）的字段名，修复时按同样的写法重建
+const LinkStyleKey = "link_style"
+
+// InodeKey 是硬链接条目中创建时记录的文件标识的字段名，用于识别被编辑器保存断开的硬链接
+const InodeKey = "inode"
+
+// RootKey 是条目中源文件所在源根目录名称的字段名，存在时源文件路径相对于本机配置的该源根目录
+const RootKey = "root"
+
+// QuarantineKey 是条目中记录创建链接前被移入隔离区的原文件路径的字段名，移除链接时据此恢复
+const QuarantineKey = "quarantine"
+
+// CreatedDirKey 是条目中记录为创建链接而新建的最上层目录的字段名，移除链接时一并清理
+const CreatedDirKey = "created_dir"
+
+// ValidateKey 是条目中声明的真实文件校验规格（如 nonempty,json）的字段名，修复时先校验真实文件再重建链接
+const ValidateKey = "validate"
+
+// NoteKey 是条目中记录的备注（如为什么这个链接指向非常规位置）的字段名，随检查结果一起显示
+const NoteKey = "note"
+
+// CreatedAtKey 是条目首次记录时间（RFC 3339）的字段名，早期版本写入的条目没有该字段
+const CreatedAtKey = "created_at"
+
+// UpdatedAtKey 是条目最近一次写入时间的字段名，修复等重新写入条目时更新
+const UpdatedAtKey = "updated_at"
+
+// CreatedByHostKey 是条目首次记录时所在主机名的字段名
+const CreatedByHostKey = "created_by_host"
+
+// VersionKey 是最近一次写入条目的 flk 版本的字段名
+const VersionKey = "flk_version"
+
+// SecoKey 是硬链接条目中次要文件路径的字段名，一组硬链接的其余次要文件依次记录在 seco.1、seco.2 等字段中
+const SecoKey = "seco"
+
+// SecoKeyAt 返回一组硬链接中第 i 个次要文件的字段名
+func SecoKeyAt(i int) string {
+	if i == 0 {
+		return SecoKey
+	}
+	return SecoKey + "." + strconv.Itoa(i)
+}
+
+// SecoPaths 返回硬链接条目中记录的全部次要文件路径
+func SecoPaths(e Entry) []string {
+	var secos []string
+	for i := 0; ; i++ {
+		seco, ok := e[SecoKeyAt(i)]
+		if !ok {
+			return secos
+		}
+		secos = append(secos, seco)
+	}
+}
+
+// metadataKeys 中的字段不是路径，写入时不做路径折叠
+var metadataKeys = map[string]bool{
+	SourceKey:        true,
+	ModeKey:          true,
+	FrequencyKey:     true,
+	AttribKey:        true,
+	LinkStyleKey:     true,
+	InodeKey:         true,
+	RootKey:          true,
+	ValidateKey:      true,
+	NoteKey:          true,
+	CreatedAtKey:     true,
+	UpdatedAtKey:     true,
+	CreatedByHostKey: true,
+	VersionKey:       true,
+}
+
+// stampKeys 由 AddRecord 自动维护的时间和来源字段
+var stampKeys = map[string]bool{
+	CreatedAtKey:     true,
+	UpdatedAtKey:     true,
+	CreatedByHostKey: true,
+	VersionKey:       true,
+}
+
+// IsStampKey 判断字段是否为 AddRecord 自动维护的时间和来源字段，比较两条记录是否相同时应忽略这些字段
+func IsStampKey(key string) bool {
+	return stampKeys[key]
+}
+
+// EqualIgnoringStamps 判断两个条目在忽略时间和来源字段后是否相同
+func EqualIgnoringStamps(a, b Entry) bool {
+	for k, v := range a {
+		if !stampKeys[k] && b[k] != v {
+			return false
+		}
+	}
+	for k, v := range b {
+		if !stampKeys[k] && a[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// KeepOrigin 将被替换的旧条目 old 的首次记录时间和主机复制到新条目 fields 中，使修复、重新应用等不改变记录的来历
+func KeepOrigin(fields map[string]string, old Entry) {
+	for _, k := range []string{CreatedAtKey, CreatedByHostKey} {
+		if v := old[k]; v != "" && fields[k] == "" {
+			fields[k] = v
+		}
+	}
+}
+
+// stamp 填充条目的时间和来源字段：已有的首次记录时间和主机保留，否则记为当前时间和本机；更新时间和版本总是改为当前值
+func stamp(e Entry) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	if e[CreatedAtKey] == "" {
+		e[CreatedAtKey] = now
+	}
+	if e[CreatedByHostKey] == "" {
+		if host, err := os.Hostname(); err == nil {
+			e[CreatedByHostKey] = host
+		}
+	}
+	e[UpdatedAtKey] = now
+	e[VersionKey] = version.String()
+}
+
+// IsMetadataKey 判断字段是否为元数据（来源、权限、频率等）而非路径
+func IsMetadataKey(key string) bool {
+	return metadataKeys[key]
+}
+
 type Manager struct { // 定义 Manager 结构体，作为存储数据的核心管理对象
 	Data RootConfig // Manager 的核心数据字段，存储按平台-设备-类型-路径层级组织的所有 Entry 数据
+
+	// base 加载时存储文件内容的摘要，用于判断待写入的更改能否安全地写回
+	base string
+	// pendingBlocked 为 true 时存在未能自动应用的待写入文件，不能再覆盖它
+	pendingBlocked bool
+	// dryRunBase --dry-run 模式下上一次保存时的条目，后续保存只报告此后的变化
+	dryRunBase map[string][]string
 }
 
 func (m *Manager) AddRecord(device, linkType, parentPath string, fields map[string]string) { // 定义 Manager 的 AddRecord 方法，用于添加一条存储记录，参数依次为设备标识、链接类型、父路径、字段键值对
@@ -32,23 +191,17 @@ func (m *Manager) AddRecord(device, linkType, parentPath string, fields map[stri
 		m.Data[platform][device] = make(TypeGroup) // 初始化 TypeGroup 类型的映射，保证层级数据结构的完整性
 	}
 
-	foldedParent, err := pathutil.FoldHome(parentPath)
-	if err != nil {
-		logger.Error("未能折叠路径 " + err.Error())
-	}
+	foldedParent := CanonicalPath(parentPath, "")
 	if m.Data[platform][device][linkType] == nil { // 检查当前链接类型对应的 PathGroup 是否未初始化（nil）
 		m.Data[platform][device][linkType] = make(PathGroup) // 初始化 PathGroup 类型的映射，确保路径层级可正常存储数据
 	}
 
-	// 处理内部字段的路径折叠
+	// 路径字段按规范形式写入，相对路径按父路径解析
 	processedEntry := make(Entry) // 初始化 Entry 类型的映射，用于存储处理后的字段键值对
-	for k, v := range fields {    // 遍历传入的原始字段键值对，k 为字段名，v 为字段原始值
-		foldedPath, err := pathutil.FoldHome(v)
-		if err != nil {
-			logger.Error("未能折叠路径 " + err.Error())
-		}
-		processedEntry[k] = foldedPath // 对每个字段值执行路径简化处理，将结果存入 processedEntry
+	for k := range fields {
+		processedEntry[k] = canonicalField(fields, k, parentPath)
 	}
+	stamp(processedEntry)
 
 	m.Data[platform][device][linkType][foldedParent] = append( // 调用 append 函数，将处理后的 Entry 添加到对应层级的切片中
 		m.Data[platform][device][linkType][foldedParent], // 目标切片：当前平台-设备-类型-简化路径对应的 Entry 切片
@@ -63,21 +216,129 @@ func (m *Manager) ToJSON() string {
 	return string(jsonResult)
 }
 
+// RemoveMatchingEntry 移除父路径下第一个包含 entry 中所有字段的条目，等同于忽略返回值的 RemoveRecord
 func (m *Manager) RemoveMatchingEntry(platform, device, linkType, parentPath string, entry Entry) {
-	entries := m.Data[platform][device][linkType][parentPath]
-	for i, e := range entries {
-		match := true
-		for k, v := range entry {
-			if e[k] != v {
-				match = false
-				break
+	m.RemoveRecord(platform, device, linkType, parentPath, entry)
+}
+
+// FindEntry 在指定平台、设备、类型下查找包含 match 中所有字段的条目，返回其所在的父路径
+func (m *Manager) FindEntry(platform, device, linkType string, match Entry) (string, Entry, bool) {
+	for parentPath, entries := range m.Data[platform][device][linkType] {
+		for _, e := range entries {
+			if entryMatches(e, match) {
+				return parentPath, e, true
+			}
+		}
+	}
+	return "", nil, false
+}
+
+// entryMatches 判断条目 e 是否包含 match 中的所有字段且取值相同
+func entryMatches(e, match Entry) bool {
+	for k, v := range match {
+		if e[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// RenameDevice 将所有平台下设备 from 的记录移到设备 to 下，to 已存在时合并，返回移动的条目数
+func (m *Manager) RenameDevice(from, to string) int {
+	moved := 0
+	for _, devices := range m.Data {
+		types, ok := devices[from]
+		if !ok {
+			continue
+		}
+		if devices[to] == nil {
+			devices[to] = make(TypeGroup)
+		}
+		for linkType, paths := range types {
+			if devices[to][linkType] == nil {
+				devices[to][linkType] = make(PathGroup)
+			}
+			for parentPath, entries := range paths {
+				devices[to][linkType][parentPath] = append(devices[to][linkType][parentPath], entries...)
+				moved += len(entries)
 			}
 		}
-		if match {
-			m.Data[platform][device][linkType][parentPath] = append(entries[:i], entries[i+1:]...)
-			break
+		delete(devices, from)
+	}
+	return moved
+}
+
+// DeviceCounts 返回各设备在各平台下的条目数，键依次为设备名称和平台
+func (m *Manager) DeviceCounts() map[string]map[string]int {
+	counts := make(map[string]map[string]int)
+	for platform, devices := range m.Data {
+		for device, types := range devices {
+			if counts[device] == nil {
+				counts[device] = make(map[string]int)
+			}
+			for _, paths := range types {
+				for _, entries := range paths {
+					counts[device][platform] += len(entries)
+				}
+			}
 		}
 	}
+	return counts
+}
+
+// LinkPath 返回条目中链接文件的路径：符号链接和模板为 fake，硬链接为第一个次要文件
+func LinkPath(e Entry) string {
+	if seco, ok := e[SecoKey]; ok {
+		return seco
+	}
+	return e["fake"]
+}
+
+// Migrate 将旧版本以创建时的工作目录为父路径、以相对路径记录的当前平台条目改写为绝对路径，
+// 并按链接文件所在目录重新分组，使记录不再依赖创建时的工作目录；返回是否有改动
+func (m *Manager) Migrate() bool {
+	changed := false
+	for _, types := range m.Data[runtime.GOOS] {
+		for linkType, paths := range types {
+			migrated := make(PathGroup)
+			for parentPath, entries := range paths {
+				base, err := pathutil.ExpandHome(parentPath)
+				if err != nil {
+					base = parentPath
+				}
+				for _, e := range entries {
+					for k, v := range e {
+						// 记录了源根目录的源文件路径相对于源根目录，不属于迁移范围
+						if metadataKeys[k] || v == "" || (e[RootKey] != "" && (k == "real" || k == "prim")) {
+							continue
+						}
+						expanded, err := pathutil.ExpandHome(v)
+						if err != nil || filepath.IsAbs(expanded) {
+							continue
+						}
+						folded, err := pathutil.FoldHome(filepath.Join(base, expanded))
+						if err != nil {
+							continue
+						}
+						e[k] = folded
+						changed = true
+					}
+					key := parentPath
+					if link, err := pathutil.ExpandHome(LinkPath(e)); err == nil && link != "" {
+						if folded, err := pathutil.FoldHome(filepath.Dir(link)); err == nil {
+							key = folded
+						}
+					}
+					if key != parentPath {
+						changed = true
+					}
+					migrated[key] = append(migrated[key], e)
+				}
+			}
+			types[linkType] = migrated
+		}
+	}
+	return changed
 }
 
 // DefaultStorePath 指定默认的持久化存储路径（不展开 JSON 中的 ~，由写入时展开实际文件系统路径）
@@ -89,8 +350,37 @@ var StorePath = DefaultStorePath
 // GlobalManager 是全局共享的 Manager 实例，用于在启动阶段加载现有数据并在命令之间共享状态
 var GlobalManager *Manager
 
+// initErr 最近一次 InitStore 失败的原因，GlobalManager 为 nil 时由 Current 返回
+var initErr error
+
+// UnavailableError 存储文件存在但无法读取，如权限错误、挂载失效或内容损坏
+type UnavailableError struct {
+	Path string
+	Err  error
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("无法读取存储 %s：%v。请检查其所在目录的挂载和权限，或使用 --store 指定其他存储文件", e.Path, e.Err)
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// Current 返回已加载的全局存储，存储不可用时返回说明原因的错误而不是 nil
+func Current() (*Manager, error) {
+	if GlobalManager != nil {
+		return GlobalManager, nil
+	}
+	if initErr != nil {
+		return nil, initErr
+	}
+	return nil, errors.New("存储尚未初始化")
+}
+
 // InitStore 初始化全局存储，若目标文件存在则加载，否则创建一个空的存储结构
 func InitStore(storePath string) error {
+	GlobalManager, initErr = nil, nil
 	// 尝试从文件加载
 	m, err := LoadFromFile(storePath)
 	if err != nil {
@@ -98,16 +388,35 @@ func InitStore(storePath string) error {
 		if os.IsNotExist(err) {
 			m = &Manager{Data: make(RootConfig)}
 		} else {
-			return err
+			initErr = &UnavailableError{Path: storePath, Err: err}
+			return initErr
 		}
 	}
 	GlobalManager = m
+	m.applyPending(storePath)
+	if m.Migrate() {
+		logger.Info("已将存储中的相对路径记录迁移为绝对路径")
+		if err := m.Save(storePath); err != nil {
+			logger.Error("保存迁移后的存储失败 " + err.Error())
+		}
+	}
 	return nil
 }
 
-// Save 将当前 Manager 的数据持久化到指定文件路径
+// Save 将当前 Manager 的数据持久化到指定文件路径，文件被暂时锁定时改为保存到待写入文件，下次运行时写回
 func (m *Manager) Save(filePath string) error {
-	data, err := json.MarshalIndent(m.Data, "", "    ")
+	_, err := m.save(filePath)
+	return err
+}
+
+// SaveAtomic 将数据先写入存储文件所在目录的临时文件再重命名覆盖，写入中断时原存储文件保持完整；
+// 存储文件是符号链接时写入其指向的文件，不改变链接本身
+func (m *Manager) SaveAtomic(filePath string) (err error) {
+	if trace.DryRun {
+		m.planSave(filePath)
+		return nil
+	}
+	data, err := CodecFor(filePath).Marshal(m.Data)
 	if err != nil {
 		return err
 	}
@@ -115,16 +424,75 @@ func (m *Manager) Save(filePath string) error {
 	if err != nil {
 		return err
 	}
+	if resolved, err := filepath.EvalSymlinks(expanded); err == nil {
+		expanded = resolved
+	}
 	if err := os.MkdirAll(filepath.Dir(expanded), 0755); err != nil {
 		return err
 	}
-	if err := os.WriteFile(expanded, data, 0644); err != nil {
+	tmp, err := os.CreateTemp(filepath.Dir(expanded), filepath.Base(expanded)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
 		return err
 	}
+	if err := os.Rename(tmp.Name(), expanded); err != nil {
+		return err
+	}
+	m.base = digest(data)
 	return nil
 }
 
-// LoadFromFile 从指定路径加载并返回一个 Manager 实例
+// save 持久化数据，queued 为 true 表示存储文件无法写入，更改已保存到待写入文件
+func (m *Manager) save(filePath string) (queued bool, err error) {
+	if trace.DryRun {
+		m.planSave(filePath)
+		return false, nil
+	}
+	data, err := CodecFor(filePath).Marshal(m.Data)
+	if err != nil {
+		return false, err
+	}
+	expanded, err := pathutil.NormalizePath(filePath)
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(expanded), 0755); err != nil {
+		return false, err
+	}
+	if err := writeWithRetry(expanded, data); err != nil {
+		// 存储文件被杀毒软件或同步客户端暂时锁定，先保存到待写入文件，下次运行时再写回
+		if !retryable(err) || m.pendingBlocked {
+			return false, err
+		}
+		if pendErr := m.savePending(filePath); pendErr != nil {
+			return false, err
+		}
+		logger.Warn(fmt.Sprintf("存储文件暂时无法写入（%v），更改已保存到 %s，下次运行 flk 时会自动写入", interference.Explain(expanded, err), PendingPath(filePath)))
+		return true, nil
+	}
+	m.base = digest(data)
+	return false, nil
+}
+
+// LoadFromFile 从指定路径加载并返回一个 Manager 实例，文件格式按扩展名决定
 func LoadFromFile(filePath string) (*Manager, error) {
 	expanded, err := pathutil.NormalizePath(filePath)
 	if err != nil {
@@ -136,11 +504,11 @@ func LoadFromFile(filePath string) (*Manager, error) {
 	}
 	var data RootConfig
 	if len(b) > 0 {
-		if err := json.Unmarshal(b, &data); err != nil {
+		if err := CodecFor(filePath).Unmarshal(b, &data); err != nil {
 			return nil, err
 		}
 	} else {
 		data = make(RootConfig)
 	}
-	return &Manager{Data: data}, nil
+	return &Manager{Data: data, base: digest(b)}, nil
 }
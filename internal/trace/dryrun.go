@@ -0,0 +1,88 @@
+package trace
+
+import (
+	"os"
+	"time"
+)
+
+// DryRun 为 true 时修改文件系统的调用只记录为计划操作而不执行，由 --dry-run 开启
+var DryRun bool
+
+// Planned 一项因 --dry-run 未执行的操作
+type Planned struct {
+	Op   string   `json:"op"`
+	Args []string `json:"args"`
+}
+
+var planned []Planned
+
+// Plan 在 --dry-run 模式下记录一项将要执行的操作并返回 true，调用方应跳过实际执行；否则返回 false
+func Plan(op string, args ...string) bool {
+	if !DryRun {
+		return false
+	}
+	mu.Lock()
+	planned = append(planned, Planned{Op: op, Args: args})
+	mu.Unlock()
+	return true
+}
+
+// PlannedOps 返回 --dry-run 模式下按顺序记录的计划操作
+func PlannedOps() []Planned {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Planned(nil), planned...)
+}
+
+// ClearPlanned 清空已记录的计划操作，用于同一进程中执行多条命令（如 flk shell）
+func ClearPlanned() {
+	mu.Lock()
+	planned = nil
+	mu.Unlock()
+}
+
+// Do 执行 fn 表示的一项修改操作，用于没有对应包装函数的调用（如创建目录联接）：
+// --dry-run 时只记录，否则执行并在 --trace 模式下记录
+func Do(op string, fn func() error, args ...string) error {
+	if Plan(op, args...) {
+		return nil
+	}
+	start := time.Now()
+	err := fn()
+	record(op, start, err, args...)
+	journalDone(op, err, args...)
+	return err
+}
+
+// WriteFile 同 os.WriteFile
+func WriteFile(name string, data []byte, perm os.FileMode) error {
+	if Plan("write_file", name) {
+		return nil
+	}
+	start := time.Now()
+	err := writeFileJournaled(name, data, perm)
+	record("write_file", start, err, name)
+	return err
+}
+
+// Chmod 同 os.Chmod
+func Chmod(name string, mode os.FileMode) error {
+	if Plan("chmod", name, mode.String()) {
+		return nil
+	}
+	start := time.Now()
+	err := chmodJournaled(name, mode)
+	record("chmod", start, err, name, mode.String())
+	return err
+}
+
+// MkdirAll 同 os.MkdirAll
+func MkdirAll(path string, perm os.FileMode) error {
+	if Plan("mkdir_all", path) {
+		return nil
+	}
+	start := time.Now()
+	err := mkdirAllJournaled(path, perm)
+	record("mkdir_all", start, err, path)
+	return err
+}
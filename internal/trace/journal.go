@@ -0,0 +1,191 @@
+package trace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// JournalDir 非空时记录本次运行对文件系统的修改，供 flk undo 撤销；
+// 此时被删除或覆盖的普通文件和目录不会真正删除，而是移到该目录下
+var JournalDir string
+
+// Change 一项已执行的修改及撤销它所需的原状态
+type Change struct {
+	Op   string   `json:"op"`
+	Args []string `json:"args"`
+	// Backup 被删除或覆盖的文件移到的位置
+	Backup string `json:"backup,omitempty"`
+	// Target 被删除的符号链接或目录联接原来的目标
+	Target string `json:"target,omitempty"`
+	// Dir 被删除的是空目录
+	Dir bool `json:"dir,omitempty"`
+	// Mode chmod 之前的权限
+	Mode os.FileMode `json:"mode,omitempty"`
+	// Created mkdir_all 新建的目录，由浅到深
+	Created []string `json:"created,omitempty"`
+}
+
+var changes []Change
+
+// TakeChanges 返回按顺序记录的修改并清空记录，flk shell 中每条命令各自记录
+func TakeChanges() []Change {
+	mu.Lock()
+	defer mu.Unlock()
+	taken := changes
+	changes = nil
+	return taken
+}
+
+func journal(c Change) {
+	mu.Lock()
+	changes = append(changes, c)
+	mu.Unlock()
+}
+
+// backupSeq 备份文件的序号，并发的 worker 各自取得不同的序号
+var backupSeq atomic.Int64
+
+// backupPath 返回 name 在 JournalDir 中的备份位置，序号保证同名文件不会互相覆盖
+func backupPath(name string) (string, error) {
+	if err := os.MkdirAll(JournalDir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Join(JournalDir, fmt.Sprintf("%d-%s", backupSeq.Add(1), filepath.Base(name))), nil
+}
+
+// preserve 将要被删除的 name 移到 JournalDir 下，符号链接和空目录只记录原状态后删除；
+// 移动失败（如跨卷）时直接删除，该项修改将无法撤销
+func preserve(c *Change, name string, all bool) error {
+	remove := os.Remove
+	if all {
+		remove = os.RemoveAll
+	}
+	info, err := os.Lstat(name)
+	if err != nil {
+		return remove(name)
+	}
+	mode := info.Mode()
+	switch {
+	case mode&(os.ModeSymlink|os.ModeIrregular) != 0:
+		c.Target, _ = os.Readlink(name)
+		return remove(name)
+	case mode.IsDir() && !all:
+		// os.Remove 只删除空目录
+		c.Dir = true
+		return remove(name)
+	}
+	backup, err := backupPath(name)
+	if err == nil {
+		if err = os.Rename(name, backup); err == nil {
+			c.Backup = backup
+			return nil
+		}
+	}
+	return remove(name)
+}
+
+// removeJournaled 执行 remove（all 为 true 时为 remove_all）并记录撤销所需的原状态
+func removeJournaled(name string, all bool) error {
+	op := "remove"
+	if all {
+		op = "remove_all"
+	}
+	if JournalDir == "" {
+		if all {
+			return os.RemoveAll(name)
+		}
+		return os.Remove(name)
+	}
+	_, statErr := os.Lstat(name)
+	c := Change{Op: op, Args: []string{name}}
+	err := preserve(&c, name, all)
+	if err == nil && statErr == nil {
+		journal(c)
+	}
+	return err
+}
+
+// renameJournaled 执行重命名，newpath 已存在时先将其备份
+func renameJournaled(oldpath, newpath string) error {
+	if JournalDir == "" {
+		return os.Rename(oldpath, newpath)
+	}
+	c := Change{Op: "rename", Args: []string{oldpath, newpath}}
+	if info, err := os.Lstat(newpath); err == nil && !info.IsDir() {
+		if backup, err := backupPath(newpath); err == nil && copyFile(newpath, backup) == nil {
+			c.Backup = backup
+		}
+	}
+	err := os.Rename(oldpath, newpath)
+	if err == nil {
+		journal(c)
+	}
+	return err
+}
+
+// writeFileJournaled 写入文件，文件已存在时先备份原内容
+func writeFileJournaled(name string, data []byte, perm os.FileMode) error {
+	if JournalDir == "" {
+		return os.WriteFile(name, data, perm)
+	}
+	c := Change{Op: "write_file", Args: []string{name}}
+	if info, err := os.Lstat(name); err == nil && info.Mode().IsRegular() {
+		if backup, err := backupPath(name); err == nil && copyFile(name, backup) == nil {
+			c.Backup = backup
+		}
+	}
+	err := os.WriteFile(name, data, perm)
+	if err == nil {
+		journal(c)
+	}
+	return err
+}
+
+// chmodJournaled 修改权限并记录原权限
+func chmodJournaled(name string, mode os.FileMode) error {
+	info, statErr := os.Stat(name)
+	err := os.Chmod(name, mode)
+	if err == nil && JournalDir != "" && statErr == nil {
+		journal(Change{Op: "chmod", Args: []string{name, mode.String()}, Mode: info.Mode().Perm()})
+	}
+	return err
+}
+
+// mkdirAllJournaled 创建目录并记录其中新建的各级目录
+func mkdirAllJournaled(path string, perm os.FileMode) error {
+	var created []string
+	if JournalDir != "" {
+		for p := filepath.Clean(path); ; p = filepath.Dir(p) {
+			if _, err := os.Lstat(p); err == nil || filepath.Dir(p) == p {
+				break
+			}
+			created = append([]string{p}, created...)
+		}
+	}
+	err := os.MkdirAll(path, perm)
+	if err == nil && len(created) > 0 {
+		journal(Change{Op: "mkdir_all", Args: []string{path}, Created: created})
+	}
+	return err
+}
+
+// journalDone 记录一项无需额外原状态即可撤销的修改，如新建链接
+func journalDone(op string, err error, args ...string) {
+	if err == nil && JournalDir != "" {
+		journal(Change{Op: op, Args: args})
+	}
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, info.Mode().Perm())
+}
@@ -0,0 +1,158 @@
+// Package trace 包装链接相关的文件系统调用，在 --trace 模式下记录每次调用的参数、结果和耗时，
+// 便于排查链接在不同文件系统（如 NAS）上表现不一致的原因；在 --dry-run 模式下修改文件系统的调用只记录不执行；
+// 设置 JournalDir 后还会记录已执行的修改及撤销所需的原状态，供 flk undo 使用
+package trace
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/logger"
+)
+
+// Enabled 为 true 时记录每次文件系统调用
+var Enabled bool
+
+var (
+	mu   sync.Mutex
+	file *os.File
+)
+
+// Event 一次文件系统调用的记录
+type Event struct {
+	Time     time.Time `json:"time"`
+	Op       string    `json:"op"`
+	Args     []string  `json:"args"`
+	Result   string    `json:"result"`
+	Duration int64     `json:"duration_us"`
+}
+
+// LastTracePath 最近一次跟踪记录的文件名，位于存储文件所在目录
+const LastTracePath = "last-trace.jsonl"
+
+// Start 开启跟踪，并将记录以 JSON Lines 格式写入 path（覆盖上一次的记录）
+func Start(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	file = f
+	mu.Unlock()
+	Enabled = true
+	return nil
+}
+
+// Stop 关闭跟踪文件
+func Stop() {
+	mu.Lock()
+	defer mu.Unlock()
+	if file != nil {
+		file.Close()
+		file = nil
+	}
+	Enabled = false
+}
+
+func record(op string, start time.Time, err error, args ...string) {
+	if !Enabled {
+		return
+	}
+	e := Event{Time: start, Op: op, Args: args, Result: "ok", Duration: time.Since(start).Microseconds()}
+	if err != nil {
+		e.Result = err.Error()
+	}
+	logger.Debug("trace "+op, "args", e.Args, "result", e.Result, "duration_us", e.Duration)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if file == nil {
+		return
+	}
+	if data, err := json.Marshal(e); err == nil {
+		file.Write(append(data, '\n'))
+	}
+}
+
+// Lstat 同 os.Lstat
+func Lstat(name string) (os.FileInfo, error) {
+	start := time.Now()
+	info, err := os.Lstat(name)
+	record("lstat", start, err, name)
+	return info, err
+}
+
+// Stat 同 os.Stat
+func Stat(name string) (os.FileInfo, error) {
+	start := time.Now()
+	info, err := os.Stat(name)
+	record("stat", start, err, name)
+	return info, err
+}
+
+// Readlink 同 os.Readlink，记录中包含读到的目标
+func Readlink(name string) (string, error) {
+	start := time.Now()
+	target, err := os.Readlink(name)
+	record("readlink", start, err, name, target)
+	return target, err
+}
+
+// Symlink 同 os.Symlink
+func Symlink(oldname, newname string) error {
+	if Plan("symlink", oldname, newname) {
+		return nil
+	}
+	start := time.Now()
+	err := os.Symlink(oldname, newname)
+	record("symlink", start, err, oldname, newname)
+	journalDone("symlink", err, oldname, newname)
+	return err
+}
+
+// Link 同 os.Link
+func Link(oldname, newname string) error {
+	if Plan("link", oldname, newname) {
+		return nil
+	}
+	start := time.Now()
+	err := os.Link(oldname, newname)
+	record("link", start, err, oldname, newname)
+	journalDone("link", err, oldname, newname)
+	return err
+}
+
+// Rename 同 os.Rename
+func Rename(oldpath, newpath string) error {
+	if Plan("rename", oldpath, newpath) {
+		return nil
+	}
+	start := time.Now()
+	err := renameJournaled(oldpath, newpath)
+	record("rename", start, err, oldpath, newpath)
+	return err
+}
+
+// Remove 同 os.Remove
+func Remove(name string) error {
+	if Plan("remove", name) {
+		return nil
+	}
+	start := time.Now()
+	err := removeJournaled(name, false)
+	record("remove", start, err, name)
+	return err
+}
+
+// RemoveAll 同 os.RemoveAll
+func RemoveAll(path string) error {
+	if Plan("remove_all", path) {
+		return nil
+	}
+	start := time.Now()
+	err := removeJournaled(path, true)
+	record("remove_all", start, err, path)
+	return err
+}
@@ -0,0 +1,173 @@
+// Package trash 保存 --force 覆盖前被移走的文件，每个文件放在以删除时间命名的子目录中，可以恢复到原位置
+package trash
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jy-eggroll/flk/internal/trace"
+)
+
+// DefaultDir 默认的回收站目录
+const DefaultDir = "~/.local/share/flk/trash"
+
+// infoFile 每个子目录中记录原位置的文件
+const infoFile = "info.json"
+
+// idLayout 子目录名使用的时间格式，精确到纳秒以免同一秒内删除的文件互相覆盖
+const idLayout = "20060102-150405.000000000"
+
+// Item 回收站中的一个文件
+type Item struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"`
+	DeletedAt time.Time `json:"deleted_at"`
+	// Name 文件在子目录中的名称，即原文件名
+	Name string `json:"name"`
+}
+
+// Move 将 path 移到回收站 dir 中，恢复时移回 origin（path 是 origin 的临时备份时两者不同）；
+// 跨卷无法直接移动时先复制再删除。修改经过 trace 包，因此 --dry-run 时不执行，也会被操作日志记录
+func Move(dir, path, origin string) (Item, error) {
+	now := time.Now()
+	item := Item{ID: now.Format(idLayout), Path: origin, DeletedAt: now, Name: filepath.Base(origin)}
+	sub := filepath.Join(dir, item.ID)
+	if err := trace.MkdirAll(sub, 0755); err != nil {
+		return item, err
+	}
+	dest := filepath.Join(sub, item.Name)
+	if err := trace.Rename(path, dest); err != nil {
+		if err := copyTree(path, dest); err != nil {
+			return item, fmt.Errorf("无法移到回收站 %s: %v", dest, err)
+		}
+		if err := trace.RemoveAll(path); err != nil {
+			return item, err
+		}
+	}
+	if trace.DryRun {
+		return item, nil
+	}
+	data, err := json.MarshalIndent(item, "", "    ")
+	if err != nil {
+		return item, err
+	}
+	return item, os.WriteFile(filepath.Join(sub, infoFile), data, 0644)
+}
+
+// List 返回回收站中的全部文件，按删除时间先后排列；文件已不在回收站中的子目录会被忽略
+func List(dir string) ([]Item, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var items []Item
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		item, err := read(dir, e.Name())
+		if err != nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].DeletedAt.Before(items[j].DeletedAt) })
+	return items, nil
+}
+
+func read(dir, id string) (Item, error) {
+	var item Item
+	data, err := os.ReadFile(filepath.Join(dir, id, infoFile))
+	if err != nil {
+		return item, err
+	}
+	if err := json.Unmarshal(data, &item); err != nil {
+		return item, err
+	}
+	item.ID = id
+	if _, err := os.Lstat(filepath.Join(dir, id, item.Name)); err != nil {
+		return item, err
+	}
+	return item, nil
+}
+
+// Restore 将回收站中的 id 移回原位置，原位置已存在文件时返回错误
+func Restore(dir, id string) (Item, error) {
+	item, err := read(dir, id)
+	if err != nil {
+		return item, fmt.Errorf("回收站中没有 %s", id)
+	}
+	if _, err := os.Lstat(item.Path); err == nil {
+		return item, fmt.Errorf("%s 已存在，请先移走它", item.Path)
+	}
+	if err := os.MkdirAll(filepath.Dir(item.Path), 0755); err != nil {
+		return item, err
+	}
+	src := filepath.Join(dir, id, item.Name)
+	if err := os.Rename(src, item.Path); err != nil {
+		if err := copyTree(src, item.Path); err != nil {
+			return item, err
+		}
+	}
+	return item, os.RemoveAll(filepath.Join(dir, id))
+}
+
+// Empty 删除回收站中早于 before 的文件，before 为零值时全部删除，返回删除的数量
+func Empty(dir string, before time.Time) (int, error) {
+	items, err := List(dir)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, item := range items {
+		if !before.IsZero() && !item.DeletedAt.Before(before) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, item.ID)); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// copyTree 复制文件、符号链接或整个目录，保留权限
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+}
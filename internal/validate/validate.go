@@ -0,0 +1,123 @@
+// Package validate 在创建或修复链接前校验真实文件，避免把写了一半或已损坏的文件链接到正在使用的配置路径
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// 内置校验器名称
+const (
+	NonEmpty = "nonempty" // 文件大小大于 0
+	JSON     = "json"     // 内容可被解析为 JSON
+	TOML     = "toml"     // 内容可被解析为 TOML
+	YAML     = "yaml"     // 内容可被解析为 YAML
+)
+
+// cmdPrefix 自定义命令校验器的前缀，如 cmd:jq -e . "$FLK_FILE"
+const cmdPrefix = "cmd:"
+
+// FileEnv 运行自定义命令时保存被校验文件路径的环境变量
+const FileEnv = "FLK_FILE"
+
+// Parse 解析校验规格，多个校验器以逗号分隔，自定义命令（cmd:）必须放在最后，其中可以包含逗号
+func Parse(spec string) ([]string, error) {
+	var validators []string
+	rest := strings.TrimSpace(spec)
+	for rest != "" {
+		if strings.HasPrefix(rest, cmdPrefix) {
+			if strings.TrimSpace(rest[len(cmdPrefix):]) == "" {
+				return nil, fmt.Errorf("校验器 cmd: 缺少命令")
+			}
+			validators = append(validators, rest)
+			break
+		}
+		name, tail, _ := strings.Cut(rest, ",")
+		name = strings.TrimSpace(name)
+		switch name {
+		case NonEmpty, JSON, TOML, YAML:
+			validators = append(validators, name)
+		case "":
+		default:
+			return nil, fmt.Errorf("未知的校验器 %s，可选 nonempty、json、toml、yaml、cmd:<命令>", name)
+		}
+		rest = strings.TrimSpace(tail)
+	}
+	return validators, nil
+}
+
+// File 按规格依次校验文件，返回第一个未通过的校验器的错误
+func File(path, spec string) error {
+	validators, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	for _, v := range validators {
+		if err := run(path, v); err != nil {
+			return fmt.Errorf("%s 未通过校验 %s：%v", path, v, err)
+		}
+	}
+	return nil
+}
+
+func run(path, validator string) error {
+	if command, ok := strings.CutPrefix(validator, cmdPrefix); ok {
+		return runCommand(path, command)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		// 目录没有可校验的内容
+		return nil
+	}
+	if validator == NonEmpty {
+		if info.Size() == 0 {
+			return fmt.Errorf("文件为空")
+		}
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var v any
+	switch validator {
+	case JSON:
+		return json.Unmarshal(data, &v)
+	case TOML:
+		return toml.Unmarshal(data, &v)
+	case YAML:
+		return yaml.Unmarshal(data, &v)
+	}
+	return nil
+}
+
+// runCommand 通过系统 shell 执行自定义校验命令，退出码非 0 视为未通过
+func runCommand(path, command string) error {
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.Command("cmd", "/C", command)
+	} else {
+		c = exec.Command("sh", "-c", command)
+	}
+	c.Env = append(os.Environ(), FileEnv+"="+path)
+	var stderr bytes.Buffer
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%v：%s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
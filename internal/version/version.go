@@ -0,0 +1,15 @@
+// Package version 提供当前 flk 的版本号
+package version
+
+import "runtime/debug"
+
+// Devel 无法从构建信息得到版本号时（如本地 go build）使用的版本
+const Devel = "(devel)"
+
+// String 返回构建信息中记录的模块版本，没有时返回 Devel
+func String() string {
+	if bi, ok := debug.ReadBuildInfo(); ok && bi.Main.Version != "" {
+		return bi.Main.Version
+	}
+	return Devel
+}
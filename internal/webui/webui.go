@@ -0,0 +1,11 @@
+// Package webui 内嵌 flk server 提供的网页界面，界面只通过 /api 下的接口操作记录
+package webui
+
+import (
+	_ "embed"
+)
+
+// Index 网页界面的唯一页面
+//
+//go:embed index.html
+var Index []byte
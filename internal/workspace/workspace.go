@@ -0,0 +1,75 @@
+// Package workspace 从当前目录向上查找工作区标记文件，工作区所在目录作为源根目录
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MarkerNames 工作区标记文件名，同一目录下存在多个时按此顺序取第一个
+var MarkerNames = []string{".flk.yaml", ".flk.yml", ".flk.json"}
+
+// Marker 工作区标记文件的内容，所有字段均可省略
+type Marker struct {
+	// Root 工作区作为源根目录时的名称，默认为工作区目录名
+	Root string `json:"root,omitempty" yaml:"root,omitempty"`
+	// Device 在工作区内创建链接时的默认设备名称
+	Device string `json:"device,omitempty" yaml:"device,omitempty"`
+	// Strict 为 true 时在工作区内启用严格模式
+	Strict bool `json:"strict,omitempty" yaml:"strict,omitempty"`
+}
+
+// Workspace 找到的工作区
+type Workspace struct {
+	Marker
+	Dir  string // 工作区根目录的绝对路径
+	File string // 标记文件的绝对路径
+}
+
+// Find 从 start 开始逐级向上查找标记文件，找不到时返回 nil
+func Find(start string) (*Workspace, error) {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		for _, name := range MarkerNames {
+			file := filepath.Join(dir, name)
+			data, err := os.ReadFile(file)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			ws := &Workspace{Dir: dir, File: file}
+			if err := unmarshal(name, data, &ws.Marker); err != nil {
+				return nil, fmt.Errorf("解析工作区标记 %s 失败: %v", file, err)
+			}
+			if ws.Root == "" {
+				ws.Root = filepath.Base(dir)
+			}
+			return ws, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+func unmarshal(name string, data []byte, m *Marker) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if filepath.Ext(name) == ".json" {
+		return json.Unmarshal(data, m)
+	}
+	return yaml.Unmarshal(data, m)
+}